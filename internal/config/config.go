@@ -2,7 +2,9 @@ package config
 
 import (
 	"fmt"
+	"net/url"
 	"os"
+	"strconv"
 	"strings"
 	"time"
 
@@ -26,27 +28,120 @@ type Config struct {
 		Name     string
 	}
 	JWT struct {
-		Secret string
+		Secret              string
+		SigningAlgorithm    string
+		KeyEncryptionKey    string
+		KeyRotationInterval time.Duration
+		KeyGracePeriod      time.Duration
+		KeyPrepublishPeriod time.Duration
+		TokenPairMode       bool
+		AccessTokenTTL      time.Duration
+		RefreshTokenTTL     time.Duration
+	}
+	Session struct {
+		IdleTimeout      time.Duration
+		AbsoluteLifetime time.Duration
+	}
+	EmailVerification struct {
+		Policy string
+	}
+	Registration struct {
+		InviteOnly bool
+	}
+	Password struct {
+		Argon2Memory      int
+		Argon2Iterations  int
+		Argon2Parallelism int
+	}
+	Lockout struct {
+		BackoffThreshold int
+		BackoffBase      time.Duration
+		BackoffMax       time.Duration
+		Threshold        int
+		Duration         time.Duration
+	}
+	Admin struct {
+		Emails []string
+	}
+	MFA struct {
+		Issuer        string
+		EncryptionKey string
+	}
+	WebAuthn struct {
+		RPID          string
+		RPDisplayName string
+		RPOrigins     []string
 	}
 	OAuth struct {
-		Google struct {
-			ClientID     string
-			ClientSecret string
-			RedirectURL  string
-		}
+		Providers        []OAuthProvider
+		StateKey         string
+		AllowedRedirects []string
+	}
+	OAuthServer struct {
+		ConsentURL      string
+		AccessTokenTTL  time.Duration
+		RefreshTokenTTL time.Duration
+		IDTokenTTL      time.Duration
+		CodeTTL         time.Duration
+	}
+	PersonalAccessToken struct {
+		MaxLifetime time.Duration
+	}
+	Impersonation struct {
+		TTL time.Duration
+	}
+	Webhook struct {
+		EncryptionKey        string
+		Timeout              time.Duration
+		MaxAttempts          int
+		BackoffBase          time.Duration
+		BackoffMax           time.Duration
+		AllowPrivateNetworks bool
+	}
+	Outbox struct {
+		MaxAttempts int
+		BackoffBase time.Duration
+		BackoffMax  time.Duration
+		Retention   time.Duration
 	}
 	CORS struct {
 		AllowedOrigins []string
 	}
 	Email struct {
-		Host     string
-		Port     string
-		Username string
-		Password string
-		From     string
+		// Transport is smtp, file or log
+		Transport   string
+		Host        string
+		Port        string
+		Username    string
+		Password    string
+		From        string
+		TLS         string
+		Timeout     time.Duration
+		PoolSize    int
+		IdleTimeout time.Duration
+		FileDir     string
+		Workers     int
+		MaxAttempts int
+		BackoffBase time.Duration
+		BackoffMax  time.Duration
+		Retention   time.Duration
+		// TemplateDirs override the built-in email templates file by file
+		TemplateDirs  []string
+		DefaultLocale string
 	}
 }
 
+// OAuthProvider configures an OpenID Connect identity provider. Endpoints and
+// signing keys are found through the issuer's discovery document.
+type OAuthProvider struct {
+	Name         string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+}
+
 func Load() (*Config, error) {
 	if err := godotenv.Load(); err != nil {
 		return nil, fmt.Errorf("error loading .env file: %w", err)
@@ -69,21 +164,161 @@ func Load() (*Config, error) {
 
 	// JWT
 	cfg.JWT.Secret = os.Getenv("JWT_SECRET")
+	cfg.JWT.TokenPairMode = os.Getenv("AUTH_TOKEN_MODE") == "token_pair"
+	cfg.JWT.AccessTokenTTL = getDuration("JWT_ACCESS_TOKEN_TTL", 15*time.Minute)
+	cfg.JWT.RefreshTokenTTL = getDuration("JWT_REFRESH_TOKEN_TTL", 30*24*time.Hour)
+	cfg.JWT.SigningAlgorithm = getEnv("JWT_SIGNING_ALGORITHM", "RS256")
+	// Encrypts the stored signing keys; deliberately separate from JWT_SECRET
+	keyEncryptionKey, err := getKey("JWT_KEY_ENCRYPTION_KEY")
+	if err != nil {
+		return nil, err
+	}
+	if keyEncryptionKey == cfg.JWT.Secret {
+		return nil, fmt.Errorf("JWT_KEY_ENCRYPTION_KEY must differ from JWT_SECRET")
+	}
+	cfg.JWT.KeyEncryptionKey = keyEncryptionKey
+	cfg.JWT.KeyRotationInterval = getDuration("JWT_KEY_ROTATION_INTERVAL", 30*24*time.Hour)
+	cfg.JWT.KeyGracePeriod = getDuration("JWT_KEY_GRACE_PERIOD", 24*time.Hour)
+	// Retired keys must outlive every access token they signed
+	if cfg.JWT.KeyGracePeriod < cfg.JWT.AccessTokenTTL {
+		cfg.JWT.KeyGracePeriod = cfg.JWT.AccessTokenTTL
+	}
+	// New keys are published this long before they sign, for verifiers caching the JWKS
+	cfg.JWT.KeyPrepublishPeriod = getDuration("JWT_KEY_PREPUBLISH_PERIOD", 24*time.Hour)
+	if cfg.JWT.KeyPrepublishPeriod >= cfg.JWT.KeyRotationInterval {
+		return nil, fmt.Errorf("JWT_KEY_PREPUBLISH_PERIOD must be shorter than JWT_KEY_ROTATION_INTERVAL")
+	}
+
+	// Sessions
+	cfg.Session.IdleTimeout = getDuration("SESSION_IDLE_TIMEOUT", 24*time.Hour)
+	cfg.Session.AbsoluteLifetime = getDuration("SESSION_ABSOLUTE_LIFETIME", 7*24*time.Hour)
+
+	// Email verification: optional, restricted or required
+	cfg.EmailVerification.Policy = getEnv("EMAIL_VERIFICATION_POLICY", "optional")
+
+	// Registration: open or invite_only
+	cfg.Registration.InviteOnly = os.Getenv("REGISTRATION_MODE") == "invite_only"
+
+	// Password hashing (Argon2id); existing hashes with weaker parameters are upgraded on login
+	cfg.Password.Argon2Memory = getInt("PASSWORD_ARGON2_MEMORY", 64*1024)
+	cfg.Password.Argon2Iterations = getInt("PASSWORD_ARGON2_ITERATIONS", 3)
+	cfg.Password.Argon2Parallelism = getInt("PASSWORD_ARGON2_PARALLELISM", 4)
+
+	// Failed login backoff and account lockout
+	cfg.Lockout.BackoffThreshold = getInt("LOGIN_BACKOFF_THRESHOLD", 3)
+	cfg.Lockout.BackoffBase = getDuration("LOGIN_BACKOFF_BASE", time.Second)
+	cfg.Lockout.BackoffMax = getDuration("LOGIN_BACKOFF_MAX", 5*time.Minute)
+	cfg.Lockout.Threshold = getInt("LOGIN_LOCKOUT_THRESHOLD", 10)
+	cfg.Lockout.Duration = getDuration("LOGIN_LOCKOUT_DURATION", 30*time.Minute)
 
-	// OAuth
-	cfg.OAuth.Google.ClientID = os.Getenv("GOOGLE_CLIENT_ID")
-	cfg.OAuth.Google.ClientSecret = os.Getenv("GOOGLE_CLIENT_SECRET")
-	cfg.OAuth.Google.RedirectURL = os.Getenv("GOOGLE_REDIRECT_URL")
+	// Bootstrap admin accounts, identified by email address
+	for _, email := range strings.Split(os.Getenv("ADMIN_EMAILS"), ",") {
+		if email = strings.TrimSpace(email); email != "" {
+			cfg.Admin.Emails = append(cfg.Admin.Emails, email)
+		}
+	}
+
+	// MFA
+	cfg.MFA.Issuer = os.Getenv("MFA_ISSUER")
+	mfaKey, err := getKey("MFA_ENCRYPTION_KEY")
+	if err != nil {
+		return nil, err
+	}
+	cfg.MFA.EncryptionKey = mfaKey
+
+	// WebAuthn (relying party defaults to the public base URL)
+	cfg.WebAuthn.RPID = os.Getenv("WEBAUTHN_RP_ID")
+	cfg.WebAuthn.RPDisplayName = os.Getenv("WEBAUTHN_RP_DISPLAY_NAME")
+	cfg.WebAuthn.RPOrigins = strings.Split(os.Getenv("WEBAUTHN_RP_ORIGINS"), ",")
+	if baseURL, err := url.Parse(cfg.Server.BaseURL); err == nil {
+		if cfg.WebAuthn.RPID == "" {
+			cfg.WebAuthn.RPID = baseURL.Hostname()
+		}
+		if os.Getenv("WEBAUTHN_RP_ORIGINS") == "" {
+			cfg.WebAuthn.RPOrigins = []string{baseURL.Scheme + "://" + baseURL.Host}
+		}
+	}
+	if cfg.WebAuthn.RPDisplayName == "" {
+		cfg.WebAuthn.RPDisplayName = cfg.MFA.Issuer
+	}
+
+	// OAuth / OpenID Connect providers
+	cfg.OAuth.Providers = loadOAuthProviders(cfg.Server.BaseURL)
+	// The state key is only needed once there is a provider to sign in with
+	if len(cfg.OAuth.Providers) > 0 {
+		stateKey, err := getKey("OAUTH_STATE_KEY")
+		if err != nil {
+			return nil, err
+		}
+		cfg.OAuth.StateKey = stateKey
+	}
+	for _, target := range strings.Split(os.Getenv("OAUTH_ALLOWED_REDIRECTS"), ",") {
+		if target = strings.TrimSpace(target); target != "" {
+			cfg.OAuth.AllowedRedirects = append(cfg.OAuth.AllowedRedirects, target)
+		}
+	}
+
+	// OAuth 2.0 / OpenID Connect authorization server
+	cfg.OAuthServer.ConsentURL = getEnv("OAUTH_SERVER_CONSENT_URL", cfg.Server.BaseURL+"/oauth/consent")
+	cfg.OAuthServer.AccessTokenTTL = getDuration("OAUTH_SERVER_ACCESS_TOKEN_TTL", time.Hour)
+	cfg.OAuthServer.RefreshTokenTTL = getDuration("OAUTH_SERVER_REFRESH_TOKEN_TTL", 30*24*time.Hour)
+	cfg.OAuthServer.IDTokenTTL = getDuration("OAUTH_SERVER_ID_TOKEN_TTL", time.Hour)
+	cfg.OAuthServer.CodeTTL = getDuration("OAUTH_SERVER_CODE_TTL", 5*time.Minute)
+
+	// Personal access tokens
+	cfg.PersonalAccessToken.MaxLifetime = getDuration("PERSONAL_ACCESS_TOKEN_MAX_LIFETIME", 365*24*time.Hour)
+
+	// Admin impersonation sessions
+	cfg.Impersonation.TTL = getDuration("IMPERSONATION_TTL", 15*time.Minute)
+
+	// Outbound webhooks
+	webhookKey, err := getKey("WEBHOOK_ENCRYPTION_KEY")
+	if err != nil {
+		return nil, err
+	}
+	cfg.Webhook.EncryptionKey = webhookKey
+	cfg.Webhook.Timeout = getDuration("WEBHOOK_TIMEOUT", 10*time.Second)
+	cfg.Webhook.MaxAttempts = getInt("WEBHOOK_MAX_ATTEMPTS", 10)
+	cfg.Webhook.BackoffBase = getDuration("WEBHOOK_BACKOFF_BASE", 30*time.Second)
+	cfg.Webhook.BackoffMax = getDuration("WEBHOOK_BACKOFF_MAX", 6*time.Hour)
+	cfg.Webhook.AllowPrivateNetworks = os.Getenv("WEBHOOK_ALLOW_PRIVATE_NETWORKS") == "true"
+
+	// Domain event outbox
+	cfg.Outbox.MaxAttempts = getInt("OUTBOX_MAX_ATTEMPTS", 15)
+	cfg.Outbox.BackoffBase = getDuration("OUTBOX_BACKOFF_BASE", 10*time.Second)
+	cfg.Outbox.BackoffMax = getDuration("OUTBOX_BACKOFF_MAX", time.Hour)
+	cfg.Outbox.Retention = getDuration("OUTBOX_RETENTION", 7*24*time.Hour)
 
 	// CORS
 	cfg.CORS.AllowedOrigins = strings.Split(os.Getenv("ALLOWED_ORIGINS"), ",")
 
-	// Email
+	// Email: sent through smtp, written to .eml files in EMAIL_FILE_DIR (file) or logged (log)
+	cfg.Email.Transport = getEnv("EMAIL_TRANSPORT", "smtp")
 	cfg.Email.Host = os.Getenv("EMAIL_HOST")
 	cfg.Email.Port = os.Getenv("EMAIL_PORT")
 	cfg.Email.Username = os.Getenv("EMAIL_USERNAME")
 	cfg.Email.Password = os.Getenv("EMAIL_PASSWORD")
 	cfg.Email.From = os.Getenv("EMAIL_FROM")
+	cfg.Email.TLS = os.Getenv("EMAIL_TLS")
+	cfg.Email.Timeout = getDuration("EMAIL_TIMEOUT", 10*time.Second)
+	cfg.Email.PoolSize = getInt("EMAIL_POOL_SIZE", 4)
+	cfg.Email.IdleTimeout = getDuration("EMAIL_IDLE_TIMEOUT", 30*time.Second)
+	cfg.Email.FileDir = getEnv("EMAIL_FILE_DIR", "mail")
+
+	// Email delivery queue
+	cfg.Email.Workers = getInt("EMAIL_WORKERS", 4)
+	cfg.Email.MaxAttempts = getInt("EMAIL_MAX_ATTEMPTS", 8)
+	cfg.Email.BackoffBase = getDuration("EMAIL_BACKOFF_BASE", 30*time.Second)
+	cfg.Email.BackoffMax = getDuration("EMAIL_BACKOFF_MAX", time.Hour)
+	cfg.Email.Retention = getDuration("EMAIL_RETENTION", 7*24*time.Hour)
+
+	// Email templates, searched in order before the built-in ones
+	for _, dir := range strings.Split(os.Getenv("EMAIL_TEMPLATE_DIRS"), ",") {
+		if dir = strings.TrimSpace(dir); dir != "" {
+			cfg.Email.TemplateDirs = append(cfg.Email.TemplateDirs, dir)
+		}
+	}
+	cfg.Email.DefaultLocale = getEnv("EMAIL_DEFAULT_LOCALE", "en")
 
 	// Set default timeouts
 	cfg.Server.ReadTimeout = 15 * time.Second
@@ -91,3 +326,77 @@ func Load() (*Config, error) {
 
 	return cfg, nil
 }
+
+// loadOAuthProviders reads the providers named in OAUTH_PROVIDERS, each configured by
+// OAUTH_<NAME>_ISSUER, _CLIENT_ID, _CLIENT_SECRET, _REDIRECT_URL and _SCOPES.
+// The legacy GOOGLE_* variables still register a "google" provider.
+func loadOAuthProviders(baseURL string) []OAuthProvider {
+	var providers []OAuthProvider
+
+	if clientID := os.Getenv("GOOGLE_CLIENT_ID"); clientID != "" {
+		providers = append(providers, OAuthProvider{
+			Name:         "google",
+			Issuer:       "https://accounts.google.com",
+			ClientID:     clientID,
+			ClientSecret: os.Getenv("GOOGLE_CLIENT_SECRET"),
+			RedirectURL:  getEnv("GOOGLE_REDIRECT_URL", baseURL+"/api/v1/oauth/google/callback"),
+			Scopes:       []string{"openid", "email", "profile"},
+		})
+	}
+
+	for _, name := range strings.Split(os.Getenv("OAUTH_PROVIDERS"), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		prefix := "OAUTH_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_")) + "_"
+		providers = append(providers, OAuthProvider{
+			Name:         name,
+			Issuer:       os.Getenv(prefix + "ISSUER"),
+			ClientID:     os.Getenv(prefix + "CLIENT_ID"),
+			ClientSecret: os.Getenv(prefix + "CLIENT_SECRET"),
+			RedirectURL:  getEnv(prefix+"REDIRECT_URL", baseURL+"/api/v1/oauth/"+name+"/callback"),
+			Scopes:       strings.Fields(getEnv(prefix+"SCOPES", "openid email profile")),
+		})
+	}
+
+	return providers
+}
+
+// getEnv returns the environment variable key, or def when it is unset or empty.
+func getEnv(key, def string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return def
+}
+
+// minKeyLength is the shortest encryption key accepted. Keys are hashed into
+// 256-bit AES keys, so shorter ones would only weaken them.
+const minKeyLength = 32
+
+// getKey returns the encryption key in the environment variable key, failing
+// when it is unset or shorter than minKeyLength.
+func getKey(key string) (string, error) {
+	v := os.Getenv(key)
+	if len(v) < minKeyLength {
+		return "", fmt.Errorf("%s must be set to at least %d characters", key, minKeyLength)
+	}
+	return v, nil
+}
+
+// getDuration parses a duration such as "15m" from the environment, falling back to def.
+func getDuration(key string, def time.Duration) time.Duration {
+	if d, err := time.ParseDuration(os.Getenv(key)); err == nil {
+		return d
+	}
+	return def
+}
+
+// getInt parses an integer from the environment, falling back to def.
+func getInt(key string, def int) int {
+	if n, err := strconv.Atoi(os.Getenv(key)); err == nil {
+		return n
+	}
+	return def
+}
@@ -0,0 +1,30 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGetKey(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		wantErr bool
+	}{
+		{"unset", "", true},
+		{"too short", "short-key", true},
+		{"long enough", strings.Repeat("k", minKeyLength), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("TEST_ENCRYPTION_KEY", tt.value)
+			key, err := getKey("TEST_ENCRYPTION_KEY")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v, want error %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && key != tt.value {
+				t.Errorf("got key %q, want %q", key, tt.value)
+			}
+		})
+	}
+}
@@ -4,12 +4,82 @@ import (
 	"rest-api/internal/models"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 func Migrate(db *gorm.DB) error {
-	return db.AutoMigrate(
+	err := db.AutoMigrate(
 		&models.User{},
 		&models.Session{},
 		&models.Token{},
+		&models.UserMFA{},
+		&models.MFARecoveryCode{},
+		&models.WebAuthnCredential{},
+		&models.WebAuthnCeremony{},
+		&models.SigningKey{},
+		&models.UserIdentity{},
+		&models.OAuthClient{},
+		&models.OAuthAuthorizationCode{},
+		&models.OAuthConsent{},
+		&models.OAuthRefreshToken{},
+		&models.PersonalAccessToken{},
+		&models.Permission{},
+		&models.Role{},
+		&models.UserRole{},
+		&models.Organization{},
+		&models.Membership{},
+		&models.Invitation{},
+		&models.AuditEvent{},
+		&models.WebhookEndpoint{},
+		&models.WebhookDelivery{},
+		&models.OutboxEvent{},
+		&models.OutboxReceipt{},
+		&models.EmailMessage{},
 	)
+	if err != nil {
+		return err
+	}
+
+	return seedRoles(db)
+}
+
+// seedRoles creates the permission catalog and the built-in roles. It runs on
+// every start, so permissions added in a release reach existing databases.
+func seedRoles(db *gorm.DB) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, permission := range models.Permissions {
+			err := tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "name"}},
+				DoUpdates: clause.AssignmentColumns([]string{"description"}),
+			}).Create(&permission).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		for _, def := range models.DefaultRoles {
+			err := tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "name"}},
+				DoUpdates: clause.AssignmentColumns([]string{"description", "builtin"}),
+			}).Create(&models.Role{Name: def.Name, Description: def.Description, Builtin: true}).Error
+			if err != nil {
+				return err
+			}
+
+			var role models.Role
+			if err := tx.Where("name = ?", def.Name).First(&role).Error; err != nil {
+				return err
+			}
+
+			var permissions []models.Permission
+			if err := tx.Where("name IN ?", def.Permissions).Find(&permissions).Error; err != nil {
+				return err
+			}
+			if err := tx.Model(&role).Association("Permissions").Append(permissions); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
 }
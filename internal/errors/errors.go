@@ -10,10 +10,55 @@ var (
 	ErrInvalidToken       = errors.New("invalid token")
 	ErrTokenExpired       = errors.New("token expired")
 	ErrUnauthorized       = errors.New("unauthorized")
+	ErrForbidden          = errors.New("forbidden")
 	ErrFailedToCreateUser = errors.New("failed to create user")
 	ErrFailedToSendEmail  = errors.New("failed to send email")
 	ErrInvalidEmailOrPass = errors.New("invalid email or password")
 	ErrSessionNotFound    = errors.New("session not found")
 	ErrSessionExpired     = errors.New("session expired")
 	ErrInvalidSession     = errors.New("invalid session")
+	ErrMFAAlreadyEnabled  = errors.New("mfa already enabled")
+	ErrMFANotEnabled      = errors.New("mfa not enabled")
+	ErrMFANotEnrolled     = errors.New("mfa enrollment not started")
+	ErrInvalidMFACode     = errors.New("invalid mfa code")
+	ErrInvalidPasskey     = errors.New("invalid passkey")
+	ErrPasskeyNotFound    = errors.New("passkey not found")
+	ErrInvalidCeremony    = errors.New("invalid or expired passkey ceremony")
+	ErrRefreshTokenReused = errors.New("refresh token reuse detected")
+	ErrEmailNotVerified   = errors.New("email not verified")
+	ErrEmailVerified      = errors.New("email already verified")
+	ErrTooManyRequests    = errors.New("too many requests")
+	ErrAccountLocked      = errors.New("account temporarily locked")
+	ErrProviderNotFound   = errors.New("unknown identity provider")
+	ErrIdentityNotFound   = errors.New("linked identity not found")
+	ErrIdentityInUse      = errors.New("identity is already linked to another account")
+	ErrLinkRequired       = errors.New("an account with this email already exists; sign in and link this provider from your profile")
+	ErrLastLoginMethod    = errors.New("cannot remove the last sign-in method")
+	ErrInvalidState       = errors.New("invalid or expired oauth state")
+	ErrInvalidRedirect    = errors.New("redirect target is not allowed")
+	ErrClientNotFound     = errors.New("oauth client not found")
+	ErrInsufficientScope  = errors.New("insufficient scope")
+	ErrTokenNotFound      = errors.New("access token not found")
+	ErrRoleNotFound       = errors.New("role not found")
+	ErrRoleExists         = errors.New("role already exists")
+	ErrBuiltinRole        = errors.New("built-in roles cannot be deleted")
+	ErrOrgNotFound        = errors.New("organization not found")
+	ErrSlugTaken          = errors.New("organization slug is already taken")
+	ErrNoActiveOrg        = errors.New("no active organization; switch to one first")
+	ErrInvitationNotFound = errors.New("invitation not found")
+	ErrInvitationEmail    = errors.New("this invitation was sent to a different email address")
+	ErrInvitationRequired = errors.New("registration is by invitation only")
+	ErrAlreadyMember      = errors.New("already a member of this organization")
+	ErrAccountDisabled    = errors.New("account disabled")
+	ErrMustResetPassword  = errors.New("password reset required; use the link sent to your email")
+	ErrOwnAccount         = errors.New("cannot disable or delete your own account")
+	ErrCannotImpersonate  = errors.New("cannot impersonate yourself")
+	ErrImpersonating      = errors.New("not allowed while impersonating a user")
+	ErrWebhookNotFound    = errors.New("webhook not found")
+	ErrDeliveryNotFound   = errors.New("webhook delivery not found")
+	ErrUnknownEvent       = errors.New("unknown webhook event")
+	ErrWebhookDestination = errors.New("webhook URL must point to a public address")
+	ErrMessageNotFound    = errors.New("email message not found")
+	ErrMessageNotFailed   = errors.New("email message has not failed")
+	ErrMessageExpired     = errors.New("the link in the email message has expired")
 )
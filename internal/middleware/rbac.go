@@ -0,0 +1,28 @@
+package middleware
+
+import (
+	"net/http"
+	"slices"
+
+	"github.com/gin-gonic/gin"
+)
+
+// PermissionsKey is the context key holding the effective permissions of the
+// authenticated user, set by the server's auth middleware.
+const PermissionsKey = "permissions"
+
+// RequirePermission rejects requests whose user lacks any of the given permissions.
+// It must run after authentication.
+func RequirePermission(permissions ...string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		granted := c.GetStringSlice(PermissionsKey)
+		for _, permission := range permissions {
+			if !slices.Contains(granted, permission) {
+				c.JSON(http.StatusForbidden, gin.H{"error": "forbidden"})
+				c.Abort()
+				return
+			}
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,68 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Audit event types.
+const (
+	AuditLogin                = "login"
+	AuditLogout               = "logout"
+	AuditRefreshTokenReused   = "refresh_token.reused"
+	AuditSessionRevoked       = "session.revoked"
+	AuditSessionsRevoked      = "sessions.revoked"
+	AuditUserRegistered       = "user.registered"
+	AuditEmailVerified        = "email.verified"
+	AuditPasswordResetRequest = "password.reset_requested"
+	AuditPasswordReset        = "password.reset"
+	AuditAccountLocked        = "account.locked"
+	AuditAccountUnlocked      = "account.unlocked"
+	AuditTokenIssued          = "token.issued"
+	AuditMFAEnabled           = "mfa.enabled"
+	AuditMFADisabled          = "mfa.disabled"
+	AuditPasskeyAdded         = "passkey.added"
+	AuditPasskeyRemoved       = "passkey.removed"
+	AuditIdentityLinked       = "identity.linked"
+	AuditIdentityUnlinked     = "identity.unlinked"
+	AuditAccessTokenCreated   = "access_token.created"
+	AuditAccessTokenRevoked   = "access_token.revoked"
+	AuditUserDisabled         = "user.disabled"
+	AuditUserEnabled          = "user.enabled"
+	AuditUserDeleted          = "user.deleted"
+	AuditPasswordResetForced  = "password.reset_forced"
+	AuditRoleAssigned         = "role.assigned"
+	AuditRoleUnassigned       = "role.unassigned"
+	AuditImpersonationStart   = "impersonation.start"
+	AuditImpersonationEnd     = "impersonation.end"
+)
+
+// Audit event outcomes.
+const (
+	AuditOutcomeSuccess = "success"
+	AuditOutcomeFailure = "failure"
+)
+
+// AuditEvent is an append-only record of a security-relevant action. ActorID
+// is who performed it and TargetID the account it concerned; either may be
+// unknown, e.g. for a failed login.
+type AuditEvent struct {
+	ID        uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	Type      string     `gorm:"index;not null"`
+	ActorID   *uuid.UUID `gorm:"type:uuid;index"`
+	TargetID  *uuid.UUID `gorm:"type:uuid;index"`
+	IPAddress string
+	UserAgent string
+	Outcome   string                 `gorm:"not null"`
+	Metadata  map[string]interface{} `gorm:"serializer:json;type:jsonb"`
+	CreatedAt time.Time              `gorm:"index"`
+}
+
+func (e *AuditEvent) BeforeCreate(tx *gorm.DB) error {
+	if e.ID == uuid.Nil {
+		e.ID = uuid.New()
+	}
+	return nil
+}
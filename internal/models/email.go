@@ -0,0 +1,45 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Email message statuses. Dead messages failed permanently or ran out of
+// attempts and stay in the queue until an administrator retries them.
+const (
+	EmailPending = "pending"
+	EmailSent    = "sent"
+	EmailDead    = "dead"
+)
+
+// EmailMessage is an outgoing email. Pending rows form the send queue.
+type EmailMessage struct {
+	ID uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	// IdempotencyKey makes enqueueing the same message twice a no-op
+	IdempotencyKey string `gorm:"uniqueIndex;not null"`
+	To             string `gorm:"column:recipient;not null"`
+	Subject        string `gorm:"not null"`
+	// Body is the HTML part and TextBody the plain text alternative; either may be empty
+	Body          string    `gorm:"type:text;not null"`
+	TextBody      string    `gorm:"type:text"`
+	Status        string    `gorm:"not null;index:idx_email_messages_due,priority:1"`
+	Attempts      int       `gorm:"default:0"`
+	NextAttemptAt time.Time `gorm:"not null;index:idx_email_messages_due,priority:2"`
+	LastAttemptAt *time.Time
+	LastError     string
+	SentAt        *time.Time `gorm:"index"`
+	CreatedAt     time.Time
+	// ExpiresAt is when the link in the message stops working. Messages are
+	// not sent or retried after it.
+	ExpiresAt *time.Time
+}
+
+func (m *EmailMessage) BeforeCreate(tx *gorm.DB) error {
+	if m.ID == uuid.Nil {
+		m.ID = uuid.New()
+	}
+	return nil
+}
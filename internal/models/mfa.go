@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type UserMFA struct {
+	ID              uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	UserID          uuid.UUID `gorm:"type:uuid;uniqueIndex;not null"`
+	SecretEncrypted string    `gorm:"not null"`
+	Enabled         bool      `gorm:"default:false"`
+	LastUsedStep    int64
+	ConfirmedAt     *time.Time
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	User            User `gorm:"foreignKey:UserID"`
+}
+
+func (m *UserMFA) BeforeCreate(tx *gorm.DB) error {
+	if m.ID == uuid.Nil {
+		m.ID = uuid.New()
+	}
+	return nil
+}
+
+type MFARecoveryCode struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	UserID    uuid.UUID `gorm:"type:uuid;index;not null"`
+	CodeHash  string    `gorm:"uniqueIndex;not null"`
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+func (r *MFARecoveryCode) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return nil
+}
@@ -0,0 +1,106 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// OAuthClient is an application registered to sign users in through this
+// service acting as an OAuth 2.0 / OpenID Connect provider. Its ID is the client_id.
+type OAuthClient struct {
+	ID   uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	Name string    `gorm:"not null"`
+	// SecretHash is empty for public clients (SPAs, mobile apps), which must use PKCE
+	SecretHash string
+	// RedirectURIs, GrantTypes and Scopes are comma separated
+	RedirectURIs string `gorm:"not null"`
+	GrantTypes   string `gorm:"not null"`
+	Scopes       string `gorm:"not null"`
+	// FirstParty clients are trusted internal apps that skip the consent prompt
+	FirstParty bool       `gorm:"default:false"`
+	CreatedBy  *uuid.UUID `gorm:"type:uuid"`
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+func (c *OAuthClient) BeforeCreate(tx *gorm.DB) error {
+	if c.ID == uuid.Nil {
+		c.ID = uuid.New()
+	}
+	return nil
+}
+
+// IsPublic reports whether the client cannot keep a secret.
+func (c *OAuthClient) IsPublic() bool {
+	return c.SecretHash == ""
+}
+
+// OAuthAuthorizationCode is a single-use authorization code awaiting redemption.
+// A used code is kept until it is purged after expiry, so presenting it again
+// can revoke the refresh tokens issued from it, which share its FamilyID.
+type OAuthAuthorizationCode struct {
+	ID                  uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	CodeHash            string    `gorm:"uniqueIndex;not null"`
+	ClientID            uuid.UUID `gorm:"type:uuid;index;not null"`
+	UserID              uuid.UUID `gorm:"type:uuid;not null"`
+	RedirectURI         string    `gorm:"not null"`
+	Scope               string
+	Nonce               string
+	CodeChallenge       string
+	CodeChallengeMethod string
+	AuthTime            time.Time `gorm:"not null"`
+	ExpiresAt           time.Time `gorm:"not null;index"`
+	FamilyID            uuid.UUID `gorm:"type:uuid"`
+	UsedAt              *time.Time
+	CreatedAt           time.Time
+}
+
+func (c *OAuthAuthorizationCode) BeforeCreate(tx *gorm.DB) error {
+	if c.ID == uuid.Nil {
+		c.ID = uuid.New()
+	}
+	return nil
+}
+
+// OAuthConsent records the scopes a user has granted to a client.
+type OAuthConsent struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	UserID    uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_oauth_consents_user_client"`
+	ClientID  uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_oauth_consents_user_client"`
+	Scope     string    `gorm:"not null"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (c *OAuthConsent) BeforeCreate(tx *gorm.DB) error {
+	if c.ID == uuid.Nil {
+		c.ID = uuid.New()
+	}
+	return nil
+}
+
+// OAuthRefreshToken is a rotating refresh token issued to a client. Tokens
+// descending from one authorization share a FamilyID so reuse of a rotated
+// token can revoke the whole chain.
+type OAuthRefreshToken struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	TokenHash string    `gorm:"uniqueIndex;not null"`
+	FamilyID  uuid.UUID `gorm:"type:uuid;index;not null"`
+	ClientID  uuid.UUID `gorm:"type:uuid;index;not null"`
+	UserID    uuid.UUID `gorm:"type:uuid;index;not null"`
+	Scope     string
+	AuthTime  time.Time `gorm:"not null"`
+	ExpiresAt time.Time `gorm:"not null"`
+	RotatedAt *time.Time
+	RevokedAt *time.Time
+	CreatedAt time.Time
+}
+
+func (t *OAuthRefreshToken) BeforeCreate(tx *gorm.DB) error {
+	if t.ID == uuid.Nil {
+		t.ID = uuid.New()
+	}
+	return nil
+}
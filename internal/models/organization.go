@@ -0,0 +1,76 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Roles a user can hold within an organization.
+const (
+	OrgRoleOwner  = "owner"
+	OrgRoleAdmin  = "admin"
+	OrgRoleMember = "member"
+)
+
+// Organization is a workspace shared by its members. Data owned by an
+// organization carries an OrganizationID and is read through tenant-scoped repositories.
+type Organization struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	Name      string    `gorm:"not null"`
+	Slug      string    `gorm:"uniqueIndex;not null"`
+	CreatedBy uuid.UUID `gorm:"type:uuid;not null"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (o *Organization) BeforeCreate(tx *gorm.DB) error {
+	if o.ID == uuid.Nil {
+		o.ID = uuid.New()
+	}
+	return nil
+}
+
+// Membership gives a user a role in an organization.
+type Membership struct {
+	ID             uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	OrganizationID uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_memberships_org_user"`
+	UserID         uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_memberships_org_user;index"`
+	Role           string    `gorm:"not null"`
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+	Organization   Organization `gorm:"foreignKey:OrganizationID"`
+	User           User         `gorm:"foreignKey:UserID"`
+}
+
+func (m *Membership) BeforeCreate(tx *gorm.DB) error {
+	if m.ID == uuid.Nil {
+		m.ID = uuid.New()
+	}
+	return nil
+}
+
+// Invitation offers a role in an organization to an email address. The
+// invitee proves the address with the invitation's token, of which only the
+// hash is stored.
+type Invitation struct {
+	ID             uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	OrganizationID uuid.UUID `gorm:"type:uuid;index;not null"`
+	Email          string    `gorm:"not null"`
+	Role           string    `gorm:"not null"`
+	InvitedBy      uuid.UUID `gorm:"type:uuid;not null"`
+	TokenHash      *string   `gorm:"uniqueIndex"`
+	ExpiresAt      time.Time `gorm:"not null"`
+	AcceptedAt     *time.Time
+	AcceptedBy     *uuid.UUID `gorm:"type:uuid"`
+	CreatedAt      time.Time
+	Organization   Organization `gorm:"foreignKey:OrganizationID"`
+}
+
+func (i *Invitation) BeforeCreate(tx *gorm.DB) error {
+	if i.ID == uuid.Nil {
+		i.ID = uuid.New()
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Outbox event statuses.
+const (
+	OutboxPending   = "pending"
+	OutboxProcessed = "processed"
+	OutboxFailed    = "failed"
+)
+
+// OutboxEvent is a domain event written in the same transaction as the change
+// it describes. Pending rows form the dispatch queue; processed ones are kept
+// for a while and then purged, failed ones as well.
+type OutboxEvent struct {
+	ID   uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	Type string    `gorm:"not null"`
+	// Payload is the JSON encoded event data
+	Payload       string    `gorm:"type:text;not null"`
+	Status        string    `gorm:"not null;index:idx_outbox_events_due,priority:1"`
+	Attempts      int       `gorm:"default:0"`
+	NextAttemptAt time.Time `gorm:"not null;index:idx_outbox_events_due,priority:2"`
+	LastError     string
+	// ProcessedAt is when dispatching finished, whether it succeeded or gave up
+	ProcessedAt *time.Time `gorm:"index"`
+	CreatedAt   time.Time
+}
+
+func (e *OutboxEvent) BeforeCreate(tx *gorm.DB) error {
+	if e.ID == uuid.Nil {
+		e.ID = uuid.New()
+	}
+	return nil
+}
+
+// OutboxReceipt records that a subscriber handled an event, so retrying the
+// event for a failed subscriber skips the ones that already succeeded.
+type OutboxReceipt struct {
+	EventID    uuid.UUID `gorm:"type:uuid;primaryKey"`
+	Subscriber string    `gorm:"primaryKey"`
+	CreatedAt  time.Time
+}
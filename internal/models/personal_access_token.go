@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// PersonalAccessToken is a long-lived API credential a user creates for scripts
+// and automation. Only a hash of the secret is stored.
+type PersonalAccessToken struct {
+	ID        uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	UserID    uuid.UUID `gorm:"type:uuid;index;not null"`
+	Name      string    `gorm:"not null"`
+	TokenHash string    `gorm:"uniqueIndex;not null"`
+	// Prefix is the start of the secret, shown so users can recognise a token
+	Prefix string `gorm:"not null"`
+	// Scopes is comma separated
+	Scopes     string `gorm:"not null"`
+	ExpiresAt  *time.Time
+	LastUsedAt *time.Time
+	LastUsedIP string
+	CreatedAt  time.Time
+	User       User `gorm:"foreignKey:UserID"`
+}
+
+func (t *PersonalAccessToken) BeforeCreate(tx *gorm.DB) error {
+	if t.ID == uuid.Nil {
+		t.ID = uuid.New()
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Permissions checked by the API. Routes name the permission they require.
+const (
+	PermissionUsersRead         = "users:read"
+	PermissionUsersWrite        = "users:write"
+	PermissionUsersImpersonate  = "users:impersonate"
+	PermissionRolesRead         = "roles:read"
+	PermissionRolesWrite        = "roles:write"
+	PermissionOAuthClientsRead  = "oauth_clients:read"
+	PermissionOAuthClientsWrite = "oauth_clients:write"
+	PermissionAuditRead         = "audit:read"
+	PermissionWebhooksRead      = "webhooks:read"
+	PermissionWebhooksWrite     = "webhooks:write"
+	PermissionEmailsRead        = "emails:read"
+	PermissionEmailsWrite       = "emails:write"
+)
+
+// RoleAdmin is the built-in role holding every permission.
+const RoleAdmin = "admin"
+
+// Permissions is the permission catalog seeded by migrations.
+var Permissions = []Permission{
+	{Name: PermissionUsersRead, Description: "View user accounts"},
+	{Name: PermissionUsersWrite, Description: "Manage user accounts"},
+	{Name: PermissionUsersImpersonate, Description: "Sign in as another user"},
+	{Name: PermissionRolesRead, Description: "View roles and role assignments"},
+	{Name: PermissionRolesWrite, Description: "Manage roles and assign them to users"},
+	{Name: PermissionOAuthClientsRead, Description: "View registered OAuth clients"},
+	{Name: PermissionOAuthClientsWrite, Description: "Register and delete OAuth clients"},
+	{Name: PermissionAuditRead, Description: "View the security audit log"},
+	{Name: PermissionWebhooksRead, Description: "View webhook endpoints and deliveries"},
+	{Name: PermissionWebhooksWrite, Description: "Manage webhook endpoints and redeliver events"},
+	{Name: PermissionEmailsRead, Description: "View the outgoing email queue"},
+	{Name: PermissionEmailsWrite, Description: "Retry failed emails"},
+}
+
+// DefaultRole describes a built-in role seeded by migrations.
+type DefaultRole struct {
+	Name        string
+	Description string
+	Permissions []string
+}
+
+// DefaultRoles are created by migrations. Permissions are only ever added to
+// them on upgrade, so grants made by administrators are kept.
+var DefaultRoles = []DefaultRole{
+	{
+		Name:        RoleAdmin,
+		Description: "Full administrative access",
+		Permissions: []string{
+			PermissionUsersRead, PermissionUsersWrite, PermissionUsersImpersonate,
+			PermissionRolesRead, PermissionRolesWrite,
+			PermissionOAuthClientsRead, PermissionOAuthClientsWrite,
+			PermissionAuditRead,
+			PermissionWebhooksRead, PermissionWebhooksWrite,
+			PermissionEmailsRead, PermissionEmailsWrite,
+		},
+	},
+	{
+		Name:        "viewer",
+		Description: "Read-only administrative access",
+		Permissions: []string{PermissionUsersRead, PermissionRolesRead, PermissionOAuthClientsRead, PermissionAuditRead, PermissionWebhooksRead, PermissionEmailsRead},
+	},
+}
+
+type Permission struct {
+	ID          uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	Name        string    `gorm:"uniqueIndex;not null"`
+	Description string
+	CreatedAt   time.Time
+}
+
+func (p *Permission) BeforeCreate(tx *gorm.DB) error {
+	if p.ID == uuid.Nil {
+		p.ID = uuid.New()
+	}
+	return nil
+}
+
+// Role is a named set of permissions assigned to users.
+type Role struct {
+	ID          uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	Name        string    `gorm:"uniqueIndex;not null"`
+	Description string
+	// Builtin roles are seeded by migrations and cannot be deleted
+	Builtin     bool         `gorm:"default:false"`
+	Permissions []Permission `gorm:"many2many:role_permissions"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+func (r *Role) BeforeCreate(tx *gorm.DB) error {
+	if r.ID == uuid.Nil {
+		r.ID = uuid.New()
+	}
+	return nil
+}
+
+// UserRole assigns a role to a user.
+type UserRole struct {
+	UserID    uuid.UUID `gorm:"type:uuid;primaryKey"`
+	RoleID    uuid.UUID `gorm:"type:uuid;primaryKey;index"`
+	CreatedAt time.Time
+	Role      Role `gorm:"foreignKey:RoleID"`
+}
@@ -8,13 +8,21 @@ import (
 )
 
 type Session struct {
-	ID             uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
-	UserID         uuid.UUID `gorm:"type:uuid;not null"`
-	SessionToken   string    `gorm:"uniqueIndex;not null"`
-	DeviceInfo     string
-	IsActive       bool `gorm:"default:true"`
-	ExpiresAt      time.Time
-	LastAccessedAt time.Time
+	ID                uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	UserID            uuid.UUID `gorm:"type:uuid;not null"`
+	SessionToken      string    `gorm:"uniqueIndex;not null"`
+	DeviceInfo        string
+	IPAddress         string
+	IsActive          bool       `gorm:"default:true"`
+	FamilyID          *uuid.UUID `gorm:"type:uuid;index"` // set for refresh-token backed sessions
+	RotatedAt         *time.Time
+	ExpiresAt         time.Time
+	AbsoluteExpiresAt *time.Time // caps how far activity can slide ExpiresAt
+	LastAccessedAt    time.Time
+	// ActiveOrganizationID is the organization the session is working in
+	ActiveOrganizationID *uuid.UUID `gorm:"type:uuid"`
+	// ImpersonatorID is the administrator acting as UserID in an impersonation session
+	ImpersonatorID *uuid.UUID `gorm:"type:uuid"`
 	CreatedAt      time.Time
 	User           User `gorm:"foreignKey:UserID"`
 }
@@ -25,3 +33,12 @@ func (s *Session) BeforeCreate(tx *gorm.DB) error {
 	}
 	return nil
 }
+
+// PublicID identifies the session to its owner. Refresh-token backed sessions are
+// identified by their family so the ID survives token rotation.
+func (s *Session) PublicID() uuid.UUID {
+	if s.FamilyID != nil {
+		return *s.FamilyID
+	}
+	return s.ID
+}
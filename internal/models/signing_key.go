@@ -0,0 +1,29 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// SigningKey is an asymmetric JWT signing key. Its ID is published as the "kid" header.
+// A key is published from creation, signs tokens from ActivatedAt until RetiredAt
+// (when its successor activates) and is still published for verification until ExpiresAt.
+type SigningKey struct {
+	ID                  uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	Algorithm           string    `gorm:"not null"`
+	PrivateKeyEncrypted string    `gorm:"not null"`
+	PublicKey           []byte    `gorm:"not null"`
+	ActivatedAt         time.Time `gorm:"index"`
+	RetiredAt           *time.Time
+	ExpiresAt           *time.Time
+	CreatedAt           time.Time
+}
+
+func (k *SigningKey) BeforeCreate(tx *gorm.DB) error {
+	if k.ID == uuid.Nil {
+		k.ID = uuid.New()
+	}
+	return nil
+}
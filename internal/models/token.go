@@ -15,6 +15,8 @@ type Token struct {
 	Used      bool      `gorm:"default:false"`
 	ExpiresAt time.Time
 	CreatedAt time.Time
+	// FailedAttempts counts wrong codes presented with an MFA challenge
+	FailedAttempts int `gorm:"not null;default:0"`
 }
 
 func (t *Token) BeforeCreate(tx *gorm.DB) error {
@@ -8,12 +8,24 @@ import (
 )
 
 type User struct {
-	ID           uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
-	Email        string    `gorm:"uniqueIndex;not null"`
-	PasswordHash string    `gorm:"not null"`
-	Name         string    `gorm:"not null"`
-	CreatedAt    time.Time
-	UpdatedAt    time.Time
+	ID              uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	Email           string    `gorm:"uniqueIndex;not null"`
+	PasswordHash    string    `gorm:"not null"`
+	Name            string    `gorm:"not null"`
+	EmailVerifiedAt *time.Time
+	// Failed password attempts since the last successful login or lockout
+	FailedLoginAttempts int `gorm:"not null;default:0"`
+	LastFailedLoginAt   *time.Time
+	LockedUntil         *time.Time
+	// DisabledAt is set while an administrator has disabled the account
+	DisabledAt *time.Time
+	// PasswordResetRequired blocks password logins until the password is reset
+	PasswordResetRequired bool `gorm:"not null;default:false"`
+	// Locale is the BCP 47 language tag emails are sent in; when empty the
+	// request's Accept-Language is used
+	Locale    string `gorm:"not null;default:''"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
 }
 
 func (u *User) BeforeCreate(tx *gorm.DB) error {
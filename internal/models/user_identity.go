@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// UserIdentity links a local user to an account at an external identity provider.
+// The provider's stable subject identifier, not the email, identifies the account.
+type UserIdentity struct {
+	ID          uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	UserID      uuid.UUID `gorm:"type:uuid;index;not null"`
+	Provider    string    `gorm:"not null;uniqueIndex:idx_user_identities_provider_subject"`
+	Subject     string    `gorm:"not null;uniqueIndex:idx_user_identities_provider_subject"`
+	Email       string
+	LinkedAt    time.Time `gorm:"not null"`
+	LastLoginAt *time.Time
+	User        User `gorm:"foreignKey:UserID"`
+}
+
+func (i *UserIdentity) BeforeCreate(tx *gorm.DB) error {
+	if i.ID == uuid.Nil {
+		i.ID = uuid.New()
+	}
+	return nil
+}
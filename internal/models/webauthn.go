@@ -0,0 +1,52 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type WebAuthnCredential struct {
+	ID              uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	UserID          uuid.UUID `gorm:"type:uuid;index;not null"`
+	Name            string    `gorm:"not null"`
+	CredentialID    []byte    `gorm:"uniqueIndex;not null"`
+	PublicKey       []byte    `gorm:"not null"`
+	AttestationType string
+	Transports      string
+	AAGUID          []byte
+	SignCount       uint32
+	CloneWarning    bool
+	UserVerified    bool
+	BackupEligible  bool
+	BackupState     bool
+	LastUsedAt      *time.Time
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
+	User            User `gorm:"foreignKey:UserID"`
+}
+
+func (c *WebAuthnCredential) BeforeCreate(tx *gorm.DB) error {
+	if c.ID == uuid.Nil {
+		c.ID = uuid.New()
+	}
+	return nil
+}
+
+// WebAuthnCeremony holds the server-side state of an in-flight registration or login ceremony.
+type WebAuthnCeremony struct {
+	ID        uuid.UUID  `gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	UserID    *uuid.UUID `gorm:"type:uuid"`
+	Type      string     `gorm:"not null"`
+	Data      string     `gorm:"not null"`
+	ExpiresAt time.Time  `gorm:"index"`
+	CreatedAt time.Time
+}
+
+func (c *WebAuthnCeremony) BeforeCreate(tx *gorm.DB) error {
+	if c.ID == uuid.Nil {
+		c.ID = uuid.New()
+	}
+	return nil
+}
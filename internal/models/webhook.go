@@ -0,0 +1,65 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+// Webhook delivery statuses.
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliverySucceeded = "succeeded"
+	WebhookDeliveryFailed    = "failed"
+)
+
+// WebhookEndpoint is a URL that receives signed event notifications.
+type WebhookEndpoint struct {
+	ID  uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	URL string    `gorm:"not null"`
+	// Secret signs payloads; it is stored encrypted because it must be recoverable
+	Secret string `gorm:"not null"`
+	// Events is comma separated; "*" subscribes to every event
+	Events      string `gorm:"not null"`
+	Description string
+	Active      bool       `gorm:"default:true"`
+	CreatedBy   *uuid.UUID `gorm:"type:uuid"`
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+func (e *WebhookEndpoint) BeforeCreate(tx *gorm.DB) error {
+	if e.ID == uuid.Nil {
+		e.ID = uuid.New()
+	}
+	return nil
+}
+
+// WebhookDelivery is one event queued for one endpoint. Pending rows form the
+// delivery queue; the rest are kept as the delivery log. Redelivering an event
+// creates a new row with the same EventID and payload.
+type WebhookDelivery struct {
+	ID         uuid.UUID `gorm:"type:uuid;primary_key;default:gen_random_uuid()"`
+	EndpointID uuid.UUID `gorm:"type:uuid;index;not null"`
+	EventID    uuid.UUID `gorm:"type:uuid;index;not null"`
+	EventType  string    `gorm:"not null"`
+	// Payload is the exact JSON body that is signed and sent
+	Payload        string    `gorm:"type:text;not null"`
+	Status         string    `gorm:"not null;index:idx_webhook_deliveries_due,priority:1"`
+	Attempts       int       `gorm:"default:0"`
+	NextAttemptAt  time.Time `gorm:"not null;index:idx_webhook_deliveries_due,priority:2"`
+	LastAttemptAt  *time.Time
+	ResponseStatus int
+	ResponseBody   string
+	LastError      string
+	DeliveredAt    *time.Time
+	CreatedAt      time.Time
+}
+
+func (d *WebhookDelivery) BeforeCreate(tx *gorm.DB) error {
+	if d.ID == uuid.Nil {
+		d.ID = uuid.New()
+	}
+	return nil
+}
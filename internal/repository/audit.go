@@ -0,0 +1,79 @@
+package repository
+
+import (
+	"time"
+
+	"rest-api/internal/models"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// AuditFilter selects a page of audit events. Zero values match every event.
+type AuditFilter struct {
+	// UserID matches events the user either performed or was the target of
+	UserID   *uuid.UUID
+	ActorID  *uuid.UUID
+	TargetID *uuid.UUID
+	Types    []string
+	Outcome  string
+	// From is inclusive and To exclusive
+	From   *time.Time
+	To     *time.Time
+	Offset int
+	Limit  int
+}
+
+// auditRepository only ever inserts: audit events are never updated or deleted.
+type auditRepository struct {
+	db *gorm.DB
+}
+
+func NewAuditRepository(db *gorm.DB) AuditRepository {
+	return &auditRepository{db: db}
+}
+
+// Create appends an event. An event whose ID is already recorded is skipped,
+// so events derived from redelivered domain events are not duplicated.
+func (r *auditRepository) Create(event *models.AuditEvent) error {
+	return r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(event).Error
+}
+
+// List returns a page of matching events, newest first, and the total number of matches.
+func (r *auditRepository) List(filter AuditFilter) ([]models.AuditEvent, int64, error) {
+	query := r.db.Model(&models.AuditEvent{})
+	if filter.UserID != nil {
+		query = query.Where("actor_id = ? OR target_id = ?", *filter.UserID, *filter.UserID)
+	}
+	if filter.ActorID != nil {
+		query = query.Where("actor_id = ?", *filter.ActorID)
+	}
+	if filter.TargetID != nil {
+		query = query.Where("target_id = ?", *filter.TargetID)
+	}
+	if len(filter.Types) > 0 {
+		query = query.Where("type IN ?", filter.Types)
+	}
+	if filter.Outcome != "" {
+		query = query.Where("outcome = ?", filter.Outcome)
+	}
+	if filter.From != nil {
+		query = query.Where("created_at >= ?", *filter.From)
+	}
+	if filter.To != nil {
+		query = query.Where("created_at < ?", *filter.To)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var events []models.AuditEvent
+	err := query.Order("created_at DESC").Offset(filter.Offset).Limit(filter.Limit).Find(&events).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return events, total, nil
+}
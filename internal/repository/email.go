@@ -0,0 +1,114 @@
+package repository
+
+import (
+	"errors"
+	"rest-api/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type emailRepository struct {
+	db *gorm.DB
+}
+
+func NewEmailRepository(db *gorm.DB) EmailRepository {
+	return &emailRepository{db: db}
+}
+
+func (r *emailRepository) Enqueue(message *models.EmailMessage) (bool, error) {
+	result := r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "idempotency_key"}},
+		DoNothing: true,
+	}).Create(message)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// ClaimDue leases up to limit pending messages that are due, in the same way
+// as webhookRepository.ClaimDue.
+func (r *emailRepository) ClaimDue(now, leaseUntil time.Time, limit int) ([]models.EmailMessage, error) {
+	due := r.db.Model(&models.EmailMessage{}).
+		Select("id").
+		Where("status = ? AND next_attempt_at <= ?", models.EmailPending, now).
+		Order("next_attempt_at").
+		Limit(limit).
+		Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"})
+
+	var messages []models.EmailMessage
+	err := r.db.Model(&messages).Clauses(clause.Returning{}).
+		Where("id IN (?)", due).
+		Update("next_attempt_at", leaseUntil).Error
+	if err != nil {
+		return nil, err
+	}
+	return messages, nil
+}
+
+// RecordAttempt stores the outcome of a send attempt.
+func (r *emailRepository) RecordAttempt(message *models.EmailMessage) error {
+	return r.db.Model(message).Select(
+		"status", "attempts", "next_attempt_at", "last_attempt_at", "last_error", "sent_at",
+	).Updates(message).Error
+}
+
+func (r *emailRepository) FindByID(id string) (*models.EmailMessage, error) {
+	var message models.EmailMessage
+	err := r.db.Where("id = ?", id).First(&message).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &message, nil
+}
+
+// List returns a page of messages, newest first, and the total number matching
+// status (every status when empty).
+func (r *emailRepository) List(status string, offset, limit int) ([]models.EmailMessage, int64, error) {
+	query := r.db.Model(&models.EmailMessage{})
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var messages []models.EmailMessage
+	err := query.Order("created_at DESC").Offset(offset).Limit(limit).Find(&messages).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return messages, total, nil
+}
+
+// Requeue puts a dead message back in the queue, due at the given time, with
+// a fresh set of attempts.
+func (r *emailRepository) Requeue(id string, at time.Time) error {
+	result := r.db.Model(&models.EmailMessage{}).
+		Where("id = ? AND status = ?", id, models.EmailDead).
+		Updates(map[string]interface{}{
+			"status":          models.EmailPending,
+			"attempts":        0,
+			"next_attempt_at": at,
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// DeleteSentBefore purges messages sent before t and returns how many were removed.
+func (r *emailRepository) DeleteSentBefore(t time.Time) (int64, error) {
+	result := r.db.Where("status = ? AND sent_at < ?", models.EmailSent, t).Delete(&models.EmailMessage{})
+	return result.RowsAffected, result.Error
+}
@@ -1,23 +1,209 @@
 package repository
 
-import "rest-api/internal/models"
+import (
+	"rest-api/internal/models"
+	"time"
+
+	"github.com/google/uuid"
+)
 
 type UserRepository interface {
 	Create(user *models.User) error
 	FindByEmail(email string) (*models.User, error)
 	FindByID(id string) (*models.User, error)
 	UpdatePassword(userID string, hashedPassword string) error
+	UpdatePasswordHash(userID string, hashedPassword string) error
+	UpdateLocale(userID, locale string) error
+	MarkEmailVerified(userID string) (bool, error)
+	RecordFailedLogin(userID string, at time.Time, lockThreshold int, lockedUntil time.Time) (*models.User, error)
+	ResetFailedLogins(userID string) error
+	List(filter UserFilter) ([]models.User, int64, error)
+	SetDisabled(userID string, disabledAt *time.Time) error
+	SetPasswordResetRequired(userID string) error
+	Delete(userID string) error
 }
 
+// SessionRepository's Invalidate methods return the active sessions they
+// deactivated.
 type SessionRepository interface {
 	Create(session *models.Session) error
 	FindByToken(token string) (*models.Session, error)
-	InvalidateSession(sessionID string) error
-	InvalidateAllUserSessions(userID string) error
+	InvalidateSession(sessionID string) ([]models.Session, error)
+	InvalidateAllUserSessions(userID string) ([]models.Session, error)
+	FindRefreshToken(token string) (*models.Session, error)
+	MarkRotated(sessionID string) (bool, error)
+	InvalidateFamily(familyID string) ([]models.Session, error)
+	FindActiveByUserID(userID string) ([]models.Session, error)
+	FindActiveByID(userID, sessionID string) (*models.Session, error)
+	InvalidateUserSession(userID, sessionID string) ([]models.Session, error)
+	InvalidateAllUserSessionsExcept(userID, sessionID string) ([]models.Session, error)
+	TouchSessions(accesses map[uuid.UUID]time.Time, idleTimeout time.Duration) error
+	SetActiveOrganization(userID, sessionID string, organizationID *uuid.UUID) error
 }
 
 type TokenRepository interface {
 	Create(token *models.Token) error
 	FindByToken(token string) (*models.Token, error)
+	FindByID(id string) (*models.Token, error)
 	InvalidateToken(token string) error
+	RecordFailedAttempt(token string, maxAttempts int) error
+	FindIssuedSince(userID string, tokenType string, since time.Time) ([]models.Token, error)
+}
+
+type MFARepository interface {
+	FindByUserID(userID string) (*models.UserMFA, error)
+	Save(mfa *models.UserMFA) error
+	Delete(userID string) error
+	UpdateLastUsedStep(userID string, step int64) (bool, error)
+	Enable(mfa *models.UserMFA, codes []models.MFARecoveryCode) error
+	UseRecoveryCode(userID string, codeHash string) (bool, error)
+}
+
+type WebAuthnRepository interface {
+	CreateCredential(credential *models.WebAuthnCredential) error
+	FindCredentialsByUserID(userID string) ([]models.WebAuthnCredential, error)
+	UpdateCredentialUsage(credential *models.WebAuthnCredential) error
+	RenameCredential(userID, id, name string) error
+	DeleteCredential(userID, id string) error
+	CreateCeremony(ceremony *models.WebAuthnCeremony) error
+	ConsumeCeremony(id string, ceremonyType string) (*models.WebAuthnCeremony, error)
+	DeleteExpiredCeremonies() error
+}
+
+type SigningKeyRepository interface {
+	FindUsable() ([]models.SigningKey, error)
+	Rotate(key *models.SigningKey, staleBefore time.Time, gracePeriod time.Duration) (bool, error)
+	DeleteExpired() error
+}
+
+type IdentityRepository interface {
+	Create(identity *models.UserIdentity) error
+	CreateWithUser(user *models.User, identity *models.UserIdentity) error
+	FindByProviderSubject(provider, subject string) (*models.UserIdentity, error)
+	FindByUserID(userID string) ([]models.UserIdentity, error)
+	RecordLogin(id string, email string, at time.Time) error
+	Delete(userID, id string) error
+}
+
+type OAuthClientRepository interface {
+	Create(client *models.OAuthClient) error
+	FindByID(id string) (*models.OAuthClient, error)
+	List() ([]models.OAuthClient, error)
+	Delete(id string) error
+}
+
+type OAuthGrantRepository interface {
+	CreateCode(code *models.OAuthAuthorizationCode) error
+	FindCode(codeHash string) (*models.OAuthAuthorizationCode, error)
+	ConsumeCode(codeHash string) (*models.OAuthAuthorizationCode, bool, error)
+	DeleteExpiredCodes(before time.Time) error
+	FindConsent(userID, clientID string) (*models.OAuthConsent, error)
+	SaveConsent(consent *models.OAuthConsent) error
+	CreateRefreshToken(token *models.OAuthRefreshToken) error
+	FindRefreshToken(tokenHash string) (*models.OAuthRefreshToken, error)
+	MarkRefreshTokenRotated(id string) (bool, error)
+	RevokeRefreshFamily(familyID string) error
+}
+
+type PersonalAccessTokenRepository interface {
+	Create(token *models.PersonalAccessToken) error
+	FindByHash(tokenHash string) (*models.PersonalAccessToken, error)
+	FindByUserID(userID string) ([]models.PersonalAccessToken, error)
+	RecordUse(id string, at time.Time, ip string, notBefore time.Time) error
+	Delete(userID, id string) error
+}
+
+type RoleRepository interface {
+	List() ([]models.Role, error)
+	FindByID(id string) (*models.Role, error)
+	FindByName(name string) (*models.Role, error)
+	Create(role *models.Role, permissions []string) error
+	Delete(id string) error
+	ListPermissions() ([]models.Permission, error)
+	FindByUserID(userID string) ([]models.Role, error)
+	PermissionsForUser(userID string) ([]string, error)
+	Assign(userRole *models.UserRole) error
+	Unassign(userID, roleID string) error
+}
+
+type OrganizationRepository interface {
+	Create(org *models.Organization, owner *models.Membership) error
+	FindBySlug(slug string) (*models.Organization, error)
+	FindMembershipsByUserID(userID string) ([]models.Membership, error)
+	// FindInvitationByTokenHash looks an invitation up across organizations,
+	// for invitees holding its token
+	FindInvitationByTokenHash(tokenHash string) (*models.Invitation, error)
+	// SetInvitationToken replaces the token hash of a pending invitation, and
+	// fails with ErrNotFound once it was accepted or revoked
+	SetInvitationToken(id, tokenHash string) error
+	// AcceptInvitation marks a pending invitation accepted and adds the membership
+	AcceptInvitation(invitation *models.Invitation, membership *models.Membership) error
+	// Members returns the memberships of one organization
+	Members(organizationID uuid.UUID) MembershipRepository
+	// Invitations returns the invitations of one organization
+	Invitations(organizationID uuid.UUID) InvitationRepository
+}
+
+// MembershipRepository is scoped to one organization; its queries never match
+// memberships of other organizations.
+type MembershipRepository interface {
+	List() ([]models.Membership, error)
+	Find(userID string) (*models.Membership, error)
+	Add(membership *models.Membership) error
+	UpdateRole(userID, role string) error
+	Remove(userID string) error
+}
+
+// InvitationRepository is scoped to one organization like MembershipRepository.
+type InvitationRepository interface {
+	Create(invitation *models.Invitation) error
+	ListPending() ([]models.Invitation, error)
+	Delete(id string) error
+}
+
+// AuditRepository is append-only.
+type AuditRepository interface {
+	Create(event *models.AuditEvent) error
+	List(filter AuditFilter) ([]models.AuditEvent, int64, error)
+}
+
+type WebhookRepository interface {
+	CreateEndpoint(endpoint *models.WebhookEndpoint) error
+	FindEndpoint(id string) (*models.WebhookEndpoint, error)
+	ListEndpoints() ([]models.WebhookEndpoint, error)
+	ListActiveEndpoints() ([]models.WebhookEndpoint, error)
+	UpdateEndpoint(endpoint *models.WebhookEndpoint) error
+	DeleteEndpoint(id string) error
+	CreateDeliveries(deliveries []models.WebhookDelivery) error
+	ClaimDue(now, leaseUntil time.Time, limit int) ([]models.WebhookDelivery, error)
+	RecordAttempt(delivery *models.WebhookDelivery) error
+	FindDelivery(endpointID, id string) (*models.WebhookDelivery, error)
+	ListDeliveries(endpointID, status string, offset, limit int) ([]models.WebhookDelivery, int64, error)
+}
+
+// Transactor runs work in a database transaction. Everything written through
+// the repositories it hands out commits or rolls back together.
+type Transactor interface {
+	Transaction(fn func(tx Repositories) error) error
+}
+
+type OutboxRepository interface {
+	Create(event *models.OutboxEvent) error
+	ClaimDue(now, leaseUntil time.Time, limit int) ([]models.OutboxEvent, error)
+	RecordAttempt(event *models.OutboxEvent) error
+	Receipts(eventID uuid.UUID) ([]string, error)
+	CreateReceipt(receipt *models.OutboxReceipt) error
+	DeleteProcessedBefore(t time.Time) (int64, error)
+}
+
+type EmailRepository interface {
+	// Enqueue adds a message unless one with its idempotency key exists, and
+	// reports whether it was added
+	Enqueue(message *models.EmailMessage) (bool, error)
+	ClaimDue(now, leaseUntil time.Time, limit int) ([]models.EmailMessage, error)
+	RecordAttempt(message *models.EmailMessage) error
+	FindByID(id string) (*models.EmailMessage, error)
+	List(status string, offset, limit int) ([]models.EmailMessage, int64, error)
+	Requeue(id string, at time.Time) error
+	DeleteSentBefore(t time.Time) (int64, error)
 }
@@ -0,0 +1,70 @@
+package repository
+
+import (
+	"rest-api/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type mfaRepository struct {
+	db *gorm.DB
+}
+
+func NewMFARepository(db *gorm.DB) MFARepository {
+	return &mfaRepository{db: db}
+}
+
+func (r *mfaRepository) FindByUserID(userID string) (*models.UserMFA, error) {
+	var mfa models.UserMFA
+	err := r.db.Where("user_id = ?", userID).First(&mfa).Error
+	if err != nil {
+		return nil, err
+	}
+	return &mfa, nil
+}
+
+func (r *mfaRepository) Save(mfa *models.UserMFA) error {
+	return r.db.Save(mfa).Error
+}
+
+func (r *mfaRepository) Delete(userID string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", userID).Delete(&models.MFARecoveryCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", userID).Delete(&models.UserMFA{}).Error
+	})
+}
+
+func (r *mfaRepository) UpdateLastUsedStep(userID string, step int64) (bool, error) {
+	result := r.db.Model(&models.UserMFA{}).
+		Where("user_id = ? AND last_used_step < ?", userID, step).
+		Update("last_used_step", step)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+func (r *mfaRepository) Enable(mfa *models.UserMFA, codes []models.MFARecoveryCode) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(mfa).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", mfa.UserID).Delete(&models.MFARecoveryCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(&codes).Error
+	})
+}
+
+func (r *mfaRepository) UseRecoveryCode(userID string, codeHash string) (bool, error) {
+	result := r.db.Model(&models.MFARecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", userID, codeHash).
+		Update("used_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
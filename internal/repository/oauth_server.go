@@ -0,0 +1,173 @@
+package repository
+
+import (
+	"errors"
+	"rest-api/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type oauthClientRepository struct {
+	db *gorm.DB
+}
+
+func NewOAuthClientRepository(db *gorm.DB) OAuthClientRepository {
+	return &oauthClientRepository{db: db}
+}
+
+func (r *oauthClientRepository) Create(client *models.OAuthClient) error {
+	return r.db.Create(client).Error
+}
+
+func (r *oauthClientRepository) FindByID(id string) (*models.OAuthClient, error) {
+	var client models.OAuthClient
+	err := r.db.Where("id = ?", id).First(&client).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &client, nil
+}
+
+func (r *oauthClientRepository) List() ([]models.OAuthClient, error) {
+	var clients []models.OAuthClient
+	err := r.db.Order("created_at").Find(&clients).Error
+	if err != nil {
+		return nil, err
+	}
+	return clients, nil
+}
+
+// Delete removes a client together with its outstanding codes, consents and refresh tokens.
+func (r *oauthClientRepository) Delete(id string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		for _, model := range []interface{}{&models.OAuthAuthorizationCode{}, &models.OAuthConsent{}, &models.OAuthRefreshToken{}} {
+			if err := tx.Where("client_id = ?", id).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+
+		result := tx.Where("id = ?", id).Delete(&models.OAuthClient{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrNotFound
+		}
+		return nil
+	})
+}
+
+type oauthGrantRepository struct {
+	db *gorm.DB
+}
+
+func NewOAuthGrantRepository(db *gorm.DB) OAuthGrantRepository {
+	return &oauthGrantRepository{db: db}
+}
+
+func (r *oauthGrantRepository) CreateCode(code *models.OAuthAuthorizationCode) error {
+	return r.db.Create(code).Error
+}
+
+// FindCode returns an authorization code without using it, used or not.
+func (r *oauthGrantRepository) FindCode(codeHash string) (*models.OAuthAuthorizationCode, error) {
+	var code models.OAuthAuthorizationCode
+	err := r.db.Where("code_hash = ?", codeHash).First(&code).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &code, nil
+}
+
+// ConsumeCode marks an unexpired authorization code used and returns it with
+// first set. A code that was already used is returned with first unset, so
+// the tokens issued from it can be revoked.
+func (r *oauthGrantRepository) ConsumeCode(codeHash string) (*models.OAuthAuthorizationCode, bool, error) {
+	var codes []models.OAuthAuthorizationCode
+	err := r.db.Model(&codes).Clauses(clause.Returning{}).
+		Where("code_hash = ? AND used_at IS NULL AND expires_at > ?", codeHash, time.Now()).
+		Update("used_at", time.Now()).Error
+	if err != nil {
+		return nil, false, err
+	}
+	if len(codes) > 0 {
+		return &codes[0], true, nil
+	}
+
+	var code models.OAuthAuthorizationCode
+	err = r.db.Where("code_hash = ? AND used_at IS NOT NULL", codeHash).First(&code).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, false, ErrNotFound
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return &code, false, nil
+}
+
+// DeleteExpiredCodes removes codes that expired before the given time, used or not.
+func (r *oauthGrantRepository) DeleteExpiredCodes(before time.Time) error {
+	return r.db.Where("expires_at < ?", before).Delete(&models.OAuthAuthorizationCode{}).Error
+}
+
+func (r *oauthGrantRepository) FindConsent(userID, clientID string) (*models.OAuthConsent, error) {
+	var consent models.OAuthConsent
+	err := r.db.Where("user_id = ? AND client_id = ?", userID, clientID).First(&consent).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &consent, nil
+}
+
+// SaveConsent creates or replaces the scopes a user has granted to a client.
+func (r *oauthGrantRepository) SaveConsent(consent *models.OAuthConsent) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "client_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"scope", "updated_at"}),
+	}).Create(consent).Error
+}
+
+func (r *oauthGrantRepository) CreateRefreshToken(token *models.OAuthRefreshToken) error {
+	return r.db.Create(token).Error
+}
+
+func (r *oauthGrantRepository) FindRefreshToken(tokenHash string) (*models.OAuthRefreshToken, error) {
+	var token models.OAuthRefreshToken
+	err := r.db.Where("token_hash = ?", tokenHash).First(&token).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+// MarkRefreshTokenRotated atomically retires a refresh token. It reports false
+// if the token had already been rotated or revoked, which signals reuse.
+func (r *oauthGrantRepository) MarkRefreshTokenRotated(id string) (bool, error) {
+	result := r.db.Model(&models.OAuthRefreshToken{}).
+		Where("id = ? AND rotated_at IS NULL AND revoked_at IS NULL", id).
+		Update("rotated_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+func (r *oauthGrantRepository) RevokeRefreshFamily(familyID string) error {
+	return r.db.Model(&models.OAuthRefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyID).
+		Update("revoked_at", time.Now()).Error
+}
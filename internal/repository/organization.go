@@ -0,0 +1,200 @@
+package repository
+
+import (
+	"errors"
+	"rest-api/internal/models"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+type organizationRepository struct {
+	db *gorm.DB
+}
+
+func NewOrganizationRepository(db *gorm.DB) OrganizationRepository {
+	return &organizationRepository{db: db}
+}
+
+// Create stores an organization together with its owner's membership.
+func (r *organizationRepository) Create(org *models.Organization, owner *models.Membership) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(org).Error; err != nil {
+			return err
+		}
+		owner.OrganizationID = org.ID
+		return tx.Omit("Organization", "User").Create(owner).Error
+	})
+}
+
+func (r *organizationRepository) FindBySlug(slug string) (*models.Organization, error) {
+	var org models.Organization
+	err := r.db.Where("slug = ?", slug).First(&org).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &org, nil
+}
+
+// FindMembershipsByUserID returns the user's memberships with their organizations.
+func (r *organizationRepository) FindMembershipsByUserID(userID string) ([]models.Membership, error) {
+	var memberships []models.Membership
+	err := r.db.Preload("Organization").Where("user_id = ?", userID).Order("created_at").Find(&memberships).Error
+	if err != nil {
+		return nil, err
+	}
+	return memberships, nil
+}
+
+// FindInvitationByTokenHash returns the invitation with its organization.
+func (r *organizationRepository) FindInvitationByTokenHash(tokenHash string) (*models.Invitation, error) {
+	var invitation models.Invitation
+	err := r.db.Preload("Organization").Where("token_hash = ?", tokenHash).First(&invitation).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &invitation, nil
+}
+
+func (r *organizationRepository) SetInvitationToken(id, tokenHash string) error {
+	result := r.db.Model(&models.Invitation{}).
+		Where("id = ? AND accepted_at IS NULL", id).
+		Update("token_hash", tokenHash)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// AcceptInvitation fails with ErrNotFound if the invitation was accepted concurrently.
+func (r *organizationRepository) AcceptInvitation(invitation *models.Invitation, membership *models.Membership) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		result := tx.Model(&models.Invitation{}).
+			Where("id = ? AND accepted_at IS NULL", invitation.ID).
+			Updates(map[string]interface{}{"accepted_at": now, "accepted_by": membership.UserID})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrNotFound
+		}
+		invitation.AcceptedAt = &now
+		invitation.AcceptedBy = &membership.UserID
+
+		membership.OrganizationID = invitation.OrganizationID
+		return tx.Omit("Organization", "User").Create(membership).Error
+	})
+}
+
+func (r *organizationRepository) Members(organizationID uuid.UUID) MembershipRepository {
+	return &membershipRepository{
+		db:             tenantDB(r.db, organizationID),
+		organizationID: organizationID,
+	}
+}
+
+func (r *organizationRepository) Invitations(organizationID uuid.UUID) InvitationRepository {
+	return &invitationRepository{
+		db:             tenantDB(r.db, organizationID),
+		organizationID: organizationID,
+	}
+}
+
+// membershipRepository is scoped to a single organization.
+type membershipRepository struct {
+	db             *gorm.DB
+	organizationID uuid.UUID
+}
+
+func (r *membershipRepository) List() ([]models.Membership, error) {
+	var memberships []models.Membership
+	err := r.db.Preload("User").Order("created_at").Find(&memberships).Error
+	if err != nil {
+		return nil, err
+	}
+	return memberships, nil
+}
+
+// Find returns the user's membership with its organization.
+func (r *membershipRepository) Find(userID string) (*models.Membership, error) {
+	var membership models.Membership
+	err := r.db.Preload("Organization").Where("user_id = ?", userID).First(&membership).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &membership, nil
+}
+
+func (r *membershipRepository) Add(membership *models.Membership) error {
+	membership.OrganizationID = r.organizationID
+	return r.db.Omit("Organization", "User").Create(membership).Error
+}
+
+func (r *membershipRepository) UpdateRole(userID, role string) error {
+	result := r.db.Model(&models.Membership{}).Where("user_id = ?", userID).Update("role", role)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *membershipRepository) Remove(userID string) error {
+	result := r.db.Where("user_id = ?", userID).Delete(&models.Membership{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// invitationRepository is scoped to a single organization.
+type invitationRepository struct {
+	db             *gorm.DB
+	organizationID uuid.UUID
+}
+
+func (r *invitationRepository) Create(invitation *models.Invitation) error {
+	invitation.OrganizationID = r.organizationID
+	return r.db.Omit("Organization").Create(invitation).Error
+}
+
+// ListPending returns the invitations that are neither accepted nor expired.
+func (r *invitationRepository) ListPending() ([]models.Invitation, error) {
+	var invitations []models.Invitation
+	err := r.db.Where("accepted_at IS NULL AND expires_at > ?", time.Now()).Order("created_at").Find(&invitations).Error
+	if err != nil {
+		return nil, err
+	}
+	return invitations, nil
+}
+
+// Delete revokes a pending invitation.
+func (r *invitationRepository) Delete(id string) error {
+	result := r.db.Where("id = ? AND accepted_at IS NULL", id).Delete(&models.Invitation{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"rest-api/internal/models"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type outboxRepository struct {
+	db *gorm.DB
+}
+
+func NewOutboxRepository(db *gorm.DB) OutboxRepository {
+	return &outboxRepository{db: db}
+}
+
+func (r *outboxRepository) Create(event *models.OutboxEvent) error {
+	return r.db.Create(event).Error
+}
+
+// ClaimDue leases up to limit pending events that are due, oldest first, in
+// the same way as webhookRepository.ClaimDue.
+func (r *outboxRepository) ClaimDue(now, leaseUntil time.Time, limit int) ([]models.OutboxEvent, error) {
+	due := r.db.Model(&models.OutboxEvent{}).
+		Select("id").
+		Where("status = ? AND next_attempt_at <= ?", models.OutboxPending, now).
+		Order("created_at").
+		Limit(limit).
+		Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"})
+
+	var events []models.OutboxEvent
+	err := r.db.Model(&events).Clauses(clause.Returning{}).
+		Where("id IN (?)", due).
+		Update("next_attempt_at", leaseUntil).Error
+	if err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+// RecordAttempt stores the outcome of a dispatch attempt.
+func (r *outboxRepository) RecordAttempt(event *models.OutboxEvent) error {
+	return r.db.Model(event).Select(
+		"status", "attempts", "next_attempt_at", "last_error", "processed_at",
+	).Updates(event).Error
+}
+
+// Receipts returns the subscribers that have already handled the event.
+func (r *outboxRepository) Receipts(eventID uuid.UUID) ([]string, error) {
+	var subscribers []string
+	err := r.db.Model(&models.OutboxReceipt{}).Where("event_id = ?", eventID).Pluck("subscriber", &subscribers).Error
+	if err != nil {
+		return nil, err
+	}
+	return subscribers, nil
+}
+
+// CreateReceipt records a handled event; recording it twice is not an error.
+func (r *outboxRepository) CreateReceipt(receipt *models.OutboxReceipt) error {
+	return r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(receipt).Error
+}
+
+// DeleteProcessedBefore purges events that finished dispatching before t,
+// with their receipts, and returns how many events were removed.
+func (r *outboxRepository) DeleteProcessedBefore(t time.Time) (int64, error) {
+	var deleted int64
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		finished := tx.Model(&models.OutboxEvent{}).
+			Select("id").
+			Where("status <> ? AND processed_at < ?", models.OutboxPending, t)
+		if err := tx.Where("event_id IN (?)", finished).Delete(&models.OutboxReceipt{}).Error; err != nil {
+			return err
+		}
+
+		result := tx.Where("status <> ? AND processed_at < ?", models.OutboxPending, t).Delete(&models.OutboxEvent{})
+		deleted = result.RowsAffected
+		return result.Error
+	})
+	return deleted, err
+}
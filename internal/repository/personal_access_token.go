@@ -0,0 +1,64 @@
+package repository
+
+import (
+	"errors"
+	"rest-api/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type personalAccessTokenRepository struct {
+	db *gorm.DB
+}
+
+func NewPersonalAccessTokenRepository(db *gorm.DB) PersonalAccessTokenRepository {
+	return &personalAccessTokenRepository{db: db}
+}
+
+func (r *personalAccessTokenRepository) Create(token *models.PersonalAccessToken) error {
+	return r.db.Create(token).Error
+}
+
+func (r *personalAccessTokenRepository) FindByHash(tokenHash string) (*models.PersonalAccessToken, error) {
+	var token models.PersonalAccessToken
+	err := r.db.Where("token_hash = ?", tokenHash).First(&token).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
+
+func (r *personalAccessTokenRepository) FindByUserID(userID string) ([]models.PersonalAccessToken, error) {
+	var tokens []models.PersonalAccessToken
+	err := r.db.Where("user_id = ?", userID).Order("created_at DESC").Find(&tokens).Error
+	if err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// RecordUse stores the last use of a token unless it was already recorded
+// after notBefore, so busy tokens do not cause a write per request.
+func (r *personalAccessTokenRepository) RecordUse(id string, at time.Time, ip string, notBefore time.Time) error {
+	return r.db.Model(&models.PersonalAccessToken{}).
+		Where("id = ? AND (last_used_at IS NULL OR last_used_at < ?)", id, notBefore).
+		Updates(map[string]interface{}{
+			"last_used_at": at,
+			"last_used_ip": ip,
+		}).Error
+}
+
+func (r *personalAccessTokenRepository) Delete(userID, id string) error {
+	result := r.db.Where("id = ? AND user_id = ?", id, userID).Delete(&models.PersonalAccessToken{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
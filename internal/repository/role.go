@@ -0,0 +1,139 @@
+package repository
+
+import (
+	"errors"
+	"rest-api/internal/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type roleRepository struct {
+	db *gorm.DB
+}
+
+func NewRoleRepository(db *gorm.DB) RoleRepository {
+	return &roleRepository{db: db}
+}
+
+func (r *roleRepository) List() ([]models.Role, error) {
+	var roles []models.Role
+	err := r.db.Preload("Permissions", func(db *gorm.DB) *gorm.DB {
+		return db.Order("name")
+	}).Order("name").Find(&roles).Error
+	if err != nil {
+		return nil, err
+	}
+	return roles, nil
+}
+
+func (r *roleRepository) FindByID(id string) (*models.Role, error) {
+	return r.findOne("id = ?", id)
+}
+
+func (r *roleRepository) FindByName(name string) (*models.Role, error) {
+	return r.findOne("name = ?", name)
+}
+
+func (r *roleRepository) findOne(query string, arg interface{}) (*models.Role, error) {
+	var role models.Role
+	err := r.db.Preload("Permissions").Where(query, arg).First(&role).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &role, nil
+}
+
+// Create stores a role with the named permissions. It returns ErrNotFound if
+// any permission does not exist.
+func (r *roleRepository) Create(role *models.Role, permissions []string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var found []models.Permission
+		if err := tx.Where("name IN ?", permissions).Find(&found).Error; err != nil {
+			return err
+		}
+		if len(found) != len(permissions) {
+			return ErrNotFound
+		}
+
+		role.Permissions = found
+		return tx.Omit("Permissions.*").Create(role).Error
+	})
+}
+
+// Delete removes a role along with its permission grants and user assignments.
+func (r *roleRepository) Delete(id string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("role_id = ?", id).Delete(&models.UserRole{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Exec("DELETE FROM role_permissions WHERE role_id = ?", id).Error; err != nil {
+			return err
+		}
+
+		result := tx.Where("id = ?", id).Delete(&models.Role{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrNotFound
+		}
+		return nil
+	})
+}
+
+func (r *roleRepository) ListPermissions() ([]models.Permission, error) {
+	var permissions []models.Permission
+	if err := r.db.Order("name").Find(&permissions).Error; err != nil {
+		return nil, err
+	}
+	return permissions, nil
+}
+
+func (r *roleRepository) FindByUserID(userID string) ([]models.Role, error) {
+	var roles []models.Role
+	err := r.db.Preload("Permissions").
+		Joins("JOIN user_roles ON user_roles.role_id = roles.id").
+		Where("user_roles.user_id = ?", userID).
+		Order("roles.name").
+		Find(&roles).Error
+	if err != nil {
+		return nil, err
+	}
+	return roles, nil
+}
+
+// PermissionsForUser returns the names of every permission granted to the
+// user through any of their roles.
+func (r *roleRepository) PermissionsForUser(userID string) ([]string, error) {
+	var names []string
+	err := r.db.Table("permissions").
+		Distinct("permissions.name").
+		Joins("JOIN role_permissions ON role_permissions.permission_id = permissions.id").
+		Joins("JOIN user_roles ON user_roles.role_id = role_permissions.role_id").
+		Where("user_roles.user_id = ?", userID).
+		Pluck("permissions.name", &names).Error
+	if err != nil {
+		return nil, err
+	}
+	return names, nil
+}
+
+// Assign gives the user a role; assigning a role the user already has is a no-op.
+func (r *roleRepository) Assign(userRole *models.UserRole) error {
+	return r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(userRole).Error
+}
+
+func (r *roleRepository) Unassign(userID, roleID string) error {
+	result := r.db.Where("user_id = ? AND role_id = ?", userID, roleID).Delete(&models.UserRole{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -1,10 +1,14 @@
 package repository
 
 import (
+	"fmt"
 	"rest-api/internal/models"
+	"strings"
 	"time"
 
+	"github.com/google/uuid"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type sessionRepository struct {
@@ -28,10 +32,126 @@ func (r *sessionRepository) FindByToken(token string) (*models.Session, error) {
 	return &session, nil
 }
 
-func (r *sessionRepository) InvalidateSession(sessionID string) error {
-	return r.db.Model(&models.Session{}).Where("id = ?", sessionID).Update("is_active", false).Error
+// invalidate deactivates the active sessions matching the query and returns them.
+func (r *sessionRepository) invalidate(query string, args ...interface{}) ([]models.Session, error) {
+	var sessions []models.Session
+	err := r.db.Model(&sessions).Clauses(clause.Returning{}).
+		Where("is_active = ?", true).
+		Where(query, args...).
+		Update("is_active", false).Error
+	if err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+func (r *sessionRepository) InvalidateSession(sessionID string) ([]models.Session, error) {
+	return r.invalidate("id = ?", sessionID)
+}
+
+func (r *sessionRepository) InvalidateAllUserSessions(userID string) ([]models.Session, error) {
+	return r.invalidate("user_id = ?", userID)
+}
+
+// FindRefreshToken returns a refresh-token backed session regardless of its state,
+// so callers can detect replays of rotated or revoked tokens.
+func (r *sessionRepository) FindRefreshToken(token string) (*models.Session, error) {
+	var session models.Session
+	err := r.db.Where("session_token = ? AND family_id IS NOT NULL", token).First(&session).Error
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (r *sessionRepository) MarkRotated(sessionID string) (bool, error) {
+	result := r.db.Model(&models.Session{}).
+		Where("id = ? AND rotated_at IS NULL AND is_active = ?", sessionID, true).
+		Updates(map[string]interface{}{"rotated_at": time.Now(), "is_active": false})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+func (r *sessionRepository) InvalidateFamily(familyID string) ([]models.Session, error) {
+	return r.invalidate("family_id = ?", familyID)
+}
+
+func (r *sessionRepository) FindActiveByUserID(userID string) ([]models.Session, error) {
+	var sessions []models.Session
+	err := r.db.Where("user_id = ? AND is_active = ? AND expires_at > ?", userID, true, time.Now()).
+		Order("last_accessed_at DESC").
+		Find(&sessions).Error
+	if err != nil {
+		return nil, err
+	}
+	return sessions, nil
 }
 
-func (r *sessionRepository) InvalidateAllUserSessions(userID string) error {
-	return r.db.Model(&models.Session{}).Where("user_id = ?", userID).Update("is_active", false).Error
+// FindActiveByID matches either a session ID or a refresh token family ID.
+func (r *sessionRepository) FindActiveByID(userID, sessionID string) (*models.Session, error) {
+	var session models.Session
+	err := r.db.Where("user_id = ? AND (id = ? OR family_id = ?) AND is_active = ? AND expires_at > ?", userID, sessionID, sessionID, true, time.Now()).
+		First(&session).Error
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+func (r *sessionRepository) InvalidateUserSession(userID, sessionID string) ([]models.Session, error) {
+	sessions, err := r.invalidate("user_id = ? AND (id = ? OR family_id = ?)", userID, sessionID, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if len(sessions) == 0 {
+		return nil, ErrNotFound
+	}
+	return sessions, nil
+}
+
+func (r *sessionRepository) InvalidateAllUserSessionsExcept(userID, sessionID string) ([]models.Session, error) {
+	return r.invalidate("user_id = ? AND id <> ? AND (family_id IS NULL OR family_id <> ?)", userID, sessionID, sessionID)
+}
+
+// TouchSessions records last access times for a batch of sessions in a single
+// statement, sliding each expiry by idleTimeout but never past the absolute deadline.
+func (r *sessionRepository) TouchSessions(accesses map[uuid.UUID]time.Time, idleTimeout time.Duration) error {
+	if len(accesses) == 0 {
+		return nil
+	}
+
+	values := make([]string, 0, len(accesses))
+	args := make([]interface{}, 0, len(accesses)*2+1)
+	args = append(args, idleTimeout.Seconds())
+	for id, accessedAt := range accesses {
+		values = append(values, "(?::uuid, ?::timestamptz)")
+		args = append(args, id.String(), accessedAt)
+	}
+
+	query := fmt.Sprintf(`
+		UPDATE sessions AS s
+		SET last_accessed_at = v.accessed_at,
+			expires_at = LEAST(v.accessed_at + make_interval(secs => ?), s.absolute_expires_at)
+		FROM (VALUES %s) AS v(id, accessed_at)
+		WHERE s.id = v.id AND s.is_active AND s.family_id IS NULL AND s.last_accessed_at < v.accessed_at`,
+		strings.Join(values, ", "))
+
+	return r.db.Exec(query, args...).Error
+}
+
+// SetActiveOrganization sets the active organization of a session, or of every
+// row of a refresh token family so the choice survives rotation.
+func (r *sessionRepository) SetActiveOrganization(userID, sessionID string, organizationID *uuid.UUID) error {
+	result := r.db.Model(&models.Session{}).
+		Where("user_id = ? AND (id = ? OR family_id = ?) AND is_active = ?", userID, sessionID, sessionID, true).
+		Update("active_organization_id", organizationID)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
 }
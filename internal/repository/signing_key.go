@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"rest-api/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// signingKeyRotationLock is the Postgres advisory lock key serializing rotations across instances.
+const signingKeyRotationLock = 7340021
+
+type signingKeyRepository struct {
+	db *gorm.DB
+}
+
+func NewSigningKeyRepository(db *gorm.DB) SigningKeyRepository {
+	return &signingKeyRepository{db: db}
+}
+
+// FindUsable returns all keys that are still valid for verification, newest first.
+func (r *signingKeyRepository) FindUsable() ([]models.SigningKey, error) {
+	var keys []models.SigningKey
+	err := r.db.Where("expires_at IS NULL OR expires_at > ?", time.Now()).
+		Order("activated_at DESC").
+		Find(&keys).Error
+	if err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// Rotate stores key as the next signing key, unless another instance already
+// created a key of the same algorithm activating after staleBefore. The keys it
+// replaces retire when key activates, at key.ActivatedAt, and expire gracePeriod later.
+func (r *signingKeyRepository) Rotate(key *models.SigningKey, staleBefore time.Time, gracePeriod time.Duration) (bool, error) {
+	rotated := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("SELECT pg_advisory_xact_lock(?)", signingKeyRotationLock).Error; err != nil {
+			return err
+		}
+
+		var fresh int64
+		err := tx.Model(&models.SigningKey{}).
+			Where("retired_at IS NULL AND algorithm = ? AND activated_at > ?", key.Algorithm, staleBefore).
+			Count(&fresh).Error
+		if err != nil {
+			return err
+		}
+		if fresh > 0 {
+			return nil
+		}
+
+		err = tx.Model(&models.SigningKey{}).
+			Where("retired_at IS NULL").
+			Updates(map[string]interface{}{"retired_at": key.ActivatedAt, "expires_at": key.ActivatedAt.Add(gracePeriod)}).Error
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Create(key).Error; err != nil {
+			return err
+		}
+
+		rotated = true
+		return nil
+	})
+	return rotated, err
+}
+
+func (r *signingKeyRepository) DeleteExpired() error {
+	return r.db.Where("expires_at < ?", time.Now()).Delete(&models.SigningKey{}).Error
+}
@@ -0,0 +1,17 @@
+package repository
+
+import (
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// tenantDB returns a handle whose queries only match rows of one organization.
+// Repositories for organization-owned data are built on it, so no query they
+// run can reach another tenant's rows. Creates must still set OrganizationID.
+func tenantDB(db *gorm.DB, organizationID uuid.UUID) *gorm.DB {
+	return db.Where(clause.Eq{
+		Column: clause.Column{Table: clause.CurrentTable, Name: "organization_id"},
+		Value:  organizationID,
+	}).Session(&gorm.Session{})
+}
@@ -1,6 +1,7 @@
 package repository
 
 import (
+	"errors"
 	"rest-api/internal/models"
 	"time"
 
@@ -28,6 +29,39 @@ func (r *tokenRepository) FindByToken(token string) (*models.Token, error) {
 	return &t, nil
 }
 
+// FindByID returns an unused, unexpired token.
+func (r *tokenRepository) FindByID(id string) (*models.Token, error) {
+	var t models.Token
+	err := r.db.Where("id = ? AND used = ? AND expires_at > ?", id, false, time.Now()).First(&t).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
 func (r *tokenRepository) InvalidateToken(token string) error {
 	return r.db.Model(&models.Token{}).Where("token = ?", token).Update("used", true).Error
 }
+
+// RecordFailedAttempt atomically counts a wrong code presented with the token
+// and invalidates the token once maxAttempts is reached.
+func (r *tokenRepository) RecordFailedAttempt(token string, maxAttempts int) error {
+	return r.db.Model(&models.Token{}).Where("token = ?", token).Updates(map[string]interface{}{
+		"failed_attempts": gorm.Expr("failed_attempts + 1"),
+		"used":            gorm.Expr("used OR failed_attempts + 1 >= ?", maxAttempts),
+	}).Error
+}
+
+func (r *tokenRepository) FindIssuedSince(userID string, tokenType string, since time.Time) ([]models.Token, error) {
+	var tokens []models.Token
+	err := r.db.Where("user_id = ? AND type = ? AND created_at > ?", userID, tokenType, since).
+		Order("created_at DESC").
+		Find(&tokens).Error
+	if err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
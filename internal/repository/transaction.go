@@ -0,0 +1,38 @@
+package repository
+
+import "gorm.io/gorm"
+
+// Repositories are bound to one database transaction by a Transactor.
+type Repositories struct {
+	Users         UserRepository
+	Sessions      SessionRepository
+	Tokens        TokenRepository
+	Identities    IdentityRepository
+	Organizations OrganizationRepository
+	Outbox        OutboxRepository
+	Emails        EmailRepository
+}
+
+type transactor struct {
+	db *gorm.DB
+}
+
+func NewTransactor(db *gorm.DB) Transactor {
+	return &transactor{db: db}
+}
+
+// Transaction commits when fn returns nil and rolls back otherwise.
+// Repositories that open transactions of their own use savepoints inside it.
+func (t *transactor) Transaction(fn func(tx Repositories) error) error {
+	return t.db.Transaction(func(tx *gorm.DB) error {
+		return fn(Repositories{
+			Users:         NewUserRepository(tx),
+			Sessions:      NewSessionRepository(tx),
+			Tokens:        NewTokenRepository(tx),
+			Identities:    NewIdentityRepository(tx),
+			Organizations: NewOrganizationRepository(tx),
+			Outbox:        NewOutboxRepository(tx),
+			Emails:        NewEmailRepository(tx),
+		})
+	})
+}
@@ -2,10 +2,30 @@ package repository
 
 import (
 	"rest-api/internal/models"
+	"strings"
+	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
+// User statuses accepted by UserFilter.
+const (
+	UserStatusActive   = "active"
+	UserStatusDisabled = "disabled"
+	UserStatusLocked   = "locked"
+)
+
+// UserFilter selects a page of users. Zero values match every user.
+type UserFilter struct {
+	// Search matches a substring of the email or name, case-insensitively
+	Search   string
+	Status   string
+	Verified *bool
+	Offset   int
+	Limit    int
+}
+
 type userRepository struct {
 	db *gorm.DB
 }
@@ -36,6 +56,174 @@ func (r *userRepository) FindByID(id string) (*models.User, error) {
 	return &user, nil
 }
 
+// UpdatePassword sets a new password hash, which satisfies a required reset.
 func (r *userRepository) UpdatePassword(userID string, hashedPassword string) error {
+	return r.db.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+		"password_hash":           hashedPassword,
+		"password_reset_required": false,
+	}).Error
+}
+
+// UpdatePasswordHash replaces the hash of an unchanged password, as when a
+// legacy hash is upgraded. Unlike UpdatePassword it leaves a required reset in place.
+func (r *userRepository) UpdatePasswordHash(userID string, hashedPassword string) error {
 	return r.db.Model(&models.User{}).Where("id = ?", userID).Update("password_hash", hashedPassword).Error
 }
+
+func (r *userRepository) UpdateLocale(userID, locale string) error {
+	result := r.db.Model(&models.User{}).Where("id = ?", userID).Update("locale", locale)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// MarkEmailVerified records the verification time unless the email is already
+// verified, and reports whether it was.
+func (r *userRepository) MarkEmailVerified(userID string) (bool, error) {
+	result := r.db.Model(&models.User{}).Where("id = ? AND email_verified_at IS NULL", userID).Update("email_verified_at", time.Now())
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// RecordFailedLogin atomically counts a failed password attempt. Reaching
+// lockThreshold locks the account until lockedUntil and restarts the count so
+// backoff begins afresh once the lock expires. The updated lockout state is returned.
+func (r *userRepository) RecordFailedLogin(userID string, at time.Time, lockThreshold int, lockedUntil time.Time) (*models.User, error) {
+	var users []models.User
+	result := r.db.Model(&users).Clauses(clause.Returning{}).
+		Where("id = ?", userID).
+		Updates(map[string]interface{}{
+			"failed_login_attempts": gorm.Expr("CASE WHEN failed_login_attempts + 1 >= ? THEN 0 ELSE failed_login_attempts + 1 END", lockThreshold),
+			"locked_until":          gorm.Expr("CASE WHEN failed_login_attempts + 1 >= ? THEN ?::timestamptz ELSE locked_until END", lockThreshold, lockedUntil),
+			"last_failed_login_at":  at,
+		})
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if len(users) == 0 {
+		return nil, ErrNotFound
+	}
+	return &users[0], nil
+}
+
+// ResetFailedLogins clears the failed attempt count and any active lock.
+func (r *userRepository) ResetFailedLogins(userID string) error {
+	result := r.db.Model(&models.User{}).Where("id = ?", userID).Updates(map[string]interface{}{
+		"failed_login_attempts": 0,
+		"last_failed_login_at":  nil,
+		"locked_until":          nil,
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// List returns a page of users matching the filter, newest first, and the number of matches.
+func (r *userRepository) List(filter UserFilter) ([]models.User, int64, error) {
+	query := r.db.Model(&models.User{})
+	if filter.Search != "" {
+		pattern := "%" + escapeLike(strings.ToLower(filter.Search)) + "%"
+		query = query.Where("LOWER(email) LIKE ? OR LOWER(name) LIKE ?", pattern, pattern)
+	}
+	switch filter.Status {
+	case UserStatusActive:
+		query = query.Where("disabled_at IS NULL")
+	case UserStatusDisabled:
+		query = query.Where("disabled_at IS NOT NULL")
+	case UserStatusLocked:
+		query = query.Where("locked_until > ?", time.Now())
+	}
+	if filter.Verified != nil {
+		if *filter.Verified {
+			query = query.Where("email_verified_at IS NOT NULL")
+		} else {
+			query = query.Where("email_verified_at IS NULL")
+		}
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var users []models.User
+	err := query.Order("created_at DESC").Offset(filter.Offset).Limit(filter.Limit).Find(&users).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return users, total, nil
+}
+
+// escapeLike escapes the LIKE wildcards in s.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// SetDisabled disables the user at disabledAt, or enables them when it is nil.
+func (r *userRepository) SetDisabled(userID string, disabledAt *time.Time) error {
+	result := r.db.Model(&models.User{}).Where("id = ?", userID).Update("disabled_at", disabledAt)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *userRepository) SetPasswordResetRequired(userID string) error {
+	result := r.db.Model(&models.User{}).Where("id = ?", userID).Update("password_reset_required", true)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Delete removes the user together with their sessions, tokens, credentials,
+// grants, roles and memberships.
+func (r *userRepository) Delete(userID string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		owned := []interface{}{
+			&models.Session{},
+			&models.Token{},
+			&models.MFARecoveryCode{},
+			&models.UserMFA{},
+			&models.WebAuthnCredential{},
+			&models.WebAuthnCeremony{},
+			&models.UserIdentity{},
+			&models.PersonalAccessToken{},
+			&models.UserRole{},
+			&models.Membership{},
+			&models.OAuthAuthorizationCode{},
+			&models.OAuthConsent{},
+			&models.OAuthRefreshToken{},
+		}
+		for _, model := range owned {
+			if err := tx.Where("user_id = ?", userID).Delete(model).Error; err != nil {
+				return err
+			}
+		}
+
+		result := tx.Where("id = ?", userID).Delete(&models.User{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrNotFound
+		}
+		return nil
+	})
+}
@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"rest-api/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type webAuthnRepository struct {
+	db *gorm.DB
+}
+
+func NewWebAuthnRepository(db *gorm.DB) WebAuthnRepository {
+	return &webAuthnRepository{db: db}
+}
+
+func (r *webAuthnRepository) CreateCredential(credential *models.WebAuthnCredential) error {
+	return r.db.Create(credential).Error
+}
+
+func (r *webAuthnRepository) FindCredentialsByUserID(userID string) ([]models.WebAuthnCredential, error) {
+	var credentials []models.WebAuthnCredential
+	err := r.db.Where("user_id = ?", userID).Order("created_at").Find(&credentials).Error
+	if err != nil {
+		return nil, err
+	}
+	return credentials, nil
+}
+
+func (r *webAuthnRepository) UpdateCredentialUsage(credential *models.WebAuthnCredential) error {
+	return r.db.Model(&models.WebAuthnCredential{}).Where("id = ?", credential.ID).Updates(map[string]interface{}{
+		"sign_count":    credential.SignCount,
+		"clone_warning": credential.CloneWarning,
+		"backup_state":  credential.BackupState,
+		"last_used_at":  credential.LastUsedAt,
+	}).Error
+}
+
+func (r *webAuthnRepository) RenameCredential(userID, id, name string) error {
+	result := r.db.Model(&models.WebAuthnCredential{}).Where("id = ? AND user_id = ?", id, userID).Update("name", name)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *webAuthnRepository) DeleteCredential(userID, id string) error {
+	result := r.db.Where("id = ? AND user_id = ?", id, userID).Delete(&models.WebAuthnCredential{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (r *webAuthnRepository) CreateCeremony(ceremony *models.WebAuthnCeremony) error {
+	return r.db.Create(ceremony).Error
+}
+
+// ConsumeCeremony deletes and returns an unexpired ceremony so it can only be completed once.
+func (r *webAuthnRepository) ConsumeCeremony(id string, ceremonyType string) (*models.WebAuthnCeremony, error) {
+	var ceremonies []models.WebAuthnCeremony
+	err := r.db.Clauses(clause.Returning{}).
+		Where("id = ? AND type = ? AND expires_at > ?", id, ceremonyType, time.Now()).
+		Delete(&ceremonies).Error
+	if err != nil {
+		return nil, err
+	}
+	if len(ceremonies) == 0 {
+		return nil, ErrNotFound
+	}
+	return &ceremonies[0], nil
+}
+
+// DeleteExpiredCeremonies removes ceremonies that were started but never completed.
+func (r *webAuthnRepository) DeleteExpiredCeremonies() error {
+	return r.db.Where("expires_at < ?", time.Now()).Delete(&models.WebAuthnCeremony{}).Error
+}
@@ -0,0 +1,150 @@
+package repository
+
+import (
+	"errors"
+	"rest-api/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type webhookRepository struct {
+	db *gorm.DB
+}
+
+func NewWebhookRepository(db *gorm.DB) WebhookRepository {
+	return &webhookRepository{db: db}
+}
+
+func (r *webhookRepository) CreateEndpoint(endpoint *models.WebhookEndpoint) error {
+	return r.db.Create(endpoint).Error
+}
+
+func (r *webhookRepository) FindEndpoint(id string) (*models.WebhookEndpoint, error) {
+	var endpoint models.WebhookEndpoint
+	err := r.db.Where("id = ?", id).First(&endpoint).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &endpoint, nil
+}
+
+func (r *webhookRepository) ListEndpoints() ([]models.WebhookEndpoint, error) {
+	var endpoints []models.WebhookEndpoint
+	err := r.db.Order("created_at").Find(&endpoints).Error
+	if err != nil {
+		return nil, err
+	}
+	return endpoints, nil
+}
+
+func (r *webhookRepository) ListActiveEndpoints() ([]models.WebhookEndpoint, error) {
+	var endpoints []models.WebhookEndpoint
+	err := r.db.Where("active = ?", true).Find(&endpoints).Error
+	if err != nil {
+		return nil, err
+	}
+	return endpoints, nil
+}
+
+func (r *webhookRepository) UpdateEndpoint(endpoint *models.WebhookEndpoint) error {
+	result := r.db.Model(endpoint).Select("url", "secret", "events", "description", "active").Updates(endpoint)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// DeleteEndpoint removes an endpoint together with its delivery queue and log.
+func (r *webhookRepository) DeleteEndpoint(id string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("endpoint_id = ?", id).Delete(&models.WebhookDelivery{}).Error; err != nil {
+			return err
+		}
+
+		result := tx.Where("id = ?", id).Delete(&models.WebhookEndpoint{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrNotFound
+		}
+		return nil
+	})
+}
+
+func (r *webhookRepository) CreateDeliveries(deliveries []models.WebhookDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+	return r.db.Create(&deliveries).Error
+}
+
+// ClaimDue leases up to limit pending deliveries that are due by pushing their
+// next attempt to leaseUntil, so no other worker picks them up meanwhile. A
+// delivery whose worker dies is retried once the lease runs out.
+func (r *webhookRepository) ClaimDue(now, leaseUntil time.Time, limit int) ([]models.WebhookDelivery, error) {
+	due := r.db.Model(&models.WebhookDelivery{}).
+		Select("id").
+		Where("status = ? AND next_attempt_at <= ?", models.WebhookDeliveryPending, now).
+		Order("next_attempt_at").
+		Limit(limit).
+		Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"})
+
+	var deliveries []models.WebhookDelivery
+	err := r.db.Model(&deliveries).Clauses(clause.Returning{}).
+		Where("id IN (?)", due).
+		Update("next_attempt_at", leaseUntil).Error
+	if err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+// RecordAttempt stores the outcome of a delivery attempt.
+func (r *webhookRepository) RecordAttempt(delivery *models.WebhookDelivery) error {
+	return r.db.Model(delivery).Select(
+		"status", "attempts", "next_attempt_at", "last_attempt_at",
+		"response_status", "response_body", "last_error", "delivered_at",
+	).Updates(delivery).Error
+}
+
+func (r *webhookRepository) FindDelivery(endpointID, id string) (*models.WebhookDelivery, error) {
+	var delivery models.WebhookDelivery
+	err := r.db.Where("id = ? AND endpoint_id = ?", id, endpointID).First(&delivery).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &delivery, nil
+}
+
+// ListDeliveries returns a page of an endpoint's deliveries, newest first, and
+// the total number matching status (every status when empty).
+func (r *webhookRepository) ListDeliveries(endpointID, status string, offset, limit int) ([]models.WebhookDelivery, int64, error) {
+	query := r.db.Model(&models.WebhookDelivery{}).Where("endpoint_id = ?", endpointID)
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var deliveries []models.WebhookDelivery
+	err := query.Order("created_at DESC").Offset(offset).Limit(limit).Find(&deliveries).Error
+	if err != nil {
+		return nil, 0, err
+	}
+	return deliveries, total, nil
+}
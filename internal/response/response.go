@@ -1,7 +1,9 @@
 package response
 
 import (
+	"encoding/json"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -27,6 +29,280 @@ type UserResponse struct {
 	Name  string `json:"name" example:"John Doe"`
 }
 
+// PasskeyResponse represents a registered passkey in responses
+// @Description Passkey (WebAuthn credential) information
+type PasskeyResponse struct {
+	ID         string     `json:"id" example:"123e4567-e89b-12d3-a456-426614174000"`
+	Name       string     `json:"name" example:"MacBook Touch ID"`
+	Transports []string   `json:"transports" example:"internal,hybrid"`
+	Synced     bool       `json:"synced" example:"true"`
+	CreatedAt  time.Time  `json:"created_at"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+}
+
+// SessionResponse represents an active session in responses
+// @Description Active session information
+type SessionResponse struct {
+	ID             string    `json:"id" example:"123e4567-e89b-12d3-a456-426614174000"`
+	DeviceInfo     string    `json:"device_info" example:"Mozilla/5.0 (Macintosh; Intel Mac OS X 14_0)"`
+	IPAddress      string    `json:"ip_address" example:"203.0.113.7"`
+	CreatedAt      time.Time `json:"created_at"`
+	LastAccessedAt time.Time `json:"last_accessed_at"`
+	ExpiresAt      time.Time `json:"expires_at"`
+	Current        bool      `json:"current" example:"true"`
+	// Impersonated sessions were started by an administrator acting as the user
+	Impersonated bool `json:"impersonated"`
+}
+
+// IdentityResponse represents a linked external identity in responses
+// @Description External identity provider account linked to the user
+type IdentityResponse struct {
+	ID          string     `json:"id" example:"123e4567-e89b-12d3-a456-426614174000"`
+	Provider    string     `json:"provider" example:"google"`
+	Email       string     `json:"email" example:"user@example.com"`
+	LinkedAt    time.Time  `json:"linked_at"`
+	LastLoginAt *time.Time `json:"last_login_at,omitempty"`
+}
+
+// PersonalAccessTokenResponse represents a personal access token in responses
+// @Description Personal access token. The token itself is only returned on creation
+type PersonalAccessTokenResponse struct {
+	ID         string     `json:"id" example:"123e4567-e89b-12d3-a456-426614174000"`
+	Name       string     `json:"name" example:"CI deploy"`
+	Token      string     `json:"token,omitempty" example:"pat_3q2+7w..."`
+	Prefix     string     `json:"prefix" example:"pat_3q2x7wAb"`
+	Scopes     []string   `json:"scopes" example:"profile:read,sessions:read"`
+	ExpiresAt  *time.Time `json:"expires_at,omitempty"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	LastUsedIP string     `json:"last_used_ip,omitempty" example:"192.168.1.1"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// RoleResponse represents a role in responses
+// @Description Named set of permissions assignable to users
+type RoleResponse struct {
+	ID          string   `json:"id" example:"123e4567-e89b-12d3-a456-426614174000"`
+	Name        string   `json:"name" example:"admin"`
+	Description string   `json:"description" example:"Full administrative access"`
+	Builtin     bool     `json:"builtin"`
+	Permissions []string `json:"permissions" example:"users:read,users:write"`
+}
+
+// PermissionResponse represents a permission in responses
+// @Description Permission checked by the API
+type PermissionResponse struct {
+	Name        string `json:"name" example:"users:read"`
+	Description string `json:"description" example:"View user accounts"`
+}
+
+// AdminUserResponse represents a user as seen by administrators
+// @Description User account with its status
+type AdminUserResponse struct {
+	ID                    string     `json:"id" example:"123e4567-e89b-12d3-a456-426614174000"`
+	Email                 string     `json:"email" example:"user@example.com"`
+	Name                  string     `json:"name" example:"John Doe"`
+	EmailVerifiedAt       *time.Time `json:"email_verified_at,omitempty"`
+	DisabledAt            *time.Time `json:"disabled_at,omitempty"`
+	LockedUntil           *time.Time `json:"locked_until,omitempty"`
+	FailedLoginAttempts   int        `json:"failed_login_attempts" example:"0"`
+	PasswordResetRequired bool       `json:"password_reset_required"`
+	CreatedAt             time.Time  `json:"created_at"`
+}
+
+// ImpersonatorResponse represents the administrator behind an impersonation session
+// @Description Administrator acting as the current user
+type ImpersonatorResponse struct {
+	ID    string `json:"id" example:"123e4567-e89b-12d3-a456-426614174000"`
+	Email string `json:"email" example:"support@example.com"`
+	Name  string `json:"name" example:"Support Agent"`
+}
+
+// ProfileResponse represents the current user, flagged when an administrator
+// is impersonating them so clients can show it
+// @Description Current user's profile
+type ProfileResponse struct {
+	UserResponse
+	EmailVerifiedAt *time.Time            `json:"email_verified_at,omitempty"`
+	Locale          string                `json:"locale,omitempty" example:"de"`
+	Impersonated    bool                  `json:"impersonated"`
+	Impersonator    *ImpersonatorResponse `json:"impersonator,omitempty"`
+}
+
+// AdminUserDetailResponse represents a single user with their sessions
+// @Description User account with MFA status and active sessions
+type AdminUserDetailResponse struct {
+	AdminUserResponse
+	MFAEnabled bool              `json:"mfa_enabled"`
+	Sessions   []SessionResponse `json:"sessions"`
+}
+
+// UserListResponse represents a page of users
+// @Description Page of users matching a search
+type UserListResponse struct {
+	Users   []AdminUserResponse `json:"users"`
+	Total   int64               `json:"total" example:"42"`
+	Page    int                 `json:"page" example:"1"`
+	PerPage int                 `json:"per_page" example:"20"`
+}
+
+// AuditEventResponse represents an entry in the security audit log
+// @Description Security-relevant action, who performed it and from where
+type AuditEventResponse struct {
+	ID        string                 `json:"id" example:"123e4567-e89b-12d3-a456-426614174000"`
+	Type      string                 `json:"type" example:"login"`
+	ActorID   string                 `json:"actor_id,omitempty" example:"123e4567-e89b-12d3-a456-426614174000"`
+	TargetID  string                 `json:"target_id,omitempty" example:"123e4567-e89b-12d3-a456-426614174000"`
+	IPAddress string                 `json:"ip_address,omitempty" example:"203.0.113.7"`
+	UserAgent string                 `json:"user_agent,omitempty" example:"Mozilla/5.0"`
+	Outcome   string                 `json:"outcome" example:"success"`
+	Metadata  map[string]interface{} `json:"metadata,omitempty"`
+	CreatedAt time.Time              `json:"created_at"`
+}
+
+// AuditEventListResponse represents a page of audit events
+// @Description Page of audit events, newest first
+type AuditEventListResponse struct {
+	Events  []AuditEventResponse `json:"events"`
+	Total   int64                `json:"total" example:"42"`
+	Page    int                  `json:"page" example:"1"`
+	PerPage int                  `json:"per_page" example:"50"`
+}
+
+// WebhookResponse represents a webhook endpoint
+// @Description Webhook endpoint; the secret is only returned when the endpoint is created
+type WebhookResponse struct {
+	ID          string    `json:"id" example:"123e4567-e89b-12d3-a456-426614174000"`
+	URL         string    `json:"url" example:"https://billing.example.com/webhooks/auth"`
+	Events      []string  `json:"events" example:"user.registered,user.deleted"`
+	Description string    `json:"description,omitempty" example:"Billing system"`
+	Active      bool      `json:"active" example:"true"`
+	Secret      string    `json:"secret,omitempty" example:"whsec_0123456789abcdef"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// WebhookDeliveryResponse represents one delivery of an event to an endpoint
+// @Description Webhook delivery with the outcome of its latest attempt
+type WebhookDeliveryResponse struct {
+	ID             string          `json:"id" example:"123e4567-e89b-12d3-a456-426614174000"`
+	EventID        string          `json:"event_id" example:"123e4567-e89b-12d3-a456-426614174000"`
+	EventType      string          `json:"event_type" example:"user.registered"`
+	Status         string          `json:"status" example:"failed"`
+	Attempts       int             `json:"attempts" example:"3"`
+	NextAttemptAt  *time.Time      `json:"next_attempt_at,omitempty"`
+	LastAttemptAt  *time.Time      `json:"last_attempt_at,omitempty"`
+	ResponseStatus int             `json:"response_status,omitempty" example:"500"`
+	ResponseBody   string          `json:"response_body,omitempty" example:"internal error"`
+	Error          string          `json:"error,omitempty" example:"endpoint responded with status 500"`
+	DeliveredAt    *time.Time      `json:"delivered_at,omitempty"`
+	Payload        json.RawMessage `json:"payload" swaggertype:"object"`
+	CreatedAt      time.Time       `json:"created_at"`
+}
+
+// WebhookDeliveryListResponse represents a page of an endpoint's deliveries
+// @Description Page of webhook deliveries, newest first
+type WebhookDeliveryListResponse struct {
+	Deliveries []WebhookDeliveryResponse `json:"deliveries"`
+	Total      int64                     `json:"total" example:"42"`
+	Page       int                       `json:"page" example:"1"`
+	PerPage    int                       `json:"per_page" example:"20"`
+}
+
+// EmailMessageResponse represents an outgoing email in the delivery queue.
+// Bodies are left out because they carry live sign-in and reset links.
+// @Description Queued email with the outcome of its latest attempt
+type EmailMessageResponse struct {
+	ID            string     `json:"id" example:"123e4567-e89b-12d3-a456-426614174000"`
+	To            string     `json:"to" example:"user@example.com"`
+	Subject       string     `json:"subject" example:"Password Reset"`
+	Status        string     `json:"status" example:"dead"`
+	Attempts      int        `json:"attempts" example:"8"`
+	NextAttemptAt *time.Time `json:"next_attempt_at,omitempty"`
+	LastAttemptAt *time.Time `json:"last_attempt_at,omitempty"`
+	Error         string     `json:"error,omitempty" example:"550 mailbox unavailable"`
+	SentAt        *time.Time `json:"sent_at,omitempty"`
+	ExpiresAt     *time.Time `json:"expires_at,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+}
+
+// EmailMessageListResponse represents a page of the email queue
+// @Description Page of queued emails, newest first
+type EmailMessageListResponse struct {
+	Messages []EmailMessageResponse `json:"messages"`
+	Total    int64                  `json:"total" example:"42"`
+	Page     int                    `json:"page" example:"1"`
+	PerPage  int                    `json:"per_page" example:"20"`
+}
+
+// OrganizationResponse represents an organization the user belongs to
+// @Description Organization with the current user's role in it
+type OrganizationResponse struct {
+	ID        string    `json:"id" example:"123e4567-e89b-12d3-a456-426614174000"`
+	Name      string    `json:"name" example:"Acme Inc"`
+	Slug      string    `json:"slug" example:"acme"`
+	Role      string    `json:"role" example:"owner"`
+	Active    bool      `json:"active"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// MemberResponse represents an organization member
+// @Description Member of an organization
+type MemberResponse struct {
+	UserID   string    `json:"user_id" example:"123e4567-e89b-12d3-a456-426614174000"`
+	Email    string    `json:"email" example:"user@example.com"`
+	Name     string    `json:"name" example:"John Doe"`
+	Role     string    `json:"role" example:"member"`
+	JoinedAt time.Time `json:"joined_at"`
+}
+
+// InvitationResponse represents an invitation to an organization
+// @Description Pending invitation to join an organization
+type InvitationResponse struct {
+	ID               string    `json:"id" example:"123e4567-e89b-12d3-a456-426614174000"`
+	OrganizationID   string    `json:"organization_id" example:"123e4567-e89b-12d3-a456-426614174000"`
+	OrganizationName string    `json:"organization_name" example:"Acme Inc"`
+	Email            string    `json:"email" example:"user@example.com"`
+	Role             string    `json:"role" example:"member"`
+	ExpiresAt        time.Time `json:"expires_at"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+// OAuthClientResponse represents a registered OAuth client in responses
+// @Description Application registered with the authorization server. The secret is only returned on registration
+type OAuthClientResponse struct {
+	ClientID     string    `json:"client_id" example:"123e4567-e89b-12d3-a456-426614174000"`
+	ClientSecret string    `json:"client_secret,omitempty"`
+	Name         string    `json:"name" example:"My App"`
+	RedirectURIs []string  `json:"redirect_uris"`
+	GrantTypes   []string  `json:"grant_types"`
+	Scopes       []string  `json:"scopes"`
+	Public       bool      `json:"public"`
+	FirstParty   bool      `json:"first_party"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// OAuthConsentResponse describes an authorization request awaiting the user's decision
+// @Description Client and scopes to show on the consent screen
+type OAuthConsentResponse struct {
+	ClientID        string   `json:"client_id"`
+	ClientName      string   `json:"client_name" example:"My App"`
+	Scopes          []string `json:"scopes" example:"openid,email"`
+	ConsentRequired bool     `json:"consent_required"`
+}
+
+// OAuthRedirectResponse carries the client redirect for a consent decision
+// @Description URL the browser must be sent to, carrying the code or error
+type OAuthRedirectResponse struct {
+	RedirectTo string `json:"redirect_to" example:"https://app.example.com/callback?code=abc&state=xyz"`
+}
+
+// OAuthErrorResponse is an OAuth 2.0 protocol error (RFC 6749 section 5.2)
+// @Description OAuth 2.0 error response
+type OAuthErrorResponse struct {
+	Error            string `json:"error" example:"invalid_grant"`
+	ErrorDescription string `json:"error_description,omitempty"`
+}
+
 // HealthResponse represents a health check response
 // @Description Health check response with status
 type HealthResponse struct {
@@ -57,6 +333,14 @@ func Unauthorized(c *gin.Context, err error) {
 	Error(c, http.StatusUnauthorized, err)
 }
 
+func Forbidden(c *gin.Context, err error) {
+	Error(c, http.StatusForbidden, err)
+}
+
+func NotFound(c *gin.Context, err error) {
+	Error(c, http.StatusNotFound, err)
+}
+
 func InternalError(c *gin.Context, err error) {
 	Error(c, http.StatusInternalServerError, err)
 }
@@ -0,0 +1,692 @@
+package server
+
+import (
+	stderrors "errors"
+	"net/http"
+
+	"rest-api/internal/errors"
+	"rest-api/internal/models"
+	"rest-api/internal/response"
+	"rest-api/internal/service"
+	"rest-api/pkg/validator"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// @Summary Unlock a user account
+// @Description Clear a user's failed login attempts and lift any active lockout
+// @Tags admin
+// @Security Bearer
+// @Produce json
+// @Param id path string true "User ID"
+// @Success 200 {object} response.SuccessResponse
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 401 {object} response.ErrorResponse
+// @Failure 403 {object} response.ErrorResponse
+// @Failure 404 {object} response.ErrorResponse
+// @Router /v1/admin/users/{id}/unlock [post]
+func (s *Server) handleAdminUnlockUser() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			response.BadRequest(c, errors.ErrInvalidRequest)
+			return
+		}
+
+		if err := s.authSvc.UnlockAccount(userID.String()); err != nil {
+			if stderrors.Is(err, errors.ErrUserNotFound) {
+				response.NotFound(c, err)
+				return
+			}
+			s.logger.Error("failed to unlock account", err)
+			response.InternalError(c, errors.ErrInvalidRequest)
+			return
+		}
+
+		s.auditUserAction(c, models.AuditAccountUnlocked, userID, map[string]interface{}{"method": "admin"})
+
+		response.SuccessWithMessage(c, "account unlocked", nil)
+	}
+}
+
+// @Summary List users
+// @Description Search users by email or name and filter them by status and email verification
+// @Tags admin
+// @Security Bearer
+// @Produce json
+// @Param q query string false "Substring of the email or name"
+// @Param status query string false "active, disabled or locked"
+// @Param verified query bool false "Whether the email is verified"
+// @Param page query int false "Page number, starting at 1"
+// @Param per_page query int false "Users per page (max 100)"
+// @Success 200 {object} response.SuccessResponse{data=response.UserListResponse}
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 401 {object} response.ErrorResponse
+// @Failure 403 {object} response.ErrorResponse
+// @Router /v1/admin/users [get]
+func (s *Server) handleAdminListUsers() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req validator.ListUsersRequest
+		if err := c.ShouldBindQuery(&req); err != nil {
+			response.BadRequest(c, errors.ErrInvalidRequest)
+			return
+		}
+
+		page, err := s.userSvc.Search(service.UserQuery{
+			Search:   req.Search,
+			Status:   req.Status,
+			Verified: req.Verified,
+			Page:     req.Page,
+			PerPage:  req.PerPage,
+		})
+		if err != nil {
+			s.logger.Error("failed to list users", err)
+			response.InternalError(c, errors.ErrInvalidRequest)
+			return
+		}
+
+		users := make([]response.AdminUserResponse, len(page.Users))
+		for i := range page.Users {
+			users[i] = toAdminUserResponse(&page.Users[i])
+		}
+
+		response.Success(c, response.UserListResponse{
+			Users:   users,
+			Total:   page.Total,
+			Page:    page.Page,
+			PerPage: page.PerPage,
+		})
+	}
+}
+
+// @Summary Get a user
+// @Description Get a user's account status, MFA status and active sessions
+// @Tags admin
+// @Security Bearer
+// @Produce json
+// @Param id path string true "User ID"
+// @Success 200 {object} response.SuccessResponse{data=response.AdminUserDetailResponse}
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 401 {object} response.ErrorResponse
+// @Failure 403 {object} response.ErrorResponse
+// @Failure 404 {object} response.ErrorResponse
+// @Router /v1/admin/users/{id} [get]
+func (s *Server) handleAdminGetUser() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, ok := s.adminTargetUser(c)
+		if !ok {
+			return
+		}
+
+		sessions, err := s.authSvc.ListSessions(user.ID.String())
+		if err != nil {
+			s.logger.Error("failed to list sessions", err)
+			response.InternalError(c, errors.ErrInvalidRequest)
+			return
+		}
+
+		result := response.AdminUserDetailResponse{
+			AdminUserResponse: toAdminUserResponse(user),
+			MFAEnabled:        s.mfaSvc.IsEnabled(user.ID.String()),
+			Sessions:          make([]response.SessionResponse, len(sessions)),
+		}
+		for i := range sessions {
+			result.Sessions[i] = toSessionResponse(&sessions[i], "")
+		}
+
+		response.Success(c, result)
+	}
+}
+
+// @Summary Disable a user
+// @Description Block every sign-in method for a user and revoke their sessions
+// @Tags admin
+// @Security Bearer
+// @Produce json
+// @Param id path string true "User ID"
+// @Success 200 {object} response.SuccessResponse
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 401 {object} response.ErrorResponse
+// @Failure 403 {object} response.ErrorResponse
+// @Failure 404 {object} response.ErrorResponse
+// @Router /v1/admin/users/{id}/disable [post]
+func (s *Server) handleAdminDisableUser() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, ok := s.adminTargetUser(c)
+		if !ok {
+			return
+		}
+		if s.isCurrentUser(c, user) {
+			response.BadRequest(c, errors.ErrOwnAccount)
+			return
+		}
+
+		if err := s.userSvc.Disable(user.ID.String()); err != nil {
+			s.respondAdminUserError(c, "failed to disable user", err)
+			return
+		}
+		s.auditUserAction(c, models.AuditUserDisabled, user.ID, nil)
+
+		// Access tokens are rejected by authMiddleware; sessions and refresh tokens are revoked outright
+		if err := s.authSvc.InvalidateAllSessions(user.ID.String(), clientInfo(c)); err != nil {
+			s.logger.Error("failed to revoke sessions", err)
+			response.InternalError(c, errors.ErrInvalidRequest)
+			return
+		}
+
+		response.SuccessWithMessage(c, "user disabled", nil)
+	}
+}
+
+// @Summary Enable a user
+// @Description Allow a disabled user to sign in again
+// @Tags admin
+// @Security Bearer
+// @Produce json
+// @Param id path string true "User ID"
+// @Success 200 {object} response.SuccessResponse
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 401 {object} response.ErrorResponse
+// @Failure 403 {object} response.ErrorResponse
+// @Failure 404 {object} response.ErrorResponse
+// @Router /v1/admin/users/{id}/enable [post]
+func (s *Server) handleAdminEnableUser() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, ok := s.adminTargetUser(c)
+		if !ok {
+			return
+		}
+
+		if err := s.userSvc.Enable(user.ID.String()); err != nil {
+			s.respondAdminUserError(c, "failed to enable user", err)
+			return
+		}
+
+		s.auditUserAction(c, models.AuditUserEnabled, user.ID, nil)
+
+		response.SuccessWithMessage(c, "user enabled", nil)
+	}
+}
+
+// @Summary Force a password reset
+// @Description Block password sign-in until the user resets their password, revoke their sessions and email them a reset link
+// @Tags admin
+// @Security Bearer
+// @Produce json
+// @Param id path string true "User ID"
+// @Success 200 {object} response.SuccessResponse
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 401 {object} response.ErrorResponse
+// @Failure 403 {object} response.ErrorResponse
+// @Failure 404 {object} response.ErrorResponse
+// @Failure 500 {object} response.ErrorResponse
+// @Router /v1/admin/users/{id}/force-password-reset [post]
+func (s *Server) handleAdminForcePasswordReset() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, ok := s.adminTargetUser(c)
+		if !ok {
+			return
+		}
+
+		// The reset link is emailed once this commits; the user can still request
+		// another one through forgot-password
+		if err := s.userSvc.ForcePasswordReset(user); err != nil {
+			s.respondAdminUserError(c, "failed to require password reset", err)
+			return
+		}
+		s.auditUserAction(c, models.AuditPasswordResetForced, user.ID, nil)
+
+		if err := s.authSvc.InvalidateAllSessions(user.ID.String(), clientInfo(c)); err != nil {
+			s.logger.Error("failed to revoke sessions", err)
+			response.InternalError(c, errors.ErrInvalidRequest)
+			return
+		}
+
+		response.SuccessWithMessage(c, "password reset required", nil)
+	}
+}
+
+// @Summary Revoke a user's sessions
+// @Description Sign a user out everywhere by revoking all their sessions and refresh tokens
+// @Tags admin
+// @Security Bearer
+// @Produce json
+// @Param id path string true "User ID"
+// @Success 200 {object} response.SuccessResponse
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 401 {object} response.ErrorResponse
+// @Failure 403 {object} response.ErrorResponse
+// @Failure 404 {object} response.ErrorResponse
+// @Router /v1/admin/users/{id}/revoke-sessions [post]
+func (s *Server) handleAdminRevokeUserSessions() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, ok := s.adminTargetUser(c)
+		if !ok {
+			return
+		}
+
+		if err := s.authSvc.InvalidateAllSessions(user.ID.String(), clientInfo(c)); err != nil {
+			s.logger.Error("failed to revoke sessions", err)
+			response.InternalError(c, errors.ErrInvalidRequest)
+			return
+		}
+
+		s.auditUserAction(c, models.AuditSessionsRevoked, user.ID, map[string]interface{}{"scope": "all"})
+
+		response.SuccessWithMessage(c, "sessions revoked", nil)
+	}
+}
+
+// @Summary Delete a user
+// @Description Permanently delete a user with their sessions, credentials, tokens, roles and memberships
+// @Tags admin
+// @Security Bearer
+// @Produce json
+// @Param id path string true "User ID"
+// @Success 200 {object} response.SuccessResponse
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 401 {object} response.ErrorResponse
+// @Failure 403 {object} response.ErrorResponse
+// @Failure 404 {object} response.ErrorResponse
+// @Router /v1/admin/users/{id} [delete]
+func (s *Server) handleAdminDeleteUser() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, ok := s.adminTargetUser(c)
+		if !ok {
+			return
+		}
+		if s.isCurrentUser(c, user) {
+			response.BadRequest(c, errors.ErrOwnAccount)
+			return
+		}
+
+		// Revoked before the sessions are deleted, so open impersonations are recorded as ended
+		if err := s.authSvc.InvalidateAllSessions(user.ID.String(), clientInfo(c)); err != nil {
+			s.logger.Error("failed to revoke sessions", err)
+			response.InternalError(c, errors.ErrInvalidRequest)
+			return
+		}
+
+		if err := s.userSvc.Delete(user.ID.String()); err != nil {
+			s.respondAdminUserError(c, "failed to delete user", err)
+			return
+		}
+
+		// The record outlives the account, so keep who it was
+		s.auditUserAction(c, models.AuditUserDeleted, user.ID, map[string]interface{}{"email": user.Email})
+
+		response.SuccessWithMessage(c, "user deleted", nil)
+	}
+}
+
+// adminTargetUser loads the user named by the id path parameter, responding
+// with an error and returning false if there is none.
+func (s *Server) adminTargetUser(c *gin.Context) (*models.User, bool) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		response.BadRequest(c, errors.ErrInvalidRequest)
+		return nil, false
+	}
+
+	user, err := s.userSvc.GetByID(userID.String())
+	if err != nil {
+		response.NotFound(c, errors.ErrUserNotFound)
+		return nil, false
+	}
+	return user, true
+}
+
+// isCurrentUser reports whether user is the one making the request.
+func (s *Server) isCurrentUser(c *gin.Context, user *models.User) bool {
+	current, exists := c.Get("user")
+	return exists && current.(*models.User).ID == user.ID
+}
+
+// respondAdminUserError maps errors from changing a user account.
+func (s *Server) respondAdminUserError(c *gin.Context, message string, err error) {
+	if stderrors.Is(err, errors.ErrUserNotFound) {
+		response.NotFound(c, err)
+		return
+	}
+	s.logger.Error(message, err)
+	response.InternalError(c, errors.ErrInvalidRequest)
+}
+
+func toAdminUserResponse(user *models.User) response.AdminUserResponse {
+	return response.AdminUserResponse{
+		ID:                    user.ID.String(),
+		Email:                 user.Email,
+		Name:                  user.Name,
+		EmailVerifiedAt:       user.EmailVerifiedAt,
+		DisabledAt:            user.DisabledAt,
+		LockedUntil:           user.LockedUntil,
+		FailedLoginAttempts:   user.FailedLoginAttempts,
+		PasswordResetRequired: user.PasswordResetRequired,
+		CreatedAt:             user.CreatedAt,
+	}
+}
+
+// @Summary List roles
+// @Description List roles with their permissions
+// @Tags admin
+// @Security Bearer
+// @Produce json
+// @Success 200 {object} response.SuccessResponse{data=[]response.RoleResponse}
+// @Failure 401 {object} response.ErrorResponse
+// @Failure 403 {object} response.ErrorResponse
+// @Router /v1/admin/roles [get]
+func (s *Server) handleAdminListRoles() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		roles, err := s.rbacSvc.ListRoles()
+		if err != nil {
+			s.logger.Error("failed to list roles", err)
+			response.InternalError(c, errors.ErrInvalidRequest)
+			return
+		}
+
+		response.Success(c, toRoleResponses(roles))
+	}
+}
+
+// @Summary Create a role
+// @Description Create a custom role granting a set of permissions
+// @Tags admin
+// @Security Bearer
+// @Accept json
+// @Produce json
+// @Param request body validator.CreateRoleRequest true "Role name, description and permissions"
+// @Success 201 {object} response.SuccessResponse{data=response.RoleResponse}
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 401 {object} response.ErrorResponse
+// @Failure 403 {object} response.ErrorResponse
+// @Failure 409 {object} response.ErrorResponse
+// @Router /v1/admin/roles [post]
+func (s *Server) handleAdminCreateRole() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req validator.CreateRoleRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.BadRequest(c, errors.ErrInvalidRequest)
+			return
+		}
+
+		role, err := s.rbacSvc.CreateRole(req.Name, req.Description, req.Permissions)
+		if err != nil {
+			switch {
+			case stderrors.Is(err, errors.ErrInvalidRequest):
+				response.BadRequest(c, err)
+			case stderrors.Is(err, errors.ErrRoleExists):
+				response.Error(c, http.StatusConflict, err)
+			default:
+				s.logger.Error("failed to create role", err)
+				response.InternalError(c, errors.ErrInvalidRequest)
+			}
+			return
+		}
+
+		c.JSON(http.StatusCreated, response.SuccessResponse{
+			Message: "role created",
+			Data:    toRoleResponse(role),
+		})
+	}
+}
+
+// @Summary Delete a role
+// @Description Delete a custom role and remove it from every user. Built-in roles cannot be deleted.
+// @Tags admin
+// @Security Bearer
+// @Produce json
+// @Param id path string true "Role ID"
+// @Success 200 {object} response.SuccessResponse
+// @Failure 401 {object} response.ErrorResponse
+// @Failure 403 {object} response.ErrorResponse
+// @Failure 404 {object} response.ErrorResponse
+// @Router /v1/admin/roles/{id} [delete]
+func (s *Server) handleAdminDeleteRole() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := s.rbacSvc.DeleteRole(c.Param("id")); err != nil {
+			switch {
+			case stderrors.Is(err, errors.ErrRoleNotFound):
+				response.NotFound(c, err)
+			case stderrors.Is(err, errors.ErrBuiltinRole):
+				response.Forbidden(c, err)
+			default:
+				s.logger.Error("failed to delete role", err)
+				response.InternalError(c, errors.ErrInvalidRequest)
+			}
+			return
+		}
+
+		response.SuccessWithMessage(c, "role deleted", nil)
+	}
+}
+
+// @Summary List permissions
+// @Description List the permissions roles can grant
+// @Tags admin
+// @Security Bearer
+// @Produce json
+// @Success 200 {object} response.SuccessResponse{data=[]response.PermissionResponse}
+// @Failure 401 {object} response.ErrorResponse
+// @Failure 403 {object} response.ErrorResponse
+// @Router /v1/admin/permissions [get]
+func (s *Server) handleAdminListPermissions() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		permissions, err := s.rbacSvc.ListPermissions()
+		if err != nil {
+			s.logger.Error("failed to list permissions", err)
+			response.InternalError(c, errors.ErrInvalidRequest)
+			return
+		}
+
+		result := make([]response.PermissionResponse, len(permissions))
+		for i, permission := range permissions {
+			result[i] = response.PermissionResponse{
+				Name:        permission.Name,
+				Description: permission.Description,
+			}
+		}
+
+		response.Success(c, result)
+	}
+}
+
+// @Summary List a user's roles
+// @Description List the roles assigned to a user
+// @Tags admin
+// @Security Bearer
+// @Produce json
+// @Param id path string true "User ID"
+// @Success 200 {object} response.SuccessResponse{data=[]response.RoleResponse}
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 401 {object} response.ErrorResponse
+// @Failure 403 {object} response.ErrorResponse
+// @Router /v1/admin/users/{id}/roles [get]
+func (s *Server) handleAdminListUserRoles() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			response.BadRequest(c, errors.ErrInvalidRequest)
+			return
+		}
+
+		roles, err := s.rbacSvc.UserRoles(userID.String())
+		if err != nil {
+			s.logger.Error("failed to list user roles", err)
+			response.InternalError(c, errors.ErrInvalidRequest)
+			return
+		}
+
+		response.Success(c, toRoleResponses(roles))
+	}
+}
+
+// @Summary Assign a role
+// @Description Assign a role to a user. Assigning a role the user already has succeeds without change.
+// @Tags admin
+// @Security Bearer
+// @Accept json
+// @Produce json
+// @Param id path string true "User ID"
+// @Param request body validator.AssignRoleRequest true "Role to assign"
+// @Success 200 {object} response.SuccessResponse{data=response.RoleResponse}
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 401 {object} response.ErrorResponse
+// @Failure 403 {object} response.ErrorResponse
+// @Failure 404 {object} response.ErrorResponse
+// @Router /v1/admin/users/{id}/roles [post]
+func (s *Server) handleAdminAssignRole() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req validator.AssignRoleRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.BadRequest(c, errors.ErrInvalidRequest)
+			return
+		}
+
+		user, err := s.userSvc.GetByID(c.Param("id"))
+		if err != nil {
+			response.NotFound(c, errors.ErrUserNotFound)
+			return
+		}
+
+		role, err := s.rbacSvc.AssignRole(user.ID, req.RoleID)
+		if err != nil {
+			if stderrors.Is(err, errors.ErrRoleNotFound) {
+				response.NotFound(c, err)
+				return
+			}
+			s.logger.Error("failed to assign role", err)
+			response.InternalError(c, errors.ErrInvalidRequest)
+			return
+		}
+
+		s.auditUserAction(c, models.AuditRoleAssigned, user.ID, map[string]interface{}{
+			"role_id": role.ID.String(),
+			"role":    role.Name,
+		})
+
+		response.SuccessWithMessage(c, "role assigned", toRoleResponse(role))
+	}
+}
+
+// @Summary Remove a role
+// @Description Remove a role from a user
+// @Tags admin
+// @Security Bearer
+// @Produce json
+// @Param id path string true "User ID"
+// @Param role_id path string true "Role ID"
+// @Success 200 {object} response.SuccessResponse
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 401 {object} response.ErrorResponse
+// @Failure 403 {object} response.ErrorResponse
+// @Failure 404 {object} response.ErrorResponse
+// @Router /v1/admin/users/{id}/roles/{role_id} [delete]
+func (s *Server) handleAdminUnassignRole() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := uuid.Parse(c.Param("id"))
+		if err != nil {
+			response.BadRequest(c, errors.ErrInvalidRequest)
+			return
+		}
+
+		if err := s.rbacSvc.UnassignRole(userID.String(), c.Param("role_id")); err != nil {
+			if stderrors.Is(err, errors.ErrRoleNotFound) {
+				response.NotFound(c, err)
+				return
+			}
+			s.logger.Error("failed to remove role", err)
+			response.InternalError(c, errors.ErrInvalidRequest)
+			return
+		}
+
+		s.auditUserAction(c, models.AuditRoleUnassigned, userID, map[string]interface{}{"role_id": c.Param("role_id")})
+
+		response.SuccessWithMessage(c, "role removed", nil)
+	}
+}
+
+func toRoleResponse(role *models.Role) response.RoleResponse {
+	permissions := make([]string, len(role.Permissions))
+	for i, permission := range role.Permissions {
+		permissions[i] = permission.Name
+	}
+
+	return response.RoleResponse{
+		ID:          role.ID.String(),
+		Name:        role.Name,
+		Description: role.Description,
+		Builtin:     role.Builtin,
+		Permissions: permissions,
+	}
+}
+
+func toRoleResponses(roles []models.Role) []response.RoleResponse {
+	result := make([]response.RoleResponse, len(roles))
+	for i := range roles {
+		result[i] = toRoleResponse(&roles[i])
+	}
+	return result
+}
+
+// @Summary Impersonate a user
+// @Description Sign in as a user to reproduce an issue. The session expires after IMPERSONATION_TTL, cannot change credentials, sessions or grants, and carries no admin permissions. Start and end are recorded in the audit log
+// @Tags admin
+// @Security Bearer
+// @Accept json
+// @Produce json
+// @Param id path string true "User ID"
+// @Param request body validator.ImpersonateUserRequest true "Reason for the impersonation"
+// @Success 200 {object} response.SuccessResponse
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 401 {object} response.ErrorResponse
+// @Failure 403 {object} response.ErrorResponse
+// @Failure 404 {object} response.ErrorResponse
+// @Router /v1/admin/users/{id}/impersonate [post]
+func (s *Server) handleAdminImpersonateUser() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req validator.ImpersonateUserRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.BadRequest(c, errors.ErrInvalidRequest)
+			return
+		}
+
+		target, ok := s.adminTargetUser(c)
+		if !ok {
+			return
+		}
+		impersonator := c.MustGet("user").(*models.User)
+
+		session, err := s.authSvc.Impersonate(impersonator, target, clientInfo(c))
+		if err != nil {
+			if stderrors.Is(err, errors.ErrCannotImpersonate) || stderrors.Is(err, errors.ErrAccountDisabled) {
+				response.BadRequest(c, err)
+				return
+			}
+			s.logger.Error("failed to start impersonation", err)
+			response.InternalError(c, errors.ErrInvalidRequest)
+			return
+		}
+
+		// No impersonation without a record of it
+		if err := s.recordAudit(c, &models.AuditEvent{
+			Type:     models.AuditImpersonationStart,
+			ActorID:  &impersonator.ID,
+			TargetID: &target.ID,
+			Metadata: map[string]interface{}{
+				"reason":     req.Reason,
+				"session_id": session.PublicID().String(),
+				"expires_at": session.ExpiresAt,
+			},
+		}); err != nil {
+			s.logger.Error("failed to record audit event", err)
+			if err := s.authSvc.RevokeSession(target.ID.String(), session.PublicID().String(), clientInfo(c)); err != nil {
+				s.logger.Error("failed to revoke impersonation session", err)
+			}
+			response.InternalError(c, errors.ErrInvalidRequest)
+			return
+		}
+
+		s.respondWithSession(c, "impersonation started", session)
+	}
+}
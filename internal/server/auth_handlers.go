@@ -1,24 +1,31 @@
 package server
 
 import (
-	"fmt"
+	stderrors "errors"
+	"math"
+	"net/http"
+	"net/url"
 	"rest-api/internal/errors"
+	"rest-api/internal/models"
 	"rest-api/internal/response"
 	"rest-api/internal/service"
 	"rest-api/pkg/validator"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/google/uuid"
 )
 
 // @Summary Register a new user
-// @Description Register a new user with email and password
+// @Description Register a new user with email and password. With an invitation token the user joins the inviting organization, which becomes the session's active one; REGISTRATION_MODE=invite_only requires it
 // @Tags auth
 // @Accept json
 // @Produce json
 // @Param request body validator.RegisterRequest true "Register Request"
 // @Success 200 {object} response.SuccessResponse
 // @Failure 400 {object} response.ErrorResponse
+// @Failure 403 {object} response.ErrorResponse
 // @Router /v1/register [post]
 func (s *Server) handleRegister() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -28,6 +35,24 @@ func (s *Server) handleRegister() gin.HandlerFunc {
 			return
 		}
 
+		// Check the invitation before creating anything
+		var invitation *models.Invitation
+		if req.InvitationToken != "" {
+			var err error
+			invitation, err = s.orgSvc.Invitation(req.InvitationToken)
+			if err != nil {
+				s.respondInvitationError(c, err)
+				return
+			}
+			if !strings.EqualFold(invitation.Email, req.Email) {
+				response.Forbidden(c, errors.ErrInvitationEmail)
+				return
+			}
+		} else if s.cfg.Registration.InviteOnly {
+			response.Forbidden(c, errors.ErrInvitationRequired)
+			return
+		}
+
 		// Find user by email
 		_, err := s.userSvc.FindByEmail(req.Email)
 		if err == nil {
@@ -36,29 +61,74 @@ func (s *Server) handleRegister() gin.HandlerFunc {
 		}
 
 		// Create new user
-		newUser, err := s.userSvc.Register(req.Email, req.Password, req.Name)
+		newUser, err := s.userSvc.Register(req.Email, req.Password, req.Name, req.Locale)
 		if err != nil {
 			s.logger.Error("failed to create user", err)
 			response.InternalError(c, errors.ErrFailedToCreateUser)
 			return
 		}
 
+		registered := map[string]interface{}{"method": "password"}
+		if invitation != nil {
+			registered["invitation_id"] = invitation.ID.String()
+		}
+		s.logAudit(c, &models.AuditEvent{
+			Type:     models.AuditUserRegistered,
+			ActorID:  &newUser.ID,
+			TargetID: &newUser.ID,
+			Metadata: registered,
+		})
+
+		var membership *models.Membership
+		if invitation != nil {
+			// The invitation link was emailed to this address, which proves it
+			if err := s.userSvc.MarkEmailVerified(newUser.ID.String()); err != nil {
+				s.logger.Error("failed to mark email verified", err)
+			} else {
+				now := time.Now()
+				newUser.EmailVerifiedAt = &now
+			}
+
+			// The account exists either way; the invitation can still be accepted after signing in
+			membership, err = s.orgSvc.AcceptInvitation(newUser, req.InvitationToken)
+			if err != nil {
+				s.logger.Error("failed to accept invitation", err)
+			}
+		} else if err := s.userSvc.RequestEmailVerification(newUser, clientInfo(c)); err != nil {
+			// Send verification email; a failure here can be recovered with a resend
+			s.logger.Error("failed to request email verification", err)
+		}
+
+		// No session until the address is verified
+		if newUser.EmailVerifiedAt == nil && s.authSvc.EmailVerificationPolicy() == service.EmailVerificationRequired {
+			response.SuccessWithMessage(c, "registration successful, please verify your email", nil)
+			return
+		}
+
 		// Create session after registration
-		session, err := s.authSvc.CreateSession(newUser, c.GetHeader("User-Agent"))
+		session, err := s.authSvc.CreateSession(newUser, clientInfo(c))
 		if err != nil {
 			s.logger.Error("failed to create session", err)
 			response.InternalError(c, errors.ErrInvalidRequest)
 			return
 		}
 
-		response.SuccessWithMessage(c, "registration successful", gin.H{
-			"token": session.SessionToken,
-		})
+		// Start out in the organization the user was invited to
+		if membership != nil {
+			active, err := s.authSvc.SetActiveOrganization(newUser.ID.String(), session.PublicID().String(), &membership.OrganizationID)
+			if err != nil {
+				s.logger.Error("failed to switch organization", err)
+			} else {
+				session = active
+			}
+		}
+
+		s.respondWithSession(c, "registration successful", session)
 	}
 }
 
 // @Summary Login user
-// @Description Authenticate user and return session token
+// @Description Authenticate user and return session token, or an MFA challenge token when MFA is enabled
 // @Tags auth
 // @Accept json
 // @Produce json
@@ -66,6 +136,8 @@ func (s *Server) handleRegister() gin.HandlerFunc {
 // @Success 200 {object} response.SuccessResponse
 // @Failure 400 {object} response.ErrorResponse
 // @Failure 401 {object} response.ErrorResponse
+// @Failure 423 {object} response.ErrorResponse
+// @Failure 429 {object} response.ErrorResponse
 // @Router /v1/login [post]
 func (s *Server) handleLogin() gin.HandlerFunc {
 	return func(c *gin.Context) {
@@ -82,16 +154,74 @@ func (s *Server) handleLogin() gin.HandlerFunc {
 		}
 
 		// Authenticate user
-		session, err := s.authSvc.Authenticate(req.Email, req.Password)
+		result, err := s.authSvc.Authenticate(req.Email, req.Password, clientInfo(c))
 		if err != nil {
+			if stderrors.Is(err, errors.ErrEmailNotVerified) ||
+				stderrors.Is(err, errors.ErrAccountDisabled) ||
+				stderrors.Is(err, errors.ErrMustResetPassword) {
+				response.Forbidden(c, err)
+				return
+			}
+			var blocked *service.LoginBlockedError
+			if stderrors.As(err, &blocked) {
+				s.respondLoginBlocked(c, blocked)
+				return
+			}
 			s.logger.Error("failed to authenticate user", err)
 			response.Unauthorized(c, errors.ErrInvalidEmailOrPass)
 			return
 		}
 
-		response.SuccessWithMessage(c, "login successful", gin.H{
-			"token": session.SessionToken,
-		})
+		// Second factor required before a session is issued
+		if result.MFAToken != "" {
+			response.SuccessWithMessage(c, "mfa required", gin.H{
+				"mfa_required": true,
+				"mfa_token":    result.MFAToken,
+			})
+			return
+		}
+
+		s.respondWithSession(c, "login successful", result.Session)
+	}
+}
+
+// @Summary Complete MFA login
+// @Description Exchange an MFA challenge token and a TOTP or recovery code for a session token
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body validator.MFALoginRequest true "MFA Login Request"
+// @Success 200 {object} response.SuccessResponse
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 401 {object} response.ErrorResponse
+// @Failure 423 {object} response.ErrorResponse
+// @Failure 429 {object} response.ErrorResponse
+// @Router /v1/login/mfa [post]
+func (s *Server) handleMFALogin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req validator.MFALoginRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.BadRequest(c, errors.ErrInvalidRequest)
+			return
+		}
+
+		session, err := s.authSvc.CompleteMFALogin(req.MFAToken, req.Code, clientInfo(c))
+		if err != nil {
+			if stderrors.Is(err, errors.ErrAccountDisabled) {
+				response.Forbidden(c, err)
+				return
+			}
+			var blocked *service.LoginBlockedError
+			if stderrors.As(err, &blocked) {
+				s.respondLoginBlocked(c, blocked)
+				return
+			}
+			s.logger.Error("failed to complete mfa login", err)
+			response.Unauthorized(c, errors.ErrInvalidMFACode)
+			return
+		}
+
+		s.respondWithSession(c, "login successful", session)
 	}
 }
 
@@ -121,29 +251,13 @@ func (s *Server) handleForgotPassword() gin.HandlerFunc {
 			return
 		}
 
-		// Generate reset token
-		token, err := s.tokenSvc.GenerateToken(user.ID.String(), service.TokenTypeReset)
-		if err != nil {
-			s.logger.Error("failed to generate reset token", err)
+		// The email is sent once the request is committed, so a mail outage doesn't fail it
+		if err := s.userSvc.RequestPasswordReset(user, clientInfo(c)); err != nil {
+			s.logger.Error("failed to request password reset", err)
 			response.InternalError(c, errors.ErrInvalidRequest)
 			return
 		}
 
-		// Create reset link and send email
-		resetLink := fmt.Sprintf("%s/reset-password?token=%s", s.cfg.Server.BaseURL, token)
-		emailBody := fmt.Sprintf(`
-			<h1>Password Reset</h1>
-			<p>Click the link below to reset your password:</p>
-			<a href="%s">Reset Password</a>
-			<p>This link will expire in 15 minutes.</p>
-		`, resetLink)
-
-		if err := s.emailSvc.SendEmail(user.Email, "Password Reset", emailBody); err != nil {
-			s.logger.Error("failed to send reset email", err)
-			response.InternalError(c, errors.ErrFailedToSendEmail)
-			return
-		}
-
 		response.SuccessWithMessage(c, "if the email exists, a password reset link will be sent", nil)
 	}
 }
@@ -174,91 +288,179 @@ func (s *Server) handleMagicLinkLogin() gin.HandlerFunc {
 			return
 		}
 
-		// Generate magic link token
-		token, err := s.tokenSvc.GenerateToken(user.ID.String(), service.TokenTypeMagicLink)
-		if err != nil {
-			s.logger.Error("failed to generate magic link token", err)
+		if err := s.authSvc.RequestMagicLink(user, clientInfo(c)); err != nil {
+			s.logger.Error("failed to request magic link", err)
 			response.InternalError(c, errors.ErrInvalidRequest)
 			return
 		}
 
-		// Create magic link and send email
-		magicLink := fmt.Sprintf("%s/magic-login?token=%s", s.cfg.Server.BaseURL, token)
-		emailBody := fmt.Sprintf(`
-			<h1>Magic Link Login</h1>
-			<p>Click the link below to log in:</p>
-			<a href="%s">Log In</a>
-			<p>This link will expire in 15 minutes.</p>
-		`, magicLink)
-
-		if err := s.emailSvc.SendEmail(user.Email, "Magic Link Login", emailBody); err != nil {
-			s.logger.Error("failed to send magic link email", err)
-			response.InternalError(c, errors.ErrFailedToSendEmail)
-			return
-		}
-
 		response.SuccessWithMessage(c, "if the email exists, a magic link will be sent", nil)
 	}
 }
 
-// @Summary Get Google OAuth URL
-// @Description Get Google OAuth URL for authentication
+// oauthBindingCookie ties an OAuth flow to the browser that started it.
+const oauthBindingCookie = "oauth_binding"
+
+// @Summary Get OAuth authorization URL
+// @Description Get the authorization URL of an OpenID Connect identity provider. Every request uses PKCE (S256) and a nonce, sealed with the post-login redirect target into the encrypted state parameter. The flow is bound to the calling browser with a cookie; when the browser navigates to this endpoint it is redirected to the provider directly. With redirect_to the callback sends the browser to that (allow-listed) target with the result in the URL fragment, for SPAs and mobile apps; without it the callback responds with JSON.
 // @Tags auth
-// @Accept json
 // @Produce json
+// @Param provider path string true "Provider name" example(google)
+// @Param redirect_to query string false "Post-login redirect target" example(http://localhost:3000/auth/callback)
 // @Success 200 {object} response.SuccessResponse
+// @Success 302 "Redirect to the provider when the browser navigated here"
 // @Failure 400 {object} response.ErrorResponse
-// @Router /api/v1/oauth/google [get]
-func (s *Server) handleGoogleOAuth() gin.HandlerFunc {
+// @Failure 404 {object} response.ErrorResponse
+// @Router /v1/oauth/{provider} [get]
+func (s *Server) handleOAuthLogin() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// Generate state token to prevent CSRF
-		state := uuid.New().String()
-
-		// Store state in cookie
-		c.SetCookie("oauth_state", state, 600, "/", "", false, true)
-
-		// Get authorization URL
-		authURL := s.oauthSvc.GetAuthURL(state)
-
-		response.Success(c, gin.H{
-			"redirect_url": authURL,
+		authURL, binding, err := s.oauthSvc.BeginAuth(c.Request.Context(), c.Param("provider"), service.OAuthRequest{
+			RedirectTo: c.Query("redirect_to"),
 		})
+		if err != nil {
+			s.respondOAuthStartError(c, err)
+			return
+		}
+
+		s.respondOAuthStart(c, authURL, binding)
 	}
 }
 
-// @Summary Google OAuth Callback
-// @Description Handle Google OAuth callback and create session
+// @Summary OAuth callback
+// @Description Handle an identity provider callback: open the state, redeem the code with the PKCE verifier, verify the ID token and create a session for the linked account. Completes a link instead when the flow was started from /v1/oauth/{provider}/link.
 // @Tags auth
-// @Accept json
 // @Produce json
+// @Param provider path string true "Provider name" example(google)
+// @Param code query string true "Authorization code"
+// @Param state query string true "State"
 // @Success 200 {object} response.SuccessResponse
+// @Success 302 "Redirect to the requested target with the result in the URL fragment"
 // @Failure 400 {object} response.ErrorResponse
-// @Router /api/v1/oauth/google/callback [get]
-func (s *Server) handleGoogleCallback() gin.HandlerFunc {
+// @Failure 401 {object} response.ErrorResponse
+// @Failure 404 {object} response.ErrorResponse
+// @Failure 409 {object} response.ErrorResponse
+// @Router /v1/oauth/{provider}/callback [get]
+func (s *Server) handleOAuthCallback() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		// Verify state token
-		state, err := c.Cookie("oauth_state")
-		if err != nil || state != c.Query("state") {
-			response.BadRequest(c, errors.ErrInvalidRequest)
+		// Open and verify the state record
+		binding, _ := c.Cookie(oauthBindingCookie)
+		state, err := s.oauthSvc.ResumeAuth(c.Query("state"), c.Param("provider"), binding)
+		if err != nil {
+			response.BadRequest(c, err)
+			return
+		}
+		s.clearOAuthBinding(c)
+
+		// The user denied access or the provider failed
+		if providerErr := c.Query("error"); providerErr != "" {
+			s.respondOAuthError(c, state, http.StatusUnauthorized, stderrors.New(providerErr))
 			return
 		}
 
-		// Clear state cookie
-		c.SetCookie("oauth_state", "", -1, "/", "", false, true)
+		// Flows started from handleLinkIdentity attach the identity instead of signing in
+		if state.LinkUserID != "" {
+			s.completeIdentityLink(c, state)
+			return
+		}
 
-		// Exchange code for token and get user info
-		code := c.Query("code")
-		session, err := s.oauthSvc.HandleCallback(code)
+		// Exchange code for tokens and verify the ID token
+		session, err := s.oauthSvc.HandleCallback(c.Request.Context(), state, c.Query("code"), clientInfo(c))
 		if err != nil {
-			s.logger.Error("failed to handle oauth callback", err)
-			response.InternalError(c, errors.ErrInvalidRequest)
+			switch {
+			case stderrors.Is(err, errors.ErrProviderNotFound):
+				s.respondOAuthError(c, state, http.StatusNotFound, err)
+			case stderrors.Is(err, errors.ErrInvalidToken):
+				s.logger.Error("rejected oauth id token", err)
+				s.respondOAuthError(c, state, http.StatusUnauthorized, errors.ErrInvalidToken)
+			case stderrors.Is(err, errors.ErrLinkRequired):
+				s.respondOAuthError(c, state, http.StatusConflict, err)
+			case stderrors.Is(err, errors.ErrInvitationRequired), stderrors.Is(err, errors.ErrAccountDisabled):
+				s.respondOAuthError(c, state, http.StatusForbidden, err)
+			default:
+				s.logger.Error("failed to handle oauth callback", err)
+				s.respondOAuthError(c, state, http.StatusInternalServerError, errors.ErrInvalidRequest)
+			}
 			return
 		}
 
-		response.SuccessWithMessage(c, "login successful", gin.H{
-			"token": session.SessionToken,
-		})
+		if state.RedirectTo == "" {
+			s.respondWithSession(c, "login successful", session)
+			return
+		}
+
+		result := url.Values{}
+		if !s.authSvc.TokenPairMode() {
+			result.Set("token", session.SessionToken)
+		} else {
+			pair, err := s.authSvc.IssueTokenPair(session)
+			if err != nil {
+				s.logger.Error("failed to issue token pair", err)
+				s.respondOAuthError(c, state, http.StatusInternalServerError, errors.ErrInvalidRequest)
+				return
+			}
+			result.Set("access_token", pair.AccessToken)
+			result.Set("refresh_token", pair.RefreshToken)
+			result.Set("token_type", pair.TokenType)
+			result.Set("expires_in", strconv.Itoa(pair.ExpiresIn))
+		}
+		redirectWithFragment(c, state.RedirectTo, result)
+	}
+}
+
+// respondOAuthStart hands the authorization URL to the client and binds the
+// flow to the browser. A browser navigating here is sent on to the provider
+// directly, which lets SPAs on another site and mobile apps set the binding
+// cookie first-party by opening this endpoint instead of fetching it.
+func (s *Server) respondOAuthStart(c *gin.Context, authURL, binding string) {
+	s.setOAuthBinding(c, binding)
+
+	if c.GetHeader("Sec-Fetch-Mode") == "navigate" {
+		c.Redirect(http.StatusFound, authURL)
+		return
+	}
+	response.Success(c, gin.H{
+		"redirect_url": authURL,
+	})
+}
+
+// respondOAuthStartError maps errors from starting an authorization request.
+func (s *Server) respondOAuthStartError(c *gin.Context, err error) {
+	switch {
+	case stderrors.Is(err, errors.ErrProviderNotFound):
+		response.NotFound(c, err)
+	case stderrors.Is(err, errors.ErrInvalidRedirect):
+		response.BadRequest(c, err)
+	default:
+		s.logger.Error("failed to build oauth authorization url", err)
+		response.InternalError(c, errors.ErrInvalidRequest)
+	}
+}
+
+// respondOAuthError reports a failed callback as JSON, or in the fragment of
+// the redirect target when the flow requested one.
+func (s *Server) respondOAuthError(c *gin.Context, state *service.OAuthState, status int, err error) {
+	if state.RedirectTo == "" {
+		response.Error(c, status, err)
+		return
 	}
+	redirectWithFragment(c, state.RedirectTo, url.Values{"error": {err.Error()}})
+}
+
+// redirectWithFragment redirects to target carrying values in the URL fragment,
+// which browsers do not send to servers or leak through the Referer header.
+func redirectWithFragment(c *gin.Context, target string, values url.Values) {
+	c.Redirect(http.StatusFound, target+"#"+values.Encode())
+}
+
+// setOAuthBinding stores the browser binding of an OAuth flow in a cookie scoped to the OAuth routes.
+func (s *Server) setOAuthBinding(c *gin.Context, binding string) {
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oauthBindingCookie, binding, int(10*time.Minute/time.Second), "/api/v1/oauth", "", strings.HasPrefix(s.cfg.Server.BaseURL, "https://"), true)
+}
+
+func (s *Server) clearOAuthBinding(c *gin.Context) {
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oauthBindingCookie, "", -1, "/api/v1/oauth", "", strings.HasPrefix(s.cfg.Server.BaseURL, "https://"), true)
 }
 
 // @Summary Reset password
@@ -293,6 +495,19 @@ func (s *Server) handleResetPassword() gin.HandlerFunc {
 			return
 		}
 
+		userID := optionalUUID(tokenRecord.UserID)
+		s.logAudit(c, &models.AuditEvent{Type: models.AuditPasswordReset, ActorID: userID, TargetID: userID})
+
+		// Receiving the reset email proves ownership of the address
+		if err := s.userSvc.MarkEmailVerified(tokenRecord.UserID); err != nil {
+			s.logger.Error("failed to mark email verified", err)
+		}
+
+		// A new password makes earlier failed attempts irrelevant
+		if err := s.authSvc.UnlockAccount(tokenRecord.UserID); err != nil {
+			s.logger.Error("failed to unlock account", err)
+		}
+
 		// Invalidate the token
 		if err := s.tokenSvc.InvalidateToken(req.Token); err != nil {
 			s.logger.Error("failed to invalidate token", err)
@@ -317,6 +532,11 @@ func (s *Server) handleMagicLinkVerify() gin.HandlerFunc {
 			return
 		}
 
+		// Receiving the magic link proves ownership of the address
+		if err := s.userSvc.MarkEmailVerified(tokenRecord.UserID); err != nil {
+			s.logger.Error("failed to mark email verified", err)
+		}
+
 		// Get user
 		user, err := s.userSvc.GetByID(tokenRecord.UserID)
 		if err != nil {
@@ -326,9 +546,13 @@ func (s *Server) handleMagicLinkVerify() gin.HandlerFunc {
 		}
 
 		// Create session
-		deviceInfo := c.GetHeader("User-Agent")
-		session, err := s.authSvc.CreateSession(user, deviceInfo)
+		session, err := s.authSvc.CreateSession(user, clientInfo(c))
+		s.auditSvc.Log(service.LoginEvent(&user.ID, "magic_link", clientInfo(c), err))
 		if err != nil {
+			if stderrors.Is(err, errors.ErrAccountDisabled) || stderrors.Is(err, errors.ErrEmailNotVerified) {
+				response.Forbidden(c, err)
+				return
+			}
 			s.logger.Error("failed to create session", err)
 			response.InternalError(c, errors.ErrInvalidRequest)
 			return
@@ -339,8 +563,187 @@ func (s *Server) handleMagicLinkVerify() gin.HandlerFunc {
 			s.logger.Error("failed to invalidate token", err)
 		}
 
-		response.SuccessWithMessage(c, "login successful", gin.H{
+		s.respondWithSession(c, "login successful", session)
+	}
+}
+
+// @Summary Verify email address
+// @Description Verify the user's email address with the token from the verification email
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body validator.VerifyEmailRequest true "Verify Email Request"
+// @Success 200 {object} response.SuccessResponse
+// @Failure 400 {object} response.ErrorResponse
+// @Router /v1/verify-email [post]
+func (s *Server) handleVerifyEmail() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req validator.VerifyEmailRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.BadRequest(c, errors.ErrInvalidRequest)
+			return
+		}
+
+		user, err := s.userSvc.VerifyEmail(req.Token)
+		if err != nil {
+			if stderrors.Is(err, errors.ErrInvalidToken) {
+				response.BadRequest(c, err)
+				return
+			}
+			s.logger.Error("failed to verify email", err)
+			response.InternalError(c, errors.ErrInvalidRequest)
+			return
+		}
+
+		s.logAudit(c, &models.AuditEvent{
+			Type:     models.AuditEmailVerified,
+			ActorID:  &user.ID,
+			TargetID: &user.ID,
+			Metadata: map[string]interface{}{"email": user.Email},
+		})
+
+		response.SuccessWithMessage(c, "email verified", nil)
+	}
+}
+
+// @Summary Resend verification email
+// @Description Send a new email verification link. Requests are throttled per account.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body validator.ResendVerificationRequest true "Resend Verification Request"
+// @Success 200 {object} response.SuccessResponse
+// @Failure 400 {object} response.ErrorResponse
+// @Router /v1/verify-email/resend [post]
+func (s *Server) handleResendVerification() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req validator.ResendVerificationRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.BadRequest(c, errors.ErrInvalidRequest)
+			return
+		}
+
+		// Don't reveal if the email exists, is verified or is being throttled
+		const message = "if the email exists and is unverified, a verification link will be sent"
+
+		user, err := s.userSvc.FindByEmail(req.Email)
+		if err != nil {
+			response.SuccessWithMessage(c, message, nil)
+			return
+		}
+
+		if err := s.userSvc.RequestEmailVerification(user, clientInfo(c)); err != nil &&
+			!stderrors.Is(err, errors.ErrEmailVerified) && !stderrors.Is(err, errors.ErrTooManyRequests) {
+			s.logger.Error("failed to request email verification", err)
+			response.InternalError(c, errors.ErrInvalidRequest)
+			return
+		}
+
+		response.SuccessWithMessage(c, message, nil)
+	}
+}
+
+// respondLoginBlocked rejects a login refused by failed-attempt protection.
+func (s *Server) respondLoginBlocked(c *gin.Context, blocked *service.LoginBlockedError) {
+	c.Header("Retry-After", strconv.Itoa(int(math.Ceil(blocked.RetryAfter.Seconds()))))
+	if stderrors.Is(blocked, errors.ErrAccountLocked) {
+		response.Error(c, http.StatusLocked, blocked)
+		return
+	}
+	response.Error(c, http.StatusTooManyRequests, blocked)
+}
+
+// @Summary Unlock account
+// @Description Lift a lockout caused by repeated failed logins with the token from the unlock email
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body validator.UnlockAccountRequest true "Unlock Account Request"
+// @Success 200 {object} response.SuccessResponse
+// @Failure 400 {object} response.ErrorResponse
+// @Router /v1/unlock-account [post]
+func (s *Server) handleUnlockAccount() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req validator.UnlockAccountRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.BadRequest(c, errors.ErrInvalidRequest)
+			return
+		}
+
+		if err := s.authSvc.UnlockAccountWithToken(req.Token, clientInfo(c)); err != nil {
+			if stderrors.Is(err, errors.ErrInvalidToken) {
+				response.BadRequest(c, err)
+				return
+			}
+			s.logger.Error("failed to unlock account", err)
+			response.InternalError(c, errors.ErrInvalidRequest)
+			return
+		}
+
+		response.SuccessWithMessage(c, "account unlocked", nil)
+	}
+}
+
+// @Summary Refresh tokens
+// @Description Exchange a refresh token for a new access and refresh token pair. Each refresh token can be used once; replaying a used token revokes the whole token family.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body validator.RefreshTokenRequest true "Refresh Token Request"
+// @Success 200 {object} response.SuccessResponse
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 401 {object} response.ErrorResponse
+// @Router /v1/token/refresh [post]
+func (s *Server) handleRefreshToken() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req validator.RefreshTokenRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.BadRequest(c, errors.ErrInvalidRequest)
+			return
+		}
+
+		pair, err := s.authSvc.RefreshTokens(req.RefreshToken, clientInfo(c))
+		if err != nil {
+			if stderrors.Is(err, errors.ErrRefreshTokenReused) {
+				s.logger.Error("refresh token reuse detected", err)
+			}
+			if stderrors.Is(err, errors.ErrAccountDisabled) {
+				response.Forbidden(c, err)
+				return
+			}
+			response.Unauthorized(c, errors.ErrInvalidToken)
+			return
+		}
+
+		response.Success(c, pair)
+	}
+}
+
+// clientInfo describes the requesting client for newly created sessions.
+func clientInfo(c *gin.Context) service.ClientInfo {
+	return service.ClientInfo{
+		UserAgent: c.GetHeader("User-Agent"),
+		IPAddress: c.ClientIP(),
+		Language:  c.GetHeader("Accept-Language"),
+	}
+}
+
+// respondWithSession writes the login response for a new session: a session token,
+// or an access/refresh token pair when token pair mode is enabled.
+func (s *Server) respondWithSession(c *gin.Context, message string, session *models.Session) {
+	if !s.authSvc.TokenPairMode() {
+		response.SuccessWithMessage(c, message, gin.H{
 			"token": session.SessionToken,
 		})
+		return
 	}
+
+	pair, err := s.authSvc.IssueTokenPair(session)
+	if err != nil {
+		s.logger.Error("failed to issue token pair", err)
+		response.InternalError(c, errors.ErrInvalidRequest)
+		return
+	}
+
+	response.SuccessWithMessage(c, message, pair)
 }
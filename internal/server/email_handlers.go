@@ -0,0 +1,134 @@
+package server
+
+import (
+	stderrors "errors"
+	"net/http"
+
+	"rest-api/internal/errors"
+	"rest-api/internal/models"
+	"rest-api/internal/response"
+	"rest-api/pkg/validator"
+
+	"github.com/gin-gonic/gin"
+)
+
+// @Summary List queued emails
+// @Description List outgoing emails with the outcome of their latest attempt, newest first. Filter by status "dead" to find messages that could not be delivered
+// @Tags admin
+// @Security Bearer
+// @Produce json
+// @Param status query string false "pending, sent or dead"
+// @Param page query int false "Page number, starting at 1"
+// @Param per_page query int false "Messages per page (max 100)"
+// @Success 200 {object} response.SuccessResponse{data=response.EmailMessageListResponse}
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 401 {object} response.ErrorResponse
+// @Failure 403 {object} response.ErrorResponse
+// @Router /v1/admin/emails [get]
+func (s *Server) handleAdminListEmails() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req validator.ListEmailMessagesRequest
+		if err := c.ShouldBindQuery(&req); err != nil {
+			response.BadRequest(c, errors.ErrInvalidRequest)
+			return
+		}
+
+		page, err := s.emailQueue.Messages(req.Status, req.Page, req.PerPage)
+		if err != nil {
+			s.respondEmailError(c, "failed to list emails", err)
+			return
+		}
+
+		messages := make([]response.EmailMessageResponse, len(page.Messages))
+		for i := range page.Messages {
+			messages[i] = toEmailMessageResponse(&page.Messages[i])
+		}
+
+		response.Success(c, response.EmailMessageListResponse{
+			Messages: messages,
+			Total:    page.Total,
+			Page:     page.Page,
+			PerPage:  page.PerPage,
+		})
+	}
+}
+
+// @Summary Get queued email
+// @Description Get an outgoing email with the outcome of its latest attempt
+// @Tags admin
+// @Security Bearer
+// @Produce json
+// @Param id path string true "Email message ID"
+// @Success 200 {object} response.SuccessResponse{data=response.EmailMessageResponse}
+// @Failure 401 {object} response.ErrorResponse
+// @Failure 403 {object} response.ErrorResponse
+// @Failure 404 {object} response.ErrorResponse
+// @Router /v1/admin/emails/{id} [get]
+func (s *Server) handleAdminGetEmail() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		message, err := s.emailQueue.Message(c.Param("id"))
+		if err != nil {
+			s.respondEmailError(c, "failed to get email", err)
+			return
+		}
+
+		response.Success(c, toEmailMessageResponse(message))
+	}
+}
+
+// @Summary Retry dead email
+// @Description Put an email that could not be delivered back in the queue to be sent right away
+// @Tags admin
+// @Security Bearer
+// @Produce json
+// @Param id path string true "Email message ID"
+// @Success 200 {object} response.SuccessResponse{data=response.EmailMessageResponse}
+// @Failure 401 {object} response.ErrorResponse
+// @Failure 403 {object} response.ErrorResponse
+// @Failure 404 {object} response.ErrorResponse
+// @Failure 409 {object} response.ErrorResponse
+// @Router /v1/admin/emails/{id}/retry [post]
+func (s *Server) handleAdminRetryEmail() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		message, err := s.emailQueue.Retry(c.Param("id"))
+		if err != nil {
+			s.respondEmailError(c, "failed to retry email", err)
+			return
+		}
+
+		response.SuccessWithMessage(c, "email queued", toEmailMessageResponse(message))
+	}
+}
+
+// respondEmailError maps email queue errors to responses, logging anything
+// unexpected under msg.
+func (s *Server) respondEmailError(c *gin.Context, msg string, err error) {
+	switch {
+	case stderrors.Is(err, errors.ErrMessageNotFound):
+		response.NotFound(c, err)
+	case stderrors.Is(err, errors.ErrMessageNotFailed), stderrors.Is(err, errors.ErrMessageExpired):
+		response.Error(c, http.StatusConflict, err)
+	default:
+		s.logger.Error(msg, err)
+		response.InternalError(c, errors.ErrInvalidRequest)
+	}
+}
+
+func toEmailMessageResponse(message *models.EmailMessage) response.EmailMessageResponse {
+	resp := response.EmailMessageResponse{
+		ID:            message.ID.String(),
+		To:            message.To,
+		Subject:       message.Subject,
+		Status:        message.Status,
+		Attempts:      message.Attempts,
+		LastAttemptAt: message.LastAttemptAt,
+		Error:         message.LastError,
+		SentAt:        message.SentAt,
+		ExpiresAt:     message.ExpiresAt,
+		CreatedAt:     message.CreatedAt,
+	}
+	if message.Status == models.EmailPending {
+		resp.NextAttemptAt = &message.NextAttemptAt
+	}
+	return resp
+}
@@ -0,0 +1,154 @@
+package server
+
+import (
+	stderrors "errors"
+	"net/http"
+	"net/url"
+
+	"rest-api/internal/errors"
+	"rest-api/internal/models"
+	"rest-api/internal/response"
+	"rest-api/internal/service"
+
+	"github.com/gin-gonic/gin"
+)
+
+// @Summary Start linking an identity provider
+// @Description Get the authorization URL that links an OpenID Connect provider account to the current user. The provider redirects back to /v1/oauth/{provider}/callback, which completes the link.
+// @Tags identities
+// @Security Bearer
+// @Produce json
+// @Param provider path string true "Provider name" example(google)
+// @Param redirect_to query string false "Redirect target once linked" example(http://localhost:3000/auth/callback)
+// @Success 200 {object} response.SuccessResponse
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 401 {object} response.ErrorResponse
+// @Failure 404 {object} response.ErrorResponse
+// @Router /v1/oauth/{provider}/link [post]
+func (s *Server) handleLinkIdentity() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, exists := c.Get("user")
+		if !exists {
+			response.Unauthorized(c, errors.ErrUnauthorized)
+			return
+		}
+
+		// The callback is unauthenticated, so the user travels in the sealed state
+		authURL, binding, err := s.oauthSvc.BeginAuth(c.Request.Context(), c.Param("provider"), service.OAuthRequest{
+			RedirectTo: c.Query("redirect_to"),
+			LinkUserID: user.(*models.User).ID.String(),
+		})
+		if err != nil {
+			s.respondOAuthStartError(c, err)
+			return
+		}
+
+		s.respondOAuthStart(c, authURL, binding)
+	}
+}
+
+// completeIdentityLink finishes a callback started by handleLinkIdentity.
+func (s *Server) completeIdentityLink(c *gin.Context, state *service.OAuthState) {
+	identity, err := s.oauthSvc.LinkIdentity(c.Request.Context(), state, c.Query("code"), clientInfo(c))
+	if err != nil {
+		switch {
+		case stderrors.Is(err, errors.ErrProviderNotFound):
+			s.respondOAuthError(c, state, http.StatusNotFound, err)
+		case stderrors.Is(err, errors.ErrIdentityInUse):
+			s.respondOAuthError(c, state, http.StatusConflict, err)
+		case stderrors.Is(err, errors.ErrInvalidToken):
+			s.logger.Error("rejected oauth id token", err)
+			s.respondOAuthError(c, state, http.StatusUnauthorized, errors.ErrInvalidToken)
+		default:
+			s.logger.Error("failed to link identity", err)
+			s.respondOAuthError(c, state, http.StatusInternalServerError, errors.ErrInvalidRequest)
+		}
+		return
+	}
+
+	if state.RedirectTo != "" {
+		redirectWithFragment(c, state.RedirectTo, url.Values{"linked": {identity.Provider}})
+		return
+	}
+	response.SuccessWithMessage(c, "identity linked", toIdentityResponse(identity))
+}
+
+// @Summary List linked identities
+// @Description List the external identity provider accounts linked to the current user
+// @Tags identities
+// @Security Bearer
+// @Produce json
+// @Success 200 {array} response.IdentityResponse
+// @Failure 401 {object} response.ErrorResponse
+// @Router /v1/profile/identities [get]
+func (s *Server) handleListIdentities() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, exists := c.Get("user")
+		if !exists {
+			response.Unauthorized(c, errors.ErrUnauthorized)
+			return
+		}
+
+		identities, err := s.oauthSvc.ListIdentities(user.(*models.User).ID.String())
+		if err != nil {
+			s.logger.Error("failed to list identities", err)
+			response.InternalError(c, errors.ErrInvalidRequest)
+			return
+		}
+
+		result := make([]response.IdentityResponse, len(identities))
+		for i := range identities {
+			result[i] = toIdentityResponse(&identities[i])
+		}
+
+		response.Success(c, result)
+	}
+}
+
+// @Summary Unlink identity
+// @Description Remove a linked identity provider account. The last sign-in method of an account without a password cannot be removed.
+// @Tags identities
+// @Security Bearer
+// @Produce json
+// @Param id path string true "Identity ID"
+// @Success 200 {object} response.SuccessResponse
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 401 {object} response.ErrorResponse
+// @Failure 404 {object} response.ErrorResponse
+// @Router /v1/profile/identities/{id} [delete]
+func (s *Server) handleUnlinkIdentity() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, exists := c.Get("user")
+		if !exists {
+			response.Unauthorized(c, errors.ErrUnauthorized)
+			return
+		}
+
+		if err := s.oauthSvc.UnlinkIdentity(user.(*models.User), c.Param("id")); err != nil {
+			switch {
+			case stderrors.Is(err, errors.ErrIdentityNotFound):
+				response.NotFound(c, err)
+			case stderrors.Is(err, errors.ErrLastLoginMethod):
+				response.BadRequest(c, err)
+			default:
+				s.logger.Error("failed to unlink identity", err)
+				response.InternalError(c, errors.ErrInvalidRequest)
+			}
+			return
+		}
+
+		s.auditUserAction(c, models.AuditIdentityUnlinked, user.(*models.User).ID, map[string]interface{}{"identity_id": c.Param("id")})
+
+		response.SuccessWithMessage(c, "identity unlinked", nil)
+	}
+}
+
+func toIdentityResponse(identity *models.UserIdentity) response.IdentityResponse {
+	return response.IdentityResponse{
+		ID:          identity.ID.String(),
+		Provider:    identity.Provider,
+		Email:       identity.Email,
+		LinkedAt:    identity.LinkedAt,
+		LastLoginAt: identity.LastLoginAt,
+	}
+}
@@ -0,0 +1,134 @@
+package server
+
+import (
+	stderrors "errors"
+
+	"rest-api/internal/errors"
+	"rest-api/internal/models"
+	"rest-api/internal/response"
+	"rest-api/pkg/validator"
+
+	"github.com/gin-gonic/gin"
+)
+
+// @Summary Start MFA enrollment
+// @Description Generate a new TOTP secret and provisioning URL for the current user
+// @Tags mfa
+// @Security Bearer
+// @Produce json
+// @Success 200 {object} response.SuccessResponse
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 401 {object} response.ErrorResponse
+// @Router /v1/mfa/enroll [post]
+func (s *Server) handleMFAEnroll() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, exists := c.Get("user")
+		if !exists {
+			response.Unauthorized(c, errors.ErrUnauthorized)
+			return
+		}
+
+		enrollment, err := s.mfaSvc.Enroll(user.(*models.User))
+		if err != nil {
+			if stderrors.Is(err, errors.ErrMFAAlreadyEnabled) {
+				response.BadRequest(c, err)
+				return
+			}
+			s.logger.Error("failed to start mfa enrollment", err)
+			response.InternalError(c, errors.ErrInvalidRequest)
+			return
+		}
+
+		response.Success(c, enrollment)
+	}
+}
+
+// @Summary Confirm MFA enrollment
+// @Description Verify a TOTP code to enable MFA and receive single-use recovery codes
+// @Tags mfa
+// @Security Bearer
+// @Accept json
+// @Produce json
+// @Param request body validator.MFACodeRequest true "MFA Code Request"
+// @Success 200 {object} response.SuccessResponse
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 401 {object} response.ErrorResponse
+// @Router /v1/mfa/confirm [post]
+func (s *Server) handleMFAConfirm() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, exists := c.Get("user")
+		if !exists {
+			response.Unauthorized(c, errors.ErrUnauthorized)
+			return
+		}
+
+		var req validator.MFACodeRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.BadRequest(c, errors.ErrInvalidRequest)
+			return
+		}
+
+		codes, err := s.mfaSvc.Confirm(user.(*models.User).ID.String(), req.Code)
+		if err != nil {
+			switch {
+			case stderrors.Is(err, errors.ErrInvalidMFACode),
+				stderrors.Is(err, errors.ErrMFANotEnrolled),
+				stderrors.Is(err, errors.ErrMFAAlreadyEnabled):
+				response.BadRequest(c, err)
+			default:
+				s.logger.Error("failed to confirm mfa enrollment", err)
+				response.InternalError(c, errors.ErrInvalidRequest)
+			}
+			return
+		}
+
+		s.auditUserAction(c, models.AuditMFAEnabled, user.(*models.User).ID, nil)
+
+		response.SuccessWithMessage(c, "mfa enabled", gin.H{
+			"recovery_codes": codes,
+		})
+	}
+}
+
+// @Summary Disable MFA
+// @Description Disable MFA for the current user after verifying a TOTP or recovery code
+// @Tags mfa
+// @Security Bearer
+// @Accept json
+// @Produce json
+// @Param request body validator.MFACodeRequest true "MFA Code Request"
+// @Success 200 {object} response.SuccessResponse
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 401 {object} response.ErrorResponse
+// @Router /v1/mfa/disable [post]
+func (s *Server) handleMFADisable() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, exists := c.Get("user")
+		if !exists {
+			response.Unauthorized(c, errors.ErrUnauthorized)
+			return
+		}
+
+		var req validator.MFACodeRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.BadRequest(c, errors.ErrInvalidRequest)
+			return
+		}
+
+		if err := s.mfaSvc.Disable(user.(*models.User).ID.String(), req.Code); err != nil {
+			switch {
+			case stderrors.Is(err, errors.ErrInvalidMFACode),
+				stderrors.Is(err, errors.ErrMFANotEnabled):
+				response.BadRequest(c, err)
+			default:
+				s.logger.Error("failed to disable mfa", err)
+				response.InternalError(c, errors.ErrInvalidRequest)
+			}
+			return
+		}
+
+		s.auditUserAction(c, models.AuditMFADisabled, user.(*models.User).ID, nil)
+
+		response.SuccessWithMessage(c, "mfa disabled", nil)
+	}
+}
@@ -1,9 +1,14 @@
 package server
 
 import (
+	stderrors "errors"
 	"net/http"
 	"rest-api/internal/errors"
+	"rest-api/internal/middleware"
+	"rest-api/internal/models"
 	"rest-api/internal/response"
+	"rest-api/internal/service"
+	"slices"
 	"strings"
 
 	"github.com/gin-gonic/gin"
@@ -38,7 +43,12 @@ func (s *Server) rateLimitMiddleware() gin.HandlerFunc {
 	}
 }
 
-func (s *Server) authMiddleware() gin.HandlerFunc {
+// authMiddleware authenticates a session token or access token and loads the
+// user's effective permissions into the context. Personal access tokens are
+// only accepted when scopes are given and the token holds all of them; their
+// granted scopes are then set as "scopes" on the context, and they carry the
+// user's permissions only with the admin scope.
+func (s *Server) authMiddleware(scopes ...string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		token := c.GetHeader("Authorization")
 		if token == "" {
@@ -50,24 +60,161 @@ func (s *Server) authMiddleware() gin.HandlerFunc {
 		// Remove "Bearer " prefix
 		token = strings.TrimPrefix(token, "Bearer ")
 
-		// Validate session
-		session, err := s.authSvc.ValidateSession(token)
+		// Access tokens are verified without a session lookup
+		var userID, sessionID, organizationID, impersonatorID string
+		var granted []string
+		if service.IsPersonalAccessToken(token) {
+			if len(scopes) == 0 {
+				response.Unauthorized(c, errors.ErrUnauthorized)
+				c.Abort()
+				return
+			}
+
+			pat, err := s.patSvc.Validate(token, c.ClientIP())
+			if err != nil {
+				response.Unauthorized(c, errors.ErrUnauthorized)
+				c.Abort()
+				return
+			}
+
+			granted = service.TokenScopes(pat)
+			for _, scope := range scopes {
+				if !slices.Contains(granted, scope) {
+					response.Forbidden(c, errors.ErrInsufficientScope)
+					c.Abort()
+					return
+				}
+			}
+			userID = pat.UserID.String()
+			c.Set("scopes", granted)
+		} else if service.IsAccessToken(token) {
+			claims, err := s.authSvc.ValidateToken(token)
+			if err != nil {
+				response.Unauthorized(c, errors.ErrUnauthorized)
+				c.Abort()
+				return
+			}
+			userID = claims.UserID
+			sessionID = claims.SessionID
+			organizationID = claims.OrganizationID
+			if claims.Actor != nil {
+				impersonatorID = claims.Actor.Subject
+			}
+		} else {
+			session, err := s.authSvc.ValidateSession(token)
+			if err != nil {
+				response.Unauthorized(c, errors.ErrUnauthorized)
+				c.Abort()
+				return
+			}
+			userID = session.UserID.String()
+			sessionID = session.PublicID().String()
+			if session.ActiveOrganizationID != nil {
+				organizationID = session.ActiveOrganizationID.String()
+			}
+			if session.ImpersonatorID != nil {
+				impersonatorID = session.ImpersonatorID.String()
+			}
+
+			// Slide the idle expiry forward
+			s.authSvc.TouchSession(session)
+		}
+
+		// Get user
+		user, err := s.userSvc.GetByID(userID)
 		if err != nil {
 			response.Unauthorized(c, errors.ErrUnauthorized)
 			c.Abort()
 			return
 		}
+		if user.DisabledAt != nil {
+			response.Forbidden(c, errors.ErrAccountDisabled)
+			c.Abort()
+			return
+		}
 
-		// Get user
-		user, err := s.userSvc.GetByID(session.UserID.String())
+		permissions, err := s.rbacSvc.Permissions(user)
 		if err != nil {
-			response.Unauthorized(c, errors.ErrUnauthorized)
+			s.logger.Error("failed to load permissions", err)
+			response.InternalError(c, errors.ErrInvalidRequest)
 			c.Abort()
 			return
 		}
+		// Impersonators never act with the permissions of the user they impersonate
+		if (granted != nil && !slices.Contains(granted, service.TokenScopeAdmin)) || impersonatorID != "" {
+			permissions = nil
+		}
 
-		// Set user in context
+		// Set user, current session, active organization, impersonator and permissions in context
 		c.Set("user", user)
+		c.Set("session_id", sessionID)
+		c.Set("organization_id", organizationID)
+		c.Set("impersonator_id", impersonatorID)
+		c.Set(middleware.PermissionsKey, permissions)
+		c.Next()
+	}
+}
+
+// denyImpersonation rejects requests made in an impersonation session. It guards
+// routes that change credentials, sessions or grants, which stay with the real user.
+// It must run after authMiddleware.
+func (s *Server) denyImpersonation() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetString("impersonator_id") != "" {
+			response.Forbidden(c, errors.ErrImpersonating)
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// organizationMiddleware requires an active organization the user is still a
+// member of and sets their membership, with the organization, as "membership".
+// It must run after authMiddleware.
+func (s *Server) organizationMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		organizationID := c.GetString("organization_id")
+		if organizationID == "" {
+			response.BadRequest(c, errors.ErrNoActiveOrg)
+			c.Abort()
+			return
+		}
+
+		user, _ := c.Get("user")
+		membership, err := s.orgSvc.Membership(organizationID, user.(*models.User).ID.String())
+		if err != nil {
+			if stderrors.Is(err, errors.ErrOrgNotFound) {
+				response.Forbidden(c, errors.ErrForbidden)
+			} else {
+				s.logger.Error("failed to load membership", err)
+				response.InternalError(c, errors.ErrInvalidRequest)
+			}
+			c.Abort()
+			return
+		}
+
+		c.Set("membership", membership)
+		c.Next()
+	}
+}
+
+// verifiedEmailMiddleware limits users with an unverified email to the routes
+// outside this group when the restricted verification policy is enabled.
+func (s *Server) verifiedEmailMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if s.authSvc.EmailVerificationPolicy() != service.EmailVerificationRestricted {
+			c.Next()
+			return
+		}
+
+		user, exists := c.Get("user")
+		if !exists || user.(*models.User).EmailVerifiedAt == nil {
+			response.Forbidden(c, errors.ErrEmailNotVerified)
+			c.Abort()
+			return
+		}
+
 		c.Next()
 	}
 }
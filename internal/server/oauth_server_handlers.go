@@ -0,0 +1,469 @@
+package server
+
+import (
+	stderrors "errors"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"rest-api/internal/errors"
+	"rest-api/internal/models"
+	"rest-api/internal/response"
+	"rest-api/internal/service"
+	"rest-api/pkg/validator"
+
+	"github.com/gin-gonic/gin"
+)
+
+// @Summary OpenID Connect discovery
+// @Description Metadata for clients of the authorization server (OpenID Connect Discovery 1.0)
+// @Tags well-known
+// @Produce json
+// @Success 200 {object} service.OpenIDConfiguration
+// @Router /.well-known/openid-configuration [get]
+func (s *Server) handleOpenIDConfiguration() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Cache-Control", "public, max-age=3600")
+		c.JSON(http.StatusOK, s.oauthServerSvc.Configuration())
+	}
+}
+
+// @Summary Authorization endpoint
+// @Description Start an authorization code flow. Valid requests are redirected to the consent page with the same query string; errors are returned to the client's redirect_uri once it has been verified.
+// @Tags oauth2
+// @Param client_id query string true "Client ID"
+// @Param redirect_uri query string true "Registered redirect URI"
+// @Param response_type query string true "Must be code" example(code)
+// @Param scope query string true "Space separated scopes" example(openid email profile)
+// @Param state query string false "Opaque client state"
+// @Param nonce query string false "ID token nonce"
+// @Param code_challenge query string false "PKCE challenge, required for public clients"
+// @Param code_challenge_method query string false "Must be S256" example(S256)
+// @Success 302
+// @Failure 400 {object} response.OAuthErrorResponse
+// @Router /v1/oauth2/authorize [get]
+func (s *Server) handleOAuth2Authorize() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req validator.OAuthAuthorizeRequest
+		if err := c.ShouldBindQuery(&req); err != nil {
+			respondOAuth2Error(c, http.StatusBadRequest, "invalid_request", err.Error())
+			return
+		}
+
+		if _, err := s.oauthServerSvc.ValidateAuthorization(toAuthorizationRequest(req)); err != nil {
+			s.respondAuthorizationError(c, err, false)
+			return
+		}
+
+		consentURL, err := url.Parse(s.cfg.OAuthServer.ConsentURL)
+		if err != nil {
+			s.logger.Error("invalid oauth consent url", err)
+			respondOAuth2Error(c, http.StatusInternalServerError, "server_error", "")
+			return
+		}
+		consentURL.RawQuery = c.Request.URL.RawQuery
+
+		c.Redirect(http.StatusFound, consentURL.String())
+	}
+}
+
+// @Summary Get an authorization request for consent
+// @Description Describe the client and scopes of an authorization request so the consent page can ask the signed-in user. consent_required is false when the user has already granted these scopes or the client is first-party.
+// @Tags oauth2
+// @Security Bearer
+// @Produce json
+// @Param client_id query string true "Client ID"
+// @Param redirect_uri query string true "Registered redirect URI"
+// @Param response_type query string true "Must be code" example(code)
+// @Param scope query string true "Space separated scopes" example(openid email profile)
+// @Success 200 {object} response.OAuthConsentResponse
+// @Failure 400 {object} response.OAuthErrorResponse
+// @Failure 401 {object} response.ErrorResponse
+// @Router /v1/oauth2/consent [get]
+func (s *Server) handleOAuth2ConsentInfo() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, exists := c.Get("user")
+		if !exists {
+			response.Unauthorized(c, errors.ErrUnauthorized)
+			return
+		}
+
+		var req validator.OAuthAuthorizeRequest
+		if err := c.ShouldBindQuery(&req); err != nil {
+			respondOAuth2Error(c, http.StatusBadRequest, "invalid_request", err.Error())
+			return
+		}
+
+		auth, err := s.oauthServerSvc.ValidateAuthorization(toAuthorizationRequest(req))
+		if err != nil {
+			s.respondAuthorizationError(c, err, false)
+			return
+		}
+
+		required, err := s.oauthServerSvc.ConsentRequired(user.(*models.User).ID.String(), auth)
+		if err != nil {
+			s.logger.Error("failed to check oauth consent", err)
+			response.InternalError(c, errors.ErrInvalidRequest)
+			return
+		}
+
+		c.JSON(http.StatusOK, response.OAuthConsentResponse{
+			ClientID:        auth.Client.ID.String(),
+			ClientName:      auth.Client.Name,
+			Scopes:          auth.Scopes,
+			ConsentRequired: required,
+		})
+	}
+}
+
+// @Summary Decide an authorization request
+// @Description Approve or deny an authorization request for the signed-in user. The response names the client redirect, carrying an authorization code or an access_denied error, that the browser must be sent to.
+// @Tags oauth2
+// @Security Bearer
+// @Accept json
+// @Produce json
+// @Param request body validator.OAuthConsentRequest true "Authorization request parameters and decision"
+// @Success 200 {object} response.OAuthRedirectResponse
+// @Failure 400 {object} response.OAuthErrorResponse
+// @Failure 401 {object} response.ErrorResponse
+// @Router /v1/oauth2/consent [post]
+func (s *Server) handleOAuth2Consent() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, exists := c.Get("user")
+		if !exists {
+			response.Unauthorized(c, errors.ErrUnauthorized)
+			return
+		}
+
+		var req validator.OAuthConsentRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			respondOAuth2Error(c, http.StatusBadRequest, "invalid_request", err.Error())
+			return
+		}
+
+		auth, err := s.oauthServerSvc.ValidateAuthorization(toAuthorizationRequest(req.OAuthAuthorizeRequest))
+		if err != nil {
+			s.respondAuthorizationError(c, err, true)
+			return
+		}
+
+		if !req.Approve {
+			redirectTo := service.AuthorizationRedirect(req.RedirectURI, url.Values{
+				"error":             {"access_denied"},
+				"error_description": {"the user denied the request"},
+			}, req.State)
+			c.JSON(http.StatusOK, response.OAuthRedirectResponse{RedirectTo: redirectTo})
+			return
+		}
+
+		// The session's creation is the login, including any MFA step;
+		// refreshes carry it over
+		session, err := s.authSvc.GetSession(user.(*models.User).ID.String(), c.GetString("session_id"))
+		if err != nil {
+			response.Unauthorized(c, errors.ErrUnauthorized)
+			return
+		}
+
+		redirectTo, err := s.oauthServerSvc.Approve(user.(*models.User), auth, session.CreatedAt)
+		if err != nil {
+			s.logger.Error("failed to issue authorization code", err)
+			response.InternalError(c, errors.ErrInvalidRequest)
+			return
+		}
+
+		c.JSON(http.StatusOK, response.OAuthRedirectResponse{RedirectTo: redirectTo})
+	}
+}
+
+// @Summary Token endpoint
+// @Description Redeem an authorization code, refresh token or client credentials. Clients authenticate with HTTP Basic or client_id/client_secret form fields; public clients send client_id only.
+// @Tags oauth2
+// @Accept x-www-form-urlencoded
+// @Produce json
+// @Param grant_type formData string true "authorization_code, refresh_token or client_credentials"
+// @Param code formData string false "Authorization code"
+// @Param redirect_uri formData string false "Redirect URI used in the authorization request"
+// @Param code_verifier formData string false "PKCE verifier"
+// @Param refresh_token formData string false "Refresh token"
+// @Param scope formData string false "Requested scopes"
+// @Param client_id formData string false "Client ID"
+// @Param client_secret formData string false "Client secret"
+// @Success 200 {object} service.OAuthTokenResponse
+// @Failure 400 {object} response.OAuthErrorResponse
+// @Failure 401 {object} response.OAuthErrorResponse
+// @Router /v1/oauth2/token [post]
+func (s *Server) handleOAuth2Token() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Header("Cache-Control", "no-store")
+		c.Header("Pragma", "no-cache")
+
+		var req validator.OAuthTokenRequest
+		if err := c.ShouldBind(&req); err != nil {
+			respondOAuth2Error(c, http.StatusBadRequest, "invalid_request", err.Error())
+			return
+		}
+
+		// client_secret_basic: credentials are form-encoded before base64 (RFC 6749 section 2.3.1)
+		clientID, clientSecret, basic := c.Request.BasicAuth()
+		if basic {
+			if req.ClientSecret != "" {
+				respondOAuth2Error(c, http.StatusBadRequest, "invalid_request", "use only one client authentication method")
+				return
+			}
+			var errID, errSecret error
+			clientID, errID = url.QueryUnescape(clientID)
+			clientSecret, errSecret = url.QueryUnescape(clientSecret)
+			if errID != nil || errSecret != nil {
+				respondOAuth2Error(c, http.StatusBadRequest, "invalid_request", "malformed client credentials")
+				return
+			}
+		} else {
+			clientID, clientSecret = req.ClientID, req.ClientSecret
+		}
+
+		tokens, err := s.oauthServerSvc.Token(service.TokenRequest{
+			GrantType:    req.GrantType,
+			ClientID:     clientID,
+			ClientSecret: clientSecret,
+			Code:         req.Code,
+			RedirectURI:  req.RedirectURI,
+			CodeVerifier: req.CodeVerifier,
+			RefreshToken: req.RefreshToken,
+			Scope:        req.Scope,
+		})
+		if err != nil {
+			var oauthErr *service.OAuthError
+			if !stderrors.As(err, &oauthErr) {
+				s.logger.Error("failed to issue oauth tokens", err)
+				respondOAuth2Error(c, http.StatusInternalServerError, "server_error", "")
+				return
+			}
+			status := http.StatusBadRequest
+			if oauthErr.Code == "invalid_client" {
+				status = http.StatusUnauthorized
+				if basic {
+					c.Header("WWW-Authenticate", `Basic realm="oauth2"`)
+				}
+			}
+			respondOAuth2Error(c, status, oauthErr.Code, oauthErr.Description)
+			return
+		}
+
+		c.JSON(http.StatusOK, tokens)
+	}
+}
+
+// @Summary UserInfo endpoint
+// @Description Claims about the user an access token was issued for, limited to its granted scopes. Requires the openid scope.
+// @Tags oauth2
+// @Security Bearer
+// @Produce json
+// @Success 200 {object} map[string]interface{}
+// @Failure 401 {object} response.OAuthErrorResponse
+// @Failure 403 {object} response.OAuthErrorResponse
+// @Router /v1/oauth2/userinfo [get]
+// @Router /v1/oauth2/userinfo [post]
+func (s *Server) handleOAuth2UserInfo() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok || token == "" {
+			c.Header("WWW-Authenticate", `Bearer`)
+			respondOAuth2Error(c, http.StatusUnauthorized, "invalid_request", "missing bearer token")
+			return
+		}
+
+		claims, err := s.oauthServerSvc.UserInfo(token)
+		if err != nil {
+			switch {
+			case stderrors.Is(err, errors.ErrInsufficientScope):
+				c.Header("WWW-Authenticate", `Bearer error="insufficient_scope", scope="openid"`)
+				respondOAuth2Error(c, http.StatusForbidden, "insufficient_scope", "the openid scope is required")
+			case stderrors.Is(err, errors.ErrInvalidToken):
+				c.Header("WWW-Authenticate", `Bearer error="invalid_token"`)
+				respondOAuth2Error(c, http.StatusUnauthorized, "invalid_token", "")
+			default:
+				s.logger.Error("failed to get oauth user info", err)
+				respondOAuth2Error(c, http.StatusInternalServerError, "server_error", "")
+			}
+			return
+		}
+
+		c.Header("Cache-Control", "no-store")
+		c.JSON(http.StatusOK, claims)
+	}
+}
+
+// @Summary Register an OAuth client
+// @Description Register an application with the authorization server. Confidential clients receive a client_secret, which is shown only in this response.
+// @Tags admin
+// @Security Bearer
+// @Accept json
+// @Produce json
+// @Param request body validator.RegisterOAuthClientRequest true "Client registration"
+// @Success 201 {object} response.SuccessResponse{data=response.OAuthClientResponse}
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 401 {object} response.ErrorResponse
+// @Failure 403 {object} response.ErrorResponse
+// @Router /v1/admin/oauth2/clients [post]
+func (s *Server) handleAdminRegisterOAuthClient() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, exists := c.Get("user")
+		if !exists {
+			response.Unauthorized(c, errors.ErrUnauthorized)
+			return
+		}
+
+		var req validator.RegisterOAuthClientRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.BadRequest(c, errors.ErrInvalidRequest)
+			return
+		}
+
+		client, secret, err := s.oauthServerSvc.RegisterClient(service.OAuthClientRegistration{
+			Name:         req.Name,
+			RedirectURIs: req.RedirectURIs,
+			GrantTypes:   req.GrantTypes,
+			Scopes:       req.Scopes,
+			Public:       req.Public,
+			FirstParty:   req.FirstParty,
+		}, user.(*models.User).ID)
+		if err != nil {
+			if stderrors.Is(err, errors.ErrInvalidRequest) {
+				response.BadRequest(c, err)
+				return
+			}
+			s.logger.Error("failed to register oauth client", err)
+			response.InternalError(c, errors.ErrInvalidRequest)
+			return
+		}
+
+		resp := toOAuthClientResponse(client)
+		resp.ClientSecret = secret
+		c.JSON(http.StatusCreated, response.SuccessResponse{
+			Message: "client registered",
+			Data:    resp,
+		})
+	}
+}
+
+// @Summary List OAuth clients
+// @Description List the applications registered with the authorization server
+// @Tags admin
+// @Security Bearer
+// @Produce json
+// @Success 200 {object} response.SuccessResponse{data=[]response.OAuthClientResponse}
+// @Failure 401 {object} response.ErrorResponse
+// @Failure 403 {object} response.ErrorResponse
+// @Router /v1/admin/oauth2/clients [get]
+func (s *Server) handleAdminListOAuthClients() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		clients, err := s.oauthServerSvc.ListClients()
+		if err != nil {
+			s.logger.Error("failed to list oauth clients", err)
+			response.InternalError(c, errors.ErrInvalidRequest)
+			return
+		}
+
+		resp := make([]response.OAuthClientResponse, 0, len(clients))
+		for i := range clients {
+			resp = append(resp, toOAuthClientResponse(&clients[i]))
+		}
+
+		response.Success(c, resp)
+	}
+}
+
+// @Summary Delete an OAuth client
+// @Description Delete a client along with its outstanding codes, consents and refresh tokens
+// @Tags admin
+// @Security Bearer
+// @Produce json
+// @Param id path string true "Client ID"
+// @Success 200 {object} response.SuccessResponse
+// @Failure 401 {object} response.ErrorResponse
+// @Failure 403 {object} response.ErrorResponse
+// @Failure 404 {object} response.ErrorResponse
+// @Router /v1/admin/oauth2/clients/{id} [delete]
+func (s *Server) handleAdminDeleteOAuthClient() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := s.oauthServerSvc.DeleteClient(c.Param("id")); err != nil {
+			if stderrors.Is(err, errors.ErrClientNotFound) {
+				response.NotFound(c, err)
+				return
+			}
+			s.logger.Error("failed to delete oauth client", err)
+			response.InternalError(c, errors.ErrInvalidRequest)
+			return
+		}
+
+		response.SuccessWithMessage(c, "client deleted", nil)
+	}
+}
+
+// respondAuthorizationError reports an authorization request error. Errors for
+// a verified redirect URI go back to the client; asRedirect returns that URL
+// in the body for the consent page instead of redirecting the request itself.
+func (s *Server) respondAuthorizationError(c *gin.Context, err error, asRedirect bool) {
+	var oauthErr *service.OAuthError
+	if !stderrors.As(err, &oauthErr) {
+		s.logger.Error("failed to validate authorization request", err)
+		respondOAuth2Error(c, http.StatusInternalServerError, "server_error", "")
+		return
+	}
+
+	if oauthErr.RedirectURI == "" {
+		respondOAuth2Error(c, http.StatusBadRequest, oauthErr.Code, oauthErr.Description)
+		return
+	}
+
+	redirectTo := service.AuthorizationRedirect(oauthErr.RedirectURI, url.Values{
+		"error":             {oauthErr.Code},
+		"error_description": {oauthErr.Description},
+	}, oauthErr.State)
+	if asRedirect {
+		c.JSON(http.StatusOK, response.OAuthRedirectResponse{RedirectTo: redirectTo})
+		return
+	}
+	c.Redirect(http.StatusFound, redirectTo)
+}
+
+// respondOAuth2Error writes an RFC 6749 error response.
+func respondOAuth2Error(c *gin.Context, status int, code, description string) {
+	c.JSON(status, response.OAuthErrorResponse{
+		Error:            code,
+		ErrorDescription: description,
+	})
+}
+
+func toAuthorizationRequest(req validator.OAuthAuthorizeRequest) service.AuthorizationRequest {
+	return service.AuthorizationRequest{
+		ClientID:            req.ClientID,
+		RedirectURI:         req.RedirectURI,
+		ResponseType:        req.ResponseType,
+		Scope:               req.Scope,
+		State:               req.State,
+		Nonce:               req.Nonce,
+		CodeChallenge:       req.CodeChallenge,
+		CodeChallengeMethod: req.CodeChallengeMethod,
+	}
+}
+
+func toOAuthClientResponse(client *models.OAuthClient) response.OAuthClientResponse {
+	return response.OAuthClientResponse{
+		ClientID:     client.ID.String(),
+		Name:         client.Name,
+		RedirectURIs: splitNonEmpty(client.RedirectURIs),
+		GrantTypes:   splitNonEmpty(client.GrantTypes),
+		Scopes:       splitNonEmpty(client.Scopes),
+		Public:       client.IsPublic(),
+		FirstParty:   client.FirstParty,
+		CreatedAt:    client.CreatedAt,
+	}
+}
+
+func splitNonEmpty(list string) []string {
+	if list == "" {
+		return []string{}
+	}
+	return strings.Split(list, ",")
+}
@@ -0,0 +1,425 @@
+package server
+
+import (
+	stderrors "errors"
+	"net/http"
+
+	"rest-api/internal/errors"
+	"rest-api/internal/models"
+	"rest-api/internal/response"
+	"rest-api/pkg/validator"
+
+	"github.com/gin-gonic/gin"
+)
+
+// @Summary Create an organization
+// @Description Create an organization owned by the current user
+// @Tags organizations
+// @Security Bearer
+// @Accept json
+// @Produce json
+// @Param request body validator.CreateOrganizationRequest true "Organization name and optional slug"
+// @Success 201 {object} response.SuccessResponse{data=response.OrganizationResponse}
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 401 {object} response.ErrorResponse
+// @Failure 409 {object} response.ErrorResponse
+// @Router /v1/organizations [post]
+func (s *Server) handleCreateOrganization() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, exists := c.Get("user")
+		if !exists {
+			response.Unauthorized(c, errors.ErrUnauthorized)
+			return
+		}
+
+		var req validator.CreateOrganizationRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.BadRequest(c, errors.ErrInvalidRequest)
+			return
+		}
+
+		membership, err := s.orgSvc.Create(user.(*models.User), req.Name, req.Slug)
+		if err != nil {
+			switch {
+			case stderrors.Is(err, errors.ErrInvalidRequest):
+				response.BadRequest(c, err)
+			case stderrors.Is(err, errors.ErrSlugTaken):
+				response.Error(c, http.StatusConflict, err)
+			default:
+				s.logger.Error("failed to create organization", err)
+				response.InternalError(c, errors.ErrInvalidRequest)
+			}
+			return
+		}
+
+		c.JSON(http.StatusCreated, response.SuccessResponse{
+			Message: "organization created",
+			Data:    toOrganizationResponse(membership, c.GetString("organization_id")),
+		})
+	}
+}
+
+// @Summary List organizations
+// @Description List the organizations the current user belongs to
+// @Tags organizations
+// @Security Bearer
+// @Produce json
+// @Success 200 {object} response.SuccessResponse{data=[]response.OrganizationResponse}
+// @Failure 401 {object} response.ErrorResponse
+// @Router /v1/organizations [get]
+func (s *Server) handleListOrganizations() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, exists := c.Get("user")
+		if !exists {
+			response.Unauthorized(c, errors.ErrUnauthorized)
+			return
+		}
+
+		memberships, err := s.orgSvc.ListForUser(user.(*models.User).ID.String())
+		if err != nil {
+			s.logger.Error("failed to list organizations", err)
+			response.InternalError(c, errors.ErrInvalidRequest)
+			return
+		}
+
+		activeID := c.GetString("organization_id")
+		result := make([]response.OrganizationResponse, len(memberships))
+		for i := range memberships {
+			result[i] = toOrganizationResponse(&memberships[i], activeID)
+		}
+
+		response.Success(c, result)
+	}
+}
+
+// @Summary Switch organization
+// @Description Set the active organization of the current session. In token pair mode the response carries a new access token for it; the refresh token is unchanged.
+// @Tags organizations
+// @Security Bearer
+// @Accept json
+// @Produce json
+// @Param request body validator.SwitchOrganizationRequest true "Organization to switch to"
+// @Success 200 {object} response.SuccessResponse
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 401 {object} response.ErrorResponse
+// @Failure 404 {object} response.ErrorResponse
+// @Router /v1/session/organization [put]
+func (s *Server) handleSwitchOrganization() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, exists := c.Get("user")
+		if !exists {
+			response.Unauthorized(c, errors.ErrUnauthorized)
+			return
+		}
+
+		var req validator.SwitchOrganizationRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.BadRequest(c, errors.ErrInvalidRequest)
+			return
+		}
+
+		userID := user.(*models.User).ID.String()
+		membership, err := s.orgSvc.Membership(req.OrganizationID, userID)
+		if err != nil {
+			if stderrors.Is(err, errors.ErrOrgNotFound) {
+				response.NotFound(c, err)
+				return
+			}
+			s.logger.Error("failed to load membership", err)
+			response.InternalError(c, errors.ErrInvalidRequest)
+			return
+		}
+
+		session, err := s.authSvc.SetActiveOrganization(userID, c.GetString("session_id"), &membership.OrganizationID)
+		if err != nil {
+			if stderrors.Is(err, errors.ErrSessionNotFound) {
+				response.Unauthorized(c, errors.ErrUnauthorized)
+				return
+			}
+			s.logger.Error("failed to switch organization", err)
+			response.InternalError(c, errors.ErrInvalidRequest)
+			return
+		}
+
+		data := gin.H{
+			"organization": toOrganizationResponse(membership, membership.OrganizationID.String()),
+		}
+		if s.authSvc.TokenPairMode() {
+			accessToken, err := s.authSvc.GenerateToken(session)
+			if err != nil {
+				s.logger.Error("failed to generate access token", err)
+				response.InternalError(c, errors.ErrInvalidRequest)
+				return
+			}
+			data["access_token"] = accessToken
+		}
+
+		response.SuccessWithMessage(c, "organization switched", data)
+	}
+}
+
+// @Summary Get the active organization
+// @Description Get the session's active organization and the current user's role in it
+// @Tags organizations
+// @Security Bearer
+// @Produce json
+// @Success 200 {object} response.SuccessResponse{data=response.OrganizationResponse}
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 401 {object} response.ErrorResponse
+// @Failure 403 {object} response.ErrorResponse
+// @Router /v1/organization [get]
+func (s *Server) handleGetOrganization() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		membership := c.MustGet("membership").(*models.Membership)
+		response.Success(c, toOrganizationResponse(membership, membership.OrganizationID.String()))
+	}
+}
+
+// @Summary List members
+// @Description List the members of the active organization
+// @Tags organizations
+// @Security Bearer
+// @Produce json
+// @Success 200 {object} response.SuccessResponse{data=[]response.MemberResponse}
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 401 {object} response.ErrorResponse
+// @Failure 403 {object} response.ErrorResponse
+// @Router /v1/organization/members [get]
+func (s *Server) handleListMembers() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		membership := c.MustGet("membership").(*models.Membership)
+
+		members, err := s.orgSvc.Members(membership.OrganizationID)
+		if err != nil {
+			s.logger.Error("failed to list members", err)
+			response.InternalError(c, errors.ErrInvalidRequest)
+			return
+		}
+
+		result := make([]response.MemberResponse, len(members))
+		for i, member := range members {
+			result[i] = response.MemberResponse{
+				UserID:   member.UserID.String(),
+				Email:    member.User.Email,
+				Name:     member.User.Name,
+				Role:     member.Role,
+				JoinedAt: member.CreatedAt,
+			}
+		}
+
+		response.Success(c, result)
+	}
+}
+
+// @Summary Invite a member
+// @Description Email an invitation to join the active organization. Requires the owner or admin role
+// @Tags organizations
+// @Security Bearer
+// @Accept json
+// @Produce json
+// @Param request body validator.CreateInvitationRequest true "Invitee email and role"
+// @Success 201 {object} response.SuccessResponse{data=response.InvitationResponse}
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 401 {object} response.ErrorResponse
+// @Failure 403 {object} response.ErrorResponse
+// @Failure 500 {object} response.ErrorResponse
+// @Router /v1/organization/invitations [post]
+func (s *Server) handleCreateInvitation() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user := c.MustGet("user").(*models.User)
+		membership := c.MustGet("membership").(*models.Membership)
+
+		var req validator.CreateInvitationRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.BadRequest(c, errors.ErrInvalidRequest)
+			return
+		}
+
+		invitation, err := s.orgSvc.Invite(membership, user, req.Email, req.Role)
+		if err != nil {
+			switch {
+			case stderrors.Is(err, errors.ErrForbidden):
+				response.Forbidden(c, err)
+			case stderrors.Is(err, errors.ErrInvalidRequest):
+				response.BadRequest(c, err)
+			default:
+				s.logger.Error("failed to create invitation", err)
+				response.InternalError(c, errors.ErrInvalidRequest)
+			}
+			return
+		}
+
+		c.JSON(http.StatusCreated, response.SuccessResponse{
+			Message: "invitation sent",
+			Data:    toInvitationResponse(invitation),
+		})
+	}
+}
+
+// @Summary List invitations
+// @Description List the pending invitations of the active organization. Requires the owner or admin role
+// @Tags organizations
+// @Security Bearer
+// @Produce json
+// @Success 200 {object} response.SuccessResponse{data=[]response.InvitationResponse}
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 401 {object} response.ErrorResponse
+// @Failure 403 {object} response.ErrorResponse
+// @Router /v1/organization/invitations [get]
+func (s *Server) handleListInvitations() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		membership := c.MustGet("membership").(*models.Membership)
+
+		invitations, err := s.orgSvc.Invitations(membership)
+		if err != nil {
+			if stderrors.Is(err, errors.ErrForbidden) {
+				response.Forbidden(c, err)
+				return
+			}
+			s.logger.Error("failed to list invitations", err)
+			response.InternalError(c, errors.ErrInvalidRequest)
+			return
+		}
+
+		result := make([]response.InvitationResponse, len(invitations))
+		for i := range invitations {
+			invitations[i].Organization = membership.Organization
+			result[i] = toInvitationResponse(&invitations[i])
+		}
+
+		response.Success(c, result)
+	}
+}
+
+// @Summary Revoke an invitation
+// @Description Revoke a pending invitation of the active organization. Requires the owner or admin role
+// @Tags organizations
+// @Security Bearer
+// @Produce json
+// @Param id path string true "Invitation ID"
+// @Success 200 {object} response.SuccessResponse
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 401 {object} response.ErrorResponse
+// @Failure 403 {object} response.ErrorResponse
+// @Failure 404 {object} response.ErrorResponse
+// @Router /v1/organization/invitations/{id} [delete]
+func (s *Server) handleRevokeInvitation() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		membership := c.MustGet("membership").(*models.Membership)
+
+		if err := s.orgSvc.RevokeInvitation(membership, c.Param("id")); err != nil {
+			switch {
+			case stderrors.Is(err, errors.ErrForbidden):
+				response.Forbidden(c, err)
+			case stderrors.Is(err, errors.ErrInvitationNotFound):
+				response.NotFound(c, err)
+			default:
+				s.logger.Error("failed to revoke invitation", err)
+				response.InternalError(c, errors.ErrInvalidRequest)
+			}
+			return
+		}
+
+		response.SuccessWithMessage(c, "invitation revoked", nil)
+	}
+}
+
+// @Summary Get an invitation
+// @Description Show the organization, email and role of the invitation a token was issued for, so the invitee can sign in or register
+// @Tags organizations
+// @Produce json
+// @Param token query string true "Invitation token"
+// @Success 200 {object} response.SuccessResponse{data=response.InvitationResponse}
+// @Failure 400 {object} response.ErrorResponse
+// @Router /v1/invitation [get]
+func (s *Server) handleGetInvitation() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		invitation, err := s.orgSvc.Invitation(c.Query("token"))
+		if err != nil {
+			if stderrors.Is(err, errors.ErrInvalidToken) {
+				response.BadRequest(c, err)
+				return
+			}
+			s.logger.Error("failed to load invitation", err)
+			response.InternalError(c, errors.ErrInvalidRequest)
+			return
+		}
+
+		response.Success(c, toInvitationResponse(invitation))
+	}
+}
+
+// @Summary Accept an invitation
+// @Description Join the organization of an invitation sent to the current user's email address
+// @Tags organizations
+// @Security Bearer
+// @Accept json
+// @Produce json
+// @Param request body validator.AcceptInvitationRequest true "Invitation token"
+// @Success 200 {object} response.SuccessResponse{data=response.OrganizationResponse}
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 401 {object} response.ErrorResponse
+// @Failure 403 {object} response.ErrorResponse
+// @Failure 409 {object} response.ErrorResponse
+// @Router /v1/invitations/accept [post]
+func (s *Server) handleAcceptInvitation() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, exists := c.Get("user")
+		if !exists {
+			response.Unauthorized(c, errors.ErrUnauthorized)
+			return
+		}
+
+		var req validator.AcceptInvitationRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.BadRequest(c, errors.ErrInvalidRequest)
+			return
+		}
+
+		membership, err := s.orgSvc.AcceptInvitation(user.(*models.User), req.Token)
+		if err != nil {
+			s.respondInvitationError(c, err)
+			return
+		}
+
+		response.SuccessWithMessage(c, "invitation accepted", toOrganizationResponse(membership, c.GetString("organization_id")))
+	}
+}
+
+// respondInvitationError maps errors from validating or accepting an invitation.
+func (s *Server) respondInvitationError(c *gin.Context, err error) {
+	switch {
+	case stderrors.Is(err, errors.ErrInvalidToken):
+		response.BadRequest(c, err)
+	case stderrors.Is(err, errors.ErrInvitationEmail):
+		response.Forbidden(c, err)
+	case stderrors.Is(err, errors.ErrAlreadyMember):
+		response.Error(c, http.StatusConflict, err)
+	default:
+		s.logger.Error("failed to accept invitation", err)
+		response.InternalError(c, errors.ErrInvalidRequest)
+	}
+}
+
+func toInvitationResponse(invitation *models.Invitation) response.InvitationResponse {
+	return response.InvitationResponse{
+		ID:               invitation.ID.String(),
+		OrganizationID:   invitation.OrganizationID.String(),
+		OrganizationName: invitation.Organization.Name,
+		Email:            invitation.Email,
+		Role:             invitation.Role,
+		ExpiresAt:        invitation.ExpiresAt,
+		CreatedAt:        invitation.CreatedAt,
+	}
+}
+
+func toOrganizationResponse(membership *models.Membership, activeID string) response.OrganizationResponse {
+	return response.OrganizationResponse{
+		ID:        membership.Organization.ID.String(),
+		Name:      membership.Organization.Name,
+		Slug:      membership.Organization.Slug,
+		Role:      membership.Role,
+		Active:    membership.OrganizationID.String() == activeID,
+		CreatedAt: membership.Organization.CreatedAt,
+	}
+}
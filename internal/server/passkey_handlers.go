@@ -0,0 +1,267 @@
+package server
+
+import (
+	stderrors "errors"
+	"strings"
+
+	"rest-api/internal/errors"
+	"rest-api/internal/models"
+	"rest-api/internal/response"
+	"rest-api/internal/service"
+	"rest-api/pkg/validator"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// @Summary Begin passkey registration
+// @Description Get WebAuthn credential creation options for registering a new passkey
+// @Tags passkeys
+// @Security Bearer
+// @Produce json
+// @Success 200 {object} response.SuccessResponse
+// @Failure 401 {object} response.ErrorResponse
+// @Router /v1/passkeys/register/options [post]
+func (s *Server) handlePasskeyRegisterOptions() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, exists := c.Get("user")
+		if !exists {
+			response.Unauthorized(c, errors.ErrUnauthorized)
+			return
+		}
+
+		ceremony, err := s.webAuthnSvc.BeginRegistration(user.(*models.User))
+		if err != nil {
+			s.logger.Error("failed to begin passkey registration", err)
+			response.InternalError(c, errors.ErrInvalidRequest)
+			return
+		}
+
+		response.Success(c, ceremony)
+	}
+}
+
+// @Summary Finish passkey registration
+// @Description Verify the authenticator attestation and store the new passkey
+// @Tags passkeys
+// @Security Bearer
+// @Accept json
+// @Produce json
+// @Param request body validator.PasskeyRegisterRequest true "Passkey Register Request"
+// @Success 200 {object} response.PasskeyResponse
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 401 {object} response.ErrorResponse
+// @Router /v1/passkeys/register [post]
+func (s *Server) handlePasskeyRegister() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, exists := c.Get("user")
+		if !exists {
+			response.Unauthorized(c, errors.ErrUnauthorized)
+			return
+		}
+
+		var req validator.PasskeyRegisterRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.BadRequest(c, errors.ErrInvalidRequest)
+			return
+		}
+
+		credential, err := s.webAuthnSvc.FinishRegistration(user.(*models.User), req.CeremonyID, req.Name, req.Credential)
+		if err != nil {
+			if stderrors.Is(err, errors.ErrInvalidPasskey) || stderrors.Is(err, errors.ErrInvalidCeremony) {
+				response.BadRequest(c, err)
+				return
+			}
+			s.logger.Error("failed to finish passkey registration", err)
+			response.InternalError(c, errors.ErrInvalidRequest)
+			return
+		}
+
+		s.auditUserAction(c, models.AuditPasskeyAdded, credential.UserID, map[string]interface{}{
+			"passkey_id": credential.ID.String(),
+			"name":       credential.Name,
+		})
+
+		response.SuccessWithMessage(c, "passkey registered", toPasskeyResponse(credential))
+	}
+}
+
+// @Summary Begin passkey login
+// @Description Get WebAuthn assertion options for a passwordless passkey login
+// @Tags passkeys
+// @Produce json
+// @Success 200 {object} response.SuccessResponse
+// @Router /v1/passkeys/login/options [post]
+func (s *Server) handlePasskeyLoginOptions() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		ceremony, err := s.webAuthnSvc.BeginLogin()
+		if err != nil {
+			s.logger.Error("failed to begin passkey login", err)
+			response.InternalError(c, errors.ErrInvalidRequest)
+			return
+		}
+
+		response.Success(c, ceremony)
+	}
+}
+
+// @Summary Passkey login
+// @Description Verify the authenticator assertion and create a session
+// @Tags passkeys
+// @Accept json
+// @Produce json
+// @Param request body validator.PasskeyLoginRequest true "Passkey Login Request"
+// @Success 200 {object} response.SuccessResponse
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 401 {object} response.ErrorResponse
+// @Router /v1/passkeys/login [post]
+func (s *Server) handlePasskeyLogin() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req validator.PasskeyLoginRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.BadRequest(c, errors.ErrInvalidRequest)
+			return
+		}
+
+		session, err := s.webAuthnSvc.FinishLogin(req.CeremonyID, req.Credential, clientInfo(c))
+		var userID *uuid.UUID
+		if session != nil {
+			userID = &session.UserID
+		}
+		s.auditSvc.Log(service.LoginEvent(userID, "passkey", clientInfo(c), err))
+		if err != nil {
+			if stderrors.Is(err, errors.ErrAccountDisabled) {
+				response.Forbidden(c, err)
+				return
+			}
+			s.logger.Error("failed to finish passkey login", err)
+			response.Unauthorized(c, errors.ErrInvalidPasskey)
+			return
+		}
+
+		s.respondWithSession(c, "login successful", session)
+	}
+}
+
+// @Summary List passkeys
+// @Description List the current user's registered passkeys
+// @Tags passkeys
+// @Security Bearer
+// @Produce json
+// @Success 200 {array} response.PasskeyResponse
+// @Failure 401 {object} response.ErrorResponse
+// @Router /v1/profile/passkeys [get]
+func (s *Server) handleListPasskeys() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, exists := c.Get("user")
+		if !exists {
+			response.Unauthorized(c, errors.ErrUnauthorized)
+			return
+		}
+
+		credentials, err := s.webAuthnSvc.ListCredentials(user.(*models.User).ID.String())
+		if err != nil {
+			s.logger.Error("failed to list passkeys", err)
+			response.InternalError(c, errors.ErrInvalidRequest)
+			return
+		}
+
+		passkeys := make([]response.PasskeyResponse, len(credentials))
+		for i := range credentials {
+			passkeys[i] = toPasskeyResponse(&credentials[i])
+		}
+
+		response.Success(c, passkeys)
+	}
+}
+
+// @Summary Rename passkey
+// @Description Rename one of the current user's passkeys
+// @Tags passkeys
+// @Security Bearer
+// @Accept json
+// @Produce json
+// @Param id path string true "Passkey ID"
+// @Param request body validator.RenamePasskeyRequest true "Rename Passkey Request"
+// @Success 200 {object} response.SuccessResponse
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 401 {object} response.ErrorResponse
+// @Failure 404 {object} response.ErrorResponse
+// @Router /v1/profile/passkeys/{id} [put]
+func (s *Server) handleRenamePasskey() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, exists := c.Get("user")
+		if !exists {
+			response.Unauthorized(c, errors.ErrUnauthorized)
+			return
+		}
+
+		var req validator.RenamePasskeyRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.BadRequest(c, errors.ErrInvalidRequest)
+			return
+		}
+
+		if err := s.webAuthnSvc.RenameCredential(user.(*models.User).ID.String(), c.Param("id"), req.Name); err != nil {
+			if stderrors.Is(err, errors.ErrPasskeyNotFound) {
+				response.NotFound(c, err)
+				return
+			}
+			s.logger.Error("failed to rename passkey", err)
+			response.InternalError(c, errors.ErrInvalidRequest)
+			return
+		}
+
+		response.SuccessWithMessage(c, "passkey renamed", nil)
+	}
+}
+
+// @Summary Delete passkey
+// @Description Delete one of the current user's passkeys
+// @Tags passkeys
+// @Security Bearer
+// @Produce json
+// @Param id path string true "Passkey ID"
+// @Success 200 {object} response.SuccessResponse
+// @Failure 401 {object} response.ErrorResponse
+// @Failure 404 {object} response.ErrorResponse
+// @Router /v1/profile/passkeys/{id} [delete]
+func (s *Server) handleDeletePasskey() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, exists := c.Get("user")
+		if !exists {
+			response.Unauthorized(c, errors.ErrUnauthorized)
+			return
+		}
+
+		if err := s.webAuthnSvc.DeleteCredential(user.(*models.User).ID.String(), c.Param("id")); err != nil {
+			if stderrors.Is(err, errors.ErrPasskeyNotFound) {
+				response.NotFound(c, err)
+				return
+			}
+			s.logger.Error("failed to delete passkey", err)
+			response.InternalError(c, errors.ErrInvalidRequest)
+			return
+		}
+
+		s.auditUserAction(c, models.AuditPasskeyRemoved, user.(*models.User).ID, map[string]interface{}{"passkey_id": c.Param("id")})
+
+		response.SuccessWithMessage(c, "passkey deleted", nil)
+	}
+}
+
+func toPasskeyResponse(credential *models.WebAuthnCredential) response.PasskeyResponse {
+	transports := []string{}
+	if credential.Transports != "" {
+		transports = strings.Split(credential.Transports, ",")
+	}
+
+	return response.PasskeyResponse{
+		ID:         credential.ID.String(),
+		Name:       credential.Name,
+		Transports: transports,
+		Synced:     credential.BackupState,
+		CreatedAt:  credential.CreatedAt,
+		LastUsedAt: credential.LastUsedAt,
+	}
+}
@@ -4,16 +4,18 @@ import (
 	"rest-api/internal/errors"
 	"rest-api/internal/models"
 	"rest-api/internal/response"
+	"rest-api/pkg/validator"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
 
 // @Summary Get user profile
-// @Description Get the current user's profile
+// @Description Get the current user's profile. In an impersonation session impersonated is true and impersonator names the administrator
 // @Tags profile
 // @Security Bearer
 // @Produce json
-// @Success 200 {object} response.UserResponse
+// @Success 200 {object} response.SuccessResponse{data=response.ProfileResponse}
 // @Failure 401 {object} response.ErrorResponse
 // @Router /v1/profile [get]
 func (s *Server) handleGetProfile() gin.HandlerFunc {
@@ -24,7 +26,64 @@ func (s *Server) handleGetProfile() gin.HandlerFunc {
 			return
 		}
 
-		response.Success(c, user.(*models.User))
+		current := user.(*models.User)
+		profile := response.ProfileResponse{
+			UserResponse: response.UserResponse{
+				ID:    current.ID.String(),
+				Email: current.Email,
+				Name:  current.Name,
+			},
+			EmailVerifiedAt: current.EmailVerifiedAt,
+			Locale:          current.Locale,
+		}
+		if impersonatorID := c.GetString("impersonator_id"); impersonatorID != "" {
+			profile.Impersonated = true
+			if impersonator, err := s.userSvc.GetByID(impersonatorID); err == nil {
+				profile.Impersonator = &response.ImpersonatorResponse{
+					ID:    impersonator.ID.String(),
+					Email: impersonator.Email,
+					Name:  impersonator.Name,
+				}
+			}
+		}
+
+		response.Success(c, profile)
+	}
+}
+
+// @Summary Set email language
+// @Description Set the language the current user's emails are sent in, as a BCP 47 tag. An empty locale uses the Accept-Language header of the request that triggers each email
+// @Tags profile
+// @Security Bearer
+// @Accept json
+// @Produce json
+// @Param request body validator.UpdateLocaleRequest true "Update Locale Request"
+// @Success 200 {object} response.SuccessResponse
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 401 {object} response.ErrorResponse
+// @Failure 403 {object} response.ErrorResponse
+// @Router /v1/profile/locale [put]
+func (s *Server) handleUpdateLocale() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, exists := c.Get("user")
+		if !exists {
+			response.Unauthorized(c, errors.ErrUnauthorized)
+			return
+		}
+
+		var req validator.UpdateLocaleRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.BadRequest(c, errors.ErrInvalidRequest)
+			return
+		}
+
+		if err := s.userSvc.UpdateLocale(user.(*models.User).ID.String(), req.Locale); err != nil {
+			s.logger.Error("failed to update locale", err)
+			response.InternalError(c, errors.ErrInvalidRequest)
+			return
+		}
+
+		response.SuccessWithMessage(c, "locale updated", nil)
 	}
 }
 
@@ -44,12 +103,18 @@ func (s *Server) handleLogout() gin.HandlerFunc {
 			return
 		}
 
-		if err := s.authSvc.InvalidateSession(token); err != nil {
+		if err := s.authSvc.InvalidateSession(strings.TrimPrefix(token, "Bearer "), clientInfo(c)); err != nil {
 			s.logger.Error("failed to invalidate session", err)
 			response.InternalError(c, errors.ErrInvalidRequest)
 			return
 		}
 
+		// Logging out of an impersonation is recorded as its end by InvalidateSession
+		if c.GetString("impersonator_id") == "" {
+			userID := c.MustGet("user").(*models.User).ID
+			s.auditUserAction(c, models.AuditLogout, userID, map[string]interface{}{"session_id": c.GetString("session_id")})
+		}
+
 		response.SuccessWithMessage(c, "logged out successfully", nil)
 	}
 }
@@ -70,12 +135,14 @@ func (s *Server) handleInvalidateSessions() gin.HandlerFunc {
 			return
 		}
 
-		if err := s.authSvc.InvalidateAllSessions(user.(*models.User).ID.String()); err != nil {
+		if err := s.authSvc.InvalidateAllSessions(user.(*models.User).ID.String(), clientInfo(c)); err != nil {
 			s.logger.Error("failed to invalidate sessions", err)
 			response.InternalError(c, errors.ErrInvalidRequest)
 			return
 		}
 
+		s.auditUserAction(c, models.AuditSessionsRevoked, user.(*models.User).ID, map[string]interface{}{"scope": "all"})
+
 		response.SuccessWithMessage(c, "all sessions invalidated", nil)
 	}
 }
@@ -5,36 +5,55 @@ import (
 	"fmt"
 	"net/http"
 	"strconv"
+	"sync"
 	"time"
 
 	"rest-api/internal/config"
 	"rest-api/internal/middleware"
+	"rest-api/internal/models"
 	"rest-api/internal/repository"
 	"rest-api/internal/service"
 	"rest-api/pkg/email"
+	"rest-api/pkg/encryption"
 	"rest-api/pkg/logger"
+	"rest-api/pkg/password"
 	"rest-api/pkg/validator"
 
 	"github.com/gin-gonic/gin"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
+	"golang.org/x/crypto/bcrypt"
 	"golang.org/x/time/rate"
 	"gorm.io/gorm"
 )
 
 type Server struct {
-	cfg         *config.Config
-	logger      *logger.Logger
-	router      *gin.Engine
-	httpSrv     *http.Server
-	validator   *validator.Validator
-	authSvc     *service.AuthService
-	userSvc     *service.UserService
-	tokenSvc    *service.TokenService
-	emailSvc    *email.EmailService
-	rateLimiter *middleware.IPRateLimiter
-	oauthSvc    *service.OAuthService
-	db          *gorm.DB
+	cfg            *config.Config
+	logger         *logger.Logger
+	router         *gin.Engine
+	httpSrv        *http.Server
+	validator      *validator.Validator
+	authSvc        *service.AuthService
+	userSvc        *service.UserService
+	tokenSvc       *service.TokenService
+	mfaSvc         *service.MFAService
+	webAuthnSvc    *service.WebAuthnService
+	keySvc         *service.KeyService
+	activity       *service.SessionActivityTracker
+	rateLimiter    *middleware.IPRateLimiter
+	oauthSvc       *service.OAuthService
+	oauthServerSvc *service.OAuthServerService
+	patSvc         *service.PersonalAccessTokenService
+	rbacSvc        *service.RBACService
+	orgSvc         *service.OrganizationService
+	auditSvc       *service.AuditService
+	webhookSvc     *service.WebhookService
+	eventBus       *service.EventBus
+	emailQueue     *service.EmailQueue
+	emailSvc       *email.EmailService
+	db             *gorm.DB
+	stopJobs       context.CancelFunc
+	jobs           sync.WaitGroup
 }
 
 func New(cfg *config.Config, logger *logger.Logger, db *gorm.DB) *Server {
@@ -42,44 +61,178 @@ func New(cfg *config.Config, logger *logger.Logger, db *gorm.DB) *Server {
 	userRepo := repository.NewUserRepository(db)
 	sessionRepo := repository.NewSessionRepository(db)
 	tokenRepo := repository.NewTokenRepository(db)
+	mfaRepo := repository.NewMFARepository(db)
+	webAuthnRepo := repository.NewWebAuthnRepository(db)
+	signingKeyRepo := repository.NewSigningKeyRepository(db)
+	identityRepo := repository.NewIdentityRepository(db)
+	oauthClientRepo := repository.NewOAuthClientRepository(db)
+	oauthGrantRepo := repository.NewOAuthGrantRepository(db)
+	patRepo := repository.NewPersonalAccessTokenRepository(db)
+	roleRepo := repository.NewRoleRepository(db)
+	orgRepo := repository.NewOrganizationRepository(db)
+	auditRepo := repository.NewAuditRepository(db)
+	webhookRepo := repository.NewWebhookRepository(db)
+	outboxRepo := repository.NewOutboxRepository(db)
+	emailRepo := repository.NewEmailRepository(db)
+	transactor := repository.NewTransactor(db)
 
 	// Initialize services
-	userSvc := service.NewUserService(userRepo)
-	authSvc := service.NewAuthService(userRepo, sessionRepo, cfg.JWT.Secret)
-	tokenSvc := service.NewTokenService(tokenRepo)
+	auditSvc := service.NewAuditService(auditRepo, logger)
+	tokenSvc := service.NewTokenService(tokenRepo, transactor)
+	webhookSvc := service.NewWebhookService(webhookRepo, encryption.NewEncryptor(cfg.Webhook.EncryptionKey), service.WebhookConfig{
+		HTTPClient:           service.NewWebhookHTTPClient(cfg.Webhook.Timeout, cfg.Webhook.AllowPrivateNetworks),
+		AllowPrivateNetworks: cfg.Webhook.AllowPrivateNetworks,
+		MaxAttempts:          cfg.Webhook.MaxAttempts,
+		BackoffBase:          cfg.Webhook.BackoffBase,
+		BackoffMax:           cfg.Webhook.BackoffMax,
+	}, logger)
+	argon2Params := password.DefaultArgon2Params
+	argon2Params.Memory = uint32(cfg.Password.Argon2Memory)
+	argon2Params.Iterations = uint32(cfg.Password.Argon2Iterations)
+	argon2Params.Parallelism = uint8(cfg.Password.Argon2Parallelism)
+	hasher := password.NewManager(password.NewArgon2id(argon2Params), password.NewBcrypt(bcrypt.DefaultCost))
 
-	// Convert port string to int
-	port, _ := strconv.Atoi(cfg.Email.Port)
+	userSvc := service.NewUserService(userRepo, transactor, tokenSvc, hasher)
+	mfaSvc := service.NewMFAService(mfaRepo, encryption.NewEncryptor(cfg.MFA.EncryptionKey), cfg.MFA.Issuer)
+	keySvc := service.NewKeyService(signingKeyRepo, encryption.NewEncryptor(cfg.JWT.KeyEncryptionKey), service.KeyConfig{
+		Algorithm:        cfg.JWT.SigningAlgorithm,
+		RotationInterval: cfg.JWT.KeyRotationInterval,
+		GracePeriod:      cfg.JWT.KeyGracePeriod,
+		PrepublishPeriod: cfg.JWT.KeyPrepublishPeriod,
+	}, logger)
+	if cfg.JWT.SigningAlgorithm != "HS256" {
+		if err := keySvc.EnsureActiveKey(); err != nil {
+			logger.Fatal("Failed to initialize JWT signing keys", err)
+		}
+	}
 
-	emailSvc := email.NewEmailService(email.Config{
-		Host:     cfg.Email.Host,
-		Port:     port, // Now using the converted int value
-		Username: cfg.Email.Username,
-		Password: cfg.Email.Password,
-		From:     cfg.Email.From,
+	activity := service.NewSessionActivityTracker(sessionRepo, cfg.Session.IdleTimeout, logger)
+
+	authSvc := service.NewAuthService(userRepo, sessionRepo, tokenSvc, mfaSvc, keySvc, activity, auditSvc, transactor, hasher, service.AuthConfig{
+		JWTSecret:               cfg.JWT.Secret,
+		SigningAlgorithm:        cfg.JWT.SigningAlgorithm,
+		Issuer:                  cfg.Server.BaseURL,
+		TokenPairMode:           cfg.JWT.TokenPairMode,
+		AccessTokenTTL:          cfg.JWT.AccessTokenTTL,
+		RefreshTokenTTL:         cfg.JWT.RefreshTokenTTL,
+		SessionIdleTimeout:      cfg.Session.IdleTimeout,
+		SessionAbsoluteLifetime: cfg.Session.AbsoluteLifetime,
+		EmailVerificationPolicy: cfg.EmailVerification.Policy,
+		Lockout: service.LockoutConfig{
+			BackoffThreshold: cfg.Lockout.BackoffThreshold,
+			BackoffBase:      cfg.Lockout.BackoffBase,
+			BackoffMax:       cfg.Lockout.BackoffMax,
+			Threshold:        cfg.Lockout.Threshold,
+			Duration:         cfg.Lockout.Duration,
+		},
+		ImpersonationTTL: cfg.Impersonation.TTL,
 	})
 
-	// Initialize OAuth service
-	oauthSvc := service.NewOAuthService(
-		cfg.OAuth.Google.ClientID,
-		cfg.OAuth.Google.ClientSecret,
-		cfg.OAuth.Google.RedirectURL,
-		userSvc,
+	webAuthnSvc, err := service.NewWebAuthnService(
+		cfg.WebAuthn.RPID,
+		cfg.WebAuthn.RPDisplayName,
+		cfg.WebAuthn.RPOrigins,
+		webAuthnRepo,
+		userRepo,
 		authSvc,
+		logger,
 	)
+	if err != nil {
+		logger.Fatal("Failed to initialize WebAuthn", err)
+	}
+
+	mailTransport, err := newMailTransport(cfg, logger)
+	if err != nil {
+		logger.Fatal("Failed to initialize mail transport", err)
+	}
+	emailSvc := email.NewEmailService(email.Config{
+		From:      cfg.Email.From,
+		Transport: mailTransport,
+	})
+
+	// Send emails from a persistent queue so requests never wait on SMTP
+	emailQueue := service.NewEmailQueue(emailRepo, emailSvc, service.EmailQueueConfig{
+		Workers:     cfg.Email.Workers,
+		MaxAttempts: cfg.Email.MaxAttempts,
+		BackoffBase: cfg.Email.BackoffBase,
+		BackoffMax:  cfg.Email.BackoffMax,
+		Retention:   cfg.Email.Retention,
+	}, logger)
+
+	emailTemplates, err := email.LoadTemplates(email.TemplateConfig{
+		Dirs:          cfg.Email.TemplateDirs,
+		DefaultLocale: cfg.Email.DefaultLocale,
+	})
+	if err != nil {
+		logger.Fatal("Failed to load email templates", err)
+	}
+
+	// Initialize OAuth service
+	oauthProviders := make([]service.OAuthProviderConfig, 0, len(cfg.OAuth.Providers))
+	for _, p := range cfg.OAuth.Providers {
+		oauthProviders = append(oauthProviders, service.OAuthProviderConfig(p))
+	}
+	oauthSvc := service.NewOAuthService(service.OAuthConfig{
+		Providers:        oauthProviders,
+		HTTPClient:       &http.Client{Timeout: 10 * time.Second},
+		AllowedRedirects: cfg.OAuth.AllowedRedirects,
+		DisableSignup:    cfg.Registration.InviteOnly,
+	}, encryption.NewEncryptor(cfg.OAuth.StateKey), identityRepo, userSvc, authSvc, auditSvc)
+
+	// Initialize the authorization server for registered client apps
+	oauthServerSvc := service.NewOAuthServerService(oauthClientRepo, oauthGrantRepo, userSvc, keySvc, service.OAuthServerConfig{
+		Issuer:          cfg.Server.BaseURL,
+		AccessTokenTTL:  cfg.OAuthServer.AccessTokenTTL,
+		RefreshTokenTTL: cfg.OAuthServer.RefreshTokenTTL,
+		IDTokenTTL:      cfg.OAuthServer.IDTokenTTL,
+		CodeTTL:         cfg.OAuthServer.CodeTTL,
+	}, logger)
+
+	patSvc := service.NewPersonalAccessTokenService(patRepo, service.PersonalAccessTokenConfig{
+		MaxLifetime: cfg.PersonalAccessToken.MaxLifetime,
+	})
+
+	rbacSvc := service.NewRBACService(roleRepo, service.RBACConfig{
+		BootstrapAdmins: cfg.Admin.Emails,
+	})
+
+	orgSvc := service.NewOrganizationService(orgRepo, transactor)
+
+	// Dispatch domain events published through the outbox
+	eventBus := service.NewEventBus(outboxRepo, service.EventBusConfig{
+		MaxAttempts: cfg.Outbox.MaxAttempts,
+		BackoffBase: cfg.Outbox.BackoffBase,
+		BackoffMax:  cfg.Outbox.BackoffMax,
+		Retention:   cfg.Outbox.Retention,
+	}, logger)
+	eventBus.Subscribe("email", service.NewNotificationService(emailQueue, tokenRepo, transactor, emailTemplates, cfg.Server.BaseURL).HandleEvent, service.NotificationEvents...)
+	eventBus.Subscribe("audit", auditSvc.HandleEvent, service.EventTokenIssued, service.EventPasswordResetRequested)
+	eventBus.Subscribe("webhooks", webhookSvc.HandleEvent, service.WebhookEvents...)
 
 	return &Server{
-		cfg:         cfg,
-		logger:      logger,
-		router:      gin.Default(),
-		validator:   validator.NewValidator(),
-		userSvc:     userSvc,
-		authSvc:     authSvc,
-		tokenSvc:    tokenSvc,
-		emailSvc:    emailSvc,
-		rateLimiter: middleware.NewIPRateLimiter(rate.Limit(1), 5),
-		oauthSvc:    oauthSvc,
-		db:          db,
+		cfg:            cfg,
+		logger:         logger,
+		router:         gin.Default(),
+		validator:      validator.NewValidator(),
+		userSvc:        userSvc,
+		authSvc:        authSvc,
+		tokenSvc:       tokenSvc,
+		mfaSvc:         mfaSvc,
+		webAuthnSvc:    webAuthnSvc,
+		keySvc:         keySvc,
+		activity:       activity,
+		rateLimiter:    middleware.NewIPRateLimiter(rate.Limit(1), 5),
+		oauthSvc:       oauthSvc,
+		oauthServerSvc: oauthServerSvc,
+		patSvc:         patSvc,
+		rbacSvc:        rbacSvc,
+		orgSvc:         orgSvc,
+		auditSvc:       auditSvc,
+		webhookSvc:     webhookSvc,
+		eventBus:       eventBus,
+		emailQueue:     emailQueue,
+		emailSvc:       emailSvc,
+		db:             db,
 	}
 }
 
@@ -87,6 +240,19 @@ func (s *Server) Start() error {
 	// Setup routes
 	s.setupRoutes()
 
+	// Start background jobs
+	ctx, cancel := context.WithCancel(context.Background())
+	s.stopJobs = cancel
+	if s.cfg.JWT.SigningAlgorithm != "HS256" {
+		s.runJob(func() { s.keySvc.Run(ctx, time.Minute) })
+	}
+	s.runJob(func() { s.activity.Run(ctx, 30*time.Second) })
+	s.runJob(func() { s.webAuthnSvc.Run(ctx, time.Minute) })
+	s.runJob(func() { s.oauthServerSvc.Run(ctx, time.Hour) })
+	s.runJob(func() { s.eventBus.Run(ctx, time.Second) })
+	s.runJob(func() { s.emailQueue.Run(ctx, time.Second) })
+	s.runJob(func() { s.webhookSvc.Run(ctx, 5*time.Second) })
+
 	// Configure HTTP server
 	s.httpSrv = &http.Server{
 		Addr:         ":" + s.cfg.Server.Port,
@@ -112,9 +278,58 @@ func (s *Server) Shutdown() error {
 		return fmt.Errorf("failed to shutdown server: %w", err)
 	}
 
+	// Stop background jobs once in-flight requests are done so they can flush
+	if s.stopJobs != nil {
+		s.stopJobs()
+	}
+	s.jobs.Wait()
+
+	// The email queue has stopped sending, so pooled connections can go
+	if err := s.emailSvc.Close(); err != nil {
+		s.logger.Error("failed to close mail transport", err)
+	}
+
 	return nil
 }
 
+// newMailTransport creates the transport chosen by EMAIL_TRANSPORT.
+func newMailTransport(cfg *config.Config, logger *logger.Logger) (email.Transport, error) {
+	switch cfg.Email.Transport {
+	case "smtp":
+		port, _ := strconv.Atoi(cfg.Email.Port)
+		return email.NewSMTPTransport(email.SMTPConfig{
+			Host:        cfg.Email.Host,
+			Port:        port,
+			Username:    cfg.Email.Username,
+			Password:    cfg.Email.Password,
+			TLS:         cfg.Email.TLS,
+			Timeout:     cfg.Email.Timeout,
+			PoolSize:    cfg.Email.PoolSize,
+			IdleTimeout: cfg.Email.IdleTimeout,
+		})
+	case "file":
+		return email.NewFileTransport(cfg.Email.FileDir), nil
+	case "log":
+		return email.NewLogTransport(logger), nil
+	}
+	return nil, fmt.Errorf("unknown email transport %q", cfg.Email.Transport)
+}
+
+// oauthServerEnabled reports whether the authorization server can run. Its ID
+// tokens must be verifiable by clients from the JWKS, which HS256 cannot offer.
+func (s *Server) oauthServerEnabled() bool {
+	return s.cfg.JWT.SigningAlgorithm != "HS256"
+}
+
+// runJob starts a background job that Shutdown waits for.
+func (s *Server) runJob(job func()) {
+	s.jobs.Add(1)
+	go func() {
+		defer s.jobs.Done()
+		job()
+	}()
+}
+
 func (s *Server) setupRoutes() {
 	// Add Swagger
 	s.router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
@@ -122,6 +337,14 @@ func (s *Server) setupRoutes() {
 	// Health check (no versioning)
 	s.router.GET("/api/health", s.handleHealthCheck())
 
+	// Well-known discovery documents
+	s.router.GET("/.well-known/jwks.json", s.handleJWKS())
+	if s.oauthServerEnabled() {
+		s.router.GET("/.well-known/openid-configuration", s.handleOpenIDConfiguration())
+	} else {
+		s.logger.Info("OAuth authorization server disabled: it requires an asymmetric JWT_SIGNING_ALGORITHM")
+	}
+
 	// Add middleware
 	s.router.Use(gin.Recovery())
 	s.router.Use(s.corsMiddleware())
@@ -133,20 +356,129 @@ func (s *Server) setupRoutes() {
 		// Public routes
 		v1.POST("/register", s.handleRegister())
 		v1.POST("/login", s.handleLogin())
+		v1.POST("/login/mfa", s.handleMFALogin())
+		v1.POST("/token/refresh", s.handleRefreshToken())
+		v1.POST("/passkeys/login/options", s.handlePasskeyLoginOptions())
+		v1.POST("/passkeys/login", s.handlePasskeyLogin())
 		v1.POST("/forgot-password", s.handleForgotPassword())
 		v1.POST("/magic-link-login", s.handleMagicLinkLogin())
-		v1.GET("/oauth/google", s.handleGoogleOAuth())
-		v1.GET("/oauth/google/callback", s.handleGoogleCallback())
+		v1.GET("/oauth/:provider", s.handleOAuthLogin())
+		v1.GET("/oauth/:provider/callback", s.handleOAuthCallback())
 		v1.POST("/reset-password", s.handleResetPassword())
 		v1.GET("/verify-magic-link", s.handleMagicLinkVerify())
+		v1.POST("/verify-email", s.handleVerifyEmail())
+		v1.POST("/verify-email/resend", s.handleResendVerification())
+		v1.POST("/unlock-account", s.handleUnlockAccount())
+		v1.GET("/invitation", s.handleGetInvitation())
+
+		// OAuth 2.0 / OpenID Connect authorization server
+		if s.oauthServerEnabled() {
+			v1.GET("/oauth2/authorize", s.handleOAuth2Authorize())
+			v1.POST("/oauth2/token", s.handleOAuth2Token())
+			v1.GET("/oauth2/userinfo", s.handleOAuth2UserInfo())
+			v1.POST("/oauth2/userinfo", s.handleOAuth2UserInfo())
+		}
 
-		// Protected routes
+		// Protected routes (available to users with an unverified email)
 		protected := v1.Group("/")
 		protected.Use(s.authMiddleware())
 		{
-			protected.GET("/profile", s.handleGetProfile())
 			protected.GET("/logout", s.handleLogout())
-			protected.POST("/invalidate-sessions", s.handleInvalidateSessions())
+			protected.GET("/profile/activity", s.handleListActivity())
+			protected.PUT("/profile/locale", s.denyImpersonation(), s.handleUpdateLocale())
+			protected.POST("/sessions/revoke-others", s.denyImpersonation(), s.handleRevokeOtherSessions())
+		}
+
+		// Protected routes also open to personal access tokens holding the scope
+		v1.GET("/profile", s.authMiddleware(service.TokenScopeProfileRead), s.handleGetProfile())
+		v1.POST("/invalidate-sessions", s.authMiddleware(service.TokenScopeSessionsWrite), s.denyImpersonation(), s.handleInvalidateSessions())
+		v1.GET("/sessions", s.authMiddleware(service.TokenScopeSessionsRead), s.handleListSessions())
+		v1.GET("/sessions/:id", s.authMiddleware(service.TokenScopeSessionsRead), s.handleGetSession())
+		v1.DELETE("/sessions/:id", s.authMiddleware(service.TokenScopeSessionsWrite), s.denyImpersonation(), s.handleRevokeSession())
+
+		// Protected routes requiring a verified email under the restricted policy
+		verified := v1.Group("/")
+		verified.Use(s.authMiddleware(), s.verifiedEmailMiddleware())
+		{
+			verified.GET("/profile/passkeys", s.handleListPasskeys())
+			verified.GET("/profile/identities", s.handleListIdentities())
+			verified.GET("/profile/tokens", s.handleListPersonalAccessTokens())
+			verified.POST("/organizations", s.handleCreateOrganization())
+			verified.GET("/organizations", s.handleListOrganizations())
+			verified.PUT("/session/organization", s.handleSwitchOrganization())
+			if s.oauthServerEnabled() {
+				verified.GET("/oauth2/consent", s.handleOAuth2ConsentInfo())
+			}
+		}
+
+		// Credential, token and grant changes, which impersonators may not make
+		credentials := v1.Group("/")
+		credentials.Use(s.authMiddleware(), s.verifiedEmailMiddleware(), s.denyImpersonation())
+		{
+			credentials.POST("/mfa/enroll", s.handleMFAEnroll())
+			credentials.POST("/mfa/confirm", s.handleMFAConfirm())
+			credentials.POST("/mfa/disable", s.handleMFADisable())
+			credentials.POST("/passkeys/register/options", s.handlePasskeyRegisterOptions())
+			credentials.POST("/passkeys/register", s.handlePasskeyRegister())
+			credentials.PUT("/profile/passkeys/:id", s.handleRenamePasskey())
+			credentials.DELETE("/profile/passkeys/:id", s.handleDeletePasskey())
+			credentials.POST("/oauth/:provider/link", s.handleLinkIdentity())
+			credentials.DELETE("/profile/identities/:id", s.handleUnlinkIdentity())
+			credentials.POST("/profile/tokens", s.handleCreatePersonalAccessToken())
+			credentials.DELETE("/profile/tokens/:id", s.handleRevokePersonalAccessToken())
+			credentials.POST("/invitations/accept", s.handleAcceptInvitation())
+			if s.oauthServerEnabled() {
+				credentials.POST("/oauth2/consent", s.handleOAuth2Consent())
+			}
+		}
+
+		// Routes working in the session's active organization
+		org := v1.Group("/organization")
+		org.Use(s.authMiddleware(), s.verifiedEmailMiddleware(), s.organizationMiddleware())
+		{
+			org.GET("", s.handleGetOrganization())
+			org.GET("/members", s.handleListMembers())
+			org.POST("/invitations", s.handleCreateInvitation())
+			org.GET("/invitations", s.handleListInvitations())
+			org.DELETE("/invitations/:id", s.handleRevokeInvitation())
+		}
+
+		// Admin routes
+		admin := v1.Group("/admin")
+		admin.Use(s.authMiddleware(service.TokenScopeAdmin), s.denyImpersonation())
+		{
+			admin.GET("/users", middleware.RequirePermission(models.PermissionUsersRead), s.handleAdminListUsers())
+			admin.GET("/users/:id", middleware.RequirePermission(models.PermissionUsersRead), s.handleAdminGetUser())
+			admin.DELETE("/users/:id", middleware.RequirePermission(models.PermissionUsersWrite), s.handleAdminDeleteUser())
+			admin.POST("/users/:id/disable", middleware.RequirePermission(models.PermissionUsersWrite), s.handleAdminDisableUser())
+			admin.POST("/users/:id/enable", middleware.RequirePermission(models.PermissionUsersWrite), s.handleAdminEnableUser())
+			admin.POST("/users/:id/force-password-reset", middleware.RequirePermission(models.PermissionUsersWrite), s.handleAdminForcePasswordReset())
+			admin.POST("/users/:id/revoke-sessions", middleware.RequirePermission(models.PermissionUsersWrite), s.handleAdminRevokeUserSessions())
+			admin.POST("/users/:id/unlock", middleware.RequirePermission(models.PermissionUsersWrite), s.handleAdminUnlockUser())
+			admin.POST("/users/:id/impersonate", middleware.RequirePermission(models.PermissionUsersImpersonate), s.handleAdminImpersonateUser())
+			admin.GET("/users/:id/roles", middleware.RequirePermission(models.PermissionRolesRead), s.handleAdminListUserRoles())
+			admin.POST("/users/:id/roles", middleware.RequirePermission(models.PermissionRolesWrite), s.handleAdminAssignRole())
+			admin.DELETE("/users/:id/roles/:role_id", middleware.RequirePermission(models.PermissionRolesWrite), s.handleAdminUnassignRole())
+			admin.GET("/roles", middleware.RequirePermission(models.PermissionRolesRead), s.handleAdminListRoles())
+			admin.POST("/roles", middleware.RequirePermission(models.PermissionRolesWrite), s.handleAdminCreateRole())
+			admin.DELETE("/roles/:id", middleware.RequirePermission(models.PermissionRolesWrite), s.handleAdminDeleteRole())
+			admin.GET("/permissions", middleware.RequirePermission(models.PermissionRolesRead), s.handleAdminListPermissions())
+			admin.GET("/audit-events", middleware.RequirePermission(models.PermissionAuditRead), s.handleAdminListAuditEvents())
+			admin.POST("/webhooks", middleware.RequirePermission(models.PermissionWebhooksWrite), s.handleAdminCreateWebhook())
+			admin.GET("/webhooks", middleware.RequirePermission(models.PermissionWebhooksRead), s.handleAdminListWebhooks())
+			admin.GET("/webhooks/:id", middleware.RequirePermission(models.PermissionWebhooksRead), s.handleAdminGetWebhook())
+			admin.PUT("/webhooks/:id", middleware.RequirePermission(models.PermissionWebhooksWrite), s.handleAdminUpdateWebhook())
+			admin.DELETE("/webhooks/:id", middleware.RequirePermission(models.PermissionWebhooksWrite), s.handleAdminDeleteWebhook())
+			admin.GET("/webhooks/:id/deliveries", middleware.RequirePermission(models.PermissionWebhooksRead), s.handleAdminListWebhookDeliveries())
+			admin.POST("/webhooks/:id/deliveries/:delivery_id/redeliver", middleware.RequirePermission(models.PermissionWebhooksWrite), s.handleAdminRedeliverWebhook())
+			admin.GET("/emails", middleware.RequirePermission(models.PermissionEmailsRead), s.handleAdminListEmails())
+			admin.GET("/emails/:id", middleware.RequirePermission(models.PermissionEmailsRead), s.handleAdminGetEmail())
+			admin.POST("/emails/:id/retry", middleware.RequirePermission(models.PermissionEmailsWrite), s.handleAdminRetryEmail())
+			if s.oauthServerEnabled() {
+				admin.POST("/oauth2/clients", middleware.RequirePermission(models.PermissionOAuthClientsWrite), s.handleAdminRegisterOAuthClient())
+				admin.GET("/oauth2/clients", middleware.RequirePermission(models.PermissionOAuthClientsRead), s.handleAdminListOAuthClients())
+				admin.DELETE("/oauth2/clients/:id", middleware.RequirePermission(models.PermissionOAuthClientsWrite), s.handleAdminDeleteOAuthClient())
+			}
 		}
 	}
 }
@@ -0,0 +1,148 @@
+package server
+
+import (
+	stderrors "errors"
+
+	"rest-api/internal/errors"
+	"rest-api/internal/models"
+	"rest-api/internal/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// @Summary List sessions
+// @Description List the current user's active sessions
+// @Tags sessions
+// @Security Bearer
+// @Produce json
+// @Success 200 {array} response.SessionResponse
+// @Failure 401 {object} response.ErrorResponse
+// @Router /v1/sessions [get]
+func (s *Server) handleListSessions() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, exists := c.Get("user")
+		if !exists {
+			response.Unauthorized(c, errors.ErrUnauthorized)
+			return
+		}
+
+		sessions, err := s.authSvc.ListSessions(user.(*models.User).ID.String())
+		if err != nil {
+			s.logger.Error("failed to list sessions", err)
+			response.InternalError(c, errors.ErrInvalidRequest)
+			return
+		}
+
+		currentID := c.GetString("session_id")
+		result := make([]response.SessionResponse, len(sessions))
+		for i := range sessions {
+			result[i] = toSessionResponse(&sessions[i], currentID)
+		}
+
+		response.Success(c, result)
+	}
+}
+
+// @Summary Get session
+// @Description Get one of the current user's active sessions
+// @Tags sessions
+// @Security Bearer
+// @Produce json
+// @Param id path string true "Session ID"
+// @Success 200 {object} response.SessionResponse
+// @Failure 401 {object} response.ErrorResponse
+// @Failure 404 {object} response.ErrorResponse
+// @Router /v1/sessions/{id} [get]
+func (s *Server) handleGetSession() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, exists := c.Get("user")
+		if !exists {
+			response.Unauthorized(c, errors.ErrUnauthorized)
+			return
+		}
+
+		session, err := s.authSvc.GetSession(user.(*models.User).ID.String(), c.Param("id"))
+		if err != nil {
+			response.NotFound(c, errors.ErrSessionNotFound)
+			return
+		}
+
+		response.Success(c, toSessionResponse(session, c.GetString("session_id")))
+	}
+}
+
+// @Summary Revoke session
+// @Description Revoke one of the current user's sessions
+// @Tags sessions
+// @Security Bearer
+// @Produce json
+// @Param id path string true "Session ID"
+// @Success 200 {object} response.SuccessResponse
+// @Failure 401 {object} response.ErrorResponse
+// @Failure 404 {object} response.ErrorResponse
+// @Router /v1/sessions/{id} [delete]
+func (s *Server) handleRevokeSession() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, exists := c.Get("user")
+		if !exists {
+			response.Unauthorized(c, errors.ErrUnauthorized)
+			return
+		}
+
+		if err := s.authSvc.RevokeSession(user.(*models.User).ID.String(), c.Param("id"), clientInfo(c)); err != nil {
+			if stderrors.Is(err, errors.ErrSessionNotFound) {
+				response.NotFound(c, err)
+				return
+			}
+			s.logger.Error("failed to revoke session", err)
+			response.InternalError(c, errors.ErrInvalidRequest)
+			return
+		}
+
+		s.auditUserAction(c, models.AuditSessionRevoked, user.(*models.User).ID, map[string]interface{}{"session_id": c.Param("id")})
+
+		response.SuccessWithMessage(c, "session revoked", nil)
+	}
+}
+
+// @Summary Revoke other sessions
+// @Description Revoke all of the current user's sessions except the current one
+// @Tags sessions
+// @Security Bearer
+// @Produce json
+// @Success 200 {object} response.SuccessResponse
+// @Failure 401 {object} response.ErrorResponse
+// @Router /v1/sessions/revoke-others [post]
+func (s *Server) handleRevokeOtherSessions() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, exists := c.Get("user")
+		if !exists {
+			response.Unauthorized(c, errors.ErrUnauthorized)
+			return
+		}
+
+		if err := s.authSvc.RevokeOtherSessions(user.(*models.User).ID.String(), c.GetString("session_id"), clientInfo(c)); err != nil {
+			s.logger.Error("failed to revoke other sessions", err)
+			response.InternalError(c, errors.ErrInvalidRequest)
+			return
+		}
+
+		s.auditUserAction(c, models.AuditSessionsRevoked, user.(*models.User).ID, map[string]interface{}{"scope": "others"})
+
+		response.SuccessWithMessage(c, "other sessions revoked", nil)
+	}
+}
+
+func toSessionResponse(session *models.Session, currentID string) response.SessionResponse {
+	id := session.PublicID().String()
+	return response.SessionResponse{
+		ID:             id,
+		DeviceInfo:     session.DeviceInfo,
+		IPAddress:      session.IPAddress,
+		CreatedAt:      session.CreatedAt,
+		LastAccessedAt: session.LastAccessedAt,
+		ExpiresAt:      session.ExpiresAt,
+		Current:        id == currentID,
+		Impersonated:   session.ImpersonatorID != nil,
+	}
+}
@@ -0,0 +1,146 @@
+package server
+
+import (
+	stderrors "errors"
+	"net/http"
+	"time"
+
+	"rest-api/internal/errors"
+	"rest-api/internal/models"
+	"rest-api/internal/response"
+	"rest-api/internal/service"
+	"rest-api/pkg/validator"
+
+	"github.com/gin-gonic/gin"
+)
+
+// @Summary Create a personal access token
+// @Description Create a scoped token for scripts and automation, sent as "Authorization: Bearer pat_...". The token is only shown in this response.
+// @Tags tokens
+// @Security Bearer
+// @Accept json
+// @Produce json
+// @Param request body validator.CreatePersonalAccessTokenRequest true "Token name, scopes and lifetime"
+// @Success 201 {object} response.SuccessResponse{data=response.PersonalAccessTokenResponse}
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 401 {object} response.ErrorResponse
+// @Router /v1/profile/tokens [post]
+func (s *Server) handleCreatePersonalAccessToken() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, exists := c.Get("user")
+		if !exists {
+			response.Unauthorized(c, errors.ErrUnauthorized)
+			return
+		}
+
+		var req validator.CreatePersonalAccessTokenRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.BadRequest(c, errors.ErrInvalidRequest)
+			return
+		}
+
+		lifetime := time.Duration(req.ExpiresInDays) * 24 * time.Hour
+		token, secret, err := s.patSvc.Create(user.(*models.User).ID, req.Name, req.Scopes, lifetime)
+		if err != nil {
+			if stderrors.Is(err, errors.ErrInvalidRequest) {
+				response.BadRequest(c, err)
+				return
+			}
+			s.logger.Error("failed to create personal access token", err)
+			response.InternalError(c, errors.ErrInvalidRequest)
+			return
+		}
+
+		s.auditUserAction(c, models.AuditAccessTokenCreated, token.UserID, map[string]interface{}{
+			"token_id": token.ID.String(),
+			"name":     token.Name,
+			"scopes":   service.TokenScopes(token),
+		})
+
+		resp := toPersonalAccessTokenResponse(token)
+		resp.Token = secret
+		c.JSON(http.StatusCreated, response.SuccessResponse{
+			Message: "token created",
+			Data:    resp,
+		})
+	}
+}
+
+// @Summary List personal access tokens
+// @Description List the current user's personal access tokens
+// @Tags tokens
+// @Security Bearer
+// @Produce json
+// @Success 200 {object} response.SuccessResponse{data=[]response.PersonalAccessTokenResponse}
+// @Failure 401 {object} response.ErrorResponse
+// @Router /v1/profile/tokens [get]
+func (s *Server) handleListPersonalAccessTokens() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, exists := c.Get("user")
+		if !exists {
+			response.Unauthorized(c, errors.ErrUnauthorized)
+			return
+		}
+
+		tokens, err := s.patSvc.List(user.(*models.User).ID.String())
+		if err != nil {
+			s.logger.Error("failed to list personal access tokens", err)
+			response.InternalError(c, errors.ErrInvalidRequest)
+			return
+		}
+
+		result := make([]response.PersonalAccessTokenResponse, len(tokens))
+		for i := range tokens {
+			result[i] = toPersonalAccessTokenResponse(&tokens[i])
+		}
+
+		response.Success(c, result)
+	}
+}
+
+// @Summary Revoke a personal access token
+// @Description Delete one of the current user's personal access tokens
+// @Tags tokens
+// @Security Bearer
+// @Produce json
+// @Param id path string true "Token ID"
+// @Success 200 {object} response.SuccessResponse
+// @Failure 401 {object} response.ErrorResponse
+// @Failure 404 {object} response.ErrorResponse
+// @Router /v1/profile/tokens/{id} [delete]
+func (s *Server) handleRevokePersonalAccessToken() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, exists := c.Get("user")
+		if !exists {
+			response.Unauthorized(c, errors.ErrUnauthorized)
+			return
+		}
+
+		if err := s.patSvc.Revoke(user.(*models.User).ID.String(), c.Param("id")); err != nil {
+			if stderrors.Is(err, errors.ErrTokenNotFound) {
+				response.NotFound(c, err)
+				return
+			}
+			s.logger.Error("failed to revoke personal access token", err)
+			response.InternalError(c, errors.ErrInvalidRequest)
+			return
+		}
+
+		s.auditUserAction(c, models.AuditAccessTokenRevoked, user.(*models.User).ID, map[string]interface{}{"token_id": c.Param("id")})
+
+		response.SuccessWithMessage(c, "token revoked", nil)
+	}
+}
+
+func toPersonalAccessTokenResponse(token *models.PersonalAccessToken) response.PersonalAccessTokenResponse {
+	return response.PersonalAccessTokenResponse{
+		ID:         token.ID.String(),
+		Name:       token.Name,
+		Prefix:     token.Prefix,
+		Scopes:     service.TokenScopes(token),
+		ExpiresAt:  token.ExpiresAt,
+		LastUsedAt: token.LastUsedAt,
+		LastUsedIP: token.LastUsedIP,
+		CreatedAt:  token.CreatedAt,
+	}
+}
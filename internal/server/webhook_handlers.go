@@ -0,0 +1,290 @@
+package server
+
+import (
+	"encoding/json"
+	stderrors "errors"
+	"net/http"
+	"strings"
+
+	"rest-api/internal/errors"
+	"rest-api/internal/models"
+	"rest-api/internal/response"
+	"rest-api/internal/service"
+	"rest-api/pkg/validator"
+
+	"github.com/gin-gonic/gin"
+)
+
+// @Summary Register webhook
+// @Description Register an endpoint to receive signed event notifications. Use "*" to subscribe to every event. The signing secret is generated when omitted and only returned in this response
+// @Tags admin
+// @Security Bearer
+// @Accept json
+// @Produce json
+// @Param request body validator.CreateWebhookRequest true "Webhook endpoint"
+// @Success 201 {object} response.SuccessResponse{data=response.WebhookResponse}
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 401 {object} response.ErrorResponse
+// @Failure 403 {object} response.ErrorResponse
+// @Router /v1/admin/webhooks [post]
+func (s *Server) handleAdminCreateWebhook() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user, exists := c.Get("user")
+		if !exists {
+			response.Unauthorized(c, errors.ErrUnauthorized)
+			return
+		}
+
+		var req validator.CreateWebhookRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.BadRequest(c, errors.ErrInvalidRequest)
+			return
+		}
+
+		endpoint, secret, err := s.webhookSvc.CreateEndpoint(service.WebhookRegistration{
+			URL:         req.URL,
+			Secret:      req.Secret,
+			Events:      req.Events,
+			Description: req.Description,
+		}, user.(*models.User).ID)
+		if err != nil {
+			s.respondWebhookError(c, "failed to create webhook", err)
+			return
+		}
+
+		resp := toWebhookResponse(endpoint)
+		resp.Secret = secret
+		c.JSON(http.StatusCreated, response.SuccessResponse{
+			Message: "webhook created",
+			Data:    resp,
+		})
+	}
+}
+
+// @Summary List webhooks
+// @Description List the registered webhook endpoints
+// @Tags admin
+// @Security Bearer
+// @Produce json
+// @Success 200 {object} response.SuccessResponse{data=[]response.WebhookResponse}
+// @Failure 401 {object} response.ErrorResponse
+// @Failure 403 {object} response.ErrorResponse
+// @Router /v1/admin/webhooks [get]
+func (s *Server) handleAdminListWebhooks() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		endpoints, err := s.webhookSvc.ListEndpoints()
+		if err != nil {
+			s.logger.Error("failed to list webhooks", err)
+			response.InternalError(c, errors.ErrInvalidRequest)
+			return
+		}
+
+		resp := make([]response.WebhookResponse, len(endpoints))
+		for i := range endpoints {
+			resp[i] = toWebhookResponse(&endpoints[i])
+		}
+
+		response.Success(c, resp)
+	}
+}
+
+// @Summary Get webhook
+// @Description Get a registered webhook endpoint
+// @Tags admin
+// @Security Bearer
+// @Produce json
+// @Param id path string true "Webhook ID"
+// @Success 200 {object} response.SuccessResponse{data=response.WebhookResponse}
+// @Failure 401 {object} response.ErrorResponse
+// @Failure 403 {object} response.ErrorResponse
+// @Failure 404 {object} response.ErrorResponse
+// @Router /v1/admin/webhooks/{id} [get]
+func (s *Server) handleAdminGetWebhook() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		endpoint, err := s.webhookSvc.GetEndpoint(c.Param("id"))
+		if err != nil {
+			s.respondWebhookError(c, "failed to get webhook", err)
+			return
+		}
+
+		response.Success(c, toWebhookResponse(endpoint))
+	}
+}
+
+// @Summary Update webhook
+// @Description Replace a webhook endpoint's URL, event filter, description and active flag. The signing secret is only changed when one is given
+// @Tags admin
+// @Security Bearer
+// @Accept json
+// @Produce json
+// @Param id path string true "Webhook ID"
+// @Param request body validator.UpdateWebhookRequest true "Webhook endpoint"
+// @Success 200 {object} response.SuccessResponse{data=response.WebhookResponse}
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 401 {object} response.ErrorResponse
+// @Failure 403 {object} response.ErrorResponse
+// @Failure 404 {object} response.ErrorResponse
+// @Router /v1/admin/webhooks/{id} [put]
+func (s *Server) handleAdminUpdateWebhook() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req validator.UpdateWebhookRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			response.BadRequest(c, errors.ErrInvalidRequest)
+			return
+		}
+
+		endpoint, err := s.webhookSvc.UpdateEndpoint(c.Param("id"), service.WebhookRegistration{
+			URL:         req.URL,
+			Secret:      req.Secret,
+			Events:      req.Events,
+			Description: req.Description,
+			Active:      *req.Active,
+		})
+		if err != nil {
+			s.respondWebhookError(c, "failed to update webhook", err)
+			return
+		}
+
+		response.SuccessWithMessage(c, "webhook updated", toWebhookResponse(endpoint))
+	}
+}
+
+// @Summary Delete webhook
+// @Description Delete a webhook endpoint together with its pending deliveries and delivery log
+// @Tags admin
+// @Security Bearer
+// @Produce json
+// @Param id path string true "Webhook ID"
+// @Success 200 {object} response.SuccessResponse
+// @Failure 401 {object} response.ErrorResponse
+// @Failure 403 {object} response.ErrorResponse
+// @Failure 404 {object} response.ErrorResponse
+// @Router /v1/admin/webhooks/{id} [delete]
+func (s *Server) handleAdminDeleteWebhook() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if err := s.webhookSvc.DeleteEndpoint(c.Param("id")); err != nil {
+			s.respondWebhookError(c, "failed to delete webhook", err)
+			return
+		}
+
+		response.SuccessWithMessage(c, "webhook deleted", nil)
+	}
+}
+
+// @Summary List webhook deliveries
+// @Description List a webhook endpoint's deliveries with the outcome of their latest attempt, newest first
+// @Tags admin
+// @Security Bearer
+// @Produce json
+// @Param id path string true "Webhook ID"
+// @Param status query string false "pending, succeeded or failed"
+// @Param page query int false "Page number, starting at 1"
+// @Param per_page query int false "Deliveries per page (max 100)"
+// @Success 200 {object} response.SuccessResponse{data=response.WebhookDeliveryListResponse}
+// @Failure 400 {object} response.ErrorResponse
+// @Failure 401 {object} response.ErrorResponse
+// @Failure 403 {object} response.ErrorResponse
+// @Failure 404 {object} response.ErrorResponse
+// @Router /v1/admin/webhooks/{id}/deliveries [get]
+func (s *Server) handleAdminListWebhookDeliveries() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var req validator.ListWebhookDeliveriesRequest
+		if err := c.ShouldBindQuery(&req); err != nil {
+			response.BadRequest(c, errors.ErrInvalidRequest)
+			return
+		}
+
+		page, err := s.webhookSvc.Deliveries(c.Param("id"), req.Status, req.Page, req.PerPage)
+		if err != nil {
+			s.respondWebhookError(c, "failed to list webhook deliveries", err)
+			return
+		}
+
+		deliveries := make([]response.WebhookDeliveryResponse, len(page.Deliveries))
+		for i := range page.Deliveries {
+			deliveries[i] = toWebhookDeliveryResponse(&page.Deliveries[i])
+		}
+
+		response.Success(c, response.WebhookDeliveryListResponse{
+			Deliveries: deliveries,
+			Total:      page.Total,
+			Page:       page.Page,
+			PerPage:    page.PerPage,
+		})
+	}
+}
+
+// @Summary Redeliver webhook event
+// @Description Queue the event of an earlier delivery to be sent again right away. The new delivery keeps the event ID so receivers can detect duplicates
+// @Tags admin
+// @Security Bearer
+// @Produce json
+// @Param id path string true "Webhook ID"
+// @Param delivery_id path string true "Delivery ID"
+// @Success 201 {object} response.SuccessResponse{data=response.WebhookDeliveryResponse}
+// @Failure 401 {object} response.ErrorResponse
+// @Failure 403 {object} response.ErrorResponse
+// @Failure 404 {object} response.ErrorResponse
+// @Router /v1/admin/webhooks/{id}/deliveries/{delivery_id}/redeliver [post]
+func (s *Server) handleAdminRedeliverWebhook() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		delivery, err := s.webhookSvc.Redeliver(c.Param("id"), c.Param("delivery_id"))
+		if err != nil {
+			s.respondWebhookError(c, "failed to redeliver webhook", err)
+			return
+		}
+
+		c.JSON(http.StatusCreated, response.SuccessResponse{
+			Message: "delivery queued",
+			Data:    toWebhookDeliveryResponse(delivery),
+		})
+	}
+}
+
+// respondWebhookError maps webhook service errors to responses, logging
+// anything unexpected under msg.
+func (s *Server) respondWebhookError(c *gin.Context, msg string, err error) {
+	switch {
+	case stderrors.Is(err, errors.ErrWebhookNotFound), stderrors.Is(err, errors.ErrDeliveryNotFound):
+		response.NotFound(c, err)
+	case stderrors.Is(err, errors.ErrUnknownEvent), stderrors.Is(err, errors.ErrInvalidRequest),
+		stderrors.Is(err, errors.ErrWebhookDestination):
+		response.BadRequest(c, err)
+	default:
+		s.logger.Error(msg, err)
+		response.InternalError(c, errors.ErrInvalidRequest)
+	}
+}
+
+func toWebhookResponse(endpoint *models.WebhookEndpoint) response.WebhookResponse {
+	return response.WebhookResponse{
+		ID:          endpoint.ID.String(),
+		URL:         endpoint.URL,
+		Events:      strings.Split(endpoint.Events, ","),
+		Description: endpoint.Description,
+		Active:      endpoint.Active,
+		CreatedAt:   endpoint.CreatedAt,
+		UpdatedAt:   endpoint.UpdatedAt,
+	}
+}
+
+func toWebhookDeliveryResponse(delivery *models.WebhookDelivery) response.WebhookDeliveryResponse {
+	resp := response.WebhookDeliveryResponse{
+		ID:             delivery.ID.String(),
+		EventID:        delivery.EventID.String(),
+		EventType:      delivery.EventType,
+		Status:         delivery.Status,
+		Attempts:       delivery.Attempts,
+		LastAttemptAt:  delivery.LastAttemptAt,
+		ResponseStatus: delivery.ResponseStatus,
+		ResponseBody:   delivery.ResponseBody,
+		Error:          delivery.LastError,
+		DeliveredAt:    delivery.DeliveredAt,
+		Payload:        json.RawMessage(delivery.Payload),
+		CreatedAt:      delivery.CreatedAt,
+	}
+	if delivery.Status == models.WebhookDeliveryPending {
+		resp.NextAttemptAt = &delivery.NextAttemptAt
+	}
+	return resp
+}
@@ -0,0 +1,31 @@
+package server
+
+import (
+	"net/http"
+
+	"rest-api/internal/errors"
+	"rest-api/internal/response"
+
+	"github.com/gin-gonic/gin"
+)
+
+// @Summary JSON Web Key Set
+// @Description Public keys for verifying access tokens issued by this service, keyed by the "kid" header
+// @Tags well-known
+// @Produce json
+// @Success 200 {object} jwk.Set
+// @Router /.well-known/jwks.json [get]
+func (s *Server) handleJWKS() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		set, err := s.keySvc.JWKS()
+		if err != nil {
+			s.logger.Error("failed to build jwks", err)
+			response.InternalError(c, errors.ErrInvalidRequest)
+			return
+		}
+
+		// Verifiers cache the set; keep it short enough to pick up rotations
+		c.Header("Cache-Control", "public, max-age=300")
+		c.JSON(http.StatusOK, set)
+	}
+}
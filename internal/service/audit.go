@@ -0,0 +1,176 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"rest-api/internal/models"
+	"rest-api/internal/repository"
+	"rest-api/pkg/logger"
+
+	"github.com/google/uuid"
+)
+
+// auditEventNamespace derives audit event IDs from event dedup keys.
+var auditEventNamespace = uuid.MustParse("6f3b2a7e-1c4d-4e8a-9b5f-0d2c7e1a4b93")
+
+const (
+	defaultEventsPerPage = 50
+	maxEventsPerPage     = 200
+)
+
+// AuditQuery selects a page of audit events for administrators.
+type AuditQuery struct {
+	UserID   *uuid.UUID
+	ActorID  *uuid.UUID
+	TargetID *uuid.UUID
+	Types    []string
+	Outcome  string
+	From     *time.Time
+	To       *time.Time
+	Page     int
+	PerPage  int
+}
+
+// AuditPage is one page of audit events with the total number of matches.
+type AuditPage struct {
+	Events  []models.AuditEvent
+	Total   int64
+	Page    int
+	PerPage int
+}
+
+type AuditService struct {
+	auditRepo repository.AuditRepository
+	logger    *logger.Logger
+}
+
+func NewAuditService(auditRepo repository.AuditRepository, logger *logger.Logger) *AuditService {
+	return &AuditService{auditRepo: auditRepo, logger: logger}
+}
+
+// Record appends an event to the audit log.
+func (s *AuditService) Record(event *models.AuditEvent) error {
+	if event.Outcome == "" {
+		event.Outcome = models.AuditOutcomeSuccess
+	}
+	return s.auditRepo.Create(event)
+}
+
+// Log appends an event like Record but only logs a failure to write it, for
+// actions that must not fail because they could not be audited.
+func (s *AuditService) Log(event *models.AuditEvent) {
+	if err := s.Record(event); err != nil {
+		s.logger.Error("failed to record audit event", err)
+	}
+}
+
+// HandleEvent records domain events that belong in the audit log. The audit
+// event ID is derived from dedupKey, so an event handled twice is recorded once.
+func (s *AuditService) HandleEvent(ctx context.Context, event *Event, dedupKey string) error {
+	var audit *models.AuditEvent
+	switch event.Type {
+	case EventTokenIssued:
+		var issued TokenIssuedEvent
+		if err := event.Decode(&issued); err != nil {
+			return err
+		}
+		audit = tokenIssuedEvent(issued)
+	case EventPasswordResetRequested:
+		var requested UserTokenEvent
+		if err := event.Decode(&requested); err != nil {
+			return err
+		}
+		// Forced resets are audited as such by the administrator's request
+		if requested.Forced {
+			return nil
+		}
+		userID, err := uuid.Parse(requested.User.ID)
+		if err != nil {
+			return err
+		}
+		// Requested by whoever knows the address, so there is no actor
+		audit = &models.AuditEvent{
+			Type:      models.AuditPasswordResetRequest,
+			TargetID:  &userID,
+			IPAddress: requested.IPAddress,
+			UserAgent: requested.UserAgent,
+		}
+	default:
+		return nil
+	}
+
+	audit.ID = uuid.NewSHA1(auditEventNamespace, []byte(dedupKey))
+	audit.CreatedAt = event.CreatedAt
+	return s.Record(audit)
+}
+
+// tokenIssuedEvent records which kind of token was issued to whom, never the token itself.
+func tokenIssuedEvent(issued TokenIssuedEvent) *models.AuditEvent {
+	event := &models.AuditEvent{
+		Type:     models.AuditTokenIssued,
+		Metadata: map[string]interface{}{"token_type": string(issued.TokenType), "expires_at": issued.ExpiresAt},
+	}
+	if id, err := uuid.Parse(issued.Subject); err == nil {
+		event.TargetID = &id
+	}
+	return event
+}
+
+// Search returns a page of events matching the query, newest first.
+func (s *AuditService) Search(query AuditQuery) (*AuditPage, error) {
+	if query.Page < 1 {
+		query.Page = 1
+	}
+	if query.PerPage < 1 {
+		query.PerPage = defaultEventsPerPage
+	}
+	if query.PerPage > maxEventsPerPage {
+		query.PerPage = maxEventsPerPage
+	}
+
+	events, total, err := s.auditRepo.List(repository.AuditFilter{
+		UserID:   query.UserID,
+		ActorID:  query.ActorID,
+		TargetID: query.TargetID,
+		Types:    query.Types,
+		Outcome:  query.Outcome,
+		From:     query.From,
+		To:       query.To,
+		Offset:   (query.Page - 1) * query.PerPage,
+		Limit:    query.PerPage,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &AuditPage{
+		Events:  events,
+		Total:   total,
+		Page:    query.Page,
+		PerPage: query.PerPage,
+	}, nil
+}
+
+// Activity returns a page of the events the user performed or was the target of.
+func (s *AuditService) Activity(userID uuid.UUID, page, perPage int) (*AuditPage, error) {
+	return s.Search(AuditQuery{UserID: &userID, Page: page, PerPage: perPage})
+}
+
+// LoginEvent describes a login attempt by the user (nil when unknown) with the given method.
+func LoginEvent(userID *uuid.UUID, method string, client ClientInfo, err error) *models.AuditEvent {
+	event := &models.AuditEvent{
+		Type:      models.AuditLogin,
+		ActorID:   userID,
+		TargetID:  userID,
+		IPAddress: client.IPAddress,
+		UserAgent: client.UserAgent,
+		Outcome:   models.AuditOutcomeSuccess,
+		Metadata:  map[string]interface{}{"method": method},
+	}
+	if err != nil {
+		event.Outcome = models.AuditOutcomeFailure
+		event.Metadata["reason"] = err.Error()
+	}
+	return event
+}
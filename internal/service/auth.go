@@ -2,73 +2,317 @@ package service
 
 import (
 	"errors"
+	"strings"
 	"time"
 
 	apperrors "rest-api/internal/errors"
 	"rest-api/internal/models"
 	"rest-api/internal/repository"
+	"rest-api/pkg/password"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
-	"golang.org/x/crypto/bcrypt"
 )
 
+// sessionTouchInterval is the minimum time between recorded activity updates for a session.
+const sessionTouchInterval = time.Minute
+
+// mfaChallengeMaxAttempts is the number of wrong codes after which an MFA
+// challenge is burned and the user has to sign in with their password again.
+const mfaChallengeMaxAttempts = 5
+
+// AuthConfig controls how sessions and tokens are issued.
+type AuthConfig struct {
+	JWTSecret string
+	// SigningAlgorithm is HS256 (shared JWTSecret) or an asymmetric algorithm
+	// whose keys are managed by KeyService and published via JWKS.
+	SigningAlgorithm string
+	Issuer           string
+	// TokenPairMode issues a short-lived JWT access token plus a rotating opaque
+	// refresh token instead of a single database-backed session token.
+	TokenPairMode   bool
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+	// SessionIdleTimeout expires sessions that have not been used for this long;
+	// activity slides the expiry forward up to SessionAbsoluteLifetime after login.
+	SessionIdleTimeout      time.Duration
+	SessionAbsoluteLifetime time.Duration
+	// EmailVerificationPolicy is one of the EmailVerification* policies
+	EmailVerificationPolicy string
+	Lockout                 LockoutConfig
+	// ImpersonationTTL is the fixed lifetime of impersonation sessions; activity never extends it
+	ImpersonationTTL time.Duration
+}
+
 type AuthService struct {
 	userRepo    repository.UserRepository
 	sessionRepo repository.SessionRepository
+	tokenSvc    *TokenService
+	mfaSvc      *MFAService
+	keySvc      *KeyService
+	activity    *SessionActivityTracker
+	auditSvc    *AuditService
+	transactor  repository.Transactor
+	hasher      *password.Manager
+	config      AuthConfig
 	jwtSecret   []byte
 }
 
+// LoginResult is the outcome of a password login. Exactly one of Session or
+// MFAToken is set: users with MFA enabled receive a short-lived challenge token
+// that must be exchanged for a session through CompleteMFALogin.
+type LoginResult struct {
+	Session  *models.Session
+	MFAToken string
+}
+
+// ClientInfo identifies the client a session is created for.
+type ClientInfo struct {
+	UserAgent string
+	IPAddress string
+	// Language is the client's Accept-Language header
+	Language string
+}
+
+// TokenPair is returned to clients in token pair mode.
+type TokenPair struct {
+	AccessToken  string `json:"access_token"`
+	RefreshToken string `json:"refresh_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+}
+
 type Claims struct {
-	UserID string `json:"user_id"`
+	UserID    string `json:"user_id"`
+	SessionID string `json:"sid,omitempty"`
+	// OrganizationID is the session's active organization when the token was issued
+	OrganizationID string `json:"org,omitempty"`
+	// Actor is set while an administrator impersonates the subject
+	Actor *ActorClaim `json:"act,omitempty"`
 	jwt.RegisteredClaims
 }
 
-func NewAuthService(userRepo repository.UserRepository, sessionRepo repository.SessionRepository, jwtSecret string) *AuthService {
+// ActorClaim identifies the party acting on behalf of the subject (RFC 8693).
+type ActorClaim struct {
+	Subject string `json:"sub"`
+}
+
+func NewAuthService(userRepo repository.UserRepository, sessionRepo repository.SessionRepository, tokenSvc *TokenService, mfaSvc *MFAService, keySvc *KeyService, activity *SessionActivityTracker, auditSvc *AuditService, transactor repository.Transactor, hasher *password.Manager, config AuthConfig) *AuthService {
 	return &AuthService{
 		userRepo:    userRepo,
 		sessionRepo: sessionRepo,
-		jwtSecret:   []byte(jwtSecret),
+		tokenSvc:    tokenSvc,
+		mfaSvc:      mfaSvc,
+		keySvc:      keySvc,
+		activity:    activity,
+		auditSvc:    auditSvc,
+		transactor:  transactor,
+		hasher:      hasher,
+		config:      config,
+		jwtSecret:   []byte(config.JWTSecret),
 	}
 }
 
-func (s *AuthService) GenerateToken(user *models.User) (string, error) {
+// TokenPairMode reports whether logins issue access/refresh token pairs.
+func (s *AuthService) TokenPairMode() bool {
+	return s.config.TokenPairMode
+}
+
+// GenerateToken issues a signed access token for the session's user. The sid
+// claim carries the session family so the token can be tied back to a login.
+func (s *AuthService) GenerateToken(session *models.Session) (string, error) {
+	sessionID := session.ID
+	if session.FamilyID != nil {
+		sessionID = *session.FamilyID
+	}
+
+	// An access token never outlives the login it belongs to
+	expiresAt := time.Now().Add(s.config.AccessTokenTTL)
+	if session.AbsoluteExpiresAt != nil {
+		expiresAt = earliest(expiresAt, *session.AbsoluteExpiresAt)
+	}
+
 	claims := &Claims{
-		UserID: user.ID.String(),
+		UserID:    session.UserID.String(),
+		SessionID: sessionID.String(),
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(24 * time.Hour)),
+			Issuer:    s.config.Issuer,
+			Subject:   session.UserID.String(),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 		},
 	}
+	if session.ActiveOrganizationID != nil {
+		claims.OrganizationID = session.ActiveOrganizationID.String()
+	}
+	if session.ImpersonatorID != nil {
+		claims.Actor = &ActorClaim{Subject: session.ImpersonatorID.String()}
+	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString(s.jwtSecret)
+	return s.sign(claims)
 }
 
-func (s *AuthService) ValidateToken(tokenString string) (*Claims, error) {
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
+// sign signs claims with the shared secret (HS256) or the current asymmetric key.
+func (s *AuthService) sign(claims jwt.Claims) (string, error) {
+	if s.config.SigningAlgorithm == jwt.SigningMethodHS256.Alg() {
+		return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(s.jwtSecret)
+	}
+
+	kid, method, key, err := s.keySvc.SigningKey()
+	if err != nil {
+		return "", err
+	}
+
+	token := jwt.NewWithClaims(method, claims)
+	token.Header["kid"] = kid
+	return token.SignedString(key)
+}
+
+// verificationKey resolves the key for a token from its kid header, rejecting
+// tokens whose alg does not match the key they claim to be signed with.
+func (s *AuthService) verificationKey(token *jwt.Token) (interface{}, error) {
+	if s.config.SigningAlgorithm == jwt.SigningMethodHS256.Alg() {
 		return s.jwtSecret, nil
-	})
+	}
 
+	kid, _ := token.Header["kid"].(string)
+	key, alg, err := s.keySvc.VerificationKey(kid)
 	if err != nil {
 		return nil, err
 	}
+	if token.Method.Alg() != alg {
+		return nil, errors.New("unexpected signing algorithm")
+	}
+
+	return key, nil
+}
+
+func (s *AuthService) ValidateToken(tokenString string) (*Claims, error) {
+	options := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{s.config.SigningAlgorithm}),
+	}
+	if s.config.Issuer != "" {
+		options = append(options, jwt.WithIssuer(s.config.Issuer))
+	}
+
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, s.verificationKey, options...)
 
-	if claims, ok := token.Claims.(*Claims); ok && token.Valid {
+	if err != nil {
+		return nil, err
+	}
+
+	// Tokens issued to OAuth clients share the signing keys but are not API
+	// credentials: access tokens are typed at+jwt and ID tokens carry no user_id
+	if typ, _ := token.Header["typ"].(string); typ == accessTokenType {
+		return nil, errors.New("invalid token")
+	}
+
+	if claims, ok := token.Claims.(*Claims); ok && token.Valid && claims.UserID != "" {
 		return claims, nil
 	}
 
 	return nil, errors.New("invalid token")
 }
 
-func (s *AuthService) CreateSession(user *models.User, deviceInfo string) (*models.Session, error) {
+// IsAccessToken reports whether a bearer token looks like a JWT rather than an opaque session token.
+func IsAccessToken(token string) bool {
+	return strings.Count(token, ".") == 2
+}
+
+// EmailVerificationPolicy returns the configured policy for unverified users.
+func (s *AuthService) EmailVerificationPolicy() string {
+	return s.config.EmailVerificationPolicy
+}
+
+func (s *AuthService) CreateSession(user *models.User, client ClientInfo) (*models.Session, error) {
+	// Every login path ends here, so this is where disabled and unverified users are turned away
+	if user.DisabledAt != nil {
+		return nil, apperrors.ErrAccountDisabled
+	}
+	if s.config.EmailVerificationPolicy == EmailVerificationRequired && user.EmailVerifiedAt == nil {
+		return nil, apperrors.ErrEmailNotVerified
+	}
+
+	now := time.Now()
+	absoluteExpiresAt := now.Add(s.config.SessionAbsoluteLifetime)
+
 	session := &models.Session{
-		UserID:         user.ID,
-		SessionToken:   uuid.New().String(),
-		DeviceInfo:     deviceInfo,
-		IsActive:       true,
-		ExpiresAt:      time.Now().Add(24 * time.Hour),
-		LastAccessedAt: time.Now(),
+		ID:                uuid.New(),
+		UserID:            user.ID,
+		SessionToken:      uuid.New().String(),
+		DeviceInfo:        client.UserAgent,
+		IPAddress:         client.IPAddress,
+		IsActive:          true,
+		ExpiresAt:         earliest(now.Add(s.config.SessionIdleTimeout), absoluteExpiresAt),
+		AbsoluteExpiresAt: &absoluteExpiresAt,
+		LastAccessedAt:    now,
+	}
+
+	// In token pair mode the session token is the refresh token and the
+	// session starts a new rotation family
+	if s.config.TokenPairMode {
+		session.FamilyID = &session.ID
+		session.ExpiresAt = earliest(now.Add(s.config.RefreshTokenTTL), absoluteExpiresAt)
+	}
+
+	err := s.transactor.Transaction(func(tx repository.Repositories) error {
+		if err := tx.Sessions.Create(session); err != nil {
+			return err
+		}
+		data := WebhookUserData(user)
+		data["ip_address"] = client.IPAddress
+		return publishEvent(tx, EventUserLoggedIn, data)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return session, nil
+}
+
+// RequestMagicLink issues a magic link token and publishes the event that
+// emails the link to the user.
+func (s *AuthService) RequestMagicLink(user *models.User, client ClientInfo) error {
+	return s.transactor.Transaction(func(tx repository.Repositories) error {
+		token, err := issueToken(tx, user.ID.String(), TokenTypeMagicLink)
+		if err != nil {
+			return err
+		}
+		return publishEvent(tx, EventMagicLinkRequested, UserTokenEvent{
+			User:     webhookUser(user),
+			TokenID:  token.ID.String(),
+			Language: preferredLanguage(user, client),
+		})
+	})
+}
+
+// Impersonate creates a session in which impersonator acts as target. It expires
+// after ImpersonationTTL regardless of activity and is marked with the
+// impersonator, so handlers can restrict what it may do.
+func (s *AuthService) Impersonate(impersonator, target *models.User, client ClientInfo) (*models.Session, error) {
+	if impersonator.ID == target.ID {
+		return nil, apperrors.ErrCannotImpersonate
+	}
+	if target.DisabledAt != nil {
+		return nil, apperrors.ErrAccountDisabled
+	}
+
+	now := time.Now()
+	expiresAt := now.Add(s.config.ImpersonationTTL)
+	session := &models.Session{
+		ID:                uuid.New(),
+		UserID:            target.ID,
+		SessionToken:      uuid.New().String(),
+		DeviceInfo:        client.UserAgent,
+		IPAddress:         client.IPAddress,
+		IsActive:          true,
+		ExpiresAt:         expiresAt,
+		AbsoluteExpiresAt: &expiresAt,
+		LastAccessedAt:    now,
+		ImpersonatorID:    &impersonator.ID,
+	}
+	if s.config.TokenPairMode {
+		session.FamilyID = &session.ID
 	}
 
 	if err := s.sessionRepo.Create(session); err != nil {
@@ -78,17 +322,242 @@ func (s *AuthService) CreateSession(user *models.User, deviceInfo string) (*mode
 	return session, nil
 }
 
-func (s *AuthService) InvalidateSession(token string) error {
-	session, err := s.sessionRepo.FindByToken(token)
+// IssueTokenPair builds the access/refresh token pair for a refresh-token backed session.
+func (s *AuthService) IssueTokenPair(session *models.Session) (*TokenPair, error) {
+	accessToken, err := s.GenerateToken(session)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: session.SessionToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(s.config.AccessTokenTTL.Seconds()),
+	}, nil
+}
+
+// RefreshTokens rotates a refresh token: the presented token is retired and a
+// new pair is issued in the same family. Presenting a token that was already
+// rotated is treated as theft and revokes every token in the family.
+func (s *AuthService) RefreshTokens(refreshToken string, client ClientInfo) (*TokenPair, error) {
+	session, err := s.sessionRepo.FindRefreshToken(refreshToken)
+	if err != nil {
+		return nil, apperrors.ErrInvalidToken
+	}
+
+	if session.RotatedAt != nil {
+		return nil, s.revokeReusedFamily(session, client)
+	}
+
+	if !session.IsActive {
+		return nil, apperrors.ErrInvalidSession
+	}
+
+	if time.Now().After(session.ExpiresAt) {
+		return nil, apperrors.ErrSessionExpired
+	}
+
+	user, err := s.userRepo.FindByID(session.UserID.String())
+	if err != nil {
+		return nil, apperrors.ErrInvalidSession
+	}
+	if user.DisabledAt != nil {
+		return nil, apperrors.ErrAccountDisabled
+	}
+
+	// CreatedAt and the absolute deadline are carried over from the original login
+	expiresAt := time.Now().Add(s.config.RefreshTokenTTL)
+	if session.AbsoluteExpiresAt != nil {
+		expiresAt = earliest(expiresAt, *session.AbsoluteExpiresAt)
+	}
+
+	next := &models.Session{
+		ID:                   uuid.New(),
+		UserID:               session.UserID,
+		FamilyID:             session.FamilyID,
+		SessionToken:         uuid.New().String(),
+		DeviceInfo:           session.DeviceInfo,
+		IPAddress:            client.IPAddress,
+		IsActive:             true,
+		ExpiresAt:            expiresAt,
+		AbsoluteExpiresAt:    session.AbsoluteExpiresAt,
+		LastAccessedAt:       time.Now(),
+		CreatedAt:            session.CreatedAt,
+		ActiveOrganizationID: session.ActiveOrganizationID,
+		ImpersonatorID:       session.ImpersonatorID,
+	}
+
+	// The old token is only spent if its successor is stored, so a failed
+	// refresh can be retried with the same token
+	var reused bool
+	err = s.transactor.Transaction(func(tx repository.Repositories) error {
+		rotated, err := tx.Sessions.MarkRotated(session.ID.String())
+		if err != nil {
+			return err
+		}
+		if !rotated {
+			// Lost the race to another request rotating the same token
+			reused = true
+			return nil
+		}
+		return tx.Sessions.Create(next)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if reused {
+		return nil, s.revokeReusedFamily(session, client)
+	}
+
+	return s.IssueTokenPair(next)
+}
+
+// revokeReusedFamily revokes every token in the family of a refresh token that
+// was presented after rotation, and records the suspected theft.
+func (s *AuthService) revokeReusedFamily(session *models.Session, client ClientInfo) error {
+	revoked, err := s.sessionRepo.InvalidateFamily(session.FamilyID.String())
+	if err != nil {
+		return err
+	}
+	s.recordImpersonationEnds(revoked, "refresh_token_reused", client)
+
+	s.auditSvc.Log(&models.AuditEvent{
+		Type:      models.AuditRefreshTokenReused,
+		TargetID:  &session.UserID,
+		IPAddress: client.IPAddress,
+		UserAgent: client.UserAgent,
+		Outcome:   models.AuditOutcomeFailure,
+		Metadata:  map[string]interface{}{"session_id": session.FamilyID.String()},
+	})
+
+	return apperrors.ErrRefreshTokenReused
+}
+
+// InvalidateSession revokes the session behind a bearer token, on logout. For
+// access tokens the whole refresh token family is revoked; the access token
+// itself remains valid until it expires.
+func (s *AuthService) InvalidateSession(token string, client ClientInfo) error {
+	var revoked []models.Session
+	if IsAccessToken(token) {
+		claims, err := s.ValidateToken(token)
+		if err != nil || claims.SessionID == "" {
+			return errors.New("session not found")
+		}
+		if revoked, err = s.sessionRepo.InvalidateFamily(claims.SessionID); err != nil {
+			return err
+		}
+	} else {
+		session, err := s.sessionRepo.FindByToken(token)
+		if err != nil {
+			return errors.New("session not found")
+		}
+
+		if session.FamilyID != nil {
+			revoked, err = s.sessionRepo.InvalidateFamily(session.FamilyID.String())
+		} else {
+			revoked, err = s.sessionRepo.InvalidateSession(session.ID.String())
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	s.recordImpersonationEnds(revoked, "logout", client)
+	return nil
+}
+
+// InvalidateAllSessions revokes every session of the user. client is the
+// request that revoked them.
+func (s *AuthService) InvalidateAllSessions(userID string, client ClientInfo) error {
+	revoked, err := s.sessionRepo.InvalidateAllUserSessions(userID)
+	if err != nil {
+		return err
+	}
+	s.recordImpersonationEnds(revoked, "revoked", client)
+	return nil
+}
+
+// recordImpersonationEnds records the end of the impersonation sessions among
+// revoked, which ended for reason. Impersonations that run out are not
+// recorded here: they end at the expires_at of their impersonation.start event,
+// as activity never extends them.
+func (s *AuthService) recordImpersonationEnds(revoked []models.Session, reason string, client ClientInfo) {
+	for i := range revoked {
+		session := &revoked[i]
+		if session.ImpersonatorID == nil {
+			continue
+		}
+		s.auditSvc.Log(&models.AuditEvent{
+			Type:      models.AuditImpersonationEnd,
+			ActorID:   session.ImpersonatorID,
+			TargetID:  &session.UserID,
+			IPAddress: client.IPAddress,
+			UserAgent: client.UserAgent,
+			Metadata:  map[string]interface{}{"session_id": session.PublicID().String(), "reason": reason},
+		})
+	}
+}
+
+// ListSessions returns the user's active sessions. Refresh-token backed sessions
+// are reported under their family ID, which stays stable across rotations.
+func (s *AuthService) ListSessions(userID string) ([]models.Session, error) {
+	return s.sessionRepo.FindActiveByUserID(userID)
+}
+
+// GetSession returns one of the user's active sessions by session or family ID.
+func (s *AuthService) GetSession(userID, sessionID string) (*models.Session, error) {
+	if _, err := uuid.Parse(sessionID); err != nil {
+		return nil, apperrors.ErrSessionNotFound
+	}
+
+	session, err := s.sessionRepo.FindActiveByID(userID, sessionID)
 	if err != nil {
-		return errors.New("session not found")
+		return nil, apperrors.ErrSessionNotFound
 	}
 
-	return s.sessionRepo.InvalidateSession(session.ID.String())
+	return session, nil
 }
 
-func (s *AuthService) InvalidateAllSessions(userID string) error {
-	return s.sessionRepo.InvalidateAllUserSessions(userID)
+// RevokeSession revokes one of the user's sessions (including its whole refresh token family).
+func (s *AuthService) RevokeSession(userID, sessionID string, client ClientInfo) error {
+	if _, err := uuid.Parse(sessionID); err != nil {
+		return apperrors.ErrSessionNotFound
+	}
+
+	revoked, err := s.sessionRepo.InvalidateUserSession(userID, sessionID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return apperrors.ErrSessionNotFound
+		}
+		return err
+	}
+
+	s.recordImpersonationEnds(revoked, "revoked", client)
+	return nil
+}
+
+// SetActiveOrganization switches the organization a session works in and returns
+// the updated session. Access tokens already issued keep their org claim until
+// they are replaced. The caller must check the user's membership.
+func (s *AuthService) SetActiveOrganization(userID, sessionID string, organizationID *uuid.UUID) (*models.Session, error) {
+	if err := s.sessionRepo.SetActiveOrganization(userID, sessionID, organizationID); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, apperrors.ErrSessionNotFound
+		}
+		return nil, err
+	}
+	return s.GetSession(userID, sessionID)
+}
+
+// RevokeOtherSessions revokes every session of the user except currentSessionID.
+func (s *AuthService) RevokeOtherSessions(userID, currentSessionID string, client ClientInfo) error {
+	revoked, err := s.sessionRepo.InvalidateAllUserSessionsExcept(userID, currentSessionID)
+	if err != nil {
+		return err
+	}
+	s.recordImpersonationEnds(revoked, "revoked", client)
+	return nil
 }
 
 func (s *AuthService) ValidateSession(token string) (*models.Session, error) {
@@ -97,6 +566,11 @@ func (s *AuthService) ValidateSession(token string) (*models.Session, error) {
 		return nil, apperrors.ErrSessionNotFound
 	}
 
+	// Refresh tokens may only be exchanged at the refresh endpoint
+	if session.FamilyID != nil {
+		return nil, apperrors.ErrInvalidSession
+	}
+
 	if !session.IsActive {
 		return nil, apperrors.ErrInvalidSession
 	}
@@ -108,15 +582,150 @@ func (s *AuthService) ValidateSession(token string) (*models.Session, error) {
 	return session, nil
 }
 
-func (s *AuthService) Authenticate(email, password string) (*models.Session, error) {
+// TouchSession records activity on a session, sliding its idle expiry. Writes are
+// throttled per session and batched by the activity tracker.
+func (s *AuthService) TouchSession(session *models.Session) {
+	now := time.Now()
+	if now.Sub(session.LastAccessedAt) < sessionTouchInterval {
+		return
+	}
+	s.activity.Touch(session.ID, now)
+}
+
+func earliest(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
+
+// Authenticate checks an email and password. Every attempt is audited except one
+// that only issues an MFA challenge, which CompleteMFALogin audits when it finishes.
+func (s *AuthService) Authenticate(email, plaintext string, client ClientInfo) (*LoginResult, error) {
+	user, result, err := s.authenticate(email, plaintext, client)
+	if err != nil || result.Session != nil {
+		var userID *uuid.UUID
+		if user != nil {
+			userID = &user.ID
+		}
+		event := LoginEvent(userID, "password", client, err)
+		event.Metadata["email"] = email
+		s.auditSvc.Log(event)
+	}
+	return result, err
+}
+
+func (s *AuthService) authenticate(email, plaintext string, client ClientInfo) (*models.User, *LoginResult, error) {
 	user, err := s.userRepo.FindByEmail(email)
 	if err != nil {
-		return nil, errors.New("invalid credentials")
+		return nil, nil, errors.New("invalid credentials")
+	}
+
+	// Lockout is checked before the password so a locked account cannot be probed
+	now := time.Now()
+	if err := s.checkLockout(user, now); err != nil {
+		return user, nil, err
+	}
+
+	ok, rehash, err := s.hasher.Verify(plaintext, user.PasswordHash)
+	if err != nil || !ok {
+		return user, nil, s.recordFailedLogin(user, now, client)
+	}
+
+	// Upgrade legacy or weaker hashes while the plaintext is available. Failure
+	// is not fatal: the old hash still works and is retried on the next login.
+	// Only the hash changes, so a reset required by an administrator still applies.
+	if rehash {
+		if hashed, err := s.hasher.Hash(plaintext); err == nil {
+			_ = s.userRepo.UpdatePasswordHash(user.ID.String(), hashed)
+		}
+	}
+
+	if user.FailedLoginAttempts > 0 || user.LockedUntil != nil {
+		if err := s.userRepo.ResetFailedLogins(user.ID.String()); err != nil {
+			return user, nil, err
+		}
+	}
+
+	// Checked only after the password so these states are not revealed to guessers
+	if user.DisabledAt != nil {
+		return user, nil, apperrors.ErrAccountDisabled
+	}
+	if user.PasswordResetRequired {
+		return user, nil, apperrors.ErrMustResetPassword
+	}
+	if s.config.EmailVerificationPolicy == EmailVerificationRequired && user.EmailVerifiedAt == nil {
+		return user, nil, apperrors.ErrEmailNotVerified
+	}
+
+	if s.mfaSvc.IsEnabled(user.ID.String()) {
+		token, err := s.tokenSvc.GenerateToken(user.ID.String(), TokenTypeMFAChallenge)
+		if err != nil {
+			return user, nil, err
+		}
+		return user, &LoginResult{MFAToken: token}, nil
+	}
+
+	session, err := s.CreateSession(user, client)
+	if err != nil {
+		return user, nil, err
+	}
+
+	return user, &LoginResult{Session: session}, nil
+}
+
+// CompleteMFALogin exchanges an MFA challenge token and a TOTP or recovery code for a session.
+func (s *AuthService) CompleteMFALogin(mfaToken, code string, client ClientInfo) (*models.Session, error) {
+	tokenRecord, err := s.tokenSvc.ValidateToken(mfaToken, TokenTypeMFAChallenge)
+	if err != nil {
+		return nil, apperrors.ErrInvalidToken
+	}
+
+	userID, err := uuid.Parse(tokenRecord.UserID)
+	if err != nil {
+		return nil, apperrors.ErrInvalidToken
+	}
+
+	session, err := s.completeMFALogin(tokenRecord, code, client)
+	s.auditSvc.Log(LoginEvent(&userID, "mfa", client, err))
+	return session, err
+}
+
+func (s *AuthService) completeMFALogin(tokenRecord *models.Token, code string, client ClientInfo) (*models.Session, error) {
+	user, err := s.userRepo.FindByID(tokenRecord.UserID)
+	if err != nil {
+		return nil, apperrors.ErrUserNotFound
+	}
+
+	// Codes are subject to the same lockout and backoff as passwords
+	now := time.Now()
+	if err := s.checkLockout(user, now); err != nil {
+		return nil, err
+	}
+
+	if err := s.mfaSvc.Verify(tokenRecord.UserID, code); err != nil {
+		if !errors.Is(err, apperrors.ErrInvalidMFACode) {
+			return nil, err
+		}
+		if err := s.tokenSvc.RecordFailedAttempt(tokenRecord.Token, mfaChallengeMaxAttempts); err != nil {
+			return nil, err
+		}
+		if err := s.recordFailedLogin(user, now, client); !errors.Is(err, apperrors.ErrInvalidCredentials) {
+			return nil, err
+		}
+		return nil, apperrors.ErrInvalidMFACode
+	}
+
+	// The challenge is single-use once a valid code has been presented
+	if err := s.tokenSvc.InvalidateToken(tokenRecord.Token); err != nil {
+		return nil, err
 	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
-		return nil, errors.New("invalid credentials")
+	if user.FailedLoginAttempts > 0 || user.LockedUntil != nil {
+		if err := s.userRepo.ResetFailedLogins(user.ID.String()); err != nil {
+			return nil, err
+		}
 	}
 
-	return s.CreateSession(user, "")
+	return s.CreateSession(user, client)
 }
@@ -0,0 +1,165 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	apperrors "rest-api/internal/errors"
+	"rest-api/internal/models"
+	"rest-api/internal/repository"
+	"rest-api/pkg/encryption"
+	"rest-api/pkg/logger"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestAuthenticateRehashKeepsRequiredReset(t *testing.T) {
+	legacy, err := bcrypt.GenerateFromPassword([]byte("correct horse"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user := &models.User{
+		ID:                    uuid.New(),
+		Email:                 "user@example.com",
+		PasswordHash:          string(legacy),
+		PasswordResetRequired: true,
+	}
+	users := newFakeUserRepo(user)
+	auth := NewAuthService(users, nil, nil, nil, nil, nil, newTestAuditService(), nil, newTestHasher(), AuthConfig{})
+
+	// The first login upgrades the hash; neither may get past the required reset
+	for i := 0; i < 2; i++ {
+		if _, err := auth.Authenticate(user.Email, "correct horse", ClientInfo{}); !errors.Is(err, apperrors.ErrMustResetPassword) {
+			t.Fatalf("login %d: got %v, want ErrMustResetPassword", i+1, err)
+		}
+	}
+
+	stored, _ := users.FindByID(user.ID.String())
+	if !strings.HasPrefix(stored.PasswordHash, "$argon2id$") {
+		t.Errorf("hash was not upgraded: %s", stored.PasswordHash)
+	}
+	if !stored.PasswordResetRequired {
+		t.Error("rehashing cleared the required password reset")
+	}
+}
+
+// newMFALoginTest returns an AuthService for a user with MFA enabled, an MFA
+// challenge for them and a recovery code that completes it.
+func newMFALoginTest(t *testing.T, lockout LockoutConfig) (*AuthService, *fakeUserRepo, string, string) {
+	t.Helper()
+	user := &models.User{ID: uuid.New(), Email: "user@example.com"}
+	users := newFakeUserRepo(user)
+	tokens := newFakeTokenRepo()
+	transactor := &fakeTransactor{repos: repository.Repositories{Tokens: tokens, Outbox: &fakeOutboxRepo{}}}
+
+	encryptor := encryption.NewEncryptor("test")
+	secret, err := encryptor.Encrypt([]byte("JBSWY3DPEHPK3PXP"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	mfaRepo := newFakeMFARepo()
+	mfaRepo.factors[user.ID.String()] = &models.UserMFA{UserID: user.ID, SecretEncrypted: secret, Enabled: true}
+	mfaRepo.recoveryCodes[user.ID.String()+":"+hashRecoveryCode("good-code")] = true
+
+	tokenSvc := NewTokenService(tokens, transactor)
+	challenge, err := tokenSvc.GenerateToken(user.ID.String(), TokenTypeMFAChallenge)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	auth := NewAuthService(users, nil, tokenSvc, NewMFAService(mfaRepo, encryptor, "test"), nil, nil,
+		newTestAuditService(), transactor, newTestHasher(), AuthConfig{Lockout: lockout})
+	return auth, users, challenge, "good-code"
+}
+
+func TestCompleteMFALoginBurnsChallengeAfterFailures(t *testing.T) {
+	auth, _, challenge, code := newMFALoginTest(t, LockoutConfig{})
+
+	for i := 0; i < mfaChallengeMaxAttempts; i++ {
+		if _, err := auth.CompleteMFALogin(challenge, "wrong-code", ClientInfo{}); !errors.Is(err, apperrors.ErrInvalidMFACode) {
+			t.Fatalf("attempt %d: got %v, want ErrInvalidMFACode", i+1, err)
+		}
+	}
+
+	if _, err := auth.CompleteMFALogin(challenge, code, ClientInfo{}); !errors.Is(err, apperrors.ErrInvalidToken) {
+		t.Fatalf("valid code after %d failures: got %v, want ErrInvalidToken", mfaChallengeMaxAttempts, err)
+	}
+}
+
+func TestCompleteMFALoginFailuresCountTowardLockout(t *testing.T) {
+	auth, users, challenge, code := newMFALoginTest(t, LockoutConfig{Threshold: 3, Duration: time.Hour})
+
+	for i := 0; i < 2; i++ {
+		if _, err := auth.CompleteMFALogin(challenge, "wrong-code", ClientInfo{}); !errors.Is(err, apperrors.ErrInvalidMFACode) {
+			t.Fatalf("attempt %d: got %v, want ErrInvalidMFACode", i+1, err)
+		}
+	}
+
+	var blocked *LoginBlockedError
+	if _, err := auth.CompleteMFALogin(challenge, "wrong-code", ClientInfo{}); !errors.As(err, &blocked) || !errors.Is(err, apperrors.ErrAccountLocked) {
+		t.Fatalf("attempt 3: got %v, want ErrAccountLocked", err)
+	}
+
+	// A valid code does not get past the lock either
+	if _, err := auth.CompleteMFALogin(challenge, code, ClientInfo{}); !errors.Is(err, apperrors.ErrAccountLocked) {
+		t.Fatalf("valid code while locked: got %v, want ErrAccountLocked", err)
+	}
+
+	for _, u := range users.users {
+		if u.LockedUntil == nil {
+			t.Error("account was not locked")
+		}
+	}
+}
+
+func TestRevokingImpersonationRecordsItsEnd(t *testing.T) {
+	admin := &models.User{ID: uuid.New()}
+	target := &models.User{ID: uuid.New()}
+	sessions := &fakeSessionRepo{}
+	audits := &fakeAuditRepo{}
+	auth := NewAuthService(nil, sessions, nil, nil, nil, nil, NewAuditService(audits, logger.NewLogger()), nil, nil,
+		AuthConfig{ImpersonationTTL: 15 * time.Minute})
+	client := ClientInfo{IPAddress: "203.0.113.7"}
+
+	// The user's own session ends without an impersonation.end
+	if err := sessions.Create(&models.Session{ID: uuid.New(), UserID: target.ID, IsActive: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := auth.Impersonate(admin, target, ClientInfo{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := auth.RevokeSession(target.ID.String(), first.PublicID().String(), client); err != nil {
+		t.Fatal(err)
+	}
+	second, err := auth.Impersonate(admin, target, ClientInfo{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := auth.InvalidateAllSessions(target.ID.String(), client); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(audits.events) != 2 {
+		t.Fatalf("recorded %d events, want 2", len(audits.events))
+	}
+	for i, session := range []*models.Session{first, second} {
+		event := audits.events[i]
+		if event.Type != models.AuditImpersonationEnd {
+			t.Errorf("event %d: type %q, want %q", i, event.Type, models.AuditImpersonationEnd)
+		}
+		if event.ActorID == nil || *event.ActorID != admin.ID || event.TargetID == nil || *event.TargetID != target.ID {
+			t.Errorf("event %d: actor %v and target %v, want the admin and the user", i, event.ActorID, event.TargetID)
+		}
+		if event.Metadata["session_id"] != session.PublicID().String() || event.Metadata["reason"] != "revoked" {
+			t.Errorf("event %d: metadata %v", i, event.Metadata)
+		}
+		if event.IPAddress != client.IPAddress {
+			t.Errorf("event %d: IP address %q, want the revoking request's", i, event.IPAddress)
+		}
+	}
+}
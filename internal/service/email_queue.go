@@ -0,0 +1,259 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	apperrors "rest-api/internal/errors"
+	"rest-api/internal/models"
+	"rest-api/internal/repository"
+	"rest-api/pkg/email"
+	"rest-api/pkg/logger"
+
+	"github.com/google/uuid"
+)
+
+const (
+	emailLease           = 5 * time.Minute
+	emailPurgeInterval   = time.Hour
+	defaultEmailsPerPage = 20
+	maxEmailsPerPage     = 100
+)
+
+// EmailQueueConfig controls how queued emails are sent.
+type EmailQueueConfig struct {
+	// Workers is the number of emails sent at the same time.
+	Workers int
+	// MaxAttempts is the number of attempts before a message is dead-lettered.
+	// Retries wait BackoffBase, doubling per attempt up to BackoffMax.
+	MaxAttempts int
+	BackoffBase time.Duration
+	BackoffMax  time.Duration
+	// Retention is how long sent messages are kept before they are purged
+	Retention time.Duration
+}
+
+// EmailPage is one page of queued messages.
+type EmailPage struct {
+	Messages []models.EmailMessage
+	Total    int64
+	Page     int
+	PerPage  int
+}
+
+// EmailQueue stores outgoing emails in the database and sends them from a pool
+// of workers, so callers return as soon as a message is enqueued. Failed sends
+// are retried with exponential backoff; messages the server rejects outright or
+// that run out of attempts are dead-lettered for an administrator to inspect.
+type EmailQueue struct {
+	emailRepo repository.EmailRepository
+	sender    *email.EmailService
+	config    EmailQueueConfig
+	logger    *logger.Logger
+	lastPurge time.Time
+}
+
+func NewEmailQueue(emailRepo repository.EmailRepository, sender *email.EmailService, config EmailQueueConfig, logger *logger.Logger) *EmailQueue {
+	if config.Workers < 1 {
+		config.Workers = 1
+	}
+	if config.MaxAttempts < 1 {
+		config.MaxAttempts = 1
+	}
+	return &EmailQueue{
+		emailRepo: emailRepo,
+		sender:    sender,
+		config:    config,
+		logger:    logger,
+	}
+}
+
+// Enqueue queues an email. Enqueueing again with the same idempotency key is a
+// no-op, so callers that may run twice for one email pass a stable key; an
+// empty key always queues a new message. expiresAt is when a link in the
+// message stops working, if it carries one.
+func (q *EmailQueue) Enqueue(idempotencyKey string, message *email.Message, expiresAt *time.Time) error {
+	_, err := q.emailRepo.Enqueue(queuedMessage(idempotencyKey, message, expiresAt))
+	return err
+}
+
+// queuedMessage returns the queue row for a message, for callers that enqueue
+// it through the EmailRepository of a transaction.
+func queuedMessage(idempotencyKey string, message *email.Message, expiresAt *time.Time) *models.EmailMessage {
+	if idempotencyKey == "" {
+		idempotencyKey = uuid.NewString()
+	}
+	return &models.EmailMessage{
+		IdempotencyKey: idempotencyKey,
+		To:             message.To,
+		Subject:        message.Subject,
+		Body:           message.HTML,
+		TextBody:       message.Text,
+		ExpiresAt:      expiresAt,
+		Status:         models.EmailPending,
+		NextAttemptAt:  time.Now(),
+	}
+}
+
+// Messages returns a page of queued messages with the given status (every
+// status when empty), newest first.
+func (q *EmailQueue) Messages(status string, page, perPage int) (*EmailPage, error) {
+	if page < 1 {
+		page = 1
+	}
+	if perPage < 1 {
+		perPage = defaultEmailsPerPage
+	}
+	if perPage > maxEmailsPerPage {
+		perPage = maxEmailsPerPage
+	}
+
+	messages, total, err := q.emailRepo.List(status, (page-1)*perPage, perPage)
+	if err != nil {
+		return nil, err
+	}
+
+	return &EmailPage{
+		Messages: messages,
+		Total:    total,
+		Page:     page,
+		PerPage:  perPage,
+	}, nil
+}
+
+func (q *EmailQueue) Message(id string) (*models.EmailMessage, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, apperrors.ErrMessageNotFound
+	}
+	message, err := q.emailRepo.FindByID(id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, apperrors.ErrMessageNotFound
+	}
+	return message, err
+}
+
+// Retry puts a dead message back in the queue to be sent right away. Messages
+// whose link has expired cannot be retried; the user has to request a new one.
+func (q *EmailQueue) Retry(id string) (*models.EmailMessage, error) {
+	message, err := q.Message(id)
+	if err != nil {
+		return nil, err
+	}
+	if message.Status != models.EmailDead {
+		return nil, apperrors.ErrMessageNotFailed
+	}
+	if linkExpired(message, time.Now()) {
+		return nil, apperrors.ErrMessageExpired
+	}
+
+	if err := q.emailRepo.Requeue(id, time.Now()); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			// Retried by someone else in the meantime
+			return nil, apperrors.ErrMessageNotFailed
+		}
+		return nil, err
+	}
+	return q.Message(id)
+}
+
+// Run sends due messages every interval until ctx is cancelled, and purges
+// sent messages past their retention.
+func (q *EmailQueue) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			// Keep going while full batches suggest a backlog
+			for q.sendDue() == q.config.Workers && ctx.Err() == nil {
+			}
+			q.purge()
+		}
+	}
+}
+
+// sendDue claims one message per worker and sends them concurrently. Sends are
+// bounded by the transport timeout and always run to completion, so a shutdown never
+// leaves a message claimed mid-send. It returns the number of messages claimed.
+func (q *EmailQueue) sendDue() int {
+	now := time.Now()
+	messages, err := q.emailRepo.ClaimDue(now, now.Add(emailLease), q.config.Workers)
+	if err != nil {
+		q.logger.Error("failed to claim email messages", err)
+		return 0
+	}
+
+	var wg sync.WaitGroup
+	for i := range messages {
+		wg.Add(1)
+		go func(message *models.EmailMessage) {
+			defer wg.Done()
+			q.send(message)
+		}(&messages[i])
+	}
+	wg.Wait()
+	return len(messages)
+}
+
+// send attempts one message and records the outcome, scheduling a retry or
+// dead-lettering it.
+func (q *EmailQueue) send(message *models.EmailMessage) {
+	now := time.Now()
+	message.Attempts++
+	message.LastAttemptAt = &now
+	message.LastError = ""
+
+	// A link that stopped working while the message waited is not worth sending
+	var err error
+	if linkExpired(message, now) {
+		err = apperrors.ErrMessageExpired
+	} else {
+		err = q.sender.Send(&email.Message{
+			To:      message.To,
+			Subject: message.Subject,
+			Text:    message.TextBody,
+			HTML:    message.Body,
+		})
+	}
+	switch {
+	case errors.Is(err, apperrors.ErrMessageExpired):
+		message.Status = models.EmailDead
+	case err == nil:
+		message.Status = models.EmailSent
+		message.SentAt = &now
+	case email.IsPermanent(err) || message.Attempts >= q.config.MaxAttempts:
+		message.Status = models.EmailDead
+		q.logger.Error("giving up on email "+message.ID.String(), err)
+	default:
+		message.NextAttemptAt = now.Add(backoffDelay(q.config.BackoffBase, q.config.BackoffMax, message.Attempts))
+	}
+	if err != nil {
+		message.LastError = err.Error()
+	}
+
+	if err := q.emailRepo.RecordAttempt(message); err != nil {
+		q.logger.Error("failed to record email attempt", err)
+	}
+}
+
+// linkExpired reports whether the link a message carries has stopped working.
+func linkExpired(message *models.EmailMessage, now time.Time) bool {
+	return message.ExpiresAt != nil && !now.Before(*message.ExpiresAt)
+}
+
+// purge deletes sent messages past their retention, at most once per purge interval.
+func (q *EmailQueue) purge() {
+	if q.config.Retention <= 0 || time.Since(q.lastPurge) < emailPurgeInterval {
+		return
+	}
+	q.lastPurge = time.Now()
+
+	if _, err := q.emailRepo.DeleteSentBefore(q.lastPurge.Add(-q.config.Retention)); err != nil {
+		q.logger.Error("failed to purge sent emails", err)
+	}
+}
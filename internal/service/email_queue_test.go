@@ -0,0 +1,55 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	apperrors "rest-api/internal/errors"
+	"rest-api/internal/models"
+	"rest-api/pkg/logger"
+
+	"github.com/google/uuid"
+)
+
+func TestRetryRejectsExpiredLinks(t *testing.T) {
+	expired := time.Now().Add(-time.Minute)
+	valid := time.Now().Add(time.Hour)
+	emails := &fakeEmailRepo{messages: []*models.EmailMessage{
+		{ID: uuid.New(), Status: models.EmailDead, ExpiresAt: &expired},
+		{ID: uuid.New(), Status: models.EmailDead, ExpiresAt: &valid},
+		{ID: uuid.New(), Status: models.EmailDead},
+	}}
+	queue := NewEmailQueue(emails, nil, EmailQueueConfig{}, logger.NewLogger())
+
+	if _, err := queue.Retry(emails.messages[0].ID.String()); !errors.Is(err, apperrors.ErrMessageExpired) {
+		t.Errorf("retrying an expired link: got %v, want ErrMessageExpired", err)
+	}
+	for _, message := range emails.messages[1:] {
+		retried, err := queue.Retry(message.ID.String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if retried.Status != models.EmailPending {
+			t.Errorf("status %q, want pending", retried.Status)
+		}
+	}
+}
+
+func TestExpiredLinksAreNotSent(t *testing.T) {
+	expired := time.Now().Add(-time.Minute)
+	message := &models.EmailMessage{ID: uuid.New(), Status: models.EmailPending, ExpiresAt: &expired}
+	emails := &fakeEmailRepo{messages: []*models.EmailMessage{message}}
+	// A nil sender fails the test if the message is sent
+	queue := NewEmailQueue(emails, nil, EmailQueueConfig{MaxAttempts: 5}, logger.NewLogger())
+
+	queue.send(message)
+
+	stored, err := emails.FindByID(message.ID.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Status != models.EmailDead || stored.LastError != apperrors.ErrMessageExpired.Error() {
+		t.Errorf("status %q with error %q, want dead because the link expired", stored.Status, stored.LastError)
+	}
+}
@@ -0,0 +1,287 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"sync"
+	"time"
+
+	"rest-api/internal/models"
+	"rest-api/internal/repository"
+	"rest-api/pkg/logger"
+
+	"github.com/google/uuid"
+)
+
+// Domain events. Services publish them in the transaction that makes the
+// change, and the EventBus hands them to subscribers once it has committed.
+// The user lifecycle events double as webhook events (see WebhookEvents).
+const (
+	EventTokenIssued                = "token.issued"
+	EventPasswordResetRequested     = "password.reset_requested"
+	EventMagicLinkRequested         = "magic_link.requested"
+	EventEmailVerificationRequested = "email_verification.requested"
+	EventAccountUnlockRequested     = "account_unlock.requested"
+	EventInvitationCreated          = "invitation.created"
+	EventUserRegistered             = WebhookEventUserRegistered
+	EventUserEmailVerified          = WebhookEventUserEmailVerified
+	EventUserPasswordChanged        = WebhookEventUserPasswordChanged
+	EventUserDisabled               = WebhookEventUserDisabled
+	EventUserEnabled                = WebhookEventUserEnabled
+	EventUserDeleted                = WebhookEventUserDeleted
+	EventUserLoggedIn               = WebhookEventUserLoggedIn
+	EventUserLocked                 = WebhookEventUserLocked
+)
+
+const (
+	outboxBatchSize     = 50
+	outboxLease         = 5 * time.Minute
+	outboxPurgeInterval = time.Hour
+)
+
+// TokenIssuedEvent is the payload of EventTokenIssued. It says which kind of
+// token was issued to whom, never the token itself.
+type TokenIssuedEvent struct {
+	// Subject is the ID of the user the token was issued to
+	Subject   string    `json:"subject"`
+	TokenType TokenType `json:"token_type"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// UserTokenEvent is the payload of events that email a user a link carrying a
+// token. It holds the token's ID, and NotificationService looks the token up
+// when it builds the link, so the outbox never holds a live token.
+type UserTokenEvent struct {
+	User      WebhookUser `json:"user"`
+	TokenID   string      `json:"token_id"`
+	IPAddress string      `json:"ip_address,omitempty"`
+	UserAgent string      `json:"user_agent,omitempty"`
+	// Forced marks password resets required by an administrator rather than requested
+	Forced bool `json:"forced,omitempty"`
+	// Language lists the languages to email the user in, in Accept-Language form
+	Language string `json:"language,omitempty"`
+}
+
+// InvitationEvent is the payload of EventInvitationCreated. The invitation's
+// token is issued by NotificationService when it emails the invitation.
+type InvitationEvent struct {
+	InvitationID string    `json:"invitation_id"`
+	Email        string    `json:"email"`
+	Organization string    `json:"organization"`
+	Role         string    `json:"role"`
+	InvitedBy    string    `json:"invited_by"`
+	ExpiresAt    time.Time `json:"expires_at"`
+	// Language is the inviter's locale, as the invitee's is not known
+	Language string `json:"language,omitempty"`
+}
+
+// Event is a committed domain event as seen by subscribers.
+type Event struct {
+	ID        uuid.UUID
+	Type      string
+	CreatedAt time.Time
+	Payload   json.RawMessage
+}
+
+// Decode unmarshals the event payload into v.
+func (e *Event) Decode(v interface{}) error {
+	return json.Unmarshal(e.Payload, v)
+}
+
+// EventHandler handles one event for one subscriber. Delivery is at least once:
+// an event is retried until every subscriber has handled it, and a handler can
+// see it again if the process stops before its success is recorded. dedupKey
+// identifies this subscriber's handling of this event and is the same on every
+// retry, so handlers can use it to make repeats harmless.
+type EventHandler func(ctx context.Context, event *Event, dedupKey string) error
+
+// EventBusConfig controls retries of events a subscriber failed to handle.
+type EventBusConfig struct {
+	// MaxAttempts is the number of attempts before an event is marked failed.
+	// Retries wait BackoffBase, doubling per attempt up to BackoffMax.
+	MaxAttempts int
+	BackoffBase time.Duration
+	BackoffMax  time.Duration
+	// Retention is how long finished events are kept before they are purged
+	Retention time.Duration
+}
+
+type subscription struct {
+	name       string
+	eventTypes []string
+	handler    EventHandler
+}
+
+// EventBus dispatches events from the transactional outbox to in-process
+// subscribers.
+type EventBus struct {
+	outboxRepo  repository.OutboxRepository
+	subscribers []subscription
+	config      EventBusConfig
+	logger      *logger.Logger
+	lastPurge   time.Time
+}
+
+func NewEventBus(outboxRepo repository.OutboxRepository, config EventBusConfig, logger *logger.Logger) *EventBus {
+	if config.MaxAttempts < 1 {
+		config.MaxAttempts = 1
+	}
+	return &EventBus{
+		outboxRepo: outboxRepo,
+		config:     config,
+		logger:     logger,
+	}
+}
+
+// Subscribe registers handler for the given event types. Subscribers must be
+// registered before Run, under names that stay the same across releases,
+// because handled events are recorded by subscriber name.
+func (b *EventBus) Subscribe(name string, handler EventHandler, eventTypes ...string) {
+	b.subscribers = append(b.subscribers, subscription{
+		name:       name,
+		eventTypes: eventTypes,
+		handler:    handler,
+	})
+}
+
+// publishEvent writes an event to the outbox of the transaction tx belongs to,
+// so it is dispatched if and only if the transaction commits.
+func publishEvent(tx repository.Repositories, eventType string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	return tx.Outbox.Create(&models.OutboxEvent{
+		Type:          eventType,
+		Payload:       string(body),
+		Status:        models.OutboxPending,
+		NextAttemptAt: time.Now(),
+	})
+}
+
+// Run dispatches due events every interval until ctx is cancelled, and purges
+// finished events past their retention.
+func (b *EventBus) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			b.dispatchDue(ctx)
+			b.purge()
+		}
+	}
+}
+
+// dispatchDue claims a batch of due events and dispatches them concurrently.
+func (b *EventBus) dispatchDue(ctx context.Context) {
+	now := time.Now()
+	events, err := b.outboxRepo.ClaimDue(now, now.Add(outboxLease), outboxBatchSize)
+	if err != nil {
+		b.logger.Error("failed to claim outbox events", err)
+		return
+	}
+
+	// Handlers already started run to completion on shutdown rather than being cut off
+	ctx = context.WithoutCancel(ctx)
+
+	var wg sync.WaitGroup
+	for i := range events {
+		wg.Add(1)
+		go func(event *models.OutboxEvent) {
+			defer wg.Done()
+			b.dispatch(ctx, event)
+		}(&events[i])
+	}
+	wg.Wait()
+}
+
+// dispatch hands an event to each subscriber that has not handled it yet and
+// records the outcome, scheduling a retry while any of them fails.
+func (b *EventBus) dispatch(ctx context.Context, record *models.OutboxEvent) {
+	handled, err := b.outboxRepo.Receipts(record.ID)
+	if err != nil {
+		b.logger.Error("failed to load outbox receipts", err)
+		return
+	}
+
+	event := &Event{
+		ID:        record.ID,
+		Type:      record.Type,
+		CreatedAt: record.CreatedAt,
+		Payload:   json.RawMessage(record.Payload),
+	}
+
+	var failures []error
+	for _, sub := range b.subscribers {
+		if !slices.Contains(sub.eventTypes, event.Type) || slices.Contains(handled, sub.name) {
+			continue
+		}
+		if err := sub.handler(ctx, event, eventDedupKey(event.ID, sub.name)); err != nil {
+			failures = append(failures, errors.New(sub.name+": "+err.Error()))
+			continue
+		}
+		if err := b.outboxRepo.CreateReceipt(&models.OutboxReceipt{EventID: event.ID, Subscriber: sub.name}); err != nil {
+			// The event is retried and this subscriber sees it again
+			failures = append(failures, errors.New(sub.name+": failed to record receipt: "+err.Error()))
+		}
+	}
+
+	now := time.Now()
+	failed := errors.Join(failures...)
+	record.Attempts++
+	record.LastError = ""
+	switch {
+	case failed == nil:
+		record.Status = models.OutboxProcessed
+		record.ProcessedAt = &now
+	case record.Attempts >= b.config.MaxAttempts:
+		record.Status = models.OutboxFailed
+		record.ProcessedAt = &now
+		b.logger.Error("giving up on outbox event "+record.Type+" "+record.ID.String(), failed)
+	default:
+		record.NextAttemptAt = now.Add(backoffDelay(b.config.BackoffBase, b.config.BackoffMax, record.Attempts))
+	}
+	if failed != nil {
+		record.LastError = failed.Error()
+	}
+
+	if err := b.outboxRepo.RecordAttempt(record); err != nil {
+		b.logger.Error("failed to record outbox attempt", err)
+	}
+}
+
+// purge deletes finished events past their retention, at most once per purge interval.
+func (b *EventBus) purge() {
+	if b.config.Retention <= 0 || time.Since(b.lastPurge) < outboxPurgeInterval {
+		return
+	}
+	b.lastPurge = time.Now()
+
+	if _, err := b.outboxRepo.DeleteProcessedBefore(b.lastPurge.Add(-b.config.Retention)); err != nil {
+		b.logger.Error("failed to purge outbox events", err)
+	}
+}
+
+// eventDedupKey identifies one subscriber's handling of one event.
+func eventDedupKey(eventID uuid.UUID, subscriber string) string {
+	return eventID.String() + ":" + subscriber
+}
+
+// backoffDelay returns the delay before the retry that follows the given
+// attempt: base, doubling per attempt up to ceiling.
+func backoffDelay(base, ceiling time.Duration, attempts int) time.Duration {
+	delay := base
+	for i := 1; i < attempts && delay < ceiling; i++ {
+		delay *= 2
+	}
+	if delay > ceiling {
+		delay = ceiling
+	}
+	return delay
+}
@@ -0,0 +1,580 @@
+package service
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"rest-api/internal/models"
+	"rest-api/internal/repository"
+	"rest-api/pkg/logger"
+	"rest-api/pkg/password"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// fakeUserRepo keeps users in memory. Methods a test doesn't need panic
+// through the nil embedded interface.
+type fakeUserRepo struct {
+	repository.UserRepository
+
+	mu    sync.Mutex
+	users map[string]*models.User
+}
+
+func newFakeUserRepo(users ...*models.User) *fakeUserRepo {
+	r := &fakeUserRepo{users: make(map[string]*models.User)}
+	for _, u := range users {
+		r.users[u.ID.String()] = u
+	}
+	return r
+}
+
+// user returns a copy of the stored user, as the database would.
+func (r *fakeUserRepo) user(id string) (*models.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	u, ok := r.users[id]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	c := *u
+	return &c, nil
+}
+
+func (r *fakeUserRepo) FindByID(id string) (*models.User, error) {
+	return r.user(id)
+}
+
+func (r *fakeUserRepo) FindByEmail(email string) (*models.User, error) {
+	r.mu.Lock()
+	var id string
+	for _, u := range r.users {
+		if u.Email == email {
+			id = u.ID.String()
+		}
+	}
+	r.mu.Unlock()
+	return r.user(id)
+}
+
+func (r *fakeUserRepo) UpdatePassword(userID, hashedPassword string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.users[userID].PasswordHash = hashedPassword
+	r.users[userID].PasswordResetRequired = false
+	return nil
+}
+
+func (r *fakeUserRepo) UpdatePasswordHash(userID, hashedPassword string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.users[userID].PasswordHash = hashedPassword
+	return nil
+}
+
+func (r *fakeUserRepo) RecordFailedLogin(userID string, at time.Time, lockThreshold int, lockedUntil time.Time) (*models.User, error) {
+	r.mu.Lock()
+	u := r.users[userID]
+	u.FailedLoginAttempts++
+	u.LastFailedLoginAt = &at
+	if u.FailedLoginAttempts >= lockThreshold {
+		u.FailedLoginAttempts = 0
+		u.LockedUntil = &lockedUntil
+	}
+	r.mu.Unlock()
+	return r.user(userID)
+}
+
+func (r *fakeUserRepo) ResetFailedLogins(userID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.users[userID].FailedLoginAttempts = 0
+	r.users[userID].LastFailedLoginAt = nil
+	r.users[userID].LockedUntil = nil
+	return nil
+}
+
+// fakeTokenRepo keeps tokens in memory, keyed by token.
+type fakeTokenRepo struct {
+	repository.TokenRepository
+
+	mu     sync.Mutex
+	tokens map[string]*models.Token
+}
+
+func newFakeTokenRepo() *fakeTokenRepo {
+	return &fakeTokenRepo{tokens: make(map[string]*models.Token)}
+}
+
+func (r *fakeTokenRepo) Create(token *models.Token) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c := *token
+	r.tokens[token.Token] = &c
+	return nil
+}
+
+func (r *fakeTokenRepo) FindByToken(token string) (*models.Token, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	t, ok := r.tokens[token]
+	if !ok || t.Used || !t.ExpiresAt.After(time.Now()) {
+		return nil, repository.ErrNotFound
+	}
+	c := *t
+	return &c, nil
+}
+
+func (r *fakeTokenRepo) FindByID(id string) (*models.Token, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, t := range r.tokens {
+		if t.ID.String() == id && !t.Used && t.ExpiresAt.After(time.Now()) {
+			c := *t
+			return &c, nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
+
+func (r *fakeTokenRepo) InvalidateToken(token string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if t, ok := r.tokens[token]; ok {
+		t.Used = true
+	}
+	return nil
+}
+
+func (r *fakeTokenRepo) RecordFailedAttempt(token string, maxAttempts int) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if t, ok := r.tokens[token]; ok {
+		t.FailedAttempts++
+		t.Used = t.Used || t.FailedAttempts >= maxAttempts
+	}
+	return nil
+}
+
+// fakeSessionRepo keeps sessions in memory in the order they were created.
+type fakeSessionRepo struct {
+	repository.SessionRepository
+
+	mu       sync.Mutex
+	sessions []*models.Session
+}
+
+func (r *fakeSessionRepo) Create(session *models.Session) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c := *session
+	r.sessions = append(r.sessions, &c)
+	return nil
+}
+
+func (r *fakeSessionRepo) invalidate(match func(*models.Session) bool) []models.Session {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var revoked []models.Session
+	for _, session := range r.sessions {
+		if session.IsActive && match(session) {
+			session.IsActive = false
+			revoked = append(revoked, *session)
+		}
+	}
+	return revoked
+}
+
+func (r *fakeSessionRepo) InvalidateAllUserSessions(userID string) ([]models.Session, error) {
+	return r.invalidate(func(s *models.Session) bool { return s.UserID.String() == userID }), nil
+}
+
+func (r *fakeSessionRepo) InvalidateUserSession(userID, sessionID string) ([]models.Session, error) {
+	revoked := r.invalidate(func(s *models.Session) bool {
+		return s.UserID.String() == userID && s.PublicID().String() == sessionID
+	})
+	if len(revoked) == 0 {
+		return nil, repository.ErrNotFound
+	}
+	return revoked, nil
+}
+
+// fakeMFARepo holds one enabled factor per user and accepts each recovery
+// code once.
+type fakeMFARepo struct {
+	repository.MFARepository
+
+	mu            sync.Mutex
+	factors       map[string]*models.UserMFA
+	recoveryCodes map[string]bool
+}
+
+func newFakeMFARepo() *fakeMFARepo {
+	return &fakeMFARepo{
+		factors:       make(map[string]*models.UserMFA),
+		recoveryCodes: make(map[string]bool),
+	}
+}
+
+func (r *fakeMFARepo) FindByUserID(userID string) (*models.UserMFA, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	mfa, ok := r.factors[userID]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	return mfa, nil
+}
+
+func (r *fakeMFARepo) UpdateLastUsedStep(userID string, step int64) (bool, error) {
+	return true, nil
+}
+
+func (r *fakeMFARepo) UseRecoveryCode(userID string, codeHash string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	key := userID + ":" + codeHash
+	if !r.recoveryCodes[key] {
+		return false, nil
+	}
+	delete(r.recoveryCodes, key)
+	return true, nil
+}
+
+type fakeOutboxRepo struct {
+	repository.OutboxRepository
+
+	mu     sync.Mutex
+	events []*models.OutboxEvent
+}
+
+func (r *fakeOutboxRepo) Create(event *models.OutboxEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+	return nil
+}
+
+// fakeEmailRepo queues messages in memory, once per idempotency key.
+type fakeEmailRepo struct {
+	repository.EmailRepository
+
+	mu       sync.Mutex
+	messages []*models.EmailMessage
+}
+
+func (r *fakeEmailRepo) Enqueue(message *models.EmailMessage) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, queued := range r.messages {
+		if queued.IdempotencyKey == message.IdempotencyKey {
+			return false, nil
+		}
+	}
+	r.messages = append(r.messages, message)
+	return true, nil
+}
+
+func (r *fakeEmailRepo) FindByID(id string) (*models.EmailMessage, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, message := range r.messages {
+		if message.ID.String() == id {
+			c := *message
+			return &c, nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
+
+func (r *fakeEmailRepo) Requeue(id string, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, message := range r.messages {
+		if message.ID.String() == id && message.Status == models.EmailDead {
+			message.Status = models.EmailPending
+			message.NextAttemptAt = at
+			return nil
+		}
+	}
+	return repository.ErrNotFound
+}
+
+func (r *fakeEmailRepo) RecordAttempt(message *models.EmailMessage) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, queued := range r.messages {
+		if queued.ID == message.ID {
+			c := *message
+			r.messages[i] = &c
+		}
+	}
+	return nil
+}
+
+// fakeOrganizationRepo holds the token hashes of pending invitations.
+type fakeOrganizationRepo struct {
+	repository.OrganizationRepository
+
+	mu          sync.Mutex
+	tokenHashes map[string]string
+}
+
+func (r *fakeOrganizationRepo) SetInvitationToken(id, tokenHash string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.tokenHashes[id]; !ok {
+		return repository.ErrNotFound
+	}
+	r.tokenHashes[id] = tokenHash
+	return nil
+}
+
+// fakeTransactor hands out the same repositories to every transaction and
+// never rolls back.
+type fakeTransactor struct {
+	repos repository.Repositories
+}
+
+func (t *fakeTransactor) Transaction(fn func(tx repository.Repositories) error) error {
+	return fn(t.repos)
+}
+
+type fakeOAuthClientRepo struct {
+	repository.OAuthClientRepository
+
+	mu      sync.Mutex
+	clients map[string]*models.OAuthClient
+}
+
+func newFakeOAuthClientRepo() *fakeOAuthClientRepo {
+	return &fakeOAuthClientRepo{clients: make(map[string]*models.OAuthClient)}
+}
+
+func (r *fakeOAuthClientRepo) Create(client *models.OAuthClient) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	client.ID = uuid.New()
+	c := *client
+	r.clients[client.ID.String()] = &c
+	return nil
+}
+
+func (r *fakeOAuthClientRepo) FindByID(id string) (*models.OAuthClient, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	client, ok := r.clients[id]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	c := *client
+	return &c, nil
+}
+
+// fakeOAuthGrantRepo keeps codes, consents and refresh tokens in memory.
+type fakeOAuthGrantRepo struct {
+	repository.OAuthGrantRepository
+
+	mu            sync.Mutex
+	codes         map[string]*models.OAuthAuthorizationCode
+	consents      map[string]*models.OAuthConsent
+	refreshTokens map[string]*models.OAuthRefreshToken
+}
+
+func newFakeOAuthGrantRepo() *fakeOAuthGrantRepo {
+	return &fakeOAuthGrantRepo{
+		codes:         make(map[string]*models.OAuthAuthorizationCode),
+		consents:      make(map[string]*models.OAuthConsent),
+		refreshTokens: make(map[string]*models.OAuthRefreshToken),
+	}
+}
+
+func (r *fakeOAuthGrantRepo) CreateCode(code *models.OAuthAuthorizationCode) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c := *code
+	r.codes[code.CodeHash] = &c
+	return nil
+}
+
+func (r *fakeOAuthGrantRepo) FindCode(codeHash string) (*models.OAuthAuthorizationCode, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	code, ok := r.codes[codeHash]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	c := *code
+	return &c, nil
+}
+
+func (r *fakeOAuthGrantRepo) ConsumeCode(codeHash string) (*models.OAuthAuthorizationCode, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	code, ok := r.codes[codeHash]
+	switch {
+	case !ok:
+		return nil, false, repository.ErrNotFound
+	case code.UsedAt != nil:
+		c := *code
+		return &c, false, nil
+	case !code.ExpiresAt.After(time.Now()):
+		return nil, false, repository.ErrNotFound
+	}
+	now := time.Now()
+	code.UsedAt = &now
+	c := *code
+	return &c, true, nil
+}
+
+func (r *fakeOAuthGrantRepo) FindConsent(userID, clientID string) (*models.OAuthConsent, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	consent, ok := r.consents[userID+":"+clientID]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	c := *consent
+	return &c, nil
+}
+
+func (r *fakeOAuthGrantRepo) SaveConsent(consent *models.OAuthConsent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c := *consent
+	r.consents[consent.UserID.String()+":"+consent.ClientID.String()] = &c
+	return nil
+}
+
+func (r *fakeOAuthGrantRepo) CreateRefreshToken(token *models.OAuthRefreshToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	token.ID = uuid.New()
+	t := *token
+	r.refreshTokens[token.TokenHash] = &t
+	return nil
+}
+
+func (r *fakeOAuthGrantRepo) FindRefreshToken(tokenHash string) (*models.OAuthRefreshToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	token, ok := r.refreshTokens[tokenHash]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	t := *token
+	return &t, nil
+}
+
+func (r *fakeOAuthGrantRepo) MarkRefreshTokenRotated(id string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, token := range r.refreshTokens {
+		if token.ID.String() == id && token.RotatedAt == nil && token.RevokedAt == nil {
+			now := time.Now()
+			token.RotatedAt = &now
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *fakeOAuthGrantRepo) RevokeRefreshFamily(familyID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	for _, token := range r.refreshTokens {
+		if token.FamilyID.String() == familyID && token.RevokedAt == nil {
+			token.RevokedAt = &now
+		}
+	}
+	return nil
+}
+
+// fakeSigningKeyRepo keeps signing keys in memory, shared by the KeyServices
+// of several "instances".
+type fakeSigningKeyRepo struct {
+	repository.SigningKeyRepository
+
+	mu   sync.Mutex
+	keys []*models.SigningKey
+}
+
+func (r *fakeSigningKeyRepo) FindUsable() ([]models.SigningKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var usable []models.SigningKey
+	for _, k := range r.keys {
+		if k.ExpiresAt == nil || k.ExpiresAt.After(time.Now()) {
+			usable = append(usable, *k)
+		}
+	}
+	sort.Slice(usable, func(i, j int) bool { return usable[i].ActivatedAt.After(usable[j].ActivatedAt) })
+	return usable, nil
+}
+
+func (r *fakeSigningKeyRepo) Rotate(key *models.SigningKey, staleBefore time.Time, gracePeriod time.Duration) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, k := range r.keys {
+		if k.RetiredAt == nil && k.Algorithm == key.Algorithm && k.ActivatedAt.After(staleBefore) {
+			return false, nil
+		}
+	}
+	for _, k := range r.keys {
+		if k.RetiredAt == nil {
+			retiredAt, expiresAt := key.ActivatedAt, key.ActivatedAt.Add(gracePeriod)
+			k.RetiredAt, k.ExpiresAt = &retiredAt, &expiresAt
+		}
+	}
+	key.ID = uuid.New()
+	c := *key
+	r.keys = append(r.keys, &c)
+	return true, nil
+}
+
+// shift moves every stored time by d, as if d had passed the other way.
+func (r *fakeSigningKeyRepo) shift(d time.Duration) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, k := range r.keys {
+		k.ActivatedAt = k.ActivatedAt.Add(d)
+		if k.RetiredAt != nil {
+			t := k.RetiredAt.Add(d)
+			k.RetiredAt = &t
+		}
+		if k.ExpiresAt != nil {
+			t := k.ExpiresAt.Add(d)
+			k.ExpiresAt = &t
+		}
+	}
+}
+
+type fakeAuditRepo struct {
+	repository.AuditRepository
+
+	mu     sync.Mutex
+	events []*models.AuditEvent
+}
+
+func (r *fakeAuditRepo) Create(event *models.AuditEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.events = append(r.events, event)
+	return nil
+}
+
+func newTestAuditService() *AuditService {
+	return NewAuditService(&fakeAuditRepo{}, logger.NewLogger())
+}
+
+// newTestHasher hashes with cheap Argon2id parameters and accepts bcrypt as
+// the legacy algorithm.
+func newTestHasher() *password.Manager {
+	return password.NewManager(
+		password.NewArgon2id(password.Argon2Params{Memory: 64, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}),
+		password.NewBcrypt(bcrypt.MinCost),
+	)
+}
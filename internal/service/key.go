@@ -0,0 +1,299 @@
+package service
+
+import (
+	"context"
+	"crypto"
+	"crypto/x509"
+	"errors"
+	"sync"
+	"time"
+
+	"rest-api/internal/models"
+	"rest-api/internal/repository"
+	"rest-api/pkg/encryption"
+	"rest-api/pkg/jwk"
+	"rest-api/pkg/logger"
+
+	"github.com/golang-jwt/jwt/v5"
+	"go.uber.org/zap"
+)
+
+// keyReloadInterval limits how often an unknown kid can trigger a reload from the database.
+const keyReloadInterval = 10 * time.Second
+
+var ErrUnknownSigningKey = errors.New("unknown signing key")
+
+// KeyConfig controls the signing key set.
+type KeyConfig struct {
+	Algorithm        string
+	RotationInterval time.Duration
+	// GracePeriod keeps retired keys published so tokens signed just before a
+	// rotation still verify. It should be at least the access token lifetime.
+	GracePeriod time.Duration
+	// PrepublishPeriod publishes a new key before it signs anything, so
+	// verifiers that cache the JWKS have it by the time tokens carry its kid
+	PrepublishPeriod time.Duration
+}
+
+type signingKey struct {
+	kid        string
+	algorithm  string
+	method     jwt.SigningMethod
+	privateKey crypto.Signer
+	activated  time.Time
+}
+
+func (k *signingKey) activatedAfter(t time.Time) bool {
+	return k.activated.After(t)
+}
+
+// KeyService manages the asymmetric keys used to sign and verify JWTs.
+type KeyService struct {
+	keyRepo   repository.SigningKeyRepository
+	encryptor *encryption.Encryptor
+	config    KeyConfig
+	logger    *logger.Logger
+
+	mu       sync.RWMutex
+	keys     []*signingKey
+	loadedAt time.Time
+}
+
+func NewKeyService(keyRepo repository.SigningKeyRepository, encryptor *encryption.Encryptor, config KeyConfig, logger *logger.Logger) *KeyService {
+	return &KeyService{
+		keyRepo:   keyRepo,
+		encryptor: encryptor,
+		config:    config,
+		logger:    logger,
+	}
+}
+
+// SigningKey returns the key new tokens should be signed with.
+func (s *KeyService) SigningKey() (kid string, method jwt.SigningMethod, key crypto.Signer, err error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if k := s.current(); k != nil {
+		return k.kid, k.method, k.privateKey, nil
+	}
+
+	return "", nil, nil, ErrUnknownSigningKey
+}
+
+// VerificationKey returns the public key and algorithm for kid, reloading the key
+// set if the kid is unknown (it may have been created by another instance).
+func (s *KeyService) VerificationKey(kid string) (crypto.PublicKey, string, error) {
+	if key := s.find(kid); key != nil {
+		return key.privateKey.Public(), key.algorithm, nil
+	}
+
+	s.mu.RLock()
+	stale := time.Since(s.loadedAt) > keyReloadInterval
+	s.mu.RUnlock()
+
+	if stale {
+		if err := s.Reload(); err != nil {
+			return nil, "", err
+		}
+		if key := s.find(kid); key != nil {
+			return key.privateKey.Public(), key.algorithm, nil
+		}
+	}
+
+	return nil, "", ErrUnknownSigningKey
+}
+
+// JWKS returns the public keys of every key that can still verify tokens,
+// including a successor that does not sign yet.
+func (s *KeyService) JWKS() (*jwk.Set, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	set := &jwk.Set{Keys: make([]jwk.Key, 0, len(s.keys))}
+	for _, k := range s.keys {
+		key, err := jwk.FromPublicKey(k.kid, k.algorithm, k.privateKey.Public())
+		if err != nil {
+			return nil, err
+		}
+		set.Keys = append(set.Keys, key)
+	}
+
+	return set, nil
+}
+
+// EnsureActiveKey rotates when there is no key, the newest key is due for
+// rotation, or the configured algorithm changed. Rotation starts one
+// pre-publication period early so the successor takes over on schedule.
+func (s *KeyService) EnsureActiveKey() error {
+	return s.rotate(time.Now().Add(s.config.PrepublishPeriod - s.config.RotationInterval))
+}
+
+// Rotate creates a new signing key unless one is already waiting to activate.
+func (s *KeyService) Rotate() error {
+	return s.rotate(time.Now())
+}
+
+func (s *KeyService) rotate(staleBefore time.Time) error {
+	if err := s.Reload(); err != nil {
+		return err
+	}
+
+	s.mu.RLock()
+	newest, current := s.newest(), s.current()
+	s.mu.RUnlock()
+
+	if newest != nil && newest.algorithm == s.config.Algorithm && newest.activatedAfter(staleBefore) {
+		return nil
+	}
+
+	// With nothing to sign with yet there is no one to warn in advance
+	activatesAt := time.Now()
+	if current != nil {
+		activatesAt = activatesAt.Add(s.config.PrepublishPeriod)
+	}
+
+	privateKey, err := jwk.GenerateKey(s.config.Algorithm)
+	if err != nil {
+		return err
+	}
+
+	der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		return err
+	}
+
+	encrypted, err := s.encryptor.Encrypt(der)
+	if err != nil {
+		return err
+	}
+
+	publicKey, err := x509.MarshalPKIXPublicKey(privateKey.Public())
+	if err != nil {
+		return err
+	}
+
+	record := &models.SigningKey{
+		Algorithm:           s.config.Algorithm,
+		PrivateKeyEncrypted: encrypted,
+		PublicKey:           publicKey,
+		ActivatedAt:         activatesAt,
+	}
+
+	rotated, err := s.keyRepo.Rotate(record, staleBefore, s.config.GracePeriod)
+	if err != nil {
+		return err
+	}
+	if rotated {
+		s.logger.Info("Rotated JWT signing key", zap.Time("activates_at", activatesAt))
+	}
+
+	return s.Reload()
+}
+
+// Reload refreshes the in-memory key set from the database.
+func (s *KeyService) Reload() error {
+	records, err := s.keyRepo.FindUsable()
+	if err != nil {
+		return err
+	}
+
+	keys := make([]*signingKey, 0, len(records))
+	for _, record := range records {
+		key, err := s.decode(&record)
+		if err != nil {
+			return err
+		}
+		keys = append(keys, key)
+	}
+
+	s.mu.Lock()
+	s.keys = keys
+	s.loadedAt = time.Now()
+	s.mu.Unlock()
+
+	return nil
+}
+
+// Run periodically rotates keys and prunes expired ones until ctx is cancelled.
+func (s *KeyService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.EnsureActiveKey(); err != nil {
+				s.logger.Error("failed to rotate signing key", err)
+			}
+			if err := s.keyRepo.DeleteExpired(); err != nil {
+				s.logger.Error("failed to prune signing keys", err)
+			}
+		}
+	}
+}
+
+func (s *KeyService) find(kid string) *signingKey {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, k := range s.keys {
+		if k.kid == kid {
+			return k
+		}
+	}
+	return nil
+}
+
+// current returns the newest key that has activated. Keys are sorted newest
+// first, and a key retires when its successor activates. It must be called
+// with s.mu held.
+func (s *KeyService) current() *signingKey {
+	now := time.Now()
+	for _, k := range s.keys {
+		if !k.activatedAfter(now) {
+			return k
+		}
+	}
+	return nil
+}
+
+// newest returns the most recent key, which may not have activated yet. It
+// must be called with s.mu held.
+func (s *KeyService) newest() *signingKey {
+	if len(s.keys) == 0 {
+		return nil
+	}
+	return s.keys[0]
+}
+
+func (s *KeyService) decode(record *models.SigningKey) (*signingKey, error) {
+	der, err := s.encryptor.Decrypt(record.PrivateKeyEncrypted)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return nil, err
+	}
+
+	privateKey, ok := parsed.(crypto.Signer)
+	if !ok {
+		return nil, jwk.ErrUnsupportedAlgorithm
+	}
+
+	method := jwt.GetSigningMethod(record.Algorithm)
+	if method == nil {
+		return nil, jwk.ErrUnsupportedAlgorithm
+	}
+
+	return &signingKey{
+		kid:        record.ID.String(),
+		algorithm:  record.Algorithm,
+		method:     method,
+		privateKey: privateKey,
+		activated:  record.ActivatedAt,
+	}, nil
+}
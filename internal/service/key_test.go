@@ -0,0 +1,210 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"rest-api/pkg/encryption"
+	"rest-api/pkg/logger"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const testKeyEncryptionKey = "test-key-encryption-key-0123456789"
+
+var testKeyConfig = KeyConfig{
+	Algorithm:        "ES256",
+	RotationInterval: 30 * 24 * time.Hour,
+	GracePeriod:      24 * time.Hour,
+	PrepublishPeriod: 24 * time.Hour,
+}
+
+func newTestKeyService(repo *fakeSigningKeyRepo, config KeyConfig) *KeyService {
+	return NewKeyService(repo, encryption.NewEncryptor(testKeyEncryptionKey), config, logger.NewLogger())
+}
+
+func signingKID(t *testing.T, s *KeyService) string {
+	t.Helper()
+	kid, _, _, err := s.SigningKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return kid
+}
+
+func jwksKIDs(t *testing.T, s *KeyService) []string {
+	t.Helper()
+	set, err := s.JWKS()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var kids []string
+	for _, key := range set.Keys {
+		kids = append(kids, key.Kid)
+	}
+	return kids
+}
+
+// signAndVerify signs a token with the current key and verifies it the way
+// access tokens are verified, by looking the kid up.
+func signAndVerify(t *testing.T, s *KeyService) string {
+	t.Helper()
+	kid, method, key, err := s.SigningKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := jwt.NewWithClaims(method, jwt.MapClaims{"sub": "alice"})
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = jwt.Parse(signed, func(token *jwt.Token) (interface{}, error) {
+		pub, alg, err := s.VerificationKey(token.Header["kid"].(string))
+		if err != nil {
+			return nil, err
+		}
+		if alg != token.Method.Alg() {
+			return nil, errors.New("algorithm mismatch")
+		}
+		return pub, nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return kid
+}
+
+func TestKeyServiceRotation(t *testing.T) {
+	repo := &fakeSigningKeyRepo{}
+	s := newTestKeyService(repo, testKeyConfig)
+
+	// The first key signs straight away, as nothing else could
+	if err := s.EnsureActiveKey(); err != nil {
+		t.Fatal(err)
+	}
+	first := signAndVerify(t, s)
+	if err := s.EnsureActiveKey(); err != nil {
+		t.Fatal(err)
+	}
+	if kids := jwksKIDs(t, s); len(kids) != 1 {
+		t.Fatalf("got keys %v, want only the first", kids)
+	}
+
+	// Once rotation is due the successor is published but does not sign yet
+	repo.shift(-(testKeyConfig.RotationInterval - testKeyConfig.PrepublishPeriod + time.Minute))
+	if err := s.EnsureActiveKey(); err != nil {
+		t.Fatal(err)
+	}
+	kids := jwksKIDs(t, s)
+	if len(kids) != 2 {
+		t.Fatalf("got keys %v, want the successor published", kids)
+	}
+	second := kids[0]
+	if kid := signingKID(t, s); kid != first {
+		t.Errorf("signing with %s before its activation", kid)
+	}
+
+	// Neither this instance nor another one rotates again while it waits
+	if err := s.EnsureActiveKey(); err != nil {
+		t.Fatal(err)
+	}
+	if err := newTestKeyService(repo, testKeyConfig).EnsureActiveKey(); err != nil {
+		t.Fatal(err)
+	}
+	if len(repo.keys) != 2 {
+		t.Errorf("got %d keys, want one pending successor", len(repo.keys))
+	}
+
+	// After pre-publication it signs, and the old key still verifies
+	repo.shift(-(testKeyConfig.PrepublishPeriod + time.Minute))
+	if err := s.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if kid := signAndVerify(t, s); kid != second {
+		t.Errorf("signing with %s, want the successor %s", kid, second)
+	}
+	if _, _, err := s.VerificationKey(first); err != nil {
+		t.Errorf("retired key within its grace period: %v", err)
+	}
+
+	// After the grace period it is gone
+	repo.shift(-testKeyConfig.GracePeriod)
+	if err := s.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if kids := jwksKIDs(t, s); len(kids) != 1 || kids[0] != second {
+		t.Errorf("got keys %v, want only %s", kids, second)
+	}
+	if _, _, err := s.VerificationKey(first); !errors.Is(err, ErrUnknownSigningKey) {
+		t.Errorf("expired key: got %v, want ErrUnknownSigningKey", err)
+	}
+}
+
+func TestKeyServiceAlgorithmChange(t *testing.T) {
+	repo := &fakeSigningKeyRepo{}
+	if err := newTestKeyService(repo, testKeyConfig).EnsureActiveKey(); err != nil {
+		t.Fatal(err)
+	}
+
+	config := testKeyConfig
+	config.Algorithm = "EdDSA"
+	s := newTestKeyService(repo, config)
+	if err := s.EnsureActiveKey(); err != nil {
+		t.Fatal(err)
+	}
+	if _, method, _, _ := s.SigningKey(); method.Alg() != "ES256" {
+		t.Errorf("switched to %s before the new key was published for long enough", method.Alg())
+	}
+
+	repo.shift(-(config.PrepublishPeriod + time.Minute))
+	if err := s.Reload(); err != nil {
+		t.Fatal(err)
+	}
+	if _, method, _, _ := s.SigningKey(); method.Alg() != "EdDSA" {
+		t.Errorf("got %s, want EdDSA after pre-publication", method.Alg())
+	}
+	signAndVerify(t, s)
+}
+
+func TestKeyServiceReloadsUnknownKids(t *testing.T) {
+	repo := &fakeSigningKeyRepo{}
+	a, b := newTestKeyService(repo, testKeyConfig), newTestKeyService(repo, testKeyConfig)
+	if err := a.EnsureActiveKey(); err != nil {
+		t.Fatal(err)
+	}
+
+	// b has never loaded, so the unknown kid triggers a reload
+	if _, _, err := b.VerificationKey(signingKID(t, a)); err != nil {
+		t.Fatalf("key created by another instance: %v", err)
+	}
+
+	if err := a.Rotate(); err != nil {
+		t.Fatal(err)
+	}
+	next := jwksKIDs(t, a)[0]
+
+	// Unknown kids reload at most once per interval, so they cannot be used
+	// to hammer the database
+	if _, _, err := b.VerificationKey(next); !errors.Is(err, ErrUnknownSigningKey) {
+		t.Errorf("got %v, want ErrUnknownSigningKey right after a reload", err)
+	}
+	b.loadedAt = time.Now().Add(-keyReloadInterval - time.Second)
+	if _, _, err := b.VerificationKey(next); err != nil {
+		t.Errorf("after the reload interval: %v", err)
+	}
+}
+
+func TestKeyServiceRejectsWrongEncryptionKey(t *testing.T) {
+	repo := &fakeSigningKeyRepo{}
+	if err := newTestKeyService(repo, testKeyConfig).EnsureActiveKey(); err != nil {
+		t.Fatal(err)
+	}
+
+	s := NewKeyService(repo, encryption.NewEncryptor("another-key-encryption-key-012345"), testKeyConfig, logger.NewLogger())
+	if err := s.Reload(); err == nil {
+		t.Error("decrypted signing keys with the wrong key")
+	}
+}
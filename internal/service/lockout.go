@@ -0,0 +1,162 @@
+package service
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	apperrors "rest-api/internal/errors"
+	"rest-api/internal/models"
+	"rest-api/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// LockoutConfig controls how repeated failed password attempts slow down and
+// eventually lock an account.
+type LockoutConfig struct {
+	// BackoffThreshold is the number of consecutive failures allowed before
+	// attempts are delayed by BackoffBase, doubling per further failure up to BackoffMax.
+	BackoffThreshold int
+	BackoffBase      time.Duration
+	BackoffMax       time.Duration
+	// Threshold is the number of consecutive failures that locks the account for Duration.
+	Threshold int
+	Duration  time.Duration
+}
+
+// LoginBlockedError is returned when a password login is refused because of
+// earlier failures. Err is ErrAccountLocked or ErrTooManyRequests.
+type LoginBlockedError struct {
+	Err        error
+	RetryAfter time.Duration
+}
+
+func (e *LoginBlockedError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *LoginBlockedError) Unwrap() error {
+	return e.Err
+}
+
+// backoffDelay returns how long after the last failure the next attempt is allowed.
+func (c LockoutConfig) backoffDelay(failures int) time.Duration {
+	if c.BackoffThreshold <= 0 || failures < c.BackoffThreshold {
+		return 0
+	}
+	delay := c.BackoffBase
+	for i := c.BackoffThreshold; i < failures && delay < c.BackoffMax; i++ {
+		delay *= 2
+	}
+	if delay > c.BackoffMax {
+		delay = c.BackoffMax
+	}
+	return delay
+}
+
+// checkLockout refuses attempts on locked accounts and attempts made before the
+// backoff delay since the last failure has elapsed.
+func (s *AuthService) checkLockout(user *models.User, now time.Time) error {
+	if user.LockedUntil != nil && now.Before(*user.LockedUntil) {
+		return &LoginBlockedError{Err: apperrors.ErrAccountLocked, RetryAfter: user.LockedUntil.Sub(now)}
+	}
+
+	if user.LastFailedLoginAt != nil {
+		allowedAt := user.LastFailedLoginAt.Add(s.config.Lockout.backoffDelay(user.FailedLoginAttempts))
+		if now.Before(allowedAt) {
+			return &LoginBlockedError{Err: apperrors.ErrTooManyRequests, RetryAfter: allowedAt.Sub(now)}
+		}
+	}
+
+	return nil
+}
+
+// recordFailedLogin counts a failed attempt and reports a lockout if this
+// attempt reached the threshold. Locking the account emails the user an unlock link.
+func (s *AuthService) recordFailedLogin(user *models.User, now time.Time, client ClientInfo) error {
+	threshold := s.config.Lockout.Threshold
+	if threshold <= 0 {
+		// Lockout disabled; failures still drive the backoff delay
+		threshold = math.MaxInt32
+	}
+	lockedUntil := now.Add(s.config.Lockout.Duration)
+	updated, err := s.userRepo.RecordFailedLogin(user.ID.String(), now, threshold, lockedUntil)
+	if err != nil {
+		return err
+	}
+
+	// The count only drops back to zero when this attempt locked the account
+	if updated.FailedLoginAttempts > 0 {
+		return apperrors.ErrInvalidCredentials
+	}
+
+	s.auditSvc.Log(&models.AuditEvent{
+		Type:      models.AuditAccountLocked,
+		TargetID:  &user.ID,
+		IPAddress: client.IPAddress,
+		UserAgent: client.UserAgent,
+		Metadata:  map[string]interface{}{"locked_until": lockedUntil},
+	})
+
+	err = s.transactor.Transaction(func(tx repository.Repositories) error {
+		data := WebhookUserData(user)
+		data["locked_until"] = lockedUntil
+		if err := publishEvent(tx, EventUserLocked, data); err != nil {
+			return err
+		}
+
+		token, err := issueToken(tx, user.ID.String(), TokenTypeUnlock)
+		if err != nil {
+			return err
+		}
+		return publishEvent(tx, EventAccountUnlockRequested, UserTokenEvent{
+			User:     webhookUser(user),
+			TokenID:  token.ID.String(),
+			Language: preferredLanguage(user, client),
+		})
+	})
+	if err != nil {
+		return err
+	}
+	return &LoginBlockedError{Err: apperrors.ErrAccountLocked, RetryAfter: s.config.Lockout.Duration}
+}
+
+// UnlockAccount clears any lockout and failed attempt history for the user.
+func (s *AuthService) UnlockAccount(userID string) error {
+	if err := s.userRepo.ResetFailedLogins(userID); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return apperrors.ErrUserNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// UnlockAccountWithToken consumes an unlock token sent by email and unlocks its account.
+func (s *AuthService) UnlockAccountWithToken(token string, client ClientInfo) error {
+	tokenRecord, err := s.tokenSvc.ValidateToken(token, TokenTypeUnlock)
+	if err != nil {
+		return apperrors.ErrInvalidToken
+	}
+
+	if err := s.tokenSvc.InvalidateToken(token); err != nil {
+		return err
+	}
+
+	if err := s.UnlockAccount(tokenRecord.UserID); err != nil {
+		return err
+	}
+
+	if userID, err := uuid.Parse(tokenRecord.UserID); err == nil {
+		s.auditSvc.Log(&models.AuditEvent{
+			Type:      models.AuditAccountUnlocked,
+			ActorID:   &userID,
+			TargetID:  &userID,
+			IPAddress: client.IPAddress,
+			UserAgent: client.UserAgent,
+			Metadata:  map[string]interface{}{"method": "email"},
+		})
+	}
+	return nil
+}
@@ -0,0 +1,200 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"strings"
+	"time"
+	"unicode"
+
+	apperrors "rest-api/internal/errors"
+	"rest-api/internal/models"
+	"rest-api/internal/repository"
+	"rest-api/pkg/encryption"
+	"rest-api/pkg/totp"
+)
+
+const (
+	recoveryCodeCount = 10
+	totpSkew          = 1
+)
+
+type MFAEnrollment struct {
+	Secret     string `json:"secret"`
+	OTPAuthURL string `json:"otpauth_url"`
+}
+
+type MFAService struct {
+	mfaRepo   repository.MFARepository
+	encryptor *encryption.Encryptor
+	issuer    string
+}
+
+func NewMFAService(mfaRepo repository.MFARepository, encryptor *encryption.Encryptor, issuer string) *MFAService {
+	return &MFAService{
+		mfaRepo:   mfaRepo,
+		encryptor: encryptor,
+		issuer:    issuer,
+	}
+}
+
+// IsEnabled reports whether the user has a confirmed TOTP factor.
+func (s *MFAService) IsEnabled(userID string) bool {
+	mfa, err := s.mfaRepo.FindByUserID(userID)
+	if err != nil {
+		return false
+	}
+	return mfa.Enabled
+}
+
+// Enroll starts (or restarts) TOTP enrollment. The factor stays disabled until Confirm succeeds.
+func (s *MFAService) Enroll(user *models.User) (*MFAEnrollment, error) {
+	mfa, err := s.mfaRepo.FindByUserID(user.ID.String())
+	if err == nil && mfa.Enabled {
+		return nil, apperrors.ErrMFAAlreadyEnabled
+	}
+	if err != nil {
+		mfa = &models.UserMFA{UserID: user.ID}
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		return nil, err
+	}
+
+	encrypted, err := s.encryptor.Encrypt([]byte(secret))
+	if err != nil {
+		return nil, err
+	}
+
+	mfa.SecretEncrypted = encrypted
+	mfa.LastUsedStep = 0
+	if err := s.mfaRepo.Save(mfa); err != nil {
+		return nil, err
+	}
+
+	return &MFAEnrollment{
+		Secret:     secret,
+		OTPAuthURL: totp.URL(s.issuer, user.Email, secret),
+	}, nil
+}
+
+// Confirm enables MFA once the user proves possession of the secret, and returns
+// the recovery codes. The plain codes are only ever available from this call.
+func (s *MFAService) Confirm(userID, code string) ([]string, error) {
+	mfa, err := s.mfaRepo.FindByUserID(userID)
+	if err != nil {
+		return nil, apperrors.ErrMFANotEnrolled
+	}
+	if mfa.Enabled {
+		return nil, apperrors.ErrMFAAlreadyEnabled
+	}
+
+	secret, err := s.encryptor.Decrypt(mfa.SecretEncrypted)
+	if err != nil {
+		return nil, err
+	}
+
+	step, ok := totp.Validate(string(secret), code, time.Now(), totpSkew)
+	if !ok {
+		return nil, apperrors.ErrInvalidMFACode
+	}
+
+	codes := make([]string, recoveryCodeCount)
+	records := make([]models.MFARecoveryCode, recoveryCodeCount)
+	for i := range codes {
+		codes[i], err = generateRecoveryCode()
+		if err != nil {
+			return nil, err
+		}
+		records[i] = models.MFARecoveryCode{
+			UserID:   mfa.UserID,
+			CodeHash: hashRecoveryCode(codes[i]),
+		}
+	}
+
+	now := time.Now()
+	mfa.Enabled = true
+	mfa.ConfirmedAt = &now
+	mfa.LastUsedStep = step
+	if err := s.mfaRepo.Enable(mfa, records); err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// Disable removes the factor and all recovery codes after verifying a current code.
+func (s *MFAService) Disable(userID, code string) error {
+	if err := s.Verify(userID, code); err != nil {
+		return err
+	}
+	return s.mfaRepo.Delete(userID)
+}
+
+// Verify accepts either a current TOTP code or an unused recovery code.
+// TOTP codes are single-use: a code for an already used time step is rejected.
+func (s *MFAService) Verify(userID, code string) error {
+	mfa, err := s.mfaRepo.FindByUserID(userID)
+	if err != nil || !mfa.Enabled {
+		return apperrors.ErrMFANotEnabled
+	}
+
+	code = strings.TrimSpace(code)
+
+	secret, err := s.encryptor.Decrypt(mfa.SecretEncrypted)
+	if err != nil {
+		return err
+	}
+
+	if step, ok := totp.Validate(string(secret), code, time.Now(), totpSkew); ok {
+		updated, err := s.mfaRepo.UpdateLastUsedStep(userID, step)
+		if err != nil {
+			return err
+		}
+		if !updated {
+			return apperrors.ErrInvalidMFACode
+		}
+		return nil
+	}
+
+	used, err := s.mfaRepo.UseRecoveryCode(userID, hashRecoveryCode(code))
+	if err != nil {
+		return err
+	}
+	if !used {
+		return apperrors.ErrInvalidMFACode
+	}
+
+	return nil
+}
+
+func generateRecoveryCode() (string, error) {
+	b := make([]byte, 10)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	code := strings.ToLower(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(b))
+	return code[:8] + "-" + code[8:16], nil
+}
+
+// hashRecoveryCode hashes the canonical form of a recovery code, so it matches
+// however the user separates the two halves. Separators are dropped and the
+// hyphen put back where generateRecoveryCode places it, which keeps the hashes
+// of codes already stored valid.
+func hashRecoveryCode(code string) string {
+	normalized := strings.Map(func(r rune) rune {
+		// The base32 alphabet has no separators to lose
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, code)
+	if len(normalized) == 16 {
+		normalized = normalized[:8] + "-" + normalized[8:]
+	}
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,30 @@
+package service
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHashRecoveryCodeNormalizes(t *testing.T) {
+	code, err := generateRecoveryCode()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := hashRecoveryCode(code)
+
+	for _, typed := range []string{
+		code[:8] + code[9:],
+		code[:8] + " " + code[9:],
+		code[:8] + "_" + code[9:],
+		" " + code[:4] + " " + code[4:] + "\t",
+		strings.ToUpper(code),
+	} {
+		if got := hashRecoveryCode(typed); got != want {
+			t.Errorf("%q does not match %q", typed, code)
+		}
+	}
+
+	if hashRecoveryCode(code[:8]+code[10:]) == want {
+		t.Error("a code missing a character matches")
+	}
+}
@@ -0,0 +1,137 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"rest-api/internal/models"
+	"rest-api/internal/repository"
+	"rest-api/pkg/email"
+)
+
+// errAlreadySent rolls back a transaction that found its email already queued.
+var errAlreadySent = errors.New("email already queued")
+
+// NotificationEvents lists the events NotificationService emails users about.
+var NotificationEvents = []string{
+	EventPasswordResetRequested,
+	EventMagicLinkRequested,
+	EventEmailVerificationRequested,
+	EventAccountUnlockRequested,
+	EventInvitationCreated,
+}
+
+// notificationEmails maps events carrying a UserTokenEvent to the template
+// they are emailed with and the frontend path their link points at.
+var notificationEmails = map[string]struct{ template, path string }{
+	EventPasswordResetRequested:     {"password_reset", "/reset-password"},
+	EventMagicLinkRequested:         {"magic_link", "/magic-login"},
+	EventEmailVerificationRequested: {"verify_email", "/verify-email"},
+	EventAccountUnlockRequested:     {"account_unlock", "/unlock-account"},
+}
+
+// NotificationService emails users links carrying the tokens that events
+// refer to, rendered from templates in the recipient's language. Links point
+// at the frontend under baseURL.
+type NotificationService struct {
+	emailQueue *EmailQueue
+	tokenRepo  repository.TokenRepository
+	transactor repository.Transactor
+	templates  *email.Templates
+	baseURL    string
+}
+
+func NewNotificationService(emailQueue *EmailQueue, tokenRepo repository.TokenRepository, transactor repository.Transactor, templates *email.Templates, baseURL string) *NotificationService {
+	return &NotificationService{
+		emailQueue: emailQueue,
+		tokenRepo:  tokenRepo,
+		transactor: transactor,
+		templates:  templates,
+		baseURL:    baseURL,
+	}
+}
+
+// HandleEvent queues the email for one of NotificationEvents. The dedup key
+// doubles as the idempotency key, so an event handled twice is emailed once.
+func (s *NotificationService) HandleEvent(ctx context.Context, event *Event, dedupKey string) error {
+	if event.Type == EventInvitationCreated {
+		var invitation InvitationEvent
+		if err := event.Decode(&invitation); err != nil {
+			return err
+		}
+		return s.sendInvitation(dedupKey, invitation)
+	}
+
+	notification, ok := notificationEmails[event.Type]
+	if !ok {
+		return nil
+	}
+	var data UserTokenEvent
+	if err := event.Decode(&data); err != nil {
+		return err
+	}
+	token, err := s.tokenRepo.FindByID(data.TokenID)
+	if errors.Is(err, repository.ErrNotFound) {
+		// Used or expired before it could be emailed, so there is nothing to send
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	message, err := s.templates.Render(notification.template, data.Language, map[string]interface{}{
+		"Name": data.User.Name,
+		"Link": fmt.Sprintf("%s%s?token=%s", s.baseURL, notification.path, token.Token),
+	})
+	if err != nil {
+		return err
+	}
+	message.To = data.User.Email
+	return s.emailQueue.Enqueue(dedupKey, message, &token.ExpiresAt)
+}
+
+// sendInvitation issues the invitation's token and emails the invitee a link to
+// accept it. The token is stored with the email in one transaction, so handling
+// the event again neither sends a second email nor replaces the token sent.
+func (s *NotificationService) sendInvitation(dedupKey string, invitation InvitationEvent) error {
+	token, err := randomToken()
+	if err != nil {
+		return err
+	}
+	message, err := s.templates.Render("invitation", invitation.Language, map[string]interface{}{
+		"InvitedBy":    invitation.InvitedBy,
+		"Organization": invitation.Organization,
+		"Role":         invitation.Role,
+		"Link":         fmt.Sprintf("%s/accept-invitation?token=%s", s.baseURL, token),
+	})
+	if err != nil {
+		return err
+	}
+	message.To = invitation.Email
+
+	err = s.transactor.Transaction(func(tx repository.Repositories) error {
+		added, err := tx.Emails.Enqueue(queuedMessage(dedupKey, message, &invitation.ExpiresAt))
+		if err != nil {
+			return err
+		}
+		if !added {
+			return errAlreadySent
+		}
+		return tx.Organizations.SetInvitationToken(invitation.InvitationID, hashToken(token))
+	})
+	// Already sent, or revoked or accepted since it was created
+	if errors.Is(err, errAlreadySent) || errors.Is(err, repository.ErrNotFound) {
+		return nil
+	}
+	return err
+}
+
+// preferredLanguage returns the languages to email a user in: their stored
+// locale, or else the Accept-Language of the request that triggered the email.
+func preferredLanguage(user *models.User, client ClientInfo) string {
+	if user.Locale != "" {
+		return user.Locale
+	}
+	return client.Language
+}
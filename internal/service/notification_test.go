@@ -0,0 +1,108 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"rest-api/internal/models"
+	"rest-api/internal/repository"
+	"rest-api/pkg/email"
+	"rest-api/pkg/logger"
+
+	"github.com/google/uuid"
+)
+
+func newTestNotificationService(t *testing.T, repos repository.Repositories) *NotificationService {
+	t.Helper()
+	templates, err := email.LoadTemplates(email.TemplateConfig{DefaultLocale: "en"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	queue := NewEmailQueue(repos.Emails, nil, EmailQueueConfig{}, logger.NewLogger())
+	return NewNotificationService(queue, repos.Tokens, &fakeTransactor{repos: repos}, templates, "https://app.example.com")
+}
+
+// outboxEvent returns the last event published to outbox as a subscriber sees it.
+func outboxEvent(t *testing.T, outbox *fakeOutboxRepo, eventType string) *Event {
+	t.Helper()
+	for i := len(outbox.events) - 1; i >= 0; i-- {
+		if record := outbox.events[i]; record.Type == eventType {
+			return &Event{ID: record.ID, Type: record.Type, Payload: []byte(record.Payload)}
+		}
+	}
+	t.Fatalf("no %s event was published", eventType)
+	return nil
+}
+
+func TestUserTokenEventsCarryNoToken(t *testing.T) {
+	user := &models.User{ID: uuid.New(), Email: "user@example.com", Name: "User"}
+	tokens := newFakeTokenRepo()
+	outbox := &fakeOutboxRepo{}
+	emails := &fakeEmailRepo{}
+	repos := repository.Repositories{Tokens: tokens, Outbox: outbox, Emails: emails}
+	auth := NewAuthService(nil, nil, nil, nil, nil, nil, nil, &fakeTransactor{repos: repos}, nil, AuthConfig{})
+	notifications := newTestNotificationService(t, repos)
+
+	if err := auth.RequestMagicLink(user, ClientInfo{}); err != nil {
+		t.Fatal(err)
+	}
+	event := outboxEvent(t, outbox, EventMagicLinkRequested)
+	var token string
+	for issued := range tokens.tokens {
+		token = issued
+	}
+	if strings.Contains(string(event.Payload), token) {
+		t.Fatalf("payload holds the token: %s", event.Payload)
+	}
+
+	if err := notifications.HandleEvent(context.Background(), event, "first"); err != nil {
+		t.Fatal(err)
+	}
+	if len(emails.messages) != 1 || !strings.Contains(emails.messages[0].TextBody, "/magic-login?token="+token) {
+		t.Fatalf("queued %d emails, want one with the link", len(emails.messages))
+	}
+	if expiresAt := emails.messages[0].ExpiresAt; expiresAt == nil || !expiresAt.Equal(tokens.tokens[token].ExpiresAt) {
+		t.Errorf("message expires at %v, want the token's expiry", expiresAt)
+	}
+
+	// A token used before its email went out is not sent
+	if err := tokens.InvalidateToken(token); err != nil {
+		t.Fatal(err)
+	}
+	if err := notifications.HandleEvent(context.Background(), event, "second"); err != nil {
+		t.Fatal(err)
+	}
+	if len(emails.messages) != 1 {
+		t.Errorf("queued %d emails, want the used token not to be sent", len(emails.messages))
+	}
+}
+
+func TestInvitationEmailIssuesTokenOnce(t *testing.T) {
+	invitationID := uuid.NewString()
+	orgs := &fakeOrganizationRepo{tokenHashes: map[string]string{invitationID: ""}}
+	emails := &fakeEmailRepo{}
+	notifications := newTestNotificationService(t, repository.Repositories{Organizations: orgs, Emails: emails})
+
+	event := &Event{ID: uuid.New(), Type: EventInvitationCreated}
+	event.Payload = []byte(`{"invitation_id":"` + invitationID + `","email":"invitee@example.com","organization":"Acme","role":"member"}`)
+
+	// Handling the event again must not replace the token already emailed
+	for i := 0; i < 2; i++ {
+		if err := notifications.HandleEvent(context.Background(), event, "dedup"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(emails.messages) != 1 {
+		t.Fatalf("queued %d emails, want 1", len(emails.messages))
+	}
+	_, link, ok := strings.Cut(emails.messages[0].TextBody, "/accept-invitation?token=")
+	if !ok {
+		t.Fatalf("no link in %q", emails.messages[0].TextBody)
+	}
+	token := strings.Fields(link)[0]
+	if orgs.tokenHashes[invitationID] != hashToken(token) {
+		t.Error("the stored hash is not the hash of the emailed token")
+	}
+}
@@ -1,77 +1,193 @@
 package service
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
-	"io"
 	"net/http"
+	"sync"
+	"time"
 
+	apperrors "rest-api/internal/errors"
 	"rest-api/internal/models"
+	"rest-api/internal/repository"
+	"rest-api/pkg/encryption"
+	"rest-api/pkg/oidc"
 
+	"github.com/google/uuid"
 	"golang.org/x/oauth2"
-	"golang.org/x/oauth2/google"
 )
 
-type GoogleUserInfo struct {
-	ID            string `json:"id"`
-	Email         string `json:"email"`
-	VerifiedEmail bool   `json:"verified_email"`
-	Name          string `json:"name"`
-	Picture       string `json:"picture"`
+// OAuthProviderConfig configures an OpenID Connect identity provider.
+type OAuthProviderConfig struct {
+	Name         string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+}
+
+// ExternalIdentity is a user identity asserted by an identity provider.
+type ExternalIdentity struct {
+	Provider      string
+	Subject       string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+// oauthProvider is a configured provider whose discovery document is fetched
+// on first use, so an unreachable provider does not prevent startup.
+type oauthProvider struct {
+	config OAuthProviderConfig
+
+	mu       sync.Mutex
+	oidc     *oidc.Provider
+	oauth2   *oauth2.Config
+	verifier *oidc.Verifier
+}
+
+// OAuthConfig configures the identity provider registry.
+type OAuthConfig struct {
+	Providers []OAuthProviderConfig
+	// HTTPClient is used for discovery, JWKS, token and userinfo requests;
+	// nil means http.DefaultClient. Tests point it at a mock provider.
+	HTTPClient *http.Client
+	// AllowedRedirects lists the post-login redirect targets clients may request.
+	AllowedRedirects []string
+	StateTTL         time.Duration
+	// DisableSignup stops sign-ins from creating accounts, for invite-only registration
+	DisableSignup bool
 }
 
 type OAuthService struct {
-	config  *oauth2.Config
-	userSvc *UserService
-	authSvc *AuthService
-}
-
-func NewOAuthService(clientID, clientSecret, redirectURL string, userSvc *UserService, authSvc *AuthService) *OAuthService {
-	config := &oauth2.Config{
-		ClientID:     clientID,
-		ClientSecret: clientSecret,
-		RedirectURL:  redirectURL,
-		Scopes: []string{
-			"https://www.googleapis.com/auth/userinfo.email",
-			"https://www.googleapis.com/auth/userinfo.profile",
-		},
-		Endpoint: google.Endpoint,
+	providers      map[string]*oauthProvider
+	config         OAuthConfig
+	httpClient     *http.Client
+	stateEncryptor *encryption.Encryptor
+	identityRepo   repository.IdentityRepository
+	userSvc        *UserService
+	authSvc        *AuthService
+	auditSvc       *AuditService
+}
+
+// NewOAuthService builds the provider registry. stateEncryptor seals the state
+// records carried through the providers.
+func NewOAuthService(config OAuthConfig, stateEncryptor *encryption.Encryptor, identityRepo repository.IdentityRepository, userSvc *UserService, authSvc *AuthService, auditSvc *AuditService) *OAuthService {
+	httpClient := config.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	if config.StateTTL <= 0 {
+		config.StateTTL = defaultOAuthStateTTL
+	}
+
+	registry := make(map[string]*oauthProvider, len(config.Providers))
+	for _, p := range config.Providers {
+		registry[p.Name] = &oauthProvider{config: p}
 	}
 
 	return &OAuthService{
-		config:  config,
-		userSvc: userSvc,
-		authSvc: authSvc,
+		providers:      registry,
+		config:         config,
+		httpClient:     httpClient,
+		stateEncryptor: stateEncryptor,
+		identityRepo:   identityRepo,
+		userSvc:        userSvc,
+		authSvc:        authSvc,
+		auditSvc:       auditSvc,
 	}
 }
 
-func (s *OAuthService) GetAuthURL(state string) string {
-	return s.config.AuthCodeURL(state)
+// provider returns a discovered provider by name.
+func (s *OAuthService) provider(ctx context.Context, name string) (*oauthProvider, error) {
+	p, ok := s.providers[name]
+	if !ok {
+		return nil, apperrors.ErrProviderNotFound
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.oidc == nil {
+		discovered, err := oidc.Discover(ctx, s.httpClient, p.config.Issuer)
+		if err != nil {
+			return nil, err
+		}
+		p.oidc = discovered
+		p.oauth2 = &oauth2.Config{
+			ClientID:     p.config.ClientID,
+			ClientSecret: p.config.ClientSecret,
+			RedirectURL:  p.config.RedirectURL,
+			Scopes:       p.config.Scopes,
+			Endpoint:     discovered.Endpoint(),
+		}
+		p.verifier = discovered.Verifier(p.config.ClientID)
+	}
+
+	return p, nil
 }
 
-func (s *OAuthService) HandleCallback(code string) (*models.Session, error) {
-	token, err := s.config.Exchange(oauth2.NoContext, code)
+// BeginAuth starts an authorization code flow with PKCE (S256) and an OIDC nonce.
+// It returns the provider's authorization URL and a binding value that the
+// caller must hand to the browser and present again to ResumeAuth.
+func (s *OAuthService) BeginAuth(ctx context.Context, providerName string, req OAuthRequest) (string, string, error) {
+	p, err := s.provider(ctx, providerName)
 	if err != nil {
-		return nil, fmt.Errorf("code exchange failed: %w", err)
+		return "", "", err
+	}
+
+	if req.RedirectTo != "" && !s.redirectAllowed(req.RedirectTo) {
+		return "", "", apperrors.ErrInvalidRedirect
 	}
 
-	userInfo, err := s.getUserInfo(token.AccessToken)
+	binding, bindingHash, err := newBinding()
 	if err != nil {
-		return nil, fmt.Errorf("failed to get user info: %w", err)
+		return "", "", err
 	}
 
-	// Check if user exists
-	user, err := s.userSvc.FindByEmail(userInfo.Email)
+	st := &OAuthState{
+		Provider:     providerName,
+		Nonce:        oauth2.GenerateVerifier(),
+		CodeVerifier: oauth2.GenerateVerifier(),
+		RedirectTo:   req.RedirectTo,
+		LinkUserID:   req.LinkUserID,
+		BindingHash:  bindingHash,
+		ExpiresAt:    time.Now().Add(s.config.StateTTL),
+	}
+	state, err := s.sealState(st)
 	if err != nil {
-		// Create new user if not exists
-		user, err = s.userSvc.CreateGoogleUser(userInfo.Email, userInfo.Name)
-		if err != nil {
-			return nil, fmt.Errorf("failed to create user: %w", err)
+		return "", "", err
+	}
+
+	authURL := p.oauth2.AuthCodeURL(state,
+		oauth2.S256ChallengeOption(st.CodeVerifier),
+		oauth2.SetAuthURLParam("nonce", st.Nonce),
+	)
+	return authURL, binding, nil
+}
+
+// HandleCallback exchanges an authorization code, verifies the returned ID token
+// and signs in the user the external identity belongs to.
+func (s *OAuthService) HandleCallback(ctx context.Context, st *OAuthState, code string, client ClientInfo) (*models.Session, error) {
+	identity, err := s.exchange(ctx, st, code)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.resolveUser(identity, client)
+	if err != nil {
+		// Attempts that never matched an account have no one to attribute them to
+		if errors.Is(err, apperrors.ErrLinkRequired) || errors.Is(err, apperrors.ErrInvitationRequired) {
+			s.auditSvc.Log(oauthLoginEvent(nil, identity, client, err))
 		}
+		return nil, err
 	}
 
 	// Create session
-	session, err := s.authSvc.CreateSession(user, "Google OAuth")
+	session, err := s.authSvc.CreateSession(user, client)
+	s.auditSvc.Log(oauthLoginEvent(&user.ID, identity, client, err))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create session: %w", err)
 	}
@@ -79,22 +195,225 @@ func (s *OAuthService) HandleCallback(code string) (*models.Session, error) {
 	return session, nil
 }
 
-func (s *OAuthService) getUserInfo(accessToken string) (*GoogleUserInfo, error) {
-	resp, err := http.Get("https://www.googleapis.com/oauth2/v2/userinfo?access_token=" + accessToken)
+// oauthLoginEvent describes a sign-in with an external identity.
+func oauthLoginEvent(userID *uuid.UUID, identity *ExternalIdentity, client ClientInfo, err error) *models.AuditEvent {
+	event := LoginEvent(userID, "oauth", client, err)
+	event.Metadata["provider"] = identity.Provider
+	event.Metadata["email"] = identity.Email
+	return event
+}
+
+// identityLinkedEvent describes an external identity being attached to a user.
+func identityLinkedEvent(link *models.UserIdentity, client ClientInfo) *models.AuditEvent {
+	return &models.AuditEvent{
+		Type:      models.AuditIdentityLinked,
+		ActorID:   &link.UserID,
+		TargetID:  &link.UserID,
+		IPAddress: client.IPAddress,
+		UserAgent: client.UserAgent,
+		Metadata: map[string]interface{}{
+			"identity_id": link.ID.String(),
+			"provider":    link.Provider,
+			"email":       link.Email,
+		},
+	}
+}
+
+// resolveUser finds or creates the local user for an external identity:
+//   - an identity that is already linked signs in its user;
+//   - an unlinked identity is attached to the local account with the same email
+//     only if both the provider and the local account have verified that address,
+//     otherwise the user must sign in and link it explicitly (ErrLinkRequired);
+//   - with no matching account a new user is created with the identity linked,
+//     unless sign-up is disabled (ErrInvitationRequired).
+func (s *OAuthService) resolveUser(identity *ExternalIdentity, client ClientInfo) (*models.User, error) {
+	now := time.Now()
+
+	linked, err := s.identityRepo.FindByProviderSubject(identity.Provider, identity.Subject)
+	if err == nil {
+		if err := s.identityRepo.RecordLogin(linked.ID.String(), identity.Email, now); err != nil {
+			return nil, err
+		}
+		return s.userSvc.GetByID(linked.UserID.String())
+	}
+	if !errors.Is(err, repository.ErrNotFound) {
+		return nil, err
+	}
+
+	link := &models.UserIdentity{
+		Provider:    identity.Provider,
+		Subject:     identity.Subject,
+		Email:       identity.Email,
+		LinkedAt:    now,
+		LastLoginAt: &now,
+	}
+
+	if existing, err := s.userSvc.FindByEmail(identity.Email); err == nil {
+		if !identity.EmailVerified || existing.EmailVerifiedAt == nil {
+			return nil, apperrors.ErrLinkRequired
+		}
+		link.UserID = existing.ID
+		if err := s.identityRepo.Create(link); err != nil {
+			return nil, err
+		}
+		s.auditSvc.Log(identityLinkedEvent(link, client))
+		return existing, nil
+	}
+
+	if s.config.DisableSignup {
+		return nil, apperrors.ErrInvitationRequired
+	}
+
+	user := &models.User{
+		Email: identity.Email,
+		Name:  identity.Name,
+	}
+	if identity.EmailVerified {
+		user.EmailVerifiedAt = &now
+	}
+	if err := s.userSvc.RegisterWithIdentity(user, link); err != nil {
+		return nil, fmt.Errorf("failed to create user: %w", err)
+	}
+
+	s.auditSvc.Log(&models.AuditEvent{
+		Type:      models.AuditUserRegistered,
+		ActorID:   &user.ID,
+		TargetID:  &user.ID,
+		IPAddress: client.IPAddress,
+		UserAgent: client.UserAgent,
+		Metadata:  map[string]interface{}{"method": "oauth", "provider": identity.Provider},
+	})
+
+	return user, nil
+}
+
+// LinkIdentity completes an authorization started by a signed-in user and links
+// the resulting external identity to their account.
+func (s *OAuthService) LinkIdentity(ctx context.Context, st *OAuthState, code string, client ClientInfo) (*models.UserIdentity, error) {
+	userID := st.LinkUserID
+	identity, err := s.exchange(ctx, st, code)
+	if err != nil {
+		return nil, err
+	}
+
+	existing, err := s.identityRepo.FindByProviderSubject(identity.Provider, identity.Subject)
+	if err == nil {
+		if existing.UserID.String() != userID {
+			return nil, apperrors.ErrIdentityInUse
+		}
+		return existing, nil
+	}
+	if !errors.Is(err, repository.ErrNotFound) {
+		return nil, err
+	}
+
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, apperrors.ErrUserNotFound
+	}
+
+	link := &models.UserIdentity{
+		UserID:   uid,
+		Provider: identity.Provider,
+		Subject:  identity.Subject,
+		Email:    identity.Email,
+		LinkedAt: time.Now(),
+	}
+	if err := s.identityRepo.Create(link); err != nil {
+		return nil, err
+	}
+
+	s.auditSvc.Log(identityLinkedEvent(link, client))
+
+	return link, nil
+}
+
+// ListIdentities returns the external identities linked to the user.
+func (s *OAuthService) ListIdentities(userID string) ([]models.UserIdentity, error) {
+	return s.identityRepo.FindByUserID(userID)
+}
+
+// UnlinkIdentity removes a linked identity unless it is the user's only way to
+// sign in (no password and no other identity).
+func (s *OAuthService) UnlinkIdentity(user *models.User, identityID string) error {
+	identities, err := s.identityRepo.FindByUserID(user.ID.String())
 	if err != nil {
-		return nil, fmt.Errorf("failed getting user info: %w", err)
+		return err
 	}
-	defer resp.Body.Close()
 
-	body, err := io.ReadAll(resp.Body)
+	found := false
+	for _, identity := range identities {
+		if identity.ID.String() == identityID {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return apperrors.ErrIdentityNotFound
+	}
+	if user.PasswordHash == "" && len(identities) == 1 {
+		return apperrors.ErrLastLoginMethod
+	}
+
+	if err := s.identityRepo.Delete(user.ID.String(), identityID); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return apperrors.ErrIdentityNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// exchange redeems the authorization code and returns the identity from the verified ID token.
+func (s *OAuthService) exchange(ctx context.Context, st *OAuthState, code string) (*ExternalIdentity, error) {
+	p, err := s.provider(ctx, st.Provider)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, s.httpClient)
+	token, err := p.oauth2.Exchange(ctx, code, oauth2.VerifierOption(st.CodeVerifier))
+	if err != nil {
+		return nil, fmt.Errorf("code exchange failed: %w", err)
+	}
+
+	rawIDToken, ok := token.Extra("id_token").(string)
+	if !ok || rawIDToken == "" {
+		return nil, errors.New("token response did not include an id_token")
+	}
+
+	idToken, err := p.verifier.Verify(ctx, rawIDToken, st.Nonce)
 	if err != nil {
-		return nil, fmt.Errorf("failed reading response body: %w", err)
+		return nil, fmt.Errorf("%w: %v", apperrors.ErrInvalidToken, err)
+	}
+
+	identity := &ExternalIdentity{
+		Provider:      st.Provider,
+		Subject:       idToken.Subject,
+		Email:         idToken.Email,
+		EmailVerified: idToken.EmailVerified,
+		Name:          idToken.Name,
+	}
+
+	// Some providers only release profile claims through the userinfo endpoint
+	if identity.Email == "" && p.oidc.UserinfoEndpoint != "" {
+		info, err := p.oidc.UserInfo(ctx, token.AccessToken)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get user info: %w", err)
+		}
+		if info.Subject != idToken.Subject {
+			return nil, errors.New("userinfo subject does not match id token")
+		}
+		identity.Email = info.Email
+		identity.EmailVerified = bool(info.EmailVerified)
+		if identity.Name == "" {
+			identity.Name = info.Name
+		}
 	}
 
-	var userInfo GoogleUserInfo
-	if err := json.Unmarshal(body, &userInfo); err != nil {
-		return nil, fmt.Errorf("failed parsing user info: %w", err)
+	if identity.Email == "" {
+		return nil, errors.New("identity provider did not return an email address")
 	}
 
-	return &userInfo, nil
+	return identity, nil
 }
@@ -0,0 +1,796 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	apperrors "rest-api/internal/errors"
+	"rest-api/internal/models"
+	"rest-api/internal/repository"
+	"rest-api/pkg/logger"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// Scopes understood by the authorization server.
+const (
+	ScopeOpenID        = "openid"
+	ScopeProfile       = "profile"
+	ScopeEmail         = "email"
+	ScopeOfflineAccess = "offline_access"
+)
+
+// Grant types supported at the token endpoint.
+const (
+	GrantTypeAuthorizationCode = "authorization_code"
+	GrantTypeRefreshToken      = "refresh_token"
+	GrantTypeClientCredentials = "client_credentials"
+)
+
+// accessTokenType is the JWT typ of access tokens (RFC 9068), which keeps them
+// from being mistaken for first-party session access tokens.
+const accessTokenType = "at+jwt"
+
+// usedCodeRetention is how long used authorization codes are kept after they
+// expire, during which presenting one again still revokes its tokens.
+const usedCodeRetention = 24 * time.Hour
+
+var (
+	SupportedScopes     = []string{ScopeOpenID, ScopeProfile, ScopeEmail, ScopeOfflineAccess}
+	SupportedGrantTypes = []string{GrantTypeAuthorizationCode, GrantTypeRefreshToken, GrantTypeClientCredentials}
+)
+
+// OAuthServerConfig controls the tokens issued to registered clients.
+type OAuthServerConfig struct {
+	Issuer          string
+	AccessTokenTTL  time.Duration
+	RefreshTokenTTL time.Duration
+	IDTokenTTL      time.Duration
+	CodeTTL         time.Duration
+}
+
+// OAuthError is a protocol error returned to OAuth clients (RFC 6749 section 5.2).
+// When RedirectURI is set the error must be delivered to the client by redirect;
+// otherwise the client or redirect URI could not be trusted and it is shown directly.
+type OAuthError struct {
+	Code        string
+	Description string
+	RedirectURI string
+	State       string
+}
+
+func (e *OAuthError) Error() string {
+	return e.Code + ": " + e.Description
+}
+
+func oauthError(code, description string) *OAuthError {
+	return &OAuthError{Code: code, Description: description}
+}
+
+// AuthorizationRequest holds the parameters of an authorization request.
+type AuthorizationRequest struct {
+	ClientID            string
+	RedirectURI         string
+	ResponseType        string
+	Scope               string
+	State               string
+	Nonce               string
+	CodeChallenge       string
+	CodeChallengeMethod string
+}
+
+// Authorization is a validated authorization request awaiting the user's decision.
+type Authorization struct {
+	Request AuthorizationRequest
+	Client  *models.OAuthClient
+	Scopes  []string
+}
+
+// TokenRequest holds the parameters of a token request.
+type TokenRequest struct {
+	GrantType    string
+	ClientID     string
+	ClientSecret string
+	Code         string
+	RedirectURI  string
+	CodeVerifier string
+	RefreshToken string
+	Scope        string
+}
+
+// OAuthTokenResponse is the token endpoint response.
+type OAuthTokenResponse struct {
+	AccessToken  string `json:"access_token"`
+	TokenType    string `json:"token_type"`
+	ExpiresIn    int    `json:"expires_in"`
+	RefreshToken string `json:"refresh_token,omitempty"`
+	IDToken      string `json:"id_token,omitempty"`
+	Scope        string `json:"scope,omitempty"`
+}
+
+// OAuthClientRegistration describes a client to register.
+type OAuthClientRegistration struct {
+	Name         string
+	RedirectURIs []string
+	GrantTypes   []string
+	Scopes       []string
+	Public       bool
+	FirstParty   bool
+}
+
+// OAuthServerService lets registered applications sign users in with this
+// service acting as an OAuth 2.0 authorization server and OpenID provider.
+type OAuthServerService struct {
+	clientRepo repository.OAuthClientRepository
+	grantRepo  repository.OAuthGrantRepository
+	userSvc    *UserService
+	keySvc     *KeyService
+	config     OAuthServerConfig
+	logger     *logger.Logger
+}
+
+func NewOAuthServerService(clientRepo repository.OAuthClientRepository, grantRepo repository.OAuthGrantRepository, userSvc *UserService, keySvc *KeyService, config OAuthServerConfig, logger *logger.Logger) *OAuthServerService {
+	return &OAuthServerService{
+		clientRepo: clientRepo,
+		grantRepo:  grantRepo,
+		userSvc:    userSvc,
+		keySvc:     keySvc,
+		config:     config,
+		logger:     logger,
+	}
+}
+
+// Run periodically purges expired authorization codes until ctx is cancelled.
+func (s *OAuthServerService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.grantRepo.DeleteExpiredCodes(time.Now().Add(-usedCodeRetention)); err != nil {
+				s.logger.Error("failed to purge authorization codes", err)
+			}
+		}
+	}
+}
+
+// OpenIDConfiguration is the provider metadata published at
+// /.well-known/openid-configuration.
+type OpenIDConfiguration struct {
+	Issuer                            string   `json:"issuer"`
+	AuthorizationEndpoint             string   `json:"authorization_endpoint"`
+	TokenEndpoint                     string   `json:"token_endpoint"`
+	UserinfoEndpoint                  string   `json:"userinfo_endpoint"`
+	JWKSURI                           string   `json:"jwks_uri"`
+	ScopesSupported                   []string `json:"scopes_supported"`
+	ResponseTypesSupported            []string `json:"response_types_supported"`
+	GrantTypesSupported               []string `json:"grant_types_supported"`
+	SubjectTypesSupported             []string `json:"subject_types_supported"`
+	IDTokenSigningAlgValuesSupported  []string `json:"id_token_signing_alg_values_supported"`
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported"`
+	CodeChallengeMethodsSupported     []string `json:"code_challenge_methods_supported"`
+	ClaimsSupported                   []string `json:"claims_supported"`
+}
+
+// Configuration returns the provider metadata.
+func (s *OAuthServerService) Configuration() *OpenIDConfiguration {
+	return &OpenIDConfiguration{
+		Issuer:                            s.config.Issuer,
+		AuthorizationEndpoint:             s.config.Issuer + "/api/v1/oauth2/authorize",
+		TokenEndpoint:                     s.config.Issuer + "/api/v1/oauth2/token",
+		UserinfoEndpoint:                  s.config.Issuer + "/api/v1/oauth2/userinfo",
+		JWKSURI:                           s.config.Issuer + "/.well-known/jwks.json",
+		ScopesSupported:                   SupportedScopes,
+		ResponseTypesSupported:            []string{"code"},
+		GrantTypesSupported:               SupportedGrantTypes,
+		SubjectTypesSupported:             []string{"public"},
+		IDTokenSigningAlgValuesSupported:  []string{s.keySvc.config.Algorithm},
+		TokenEndpointAuthMethodsSupported: []string{"client_secret_basic", "client_secret_post", "none"},
+		CodeChallengeMethodsSupported:     []string{"S256"},
+		ClaimsSupported:                   []string{"sub", "iss", "aud", "exp", "iat", "auth_time", "nonce", "email", "email_verified", "name", "updated_at"},
+	}
+}
+
+// RegisterClient creates a client. The returned secret is empty for public
+// clients and is not stored in plaintext, so it can only be shown once.
+func (s *OAuthServerService) RegisterClient(reg OAuthClientRegistration, createdBy uuid.UUID) (*models.OAuthClient, string, error) {
+	if strings.TrimSpace(reg.Name) == "" || len(reg.GrantTypes) == 0 {
+		return nil, "", apperrors.ErrInvalidRequest
+	}
+	for _, grant := range reg.GrantTypes {
+		if !slices.Contains(SupportedGrantTypes, grant) {
+			return nil, "", apperrors.ErrInvalidRequest
+		}
+	}
+	if reg.Public && slices.Contains(reg.GrantTypes, GrantTypeClientCredentials) {
+		return nil, "", apperrors.ErrInvalidRequest
+	}
+	if slices.Contains(reg.GrantTypes, GrantTypeAuthorizationCode) && len(reg.RedirectURIs) == 0 {
+		return nil, "", apperrors.ErrInvalidRequest
+	}
+	for _, uri := range reg.RedirectURIs {
+		if !validRedirectURI(uri) {
+			return nil, "", apperrors.ErrInvalidRequest
+		}
+	}
+	scopes := reg.Scopes
+	if len(scopes) == 0 {
+		scopes = SupportedScopes
+	}
+	for _, scope := range scopes {
+		if !slices.Contains(SupportedScopes, scope) {
+			return nil, "", apperrors.ErrInvalidRequest
+		}
+	}
+
+	client := &models.OAuthClient{
+		Name:         reg.Name,
+		RedirectURIs: strings.Join(reg.RedirectURIs, ","),
+		GrantTypes:   strings.Join(reg.GrantTypes, ","),
+		Scopes:       strings.Join(scopes, ","),
+		FirstParty:   reg.FirstParty,
+		CreatedBy:    &createdBy,
+	}
+
+	var secret string
+	if !reg.Public {
+		var err error
+		if secret, err = randomToken(); err != nil {
+			return nil, "", err
+		}
+		client.SecretHash = hashToken(secret)
+	}
+
+	if err := s.clientRepo.Create(client); err != nil {
+		return nil, "", err
+	}
+	return client, secret, nil
+}
+
+// blockedRedirectSchemes can run script or read local data in the browser,
+// whatever else a redirect URI looks like.
+var blockedRedirectSchemes = []string{"javascript", "data", "file", "vbscript"}
+
+// validRedirectURI accepts absolute URIs without fragments or credentials
+// (RFC 6749 section 3.1.2, RFC 8252 section 7):
+//   - https with a host;
+//   - http with a loopback host, for native apps and local development;
+//   - private-use schemes in reverse domain name form such as
+//     com.example.app:/callback, for native apps.
+func validRedirectURI(uri string) bool {
+	u, err := url.Parse(uri)
+	if err != nil || u.Fragment != "" || u.User != nil || strings.Contains(uri, ",") {
+		return false
+	}
+	scheme := strings.ToLower(u.Scheme)
+	if slices.Contains(blockedRedirectSchemes, scheme) {
+		return false
+	}
+
+	switch scheme {
+	case "https":
+		return u.Host != "" && u.Opaque == ""
+	case "http":
+		host := u.Hostname()
+		return u.Opaque == "" && (host == "localhost" || host == "127.0.0.1" || host == "::1")
+	}
+	return reverseDomainScheme(scheme)
+}
+
+// reverseDomainScheme reports whether scheme has the reverse domain name form
+// RFC 8252 requires of private-use URI schemes, with at least two labels.
+func reverseDomainScheme(scheme string) bool {
+	labels := strings.Split(scheme, ".")
+	if len(labels) < 2 {
+		return false
+	}
+	for _, label := range labels {
+		if label == "" {
+			return false
+		}
+		for _, r := range label {
+			if (r < 'a' || r > 'z') && (r < '0' || r > '9') && r != '-' {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (s *OAuthServerService) ListClients() ([]models.OAuthClient, error) {
+	return s.clientRepo.List()
+}
+
+// DeleteClient removes a client and revokes everything issued to it.
+func (s *OAuthServerService) DeleteClient(id string) error {
+	if _, err := uuid.Parse(id); err != nil {
+		return apperrors.ErrClientNotFound
+	}
+	if err := s.clientRepo.Delete(id); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return apperrors.ErrClientNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *OAuthServerService) findClient(clientID string) (*models.OAuthClient, error) {
+	if _, err := uuid.Parse(clientID); err != nil {
+		return nil, repository.ErrNotFound
+	}
+	return s.clientRepo.FindByID(clientID)
+}
+
+// ValidateAuthorization checks an authorization request. Errors are *OAuthError;
+// they carry a RedirectURI only once the client and redirect URI are trusted.
+func (s *OAuthServerService) ValidateAuthorization(req AuthorizationRequest) (*Authorization, error) {
+	client, err := s.findClient(req.ClientID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, oauthError("invalid_client", "unknown client")
+		}
+		return nil, err
+	}
+	if req.RedirectURI == "" || !slices.Contains(splitList(client.RedirectURIs), req.RedirectURI) {
+		return nil, oauthError("invalid_request", "redirect_uri is not registered for this client")
+	}
+
+	fail := func(code, description string) error {
+		return &OAuthError{Code: code, Description: description, RedirectURI: req.RedirectURI, State: req.State}
+	}
+
+	if req.ResponseType != "code" {
+		return nil, fail("unsupported_response_type", "only the code response type is supported")
+	}
+	if !slices.Contains(splitList(client.GrantTypes), GrantTypeAuthorizationCode) {
+		return nil, fail("unauthorized_client", "client may not use the authorization code grant")
+	}
+
+	scopes := strings.Fields(req.Scope)
+	if len(scopes) == 0 {
+		return nil, fail("invalid_scope", "scope is required")
+	}
+	allowed := splitList(client.Scopes)
+	for _, scope := range scopes {
+		if !slices.Contains(allowed, scope) {
+			return nil, fail("invalid_scope", "scope "+scope+" is not allowed for this client")
+		}
+	}
+
+	if req.CodeChallenge == "" {
+		if client.IsPublic() {
+			return nil, fail("invalid_request", "public clients must use PKCE")
+		}
+	} else if req.CodeChallengeMethod != "S256" {
+		return nil, fail("invalid_request", "code_challenge_method must be S256")
+	}
+
+	return &Authorization{Request: req, Client: client, Scopes: scopes}, nil
+}
+
+// ConsentRequired reports whether the user must approve the requested scopes.
+// First-party clients and scopes granted before are approved implicitly.
+func (s *OAuthServerService) ConsentRequired(userID string, auth *Authorization) (bool, error) {
+	if auth.Client.FirstParty {
+		return false, nil
+	}
+
+	consent, err := s.grantRepo.FindConsent(userID, auth.Client.ID.String())
+	if errors.Is(err, repository.ErrNotFound) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	granted := splitList(consent.Scope)
+	for _, scope := range auth.Scopes {
+		if !slices.Contains(granted, scope) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Approve records the user's consent and returns the client redirect URL
+// carrying a new authorization code. authTime is when the user authenticated,
+// reported to clients as the ID token's auth_time.
+func (s *OAuthServerService) Approve(user *models.User, auth *Authorization, authTime time.Time) (string, error) {
+	if !auth.Client.FirstParty {
+		err := s.grantRepo.SaveConsent(&models.OAuthConsent{
+			UserID:   user.ID,
+			ClientID: auth.Client.ID,
+			Scope:    strings.Join(auth.Scopes, ","),
+		})
+		if err != nil {
+			return "", err
+		}
+	}
+
+	code, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+
+	now := time.Now()
+	err = s.grantRepo.CreateCode(&models.OAuthAuthorizationCode{
+		CodeHash:            hashToken(code),
+		ClientID:            auth.Client.ID,
+		UserID:              user.ID,
+		RedirectURI:         auth.Request.RedirectURI,
+		Scope:               strings.Join(auth.Scopes, " "),
+		Nonce:               auth.Request.Nonce,
+		CodeChallenge:       auth.Request.CodeChallenge,
+		CodeChallengeMethod: auth.Request.CodeChallengeMethod,
+		AuthTime:            authTime,
+		ExpiresAt:           now.Add(s.config.CodeTTL),
+		FamilyID:            uuid.New(),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return AuthorizationRedirect(auth.Request.RedirectURI, url.Values{"code": {code}}, auth.Request.State), nil
+}
+
+// AuthorizationRedirect appends response parameters and state to a client redirect URI.
+func AuthorizationRedirect(redirectURI string, params url.Values, state string) string {
+	u, _ := url.Parse(redirectURI)
+	query := u.Query()
+	for key, values := range params {
+		query[key] = values
+	}
+	if state != "" {
+		query.Set("state", state)
+	}
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// Token handles a token endpoint request. Errors are *OAuthError.
+func (s *OAuthServerService) Token(req TokenRequest) (*OAuthTokenResponse, error) {
+	client, err := s.authenticateClient(req.ClientID, req.ClientSecret)
+	if err != nil {
+		return nil, err
+	}
+	if !slices.Contains(splitList(client.GrantTypes), req.GrantType) {
+		if !slices.Contains(SupportedGrantTypes, req.GrantType) {
+			return nil, oauthError("unsupported_grant_type", "grant type is not supported")
+		}
+		return nil, oauthError("unauthorized_client", "client may not use this grant type")
+	}
+
+	switch req.GrantType {
+	case GrantTypeAuthorizationCode:
+		return s.exchangeCode(client, req)
+	case GrantTypeRefreshToken:
+		return s.refresh(client, req)
+	default:
+		return s.clientCredentials(client, req)
+	}
+}
+
+// authenticateClient checks client credentials. Public clients identify
+// themselves by client_id only.
+func (s *OAuthServerService) authenticateClient(clientID, secret string) (*models.OAuthClient, error) {
+	client, err := s.findClient(clientID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, oauthError("invalid_client", "client authentication failed")
+		}
+		return nil, err
+	}
+
+	if client.IsPublic() {
+		if secret != "" {
+			return nil, oauthError("invalid_client", "client authentication failed")
+		}
+		return client, nil
+	}
+
+	if subtle.ConstantTimeCompare([]byte(hashToken(secret)), []byte(client.SecretHash)) != 1 {
+		return nil, oauthError("invalid_client", "client authentication failed")
+	}
+	return client, nil
+}
+
+func (s *OAuthServerService) exchangeCode(client *models.OAuthClient, req TokenRequest) (*OAuthTokenResponse, error) {
+	codeHash := hashToken(req.Code)
+	code, err := s.grantRepo.FindCode(codeHash)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, oauthError("invalid_grant", "authorization code is invalid or expired")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Only the party the code was issued to may use it up, or revoke what it
+	// was exchanged for by presenting it again
+	if code.ClientID != client.ID || code.RedirectURI != req.RedirectURI {
+		return nil, oauthError("invalid_grant", "authorization code was issued to another client or redirect_uri")
+	}
+	if code.CodeChallenge != "" {
+		sum := sha256.Sum256([]byte(req.CodeVerifier))
+		challenge := base64.RawURLEncoding.EncodeToString(sum[:])
+		if req.CodeVerifier == "" || subtle.ConstantTimeCompare([]byte(challenge), []byte(code.CodeChallenge)) != 1 {
+			return nil, oauthError("invalid_grant", "code_verifier does not match the code challenge")
+		}
+	}
+
+	code, first, err := s.grantRepo.ConsumeCode(codeHash)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, oauthError("invalid_grant", "authorization code is invalid or expired")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !first {
+		// The code leaked; revoke what it was exchanged for (RFC 6749 section 4.1.2)
+		if err := s.grantRepo.RevokeRefreshFamily(code.FamilyID.String()); err != nil {
+			return nil, err
+		}
+		return nil, oauthError("invalid_grant", "authorization code was already used")
+	}
+
+	user, err := s.userSvc.GetByID(code.UserID.String())
+	if err != nil {
+		return nil, oauthError("invalid_grant", "user no longer exists")
+	}
+	if user.DisabledAt != nil {
+		return nil, oauthError("invalid_grant", "user is disabled")
+	}
+
+	return s.issueTokens(client, user, strings.Fields(code.Scope), code.Nonce, code.AuthTime, code.FamilyID)
+}
+
+func (s *OAuthServerService) refresh(client *models.OAuthClient, req TokenRequest) (*OAuthTokenResponse, error) {
+	token, err := s.grantRepo.FindRefreshToken(hashToken(req.RefreshToken))
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, oauthError("invalid_grant", "refresh token is invalid")
+	}
+	if err != nil {
+		return nil, err
+	}
+	if token.ClientID != client.ID || token.RevokedAt != nil || time.Now().After(token.ExpiresAt) {
+		return nil, oauthError("invalid_grant", "refresh token is invalid")
+	}
+
+	// A refresh may narrow but never widen the original grant. Check this
+	// before rotating so a rejected request does not use the token up
+	scopes := strings.Fields(token.Scope)
+	if req.Scope != "" {
+		requested := strings.Fields(req.Scope)
+		for _, scope := range requested {
+			if !slices.Contains(scopes, scope) {
+				return nil, oauthError("invalid_scope", "scope exceeds the original grant")
+			}
+		}
+		scopes = requested
+	}
+
+	rotated, err := s.grantRepo.MarkRefreshTokenRotated(token.ID.String())
+	if err != nil {
+		return nil, err
+	}
+	if !rotated {
+		// A rotated token was presented again: assume it was stolen and revoke the chain
+		if err := s.grantRepo.RevokeRefreshFamily(token.FamilyID.String()); err != nil {
+			return nil, err
+		}
+		return nil, oauthError("invalid_grant", "refresh token was already used")
+	}
+
+	user, err := s.userSvc.GetByID(token.UserID.String())
+	if err != nil {
+		return nil, oauthError("invalid_grant", "user no longer exists")
+	}
+	if user.DisabledAt != nil {
+		return nil, oauthError("invalid_grant", "user is disabled")
+	}
+
+	return s.issueTokens(client, user, scopes, "", token.AuthTime, token.FamilyID)
+}
+
+func (s *OAuthServerService) clientCredentials(client *models.OAuthClient, req TokenRequest) (*OAuthTokenResponse, error) {
+	if client.IsPublic() {
+		return nil, oauthError("unauthorized_client", "public clients may not use client credentials")
+	}
+
+	// User-facing scopes make no sense without a user
+	var scopes []string
+	allowed := splitList(client.Scopes)
+	for _, scope := range strings.Fields(req.Scope) {
+		if scope == ScopeOpenID || scope == ScopeOfflineAccess || !slices.Contains(allowed, scope) {
+			return nil, oauthError("invalid_scope", "scope "+scope+" is not allowed for client credentials")
+		}
+		scopes = append(scopes, scope)
+	}
+
+	accessToken, err := s.signAccessToken(client.ID.String(), client, scopes, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	return &OAuthTokenResponse{
+		AccessToken: accessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   int(s.config.AccessTokenTTL.Seconds()),
+		Scope:       strings.Join(scopes, " "),
+	}, nil
+}
+
+// issueTokens issues an access token, plus a refresh token for offline_access
+// and an ID token for openid requests.
+func (s *OAuthServerService) issueTokens(client *models.OAuthClient, user *models.User, scopes []string, nonce string, authTime time.Time, familyID uuid.UUID) (*OAuthTokenResponse, error) {
+	accessToken, err := s.signAccessToken(user.ID.String(), client, scopes, authTime)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &OAuthTokenResponse{
+		AccessToken: accessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   int(s.config.AccessTokenTTL.Seconds()),
+		Scope:       strings.Join(scopes, " "),
+	}
+
+	if slices.Contains(scopes, ScopeOfflineAccess) && slices.Contains(splitList(client.GrantTypes), GrantTypeRefreshToken) {
+		refreshToken, err := randomToken()
+		if err != nil {
+			return nil, err
+		}
+		err = s.grantRepo.CreateRefreshToken(&models.OAuthRefreshToken{
+			TokenHash: hashToken(refreshToken),
+			FamilyID:  familyID,
+			ClientID:  client.ID,
+			UserID:    user.ID,
+			Scope:     strings.Join(scopes, " "),
+			AuthTime:  authTime,
+			ExpiresAt: time.Now().Add(s.config.RefreshTokenTTL),
+		})
+		if err != nil {
+			return nil, err
+		}
+		resp.RefreshToken = refreshToken
+	}
+
+	if slices.Contains(scopes, ScopeOpenID) {
+		now := time.Now()
+		claims := jwt.MapClaims{
+			"iss":       s.config.Issuer,
+			"sub":       user.ID.String(),
+			"aud":       client.ID.String(),
+			"iat":       now.Unix(),
+			"exp":       now.Add(s.config.IDTokenTTL).Unix(),
+			"auth_time": authTime.Unix(),
+		}
+		if nonce != "" {
+			claims["nonce"] = nonce
+		}
+		for key, value := range userClaims(user, scopes) {
+			claims[key] = value
+		}
+
+		if resp.IDToken, err = s.sign(claims, "JWT"); err != nil {
+			return nil, err
+		}
+	}
+
+	return resp, nil
+}
+
+// signAccessToken issues a JWT access token (RFC 9068) for subject.
+func (s *OAuthServerService) signAccessToken(subject string, client *models.OAuthClient, scopes []string, authTime time.Time) (string, error) {
+	now := time.Now()
+	return s.sign(jwt.MapClaims{
+		"iss":       s.config.Issuer,
+		"sub":       subject,
+		"aud":       s.config.Issuer,
+		"client_id": client.ID.String(),
+		"scope":     strings.Join(scopes, " "),
+		"jti":       uuid.New().String(),
+		"iat":       now.Unix(),
+		"exp":       now.Add(s.config.AccessTokenTTL).Unix(),
+		"auth_time": authTime.Unix(),
+	}, accessTokenType)
+}
+
+func (s *OAuthServerService) sign(claims jwt.Claims, typ string) (string, error) {
+	kid, method, key, err := s.keySvc.SigningKey()
+	if err != nil {
+		return "", err
+	}
+
+	token := jwt.NewWithClaims(method, claims)
+	token.Header["kid"] = kid
+	token.Header["typ"] = typ
+	return token.SignedString(key)
+}
+
+// UserInfo returns the claims of the user an access token was issued for.
+func (s *OAuthServerService) UserInfo(accessToken string) (map[string]interface{}, error) {
+	claims := jwt.MapClaims{}
+	token, err := jwt.ParseWithClaims(accessToken, claims, func(token *jwt.Token) (interface{}, error) {
+		if typ, _ := token.Header["typ"].(string); typ != accessTokenType {
+			return nil, errors.New("not an access token")
+		}
+		kid, _ := token.Header["kid"].(string)
+		key, alg, err := s.keySvc.VerificationKey(kid)
+		if err != nil {
+			return nil, err
+		}
+		if token.Method.Alg() != alg {
+			return nil, errors.New("unexpected signing algorithm")
+		}
+		return key, nil
+	}, jwt.WithIssuer(s.config.Issuer), jwt.WithAudience(s.config.Issuer), jwt.WithExpirationRequired())
+	if err != nil || !token.Valid {
+		return nil, apperrors.ErrInvalidToken
+	}
+
+	scope, _ := claims["scope"].(string)
+	scopes := strings.Fields(scope)
+	if !slices.Contains(scopes, ScopeOpenID) {
+		return nil, apperrors.ErrInsufficientScope
+	}
+
+	subject, _ := claims.GetSubject()
+	user, err := s.userSvc.GetByID(subject)
+	if err != nil || user.DisabledAt != nil {
+		return nil, apperrors.ErrInvalidToken
+	}
+
+	info := userClaims(user, scopes)
+	info["sub"] = user.ID.String()
+	return info, nil
+}
+
+// userClaims returns the standard claims released for the granted scopes.
+func userClaims(user *models.User, scopes []string) map[string]interface{} {
+	claims := map[string]interface{}{}
+	if slices.Contains(scopes, ScopeEmail) {
+		claims["email"] = user.Email
+		claims["email_verified"] = user.EmailVerifiedAt != nil
+	}
+	if slices.Contains(scopes, ScopeProfile) {
+		claims["name"] = user.Name
+		claims["updated_at"] = user.UpdatedAt.Unix()
+	}
+	return claims
+}
+
+// randomToken returns 32 random bytes encoded for use in URLs.
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashToken returns the SHA-256 of a high-entropy token for storage.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func splitList(list string) []string {
+	if list == "" {
+		return nil
+	}
+	return strings.Split(list, ",")
+}
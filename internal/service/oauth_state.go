@@ -0,0 +1,115 @@
+package service
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"net/url"
+	"strings"
+	"time"
+
+	apperrors "rest-api/internal/errors"
+)
+
+// defaultOAuthStateTTL bounds how long a user may take at the identity provider.
+const defaultOAuthStateTTL = 10 * time.Minute
+
+// OAuthRequest describes an authorization request being started.
+type OAuthRequest struct {
+	// RedirectTo receives the result of the flow in its URL fragment. When empty
+	// the callback responds with JSON instead. It must match AllowedRedirects.
+	RedirectTo string
+	// LinkUserID links the identity to this user instead of signing in.
+	LinkUserID string
+}
+
+// OAuthState is the record carried through the provider in the state parameter.
+// It is sealed with AES-GCM, so clients can neither read nor alter it, and it
+// only works in the browser that started the flow: a state replayed elsewhere
+// would otherwise sign the victim in to the attacker's account (login CSRF) or
+// link the attacker's identity to the victim's account.
+type OAuthState struct {
+	Provider     string    `json:"p"`
+	Nonce        string    `json:"n"`
+	CodeVerifier string    `json:"v"`
+	RedirectTo   string    `json:"r,omitempty"`
+	LinkUserID   string    `json:"u,omitempty"`
+	BindingHash  string    `json:"b"`
+	ExpiresAt    time.Time `json:"e"`
+}
+
+// newBinding returns a random browser binding value and its hash.
+func newBinding() (string, string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	binding := base64.RawURLEncoding.EncodeToString(b)
+	return binding, hashBinding(binding), nil
+}
+
+func hashBinding(binding string) string {
+	sum := sha256.Sum256([]byte(binding))
+	return hex.EncodeToString(sum[:])
+}
+
+// sealState encrypts a state record for use as the state parameter.
+func (s *OAuthService) sealState(st *OAuthState) (string, error) {
+	data, err := json.Marshal(st)
+	if err != nil {
+		return "", err
+	}
+	return s.stateEncryptor.Encrypt(data)
+}
+
+// ResumeAuth opens the state returned to the callback and checks that it is
+// unexpired, belongs to providerName and that binding is the value issued to
+// the browser that started the flow.
+func (s *OAuthService) ResumeAuth(rawState, providerName, binding string) (*OAuthState, error) {
+	data, err := s.stateEncryptor.Decrypt(rawState)
+	if err != nil {
+		return nil, apperrors.ErrInvalidState
+	}
+
+	var st OAuthState
+	if err := json.Unmarshal(data, &st); err != nil {
+		return nil, apperrors.ErrInvalidState
+	}
+
+	if time.Now().After(st.ExpiresAt) || st.Provider != providerName {
+		return nil, apperrors.ErrInvalidState
+	}
+
+	if binding == "" || subtle.ConstantTimeCompare([]byte(hashBinding(binding)), []byte(st.BindingHash)) != 1 {
+		return nil, apperrors.ErrInvalidState
+	}
+
+	return &st, nil
+}
+
+// redirectAllowed reports whether target matches one of the configured
+// redirect targets. Scheme and host must match exactly; the path must match
+// exactly, or fall under it when the allowed path ends with a slash.
+func (s *OAuthService) redirectAllowed(target string) bool {
+	u, err := url.Parse(target)
+	if err != nil || u.Scheme == "" || u.Fragment != "" || u.User != nil || strings.Contains(u.Path, "..") {
+		return false
+	}
+
+	for _, allowed := range s.config.AllowedRedirects {
+		a, err := url.Parse(allowed)
+		if err != nil {
+			continue
+		}
+		if !strings.EqualFold(u.Scheme, a.Scheme) || !strings.EqualFold(u.Host, a.Host) {
+			continue
+		}
+		if u.Path == a.Path || (strings.HasSuffix(a.Path, "/") && strings.HasPrefix(u.Path, a.Path)) {
+			return true
+		}
+	}
+	return false
+}
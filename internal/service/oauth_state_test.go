@@ -0,0 +1,40 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	apperrors "rest-api/internal/errors"
+	"rest-api/pkg/encryption"
+)
+
+func TestResumeAuthRequiresBinding(t *testing.T) {
+	s := &OAuthService{stateEncryptor: encryption.NewEncryptor("test")}
+	binding, bindingHash, err := newBinding()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, st := range []*OAuthState{
+		{Provider: "test"},
+		{Provider: "test", RedirectTo: "https://app.example.com/callback"},
+		{Provider: "test", LinkUserID: "user"},
+	} {
+		st.BindingHash = bindingHash
+		st.ExpiresAt = time.Now().Add(time.Minute)
+		state, err := s.sealState(st)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := s.ResumeAuth(state, "test", binding); err != nil {
+			t.Errorf("%+v: own browser rejected: %v", st, err)
+		}
+		for _, other := range []string{"", "other-browser"} {
+			if _, err := s.ResumeAuth(state, "test", other); !errors.Is(err, apperrors.ErrInvalidState) {
+				t.Errorf("%+v with binding %q: got %v, want ErrInvalidState", st, other, err)
+			}
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package service
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+
+	apperrors "rest-api/internal/errors"
+	"rest-api/pkg/encryption"
+	"rest-api/pkg/oidc"
+	"rest-api/pkg/oidc/oidctest"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func newOAuthTest(t *testing.T) (*oidctest.Provider, *OAuthService) {
+	t.Helper()
+	op := oidctest.NewProvider()
+	t.Cleanup(op.Close)
+
+	s := NewOAuthService(OAuthConfig{
+		Providers: []OAuthProviderConfig{{
+			Name:         "test",
+			Issuer:       op.Issuer,
+			ClientID:     oidctest.ClientID,
+			ClientSecret: "secret",
+			RedirectURL:  "https://api.example.com/api/v1/auth/oauth/test/callback",
+			Scopes:       []string{"openid", "email"},
+		}},
+		HTTPClient:       op.Client(),
+		AllowedRedirects: []string{"https://app.example.com/"},
+	}, encryption.NewEncryptor("test"), nil, nil, nil, nil)
+	return op, s
+}
+
+// beginOAuth starts a flow and returns the state record the callback would
+// resume, after checking the authorization URL carries its PKCE challenge and nonce.
+func beginOAuth(t *testing.T, s *OAuthService) *OAuthState {
+	t.Helper()
+	authURL, binding, err := s.BeginAuth(context.Background(), "test", OAuthRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := u.Query()
+
+	st, err := s.ResumeAuth(query.Get("state"), "test", binding)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256([]byte(st.CodeVerifier))
+	if query.Get("code_challenge_method") != "S256" || query.Get("code_challenge") != base64.RawURLEncoding.EncodeToString(sum[:]) {
+		t.Errorf("authorization URL lacks the PKCE challenge: %s", authURL)
+	}
+	if st.Nonce == "" || query.Get("nonce") != st.Nonce {
+		t.Errorf("authorization URL lacks the nonce: %s", authURL)
+	}
+	if query.Get("client_id") != oidctest.ClientID {
+		t.Errorf("authorization URL has the wrong client: %s", authURL)
+	}
+	return st
+}
+
+// authorizeAt completes st at the provider, which will issue an ID token with claims.
+func authorizeAt(op *oidctest.Provider, st *OAuthState, claims jwt.MapClaims) string {
+	sum := sha256.Sum256([]byte(st.CodeVerifier))
+	return op.Authorize(claims, base64.RawURLEncoding.EncodeToString(sum[:]))
+}
+
+func TestOAuthExchange(t *testing.T) {
+	op, s := newOAuthTest(t)
+	st := beginOAuth(t, s)
+
+	claims := op.Claims("alice", st.Nonce)
+	claims["email"] = "alice@example.com"
+	claims["email_verified"] = "true"
+	claims["name"] = "Alice"
+
+	identity, err := s.exchange(context.Background(), st, authorizeAt(op, st, claims))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := ExternalIdentity{Provider: "test", Subject: "alice", Email: "alice@example.com", EmailVerified: true, Name: "Alice"}
+	if *identity != want {
+		t.Errorf("got %+v, want %+v", *identity, want)
+	}
+}
+
+func TestOAuthExchangeRejectsInvalidIDTokens(t *testing.T) {
+	op, s := newOAuthTest(t)
+
+	tests := []struct {
+		name   string
+		modify func(jwt.MapClaims)
+	}{
+		{"nonce of another flow", func(c jwt.MapClaims) { c["nonce"] = "other" }},
+		{"other issuer", func(c jwt.MapClaims) { c["iss"] = "https://attacker.example.com" }},
+		{"other audience", func(c jwt.MapClaims) { c["aud"] = "other-client" }},
+		{"expired", func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-time.Hour).Unix() }},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := beginOAuth(t, s)
+			claims := op.Claims("alice", st.Nonce)
+			claims["email"] = "alice@example.com"
+			tt.modify(claims)
+
+			if _, err := s.exchange(context.Background(), st, authorizeAt(op, st, claims)); !errors.Is(err, apperrors.ErrInvalidToken) {
+				t.Errorf("got %v, want ErrInvalidToken", err)
+			}
+		})
+	}
+
+	// A code issued to another flow fails PKCE at the provider
+	st, other := beginOAuth(t, s), beginOAuth(t, s)
+	code := authorizeAt(op, other, op.Claims("alice", other.Nonce))
+	if _, err := s.exchange(context.Background(), st, code); err == nil {
+		t.Error("redeemed a code with another flow's verifier")
+	}
+}
+
+func TestOAuthExchangeFallsBackToUserInfo(t *testing.T) {
+	op, s := newOAuthTest(t)
+
+	op.SetUserInfo(map[string]interface{}{"sub": "alice", "email": "alice@example.com", "email_verified": true, "name": "Alice"})
+	st := beginOAuth(t, s)
+	identity, err := s.exchange(context.Background(), st, authorizeAt(op, st, op.Claims("alice", st.Nonce)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if identity.Email != "alice@example.com" || !identity.EmailVerified || identity.Name != "Alice" {
+		t.Errorf("userinfo claims not used: %+v", identity)
+	}
+
+	// Userinfo for someone else must not be attributed to the ID token's subject
+	op.SetUserInfo(map[string]interface{}{"sub": "mallory", "email": "mallory@example.com"})
+	st = beginOAuth(t, s)
+	if _, err := s.exchange(context.Background(), st, authorizeAt(op, st, op.Claims("alice", st.Nonce))); err == nil {
+		t.Error("accepted userinfo for another subject")
+	}
+}
+
+func TestOAuthBeginAuth(t *testing.T) {
+	op, s := newOAuthTest(t)
+
+	if _, _, err := s.BeginAuth(context.Background(), "other", OAuthRequest{}); !errors.Is(err, apperrors.ErrProviderNotFound) {
+		t.Errorf("unknown provider: got %v, want ErrProviderNotFound", err)
+	}
+	if _, _, err := s.BeginAuth(context.Background(), "test", OAuthRequest{RedirectTo: "https://attacker.example.com/"}); !errors.Is(err, apperrors.ErrInvalidRedirect) {
+		t.Errorf("foreign redirect: got %v, want ErrInvalidRedirect", err)
+	}
+
+	s.providers["test"].config.Issuer = op.Issuer + "/tenant"
+	s.providers["test"].oidc = nil
+	if _, _, err := s.BeginAuth(context.Background(), "test", OAuthRequest{}); err == nil {
+		t.Error("started a flow with a provider whose discovery failed")
+	}
+	s.providers["test"].config.Issuer = op.Issuer + "/"
+	if _, _, err := s.BeginAuth(context.Background(), "test", OAuthRequest{}); !errors.Is(err, oidc.ErrIssuerMismatch) {
+		t.Errorf("issuer mismatch: got %v, want ErrIssuerMismatch", err)
+	}
+}
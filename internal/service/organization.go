@@ -0,0 +1,224 @@
+package service
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+
+	apperrors "rest-api/internal/errors"
+	"rest-api/internal/models"
+	"rest-api/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+var (
+	slugPattern    = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+	slugSeparators = regexp.MustCompile(`[^a-z0-9]+`)
+	maxSlugLength  = 50
+)
+
+const invitationTTL = 7 * 24 * time.Hour
+
+type OrganizationService struct {
+	orgRepo    repository.OrganizationRepository
+	transactor repository.Transactor
+}
+
+func NewOrganizationService(orgRepo repository.OrganizationRepository, transactor repository.Transactor) *OrganizationService {
+	return &OrganizationService{
+		orgRepo:    orgRepo,
+		transactor: transactor,
+	}
+}
+
+// Create creates an organization owned by user. The slug is derived from the
+// name when empty.
+func (s *OrganizationService) Create(user *models.User, name, slug string) (*models.Membership, error) {
+	name = strings.TrimSpace(name)
+	if slug == "" {
+		slug = slugify(name)
+	}
+	if name == "" || len(slug) > maxSlugLength || !slugPattern.MatchString(slug) {
+		return nil, apperrors.ErrInvalidRequest
+	}
+
+	if _, err := s.orgRepo.FindBySlug(slug); err == nil {
+		return nil, apperrors.ErrSlugTaken
+	} else if !errors.Is(err, repository.ErrNotFound) {
+		return nil, err
+	}
+
+	org := &models.Organization{
+		Name:      name,
+		Slug:      slug,
+		CreatedBy: user.ID,
+	}
+	owner := &models.Membership{
+		UserID: user.ID,
+		Role:   models.OrgRoleOwner,
+	}
+	if err := s.orgRepo.Create(org, owner); err != nil {
+		return nil, err
+	}
+
+	owner.Organization = *org
+	return owner, nil
+}
+
+func slugify(name string) string {
+	slug := strings.Trim(slugSeparators.ReplaceAllString(strings.ToLower(name), "-"), "-")
+	if len(slug) > maxSlugLength {
+		slug = strings.TrimRight(slug[:maxSlugLength], "-")
+	}
+	return slug
+}
+
+// ListForUser returns the user's memberships with their organizations.
+func (s *OrganizationService) ListForUser(userID string) ([]models.Membership, error) {
+	return s.orgRepo.FindMembershipsByUserID(userID)
+}
+
+// Membership returns the user's membership in an organization. Organizations
+// the user does not belong to are reported as not found.
+func (s *OrganizationService) Membership(organizationID, userID string) (*models.Membership, error) {
+	orgID, err := uuid.Parse(organizationID)
+	if err != nil {
+		return nil, apperrors.ErrOrgNotFound
+	}
+
+	membership, err := s.orgRepo.Members(orgID).Find(userID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, apperrors.ErrOrgNotFound
+	}
+	return membership, err
+}
+
+// Members lists the members of an organization.
+func (s *OrganizationService) Members(organizationID uuid.UUID) ([]models.Membership, error) {
+	return s.orgRepo.Members(organizationID).List()
+}
+
+// canManageMembers reports whether a membership may invite and manage members.
+func canManageMembers(membership *models.Membership) bool {
+	return membership.Role == models.OrgRoleOwner || membership.Role == models.OrgRoleAdmin
+}
+
+// Invite creates an invitation to the inviter's organization and publishes the
+// event that emails it to the invitee. Only owners and admins may invite.
+func (s *OrganizationService) Invite(inviter *models.Membership, invitedBy *models.User, email, role string) (*models.Invitation, error) {
+	if !canManageMembers(inviter) {
+		return nil, apperrors.ErrForbidden
+	}
+	if role != models.OrgRoleAdmin && role != models.OrgRoleMember {
+		return nil, apperrors.ErrInvalidRequest
+	}
+
+	// The token is issued when the invitation is emailed
+	invitation := &models.Invitation{
+		Email:        strings.ToLower(strings.TrimSpace(email)),
+		Role:         role,
+		InvitedBy:    inviter.UserID,
+		ExpiresAt:    time.Now().Add(invitationTTL),
+		Organization: inviter.Organization,
+	}
+	err := s.transactor.Transaction(func(tx repository.Repositories) error {
+		if err := tx.Organizations.Invitations(inviter.OrganizationID).Create(invitation); err != nil {
+			return err
+		}
+		return publishEvent(tx, EventInvitationCreated, InvitationEvent{
+			InvitationID: invitation.ID.String(),
+			Email:        invitation.Email,
+			Organization: inviter.Organization.Name,
+			Role:         invitation.Role,
+			InvitedBy:    invitedBy.Name,
+			ExpiresAt:    invitation.ExpiresAt,
+			Language:     invitedBy.Locale,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return invitation, nil
+}
+
+// Invitations lists the pending invitations of the member's organization.
+func (s *OrganizationService) Invitations(member *models.Membership) ([]models.Invitation, error) {
+	if !canManageMembers(member) {
+		return nil, apperrors.ErrForbidden
+	}
+	return s.orgRepo.Invitations(member.OrganizationID).ListPending()
+}
+
+// RevokeInvitation deletes a pending invitation of the member's organization,
+// which invalidates its token.
+func (s *OrganizationService) RevokeInvitation(member *models.Membership, id string) error {
+	if !canManageMembers(member) {
+		return apperrors.ErrForbidden
+	}
+	if _, err := uuid.Parse(id); err != nil {
+		return apperrors.ErrInvitationNotFound
+	}
+
+	err := s.orgRepo.Invitations(member.OrganizationID).Delete(id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return apperrors.ErrInvitationNotFound
+	}
+	return err
+}
+
+// Invitation returns the pending invitation, with its organization, that a token
+// was issued for.
+func (s *OrganizationService) Invitation(token string) (*models.Invitation, error) {
+	if token == "" {
+		return nil, apperrors.ErrInvalidToken
+	}
+
+	invitation, err := s.orgRepo.FindInvitationByTokenHash(hashToken(token))
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, apperrors.ErrInvalidToken
+	}
+	if err != nil {
+		return nil, err
+	}
+	if invitation.AcceptedAt != nil || time.Now().After(invitation.ExpiresAt) {
+		return nil, apperrors.ErrInvalidToken
+	}
+
+	return invitation, nil
+}
+
+// AcceptInvitation adds the user to the organization of the invitation the token
+// was issued for. The invitation must have been sent to the user's email address.
+// Accepting it invalidates the token.
+func (s *OrganizationService) AcceptInvitation(user *models.User, token string) (*models.Membership, error) {
+	invitation, err := s.Invitation(token)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.EqualFold(invitation.Email, user.Email) {
+		return nil, apperrors.ErrInvitationEmail
+	}
+
+	if _, err := s.orgRepo.Members(invitation.OrganizationID).Find(user.ID.String()); err == nil {
+		return nil, apperrors.ErrAlreadyMember
+	} else if !errors.Is(err, repository.ErrNotFound) {
+		return nil, err
+	}
+
+	membership := &models.Membership{
+		UserID: user.ID,
+		Role:   invitation.Role,
+	}
+	if err := s.orgRepo.AcceptInvitation(invitation, membership); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, apperrors.ErrInvalidToken
+		}
+		return nil, err
+	}
+
+	membership.Organization = invitation.Organization
+	return membership, nil
+}
@@ -0,0 +1,142 @@
+package service
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"time"
+
+	apperrors "rest-api/internal/errors"
+	"rest-api/internal/models"
+	"rest-api/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// personalAccessTokenPrefix marks personal access tokens so they can be told
+// apart from session tokens and JWTs without a lookup.
+const personalAccessTokenPrefix = "pat_"
+
+// personalAccessTokenUseInterval limits how often last-used tracking writes to the database.
+const personalAccessTokenUseInterval = time.Minute
+
+// Scopes a personal access token can be granted. Routes not covered by a scope
+// only accept interactive sessions.
+const (
+	TokenScopeProfileRead   = "profile:read"
+	TokenScopeSessionsRead  = "sessions:read"
+	TokenScopeSessionsWrite = "sessions:write"
+	TokenScopeAdmin         = "admin"
+)
+
+var PersonalAccessTokenScopes = []string{TokenScopeProfileRead, TokenScopeSessionsRead, TokenScopeSessionsWrite, TokenScopeAdmin}
+
+type PersonalAccessTokenConfig struct {
+	// MaxLifetime caps token expiry; zero allows tokens that never expire.
+	MaxLifetime time.Duration
+}
+
+type PersonalAccessTokenService struct {
+	repo   repository.PersonalAccessTokenRepository
+	config PersonalAccessTokenConfig
+}
+
+func NewPersonalAccessTokenService(repo repository.PersonalAccessTokenRepository, config PersonalAccessTokenConfig) *PersonalAccessTokenService {
+	return &PersonalAccessTokenService{
+		repo:   repo,
+		config: config,
+	}
+}
+
+// IsPersonalAccessToken reports whether a bearer token is a personal access token.
+func IsPersonalAccessToken(token string) bool {
+	return strings.HasPrefix(token, personalAccessTokenPrefix)
+}
+
+// Create issues a token for the user. The secret is returned once and only its
+// hash is stored. A zero lifetime creates a token without expiry, which is only
+// allowed when no maximum lifetime is configured.
+func (s *PersonalAccessTokenService) Create(userID uuid.UUID, name string, scopes []string, lifetime time.Duration) (*models.PersonalAccessToken, string, error) {
+	if strings.TrimSpace(name) == "" || len(scopes) == 0 || lifetime < 0 {
+		return nil, "", apperrors.ErrInvalidRequest
+	}
+	if s.config.MaxLifetime > 0 && (lifetime == 0 || lifetime > s.config.MaxLifetime) {
+		return nil, "", apperrors.ErrInvalidRequest
+	}
+
+	granted := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		if !slices.Contains(PersonalAccessTokenScopes, scope) {
+			return nil, "", apperrors.ErrInvalidRequest
+		}
+		if !slices.Contains(granted, scope) {
+			granted = append(granted, scope)
+		}
+	}
+
+	random, err := randomToken()
+	if err != nil {
+		return nil, "", err
+	}
+	secret := personalAccessTokenPrefix + random
+
+	token := &models.PersonalAccessToken{
+		UserID:    userID,
+		Name:      strings.TrimSpace(name),
+		TokenHash: hashToken(secret),
+		Prefix:    secret[:len(personalAccessTokenPrefix)+8],
+		Scopes:    strings.Join(granted, ","),
+	}
+	if lifetime > 0 {
+		expiresAt := time.Now().Add(lifetime)
+		token.ExpiresAt = &expiresAt
+	}
+
+	if err := s.repo.Create(token); err != nil {
+		return nil, "", err
+	}
+	return token, secret, nil
+}
+
+func (s *PersonalAccessTokenService) List(userID string) ([]models.PersonalAccessToken, error) {
+	return s.repo.FindByUserID(userID)
+}
+
+func (s *PersonalAccessTokenService) Revoke(userID, id string) error {
+	if _, err := uuid.Parse(id); err != nil {
+		return apperrors.ErrTokenNotFound
+	}
+	if err := s.repo.Delete(userID, id); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return apperrors.ErrTokenNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// Validate checks a presented token and records its use from ip.
+func (s *PersonalAccessTokenService) Validate(secret, ip string) (*models.PersonalAccessToken, error) {
+	token, err := s.repo.FindByHash(hashToken(secret))
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, apperrors.ErrInvalidToken
+		}
+		return nil, err
+	}
+
+	now := time.Now()
+	if token.ExpiresAt != nil && now.After(*token.ExpiresAt) {
+		return nil, apperrors.ErrTokenExpired
+	}
+
+	// Usage tracking is best-effort; a failed write must not reject the request
+	_ = s.repo.RecordUse(token.ID.String(), now, ip, now.Add(-personalAccessTokenUseInterval))
+
+	return token, nil
+}
+
+// TokenScopes returns the scopes granted to a token.
+func TokenScopes(token *models.PersonalAccessToken) []string {
+	return splitList(token.Scopes)
+}
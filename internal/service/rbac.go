@@ -0,0 +1,165 @@
+package service
+
+import (
+	"errors"
+	"regexp"
+	"slices"
+	"strings"
+
+	apperrors "rest-api/internal/errors"
+	"rest-api/internal/models"
+	"rest-api/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+var roleNamePattern = regexp.MustCompile(`^[a-z][a-z0-9_-]{1,49}$`)
+
+type RBACConfig struct {
+	// BootstrapAdmins are email addresses that hold the admin role once
+	// verified, so a fresh installation has someone who can assign roles.
+	BootstrapAdmins []string
+}
+
+// RBACService manages roles and resolves the permissions of users.
+type RBACService struct {
+	roleRepo repository.RoleRepository
+	config   RBACConfig
+}
+
+func NewRBACService(roleRepo repository.RoleRepository, config RBACConfig) *RBACService {
+	return &RBACService{
+		roleRepo: roleRepo,
+		config:   config,
+	}
+}
+
+// Permissions returns the effective permissions of a user.
+func (s *RBACService) Permissions(user *models.User) ([]string, error) {
+	permissions, err := s.roleRepo.PermissionsForUser(user.ID.String())
+	if err != nil {
+		return nil, err
+	}
+
+	if s.isBootstrapAdmin(user) {
+		admin, err := s.roleRepo.FindByName(models.RoleAdmin)
+		if err != nil {
+			return nil, err
+		}
+		for _, permission := range admin.Permissions {
+			if !slices.Contains(permissions, permission.Name) {
+				permissions = append(permissions, permission.Name)
+			}
+		}
+	}
+
+	return permissions, nil
+}
+
+func (s *RBACService) isBootstrapAdmin(user *models.User) bool {
+	if user.EmailVerifiedAt == nil {
+		return false
+	}
+	for _, email := range s.config.BootstrapAdmins {
+		if strings.EqualFold(email, user.Email) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *RBACService) ListRoles() ([]models.Role, error) {
+	return s.roleRepo.List()
+}
+
+func (s *RBACService) ListPermissions() ([]models.Permission, error) {
+	return s.roleRepo.ListPermissions()
+}
+
+// CreateRole creates a custom role granting the named permissions.
+func (s *RBACService) CreateRole(name, description string, permissions []string) (*models.Role, error) {
+	if !roleNamePattern.MatchString(name) {
+		return nil, apperrors.ErrInvalidRequest
+	}
+
+	if _, err := s.roleRepo.FindByName(name); err == nil {
+		return nil, apperrors.ErrRoleExists
+	} else if !errors.Is(err, repository.ErrNotFound) {
+		return nil, err
+	}
+
+	unique := make([]string, 0, len(permissions))
+	for _, permission := range permissions {
+		if !slices.Contains(unique, permission) {
+			unique = append(unique, permission)
+		}
+	}
+
+	role := &models.Role{Name: name, Description: description}
+	if err := s.roleRepo.Create(role, unique); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, apperrors.ErrInvalidRequest
+		}
+		return nil, err
+	}
+	return role, nil
+}
+
+// DeleteRole deletes a custom role and removes it from every user.
+func (s *RBACService) DeleteRole(id string) error {
+	role, err := s.findRole(id)
+	if err != nil {
+		return err
+	}
+	if role.Builtin {
+		return apperrors.ErrBuiltinRole
+	}
+
+	if err := s.roleRepo.Delete(id); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return apperrors.ErrRoleNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *RBACService) UserRoles(userID string) ([]models.Role, error) {
+	return s.roleRepo.FindByUserID(userID)
+}
+
+func (s *RBACService) AssignRole(userID uuid.UUID, roleID string) (*models.Role, error) {
+	role, err := s.findRole(roleID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.roleRepo.Assign(&models.UserRole{UserID: userID, RoleID: role.ID}); err != nil {
+		return nil, err
+	}
+	return role, nil
+}
+
+func (s *RBACService) UnassignRole(userID, roleID string) error {
+	if _, err := uuid.Parse(roleID); err != nil {
+		return apperrors.ErrRoleNotFound
+	}
+	if err := s.roleRepo.Unassign(userID, roleID); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return apperrors.ErrRoleNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *RBACService) findRole(id string) (*models.Role, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, apperrors.ErrRoleNotFound
+	}
+	role, err := s.roleRepo.FindByID(id)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, apperrors.ErrRoleNotFound
+	}
+	return role, err
+}
@@ -0,0 +1,76 @@
+package service
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"rest-api/internal/repository"
+	"rest-api/pkg/logger"
+
+	"github.com/google/uuid"
+)
+
+// SessionActivityTracker buffers session activity in memory and writes it to the
+// database in batches, so authenticated requests do not each cause an UPDATE.
+type SessionActivityTracker struct {
+	sessionRepo repository.SessionRepository
+	idleTimeout time.Duration
+	logger      *logger.Logger
+
+	mu      sync.Mutex
+	pending map[uuid.UUID]time.Time
+}
+
+func NewSessionActivityTracker(sessionRepo repository.SessionRepository, idleTimeout time.Duration, logger *logger.Logger) *SessionActivityTracker {
+	return &SessionActivityTracker{
+		sessionRepo: sessionRepo,
+		idleTimeout: idleTimeout,
+		logger:      logger,
+		pending:     make(map[uuid.UUID]time.Time),
+	}
+}
+
+// Touch records that the session was used at the given time.
+func (t *SessionActivityTracker) Touch(sessionID uuid.UUID, at time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if last, ok := t.pending[sessionID]; !ok || at.After(last) {
+		t.pending[sessionID] = at
+	}
+}
+
+// Flush writes all buffered activity, extending each session's idle expiry.
+func (t *SessionActivityTracker) Flush() error {
+	t.mu.Lock()
+	if len(t.pending) == 0 {
+		t.mu.Unlock()
+		return nil
+	}
+	batch := t.pending
+	t.pending = make(map[uuid.UUID]time.Time)
+	t.mu.Unlock()
+
+	return t.sessionRepo.TouchSessions(batch, t.idleTimeout)
+}
+
+// Run flushes buffered activity every interval until ctx is cancelled, then flushes once more.
+func (t *SessionActivityTracker) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			if err := t.Flush(); err != nil {
+				t.logger.Error("failed to flush session activity", err)
+			}
+			return
+		case <-ticker.C:
+			if err := t.Flush(); err != nil {
+				t.logger.Error("failed to flush session activity", err)
+			}
+		}
+	}
+}
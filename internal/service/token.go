@@ -8,46 +8,94 @@ import (
 
 	"rest-api/internal/models"
 	"rest-api/internal/repository"
+
+	"github.com/google/uuid"
 )
 
 type TokenType string
 
 const (
-	TokenTypeReset     TokenType = "reset"
-	TokenTypeMagicLink TokenType = "magic_link"
+	TokenTypeReset        TokenType = "reset"
+	TokenTypeMagicLink    TokenType = "magic_link"
+	TokenTypeMFAChallenge TokenType = "mfa_challenge"
+	TokenTypeVerifyEmail  TokenType = "verify_email"
+	TokenTypeUnlock       TokenType = "unlock_account"
 )
 
+const defaultTokenTTL = 15 * time.Minute
+
+// tokenTTLs overrides defaultTokenTTL for token types that must be shorter lived.
+var tokenTTLs = map[TokenType]time.Duration{
+	TokenTypeMFAChallenge: 5 * time.Minute,
+	TokenTypeVerifyEmail:  24 * time.Hour,
+	TokenTypeUnlock:       time.Hour,
+}
+
 type TokenService struct {
-	tokenRepo repository.TokenRepository
+	tokenRepo  repository.TokenRepository
+	transactor repository.Transactor
 }
 
-func NewTokenService(tokenRepo repository.TokenRepository) *TokenService {
+func NewTokenService(tokenRepo repository.TokenRepository, transactor repository.Transactor) *TokenService {
 	return &TokenService{
-		tokenRepo: tokenRepo,
+		tokenRepo:  tokenRepo,
+		transactor: transactor,
 	}
 }
 
 func (s *TokenService) GenerateToken(userID string, tokenType TokenType) (string, error) {
+	var token string
+	err := s.transactor.Transaction(func(tx repository.Repositories) error {
+		var err error
+		record, err := issueToken(tx, userID, tokenType)
+		if err != nil {
+			return err
+		}
+		token = record.Token
+		return nil
+	})
+	return token, err
+}
+
+// issueToken creates a token in the transaction of tx, for services that send
+// it out through an event published in the same transaction. Events carry the
+// token's ID, never the token itself.
+func issueToken(tx repository.Repositories, userID string, tokenType TokenType) (*models.Token, error) {
 	// Generate random token
 	b := make([]byte, 32)
 	if _, err := rand.Read(b); err != nil {
-		return "", err
+		return nil, err
 	}
 	token := base64.URLEncoding.EncodeToString(b)
 
+	ttl, ok := tokenTTLs[tokenType]
+	if !ok {
+		ttl = defaultTokenTTL
+	}
+
 	// Create token record
 	tokenRecord := &models.Token{
+		ID:        uuid.New(),
 		UserID:    userID,
 		Token:     token,
 		Type:      string(tokenType),
-		ExpiresAt: time.Now().Add(15 * time.Minute),
+		ExpiresAt: time.Now().Add(ttl),
 	}
 
-	if err := s.tokenRepo.Create(tokenRecord); err != nil {
-		return "", err
+	if err := tx.Tokens.Create(tokenRecord); err != nil {
+		return nil, err
 	}
 
-	return token, nil
+	err := publishEvent(tx, EventTokenIssued, TokenIssuedEvent{
+		Subject:   userID,
+		TokenType: tokenType,
+		ExpiresAt: tokenRecord.ExpiresAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return tokenRecord, nil
 }
 
 func (s *TokenService) ValidateToken(token string, tokenType TokenType) (*models.Token, error) {
@@ -70,3 +118,14 @@ func (s *TokenService) ValidateToken(token string, tokenType TokenType) (*models
 func (s *TokenService) InvalidateToken(token string) error {
 	return s.tokenRepo.InvalidateToken(token)
 }
+
+// RecordFailedAttempt counts a wrong code presented with the token and
+// invalidates it after maxAttempts.
+func (s *TokenService) RecordFailedAttempt(token string, maxAttempts int) error {
+	return s.tokenRepo.RecordFailedAttempt(token, maxAttempts)
+}
+
+// IssuedSince returns the tokens of the given type issued to the user since t, newest first.
+func (s *TokenService) IssuedSince(userID string, tokenType TokenType, since time.Time) ([]models.Token, error) {
+	return s.tokenRepo.FindIssuedSince(userID, string(tokenType), since)
+}
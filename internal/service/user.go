@@ -2,20 +2,66 @@ package service
 
 import (
 	"errors"
+	"strings"
+	"time"
 
+	apperrors "rest-api/internal/errors"
 	"rest-api/internal/models"
 	"rest-api/internal/repository"
+	"rest-api/pkg/password"
+)
+
+// Email verification policies control what unverified users may do.
+const (
+	// EmailVerificationOptional lets unverified users use the API normally.
+	EmailVerificationOptional = "optional"
+	// EmailVerificationRestricted lets unverified users log in with a limited scope.
+	EmailVerificationRestricted = "restricted"
+	// EmailVerificationRequired rejects logins until the address is verified.
+	EmailVerificationRequired = "required"
+)
+
+const (
+	verificationResendInterval = time.Minute
+	verificationMaxPerHour     = 5
+)
 
-	"golang.org/x/crypto/bcrypt"
+const (
+	defaultUsersPerPage = 20
+	maxUsersPerPage     = 100
 )
 
+// UserQuery selects a page of users for administrators.
+type UserQuery struct {
+	Search string
+	// Status is one of active, disabled or locked
+	Status   string
+	Verified *bool
+	Page     int
+	PerPage  int
+}
+
+// UserPage is one page of users with the total number of matches.
+type UserPage struct {
+	Users   []models.User
+	Total   int64
+	Page    int
+	PerPage int
+}
+
 type UserService struct {
-	userRepo repository.UserRepository
+	userRepo   repository.UserRepository
+	transactor repository.Transactor
+	tokenSvc   *TokenService
+	hasher     *password.Manager
 }
 
-func NewUserService(userRepo repository.UserRepository) *UserService {
+func NewUserService(userRepo repository.UserRepository, transactor repository.Transactor, tokenSvc *TokenService, hasher *password.Manager) *UserService {
 	return &UserService{
-		userRepo: userRepo,
+		userRepo:   userRepo,
+		transactor: transactor,
+		tokenSvc:   tokenSvc,
+		hasher:     hasher,
 	}
 }
 
@@ -27,37 +73,55 @@ func (s *UserService) FindByEmail(email string) (*models.User, error) {
 	return s.userRepo.FindByEmail(email)
 }
 
-func (s *UserService) Register(email, password, name string) (*models.User, error) {
+func (s *UserService) Register(email, plaintext, name, locale string) (*models.User, error) {
 	existingUser, err := s.userRepo.FindByEmail(email)
 	if err == nil && existingUser != nil {
 		return nil, errors.New("user already exists")
 	}
 
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	hashedPassword, err := s.hasher.Hash(plaintext)
 	if err != nil {
 		return nil, err
 	}
 
 	user := &models.User{
 		Email:        email,
-		PasswordHash: string(hashedPassword),
+		PasswordHash: hashedPassword,
 		Name:         name,
+		Locale:       locale,
 	}
 
-	if err := s.userRepo.Create(user); err != nil {
+	err = s.transactor.Transaction(func(tx repository.Repositories) error {
+		if err := tx.Users.Create(user); err != nil {
+			return err
+		}
+		return publishEvent(tx, EventUserRegistered, WebhookUserData(user))
+	})
+	if err != nil {
 		return nil, err
 	}
 
 	return user, nil
 }
 
-func (s *UserService) ValidateCredentials(email, password string) (*models.User, error) {
+// RegisterWithIdentity creates a user who signed up through an external
+// identity provider, together with the linked identity.
+func (s *UserService) RegisterWithIdentity(user *models.User, identity *models.UserIdentity) error {
+	return s.transactor.Transaction(func(tx repository.Repositories) error {
+		if err := tx.Identities.CreateWithUser(user, identity); err != nil {
+			return err
+		}
+		return publishEvent(tx, EventUserRegistered, WebhookUserData(user))
+	})
+}
+
+func (s *UserService) ValidateCredentials(email, plaintext string) (*models.User, error) {
 	user, err := s.userRepo.FindByEmail(email)
 	if err != nil {
 		return nil, errors.New("invalid credentials")
 	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
+	if ok, _, err := s.hasher.Verify(plaintext, user.PasswordHash); err != nil || !ok {
 		return nil, errors.New("invalid credentials")
 	}
 
@@ -65,23 +129,199 @@ func (s *UserService) ValidateCredentials(email, password string) (*models.User,
 }
 
 func (s *UserService) UpdatePassword(userID string, newPassword string) error {
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(newPassword), bcrypt.DefaultCost)
+	hashedPassword, err := s.hasher.Hash(newPassword)
 	if err != nil {
 		return err
 	}
 
-	return s.userRepo.UpdatePassword(userID, string(hashedPassword))
+	return s.transactor.Transaction(func(tx repository.Repositories) error {
+		if err := tx.Users.UpdatePassword(userID, hashedPassword); err != nil {
+			return err
+		}
+		return publishUser(tx, EventUserPasswordChanged, userID)
+	})
 }
 
-func (s *UserService) CreateGoogleUser(email, name string) (*models.User, error) {
-	user := &models.User{
-		Email: email,
-		Name:  name,
+// RequestPasswordReset issues a reset token and publishes the event that emails
+// it to the user.
+func (s *UserService) RequestPasswordReset(user *models.User, client ClientInfo) error {
+	return s.transactor.Transaction(func(tx repository.Repositories) error {
+		return requestPasswordReset(tx, user, UserTokenEvent{
+			IPAddress: client.IPAddress,
+			UserAgent: client.UserAgent,
+			Language:  preferredLanguage(user, client),
+		})
+	})
+}
+
+// ForcePasswordReset blocks password logins until the user resets their
+// password, and emails them a reset link.
+func (s *UserService) ForcePasswordReset(user *models.User) error {
+	return s.transactor.Transaction(func(tx repository.Repositories) error {
+		if err := tx.Users.SetPasswordResetRequired(user.ID.String()); err != nil {
+			return userNotFound(err)
+		}
+		return requestPasswordReset(tx, user, UserTokenEvent{Forced: true, Language: user.Locale})
+	})
+}
+
+func requestPasswordReset(tx repository.Repositories, user *models.User, event UserTokenEvent) error {
+	token, err := issueToken(tx, user.ID.String(), TokenTypeReset)
+	if err != nil {
+		return err
+	}
+	event.User = webhookUser(user)
+	event.TokenID = token.ID.String()
+	return publishEvent(tx, EventPasswordResetRequested, event)
+}
+
+// RequestEmailVerification issues a verification token and publishes the event
+// that emails it to the user, throttled to one per resend interval and a fixed
+// number per hour.
+func (s *UserService) RequestEmailVerification(user *models.User, client ClientInfo) error {
+	if user.EmailVerifiedAt != nil {
+		return apperrors.ErrEmailVerified
+	}
+
+	recent, err := s.tokenSvc.IssuedSince(user.ID.String(), TokenTypeVerifyEmail, time.Now().Add(-time.Hour))
+	if err != nil {
+		return err
+	}
+	if len(recent) >= verificationMaxPerHour ||
+		(len(recent) > 0 && time.Since(recent[0].CreatedAt) < verificationResendInterval) {
+		return apperrors.ErrTooManyRequests
+	}
+
+	return s.transactor.Transaction(func(tx repository.Repositories) error {
+		token, err := issueToken(tx, user.ID.String(), TokenTypeVerifyEmail)
+		if err != nil {
+			return err
+		}
+		return publishEvent(tx, EventEmailVerificationRequested, UserTokenEvent{
+			User:     webhookUser(user),
+			TokenID:  token.ID.String(),
+			Language: preferredLanguage(user, client),
+		})
+	})
+}
+
+// VerifyEmail consumes a verification token and marks the user's address as verified.
+func (s *UserService) VerifyEmail(token string) (*models.User, error) {
+	tokenRecord, err := s.tokenSvc.ValidateToken(token, TokenTypeVerifyEmail)
+	if err != nil {
+		return nil, apperrors.ErrInvalidToken
 	}
 
-	if err := s.userRepo.Create(user); err != nil {
+	if err := s.tokenSvc.InvalidateToken(token); err != nil {
 		return nil, err
 	}
 
-	return user, nil
+	if err := s.MarkEmailVerified(tokenRecord.UserID); err != nil {
+		return nil, err
+	}
+
+	return s.userRepo.FindByID(tokenRecord.UserID)
+}
+
+// UpdateLocale sets the language the user is emailed in. An empty locale
+// follows the Accept-Language of each request instead.
+func (s *UserService) UpdateLocale(userID, locale string) error {
+	return userNotFound(s.userRepo.UpdateLocale(userID, locale))
+}
+
+// MarkEmailVerified records that the user proved ownership of their address.
+func (s *UserService) MarkEmailVerified(userID string) error {
+	return s.transactor.Transaction(func(tx repository.Repositories) error {
+		verified, err := tx.Users.MarkEmailVerified(userID)
+		if err != nil || !verified {
+			// Only the first verification is news to subscribers
+			return err
+		}
+		return publishUser(tx, EventUserEmailVerified, userID)
+	})
+}
+
+// Search returns a page of users matching the query.
+func (s *UserService) Search(query UserQuery) (*UserPage, error) {
+	if query.Page < 1 {
+		query.Page = 1
+	}
+	if query.PerPage < 1 {
+		query.PerPage = defaultUsersPerPage
+	}
+	if query.PerPage > maxUsersPerPage {
+		query.PerPage = maxUsersPerPage
+	}
+
+	users, total, err := s.userRepo.List(repository.UserFilter{
+		Search:   strings.TrimSpace(query.Search),
+		Status:   query.Status,
+		Verified: query.Verified,
+		Offset:   (query.Page - 1) * query.PerPage,
+		Limit:    query.PerPage,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &UserPage{
+		Users:   users,
+		Total:   total,
+		Page:    query.Page,
+		PerPage: query.PerPage,
+	}, nil
+}
+
+// Disable blocks every login path for the user. Existing sessions are left to the caller.
+func (s *UserService) Disable(userID string) error {
+	now := time.Now()
+	return s.transactor.Transaction(func(tx repository.Repositories) error {
+		if err := tx.Users.SetDisabled(userID, &now); err != nil {
+			return userNotFound(err)
+		}
+		return publishUser(tx, EventUserDisabled, userID)
+	})
+}
+
+// Enable lifts a disable.
+func (s *UserService) Enable(userID string) error {
+	return s.transactor.Transaction(func(tx repository.Repositories) error {
+		if err := tx.Users.SetDisabled(userID, nil); err != nil {
+			return userNotFound(err)
+		}
+		return publishUser(tx, EventUserEnabled, userID)
+	})
+}
+
+// Delete permanently removes the user and everything they own.
+func (s *UserService) Delete(userID string) error {
+	return s.transactor.Transaction(func(tx repository.Repositories) error {
+		// Loaded first so subscribers learn who was deleted
+		user, err := tx.Users.FindByID(userID)
+		if err != nil {
+			return apperrors.ErrUserNotFound
+		}
+
+		if err := tx.Users.Delete(userID); err != nil {
+			return userNotFound(err)
+		}
+		return publishEvent(tx, EventUserDeleted, WebhookUserData(user))
+	})
+}
+
+// publishUser publishes a change to the user, with the user as it is now in tx.
+func publishUser(tx repository.Repositories, eventType, userID string) error {
+	user, err := tx.Users.FindByID(userID)
+	if err != nil {
+		return err
+	}
+	return publishEvent(tx, eventType, WebhookUserData(user))
+}
+
+// userNotFound maps a missing row to ErrUserNotFound.
+func userNotFound(err error) error {
+	if errors.Is(err, repository.ErrNotFound) {
+		return apperrors.ErrUserNotFound
+	}
+	return err
 }
@@ -0,0 +1,358 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	apperrors "rest-api/internal/errors"
+	"rest-api/internal/models"
+	"rest-api/internal/repository"
+	"rest-api/pkg/logger"
+
+	"github.com/go-webauthn/webauthn/protocol"
+	"github.com/go-webauthn/webauthn/webauthn"
+	"github.com/google/uuid"
+)
+
+const (
+	ceremonyTypeRegistration = "registration"
+	ceremonyTypeLogin        = "login"
+
+	ceremonyTTL = 5 * time.Minute
+)
+
+// PasskeyCeremony is returned when a ceremony starts. The client passes Options to
+// navigator.credentials and sends CeremonyID back with the authenticator response.
+type PasskeyCeremony struct {
+	CeremonyID string      `json:"ceremony_id"`
+	Options    interface{} `json:"options"`
+}
+
+type WebAuthnService struct {
+	webAuthn     *webauthn.WebAuthn
+	webAuthnRepo repository.WebAuthnRepository
+	userRepo     repository.UserRepository
+	authSvc      *AuthService
+	logger       *logger.Logger
+}
+
+func NewWebAuthnService(rpID, rpDisplayName string, rpOrigins []string, webAuthnRepo repository.WebAuthnRepository, userRepo repository.UserRepository, authSvc *AuthService, logger *logger.Logger) (*WebAuthnService, error) {
+	w, err := webauthn.New(&webauthn.Config{
+		RPID:          rpID,
+		RPDisplayName: rpDisplayName,
+		RPOrigins:     rpOrigins,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &WebAuthnService{
+		webAuthn:     w,
+		webAuthnRepo: webAuthnRepo,
+		userRepo:     userRepo,
+		authSvc:      authSvc,
+		logger:       logger,
+	}, nil
+}
+
+// Run periodically prunes expired ceremonies until ctx is cancelled. Login
+// ceremonies are started without authentication, so abandoned ones would
+// otherwise accumulate.
+func (s *WebAuthnService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.webAuthnRepo.DeleteExpiredCeremonies(); err != nil {
+				s.logger.Error("failed to prune passkey ceremonies", err)
+			}
+		}
+	}
+}
+
+// webAuthnUser adapts a user and their stored credentials to webauthn.User.
+type webAuthnUser struct {
+	user        *models.User
+	credentials []models.WebAuthnCredential
+}
+
+func (u *webAuthnUser) WebAuthnID() []byte {
+	return u.user.ID[:]
+}
+
+func (u *webAuthnUser) WebAuthnName() string {
+	return u.user.Email
+}
+
+func (u *webAuthnUser) WebAuthnDisplayName() string {
+	return u.user.Name
+}
+
+func (u *webAuthnUser) WebAuthnCredentials() []webauthn.Credential {
+	credentials := make([]webauthn.Credential, len(u.credentials))
+	for i, c := range u.credentials {
+		credentials[i] = toWebAuthnCredential(&c)
+	}
+	return credentials
+}
+
+func (s *WebAuthnService) loadUser(user *models.User) (*webAuthnUser, error) {
+	credentials, err := s.webAuthnRepo.FindCredentialsByUserID(user.ID.String())
+	if err != nil {
+		return nil, err
+	}
+	return &webAuthnUser{user: user, credentials: credentials}, nil
+}
+
+// BeginRegistration returns credential creation options for a new passkey.
+func (s *WebAuthnService) BeginRegistration(user *models.User) (*PasskeyCeremony, error) {
+	waUser, err := s.loadUser(user)
+	if err != nil {
+		return nil, err
+	}
+
+	exclusions := make([]protocol.CredentialDescriptor, len(waUser.credentials))
+	for i, c := range waUser.WebAuthnCredentials() {
+		exclusions[i] = c.Descriptor()
+	}
+
+	creation, sessionData, err := s.webAuthn.BeginRegistration(waUser,
+		webauthn.WithResidentKeyRequirement(protocol.ResidentKeyRequirementRequired),
+		webauthn.WithExclusions(exclusions),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	ceremonyID, err := s.saveCeremony(&user.ID, ceremonyTypeRegistration, sessionData)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PasskeyCeremony{CeremonyID: ceremonyID, Options: creation}, nil
+}
+
+// FinishRegistration verifies the attestation response and stores the new credential.
+func (s *WebAuthnService) FinishRegistration(user *models.User, ceremonyID, name string, response []byte) (*models.WebAuthnCredential, error) {
+	sessionData, ceremony, err := s.consumeCeremony(ceremonyID, ceremonyTypeRegistration)
+	if err != nil {
+		return nil, err
+	}
+	if ceremony.UserID == nil || *ceremony.UserID != user.ID {
+		return nil, apperrors.ErrInvalidCeremony
+	}
+
+	parsed, err := protocol.ParseCredentialCreationResponseBytes(response)
+	if err != nil {
+		return nil, apperrors.ErrInvalidPasskey
+	}
+
+	waUser, err := s.loadUser(user)
+	if err != nil {
+		return nil, err
+	}
+
+	credential, err := s.webAuthn.CreateCredential(waUser, *sessionData, parsed)
+	if err != nil {
+		return nil, apperrors.ErrInvalidPasskey
+	}
+
+	if strings.TrimSpace(name) == "" {
+		name = "Passkey"
+	}
+
+	transports := make([]string, len(credential.Transport))
+	for i, t := range credential.Transport {
+		transports[i] = string(t)
+	}
+
+	record := &models.WebAuthnCredential{
+		UserID:          user.ID,
+		Name:            name,
+		CredentialID:    credential.ID,
+		PublicKey:       credential.PublicKey,
+		AttestationType: credential.AttestationType,
+		Transports:      strings.Join(transports, ","),
+		AAGUID:          credential.Authenticator.AAGUID,
+		SignCount:       credential.Authenticator.SignCount,
+		UserVerified:    credential.Flags.UserVerified,
+		BackupEligible:  credential.Flags.BackupEligible,
+		BackupState:     credential.Flags.BackupState,
+	}
+
+	if err := s.webAuthnRepo.CreateCredential(record); err != nil {
+		return nil, err
+	}
+
+	return record, nil
+}
+
+// BeginLogin returns assertion options for a discoverable (usernameless) passkey login.
+func (s *WebAuthnService) BeginLogin() (*PasskeyCeremony, error) {
+	assertion, sessionData, err := s.webAuthn.BeginDiscoverableLogin()
+	if err != nil {
+		return nil, err
+	}
+
+	ceremonyID, err := s.saveCeremony(nil, ceremonyTypeLogin, sessionData)
+	if err != nil {
+		return nil, err
+	}
+
+	return &PasskeyCeremony{CeremonyID: ceremonyID, Options: assertion}, nil
+}
+
+// FinishLogin verifies the assertion, updates the sign counter and creates a session.
+func (s *WebAuthnService) FinishLogin(ceremonyID string, response []byte, client ClientInfo) (*models.Session, error) {
+	sessionData, _, err := s.consumeCeremony(ceremonyID, ceremonyTypeLogin)
+	if err != nil {
+		return nil, err
+	}
+
+	parsed, err := protocol.ParseCredentialRequestResponseBytes(response)
+	if err != nil {
+		return nil, apperrors.ErrInvalidPasskey
+	}
+
+	var waUser *webAuthnUser
+	handler := func(rawID, userHandle []byte) (webauthn.User, error) {
+		userID, err := uuid.FromBytes(userHandle)
+		if err != nil {
+			return nil, err
+		}
+		user, err := s.userRepo.FindByID(userID.String())
+		if err != nil {
+			return nil, err
+		}
+		waUser, err = s.loadUser(user)
+		if err != nil {
+			return nil, err
+		}
+		return waUser, nil
+	}
+
+	credential, err := s.webAuthn.ValidateDiscoverableLogin(handler, *sessionData, parsed)
+	if err != nil {
+		return nil, apperrors.ErrInvalidPasskey
+	}
+
+	for i := range waUser.credentials {
+		stored := &waUser.credentials[i]
+		if !bytes.Equal(stored.CredentialID, credential.ID) {
+			continue
+		}
+
+		now := time.Now()
+		stored.SignCount = credential.Authenticator.SignCount
+		stored.CloneWarning = credential.Authenticator.CloneWarning
+		stored.BackupState = credential.Flags.BackupState
+		stored.LastUsedAt = &now
+		if err := s.webAuthnRepo.UpdateCredentialUsage(stored); err != nil {
+			return nil, err
+		}
+		break
+	}
+
+	// A non-increasing sign counter means the authenticator may have been cloned
+	if credential.Authenticator.CloneWarning {
+		return nil, apperrors.ErrInvalidPasskey
+	}
+
+	return s.authSvc.CreateSession(waUser.user, client)
+}
+
+func (s *WebAuthnService) ListCredentials(userID string) ([]models.WebAuthnCredential, error) {
+	return s.webAuthnRepo.FindCredentialsByUserID(userID)
+}
+
+func (s *WebAuthnService) RenameCredential(userID, id, name string) error {
+	if err := s.webAuthnRepo.RenameCredential(userID, id, name); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return apperrors.ErrPasskeyNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *WebAuthnService) DeleteCredential(userID, id string) error {
+	if err := s.webAuthnRepo.DeleteCredential(userID, id); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return apperrors.ErrPasskeyNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *WebAuthnService) saveCeremony(userID *uuid.UUID, ceremonyType string, sessionData *webauthn.SessionData) (string, error) {
+	data, err := json.Marshal(sessionData)
+	if err != nil {
+		return "", err
+	}
+
+	ceremony := &models.WebAuthnCeremony{
+		UserID:    userID,
+		Type:      ceremonyType,
+		Data:      string(data),
+		ExpiresAt: time.Now().Add(ceremonyTTL),
+	}
+
+	if err := s.webAuthnRepo.CreateCeremony(ceremony); err != nil {
+		return "", err
+	}
+
+	return ceremony.ID.String(), nil
+}
+
+func (s *WebAuthnService) consumeCeremony(ceremonyID, ceremonyType string) (*webauthn.SessionData, *models.WebAuthnCeremony, error) {
+	if _, err := uuid.Parse(ceremonyID); err != nil {
+		return nil, nil, apperrors.ErrInvalidCeremony
+	}
+
+	ceremony, err := s.webAuthnRepo.ConsumeCeremony(ceremonyID, ceremonyType)
+	if err != nil {
+		return nil, nil, apperrors.ErrInvalidCeremony
+	}
+
+	var sessionData webauthn.SessionData
+	if err := json.Unmarshal([]byte(ceremony.Data), &sessionData); err != nil {
+		return nil, nil, err
+	}
+
+	return &sessionData, ceremony, nil
+}
+
+func toWebAuthnCredential(c *models.WebAuthnCredential) webauthn.Credential {
+	var transports []protocol.AuthenticatorTransport
+	if c.Transports != "" {
+		for _, t := range strings.Split(c.Transports, ",") {
+			transports = append(transports, protocol.AuthenticatorTransport(t))
+		}
+	}
+
+	return webauthn.Credential{
+		ID:              c.CredentialID,
+		PublicKey:       c.PublicKey,
+		AttestationType: c.AttestationType,
+		Transport:       transports,
+		Flags: webauthn.CredentialFlags{
+			UserVerified:   c.UserVerified,
+			BackupEligible: c.BackupEligible,
+			BackupState:    c.BackupState,
+		},
+		Authenticator: webauthn.Authenticator{
+			AAGUID:       c.AAGUID,
+			SignCount:    c.SignCount,
+			CloneWarning: c.CloneWarning,
+		},
+	}
+}
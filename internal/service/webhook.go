@@ -0,0 +1,506 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	apperrors "rest-api/internal/errors"
+	"rest-api/internal/models"
+	"rest-api/internal/repository"
+	"rest-api/pkg/encryption"
+	"rest-api/pkg/logger"
+
+	"github.com/google/uuid"
+)
+
+// Webhook events. Endpoints subscribe to a list of them, or to WebhookEventAll.
+const (
+	WebhookEventAll                 = "*"
+	WebhookEventUserRegistered      = "user.registered"
+	WebhookEventUserEmailVerified   = "user.email_verified"
+	WebhookEventUserPasswordChanged = "user.password_changed"
+	WebhookEventUserDisabled        = "user.disabled"
+	WebhookEventUserEnabled         = "user.enabled"
+	WebhookEventUserDeleted         = "user.deleted"
+	WebhookEventUserLoggedIn        = "user.logged_in"
+	WebhookEventUserLocked          = "user.locked"
+)
+
+// WebhookEvents lists the events endpoints can subscribe to.
+var WebhookEvents = []string{
+	WebhookEventUserRegistered,
+	WebhookEventUserEmailVerified,
+	WebhookEventUserPasswordChanged,
+	WebhookEventUserDisabled,
+	WebhookEventUserEnabled,
+	WebhookEventUserDeleted,
+	WebhookEventUserLoggedIn,
+	WebhookEventUserLocked,
+}
+
+// Webhook request headers. The signature is "t=<unix time>,v1=<hex HMAC-SHA256>"
+// over "<unix time>.<body>" keyed with the endpoint secret.
+const (
+	WebhookIDHeader        = "X-Webhook-Id"
+	WebhookEventHeader     = "X-Webhook-Event"
+	WebhookSignatureHeader = "X-Webhook-Signature"
+)
+
+const (
+	webhookBatchSize       = 20
+	webhookResponseMaxSize = 1024
+	webhookResolveTimeout  = 5 * time.Second
+	defaultDeliveriesPage  = 20
+	maxDeliveriesPage      = 100
+)
+
+// WebhookConfig controls how deliveries are attempted.
+type WebhookConfig struct {
+	HTTPClient *http.Client
+	// AllowPrivateNetworks permits endpoints on loopback, link-local and
+	// private addresses, for development only
+	AllowPrivateNetworks bool
+	// MaxAttempts is the number of attempts before a delivery is marked failed.
+	// Retries wait BackoffBase, doubling per attempt up to BackoffMax.
+	MaxAttempts int
+	BackoffBase time.Duration
+	BackoffMax  time.Duration
+}
+
+// WebhookRegistration describes an endpoint to create or update. An empty
+// Secret is generated on creation and left unchanged on update.
+type WebhookRegistration struct {
+	URL         string
+	Secret      string
+	Events      []string
+	Description string
+	Active      bool
+}
+
+// WebhookDeliveryPage is one page of an endpoint's deliveries.
+type WebhookDeliveryPage struct {
+	Deliveries []models.WebhookDelivery
+	Total      int64
+	Page       int
+	PerPage    int
+}
+
+// WebhookPayload is the JSON body sent to endpoints.
+type WebhookPayload struct {
+	ID        uuid.UUID              `json:"id"`
+	Type      string                 `json:"type"`
+	CreatedAt time.Time              `json:"created_at"`
+	Data      map[string]interface{} `json:"data"`
+}
+
+// WebhookUser is the user representation in webhook payloads.
+type WebhookUser struct {
+	ID            string    `json:"id"`
+	Email         string    `json:"email"`
+	Name          string    `json:"name"`
+	EmailVerified bool      `json:"email_verified"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// WebhookService queues signed event notifications for registered endpoints
+// and delivers them from a background worker, retrying with exponential backoff.
+type WebhookService struct {
+	webhookRepo repository.WebhookRepository
+	encryptor   *encryption.Encryptor
+	config      WebhookConfig
+	logger      *logger.Logger
+}
+
+func NewWebhookService(webhookRepo repository.WebhookRepository, encryptor *encryption.Encryptor, config WebhookConfig, logger *logger.Logger) *WebhookService {
+	if config.HTTPClient == nil {
+		config.HTTPClient = NewWebhookHTTPClient(0, config.AllowPrivateNetworks)
+	}
+	if config.MaxAttempts < 1 {
+		config.MaxAttempts = 1
+	}
+	return &WebhookService{
+		webhookRepo: webhookRepo,
+		encryptor:   encryptor,
+		config:      config,
+		logger:      logger,
+	}
+}
+
+// CreateEndpoint registers an endpoint and returns it with its plaintext secret.
+func (s *WebhookService) CreateEndpoint(reg WebhookRegistration, createdBy uuid.UUID) (*models.WebhookEndpoint, string, error) {
+	if err := s.validateWebhook(reg); err != nil {
+		return nil, "", err
+	}
+
+	secret := reg.Secret
+	if secret == "" {
+		token, err := randomToken()
+		if err != nil {
+			return nil, "", err
+		}
+		secret = "whsec_" + token
+	}
+	sealed, err := s.encryptor.Encrypt([]byte(secret))
+	if err != nil {
+		return nil, "", err
+	}
+
+	endpoint := &models.WebhookEndpoint{
+		URL:         reg.URL,
+		Secret:      sealed,
+		Events:      strings.Join(reg.Events, ","),
+		Description: reg.Description,
+		Active:      true,
+		CreatedBy:   &createdBy,
+	}
+	if err := s.webhookRepo.CreateEndpoint(endpoint); err != nil {
+		return nil, "", err
+	}
+
+	return endpoint, secret, nil
+}
+
+func (s *WebhookService) ListEndpoints() ([]models.WebhookEndpoint, error) {
+	return s.webhookRepo.ListEndpoints()
+}
+
+func (s *WebhookService) GetEndpoint(id string) (*models.WebhookEndpoint, error) {
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, apperrors.ErrWebhookNotFound
+	}
+	return webhookNotFound(s.webhookRepo.FindEndpoint(id))
+}
+
+// UpdateEndpoint replaces an endpoint's URL, events, description and active flag,
+// and its secret when one is given.
+func (s *WebhookService) UpdateEndpoint(id string, reg WebhookRegistration) (*models.WebhookEndpoint, error) {
+	if err := s.validateWebhook(reg); err != nil {
+		return nil, err
+	}
+
+	endpoint, err := s.GetEndpoint(id)
+	if err != nil {
+		return nil, err
+	}
+
+	endpoint.URL = reg.URL
+	endpoint.Events = strings.Join(reg.Events, ",")
+	endpoint.Description = reg.Description
+	endpoint.Active = reg.Active
+	if reg.Secret != "" {
+		if endpoint.Secret, err = s.encryptor.Encrypt([]byte(reg.Secret)); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.webhookRepo.UpdateEndpoint(endpoint); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, apperrors.ErrWebhookNotFound
+		}
+		return nil, err
+	}
+	return endpoint, nil
+}
+
+// DeleteEndpoint removes an endpoint with its pending deliveries and delivery log.
+func (s *WebhookService) DeleteEndpoint(id string) error {
+	if _, err := uuid.Parse(id); err != nil {
+		return apperrors.ErrWebhookNotFound
+	}
+	if err := s.webhookRepo.DeleteEndpoint(id); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return apperrors.ErrWebhookNotFound
+		}
+		return err
+	}
+	return nil
+}
+
+// Deliveries returns a page of an endpoint's delivery log, newest first.
+func (s *WebhookService) Deliveries(endpointID, status string, page, perPage int) (*WebhookDeliveryPage, error) {
+	if _, err := s.GetEndpoint(endpointID); err != nil {
+		return nil, err
+	}
+	if page < 1 {
+		page = 1
+	}
+	if perPage < 1 {
+		perPage = defaultDeliveriesPage
+	}
+	if perPage > maxDeliveriesPage {
+		perPage = maxDeliveriesPage
+	}
+
+	deliveries, total, err := s.webhookRepo.ListDeliveries(endpointID, status, (page-1)*perPage, perPage)
+	if err != nil {
+		return nil, err
+	}
+
+	return &WebhookDeliveryPage{
+		Deliveries: deliveries,
+		Total:      total,
+		Page:       page,
+		PerPage:    perPage,
+	}, nil
+}
+
+// Redeliver queues the event of an earlier delivery to be sent again right away.
+// The new delivery keeps the event ID, so receivers can recognise duplicates.
+func (s *WebhookService) Redeliver(endpointID, deliveryID string) (*models.WebhookDelivery, error) {
+	if _, err := uuid.Parse(deliveryID); err != nil {
+		return nil, apperrors.ErrDeliveryNotFound
+	}
+	if _, err := s.GetEndpoint(endpointID); err != nil {
+		return nil, err
+	}
+
+	original, err := s.webhookRepo.FindDelivery(endpointID, deliveryID)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, apperrors.ErrDeliveryNotFound
+		}
+		return nil, err
+	}
+
+	deliveries := []models.WebhookDelivery{{
+		EndpointID:    original.EndpointID,
+		EventID:       original.EventID,
+		EventType:     original.EventType,
+		Payload:       original.Payload,
+		Status:        models.WebhookDeliveryPending,
+		NextAttemptAt: time.Now(),
+	}}
+	if err := s.webhookRepo.CreateDeliveries(deliveries); err != nil {
+		return nil, err
+	}
+	return &deliveries[0], nil
+}
+
+// HandleEvent queues a user lifecycle event for every active endpoint
+// subscribed to it. The webhook event ID is the domain event ID, which stays
+// the same if the event is handled again, so receivers can discard repeats.
+func (s *WebhookService) HandleEvent(ctx context.Context, event *Event, dedupKey string) error {
+	var data map[string]interface{}
+	if err := event.Decode(&data); err != nil {
+		return err
+	}
+
+	endpoints, err := s.webhookRepo.ListActiveEndpoints()
+	if err != nil {
+		return err
+	}
+
+	var subscribed []models.WebhookEndpoint
+	for _, endpoint := range endpoints {
+		events := splitList(endpoint.Events)
+		if slices.Contains(events, event.Type) || slices.Contains(events, WebhookEventAll) {
+			subscribed = append(subscribed, endpoint)
+		}
+	}
+	if len(subscribed) == 0 {
+		return nil
+	}
+
+	payload := WebhookPayload{
+		ID:        event.ID,
+		Type:      event.Type,
+		CreatedAt: event.CreatedAt.UTC(),
+		Data:      data,
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	deliveries := make([]models.WebhookDelivery, len(subscribed))
+	for i, endpoint := range subscribed {
+		deliveries[i] = models.WebhookDelivery{
+			EndpointID:    endpoint.ID,
+			EventID:       payload.ID,
+			EventType:     event.Type,
+			Payload:       string(body),
+			Status:        models.WebhookDeliveryPending,
+			NextAttemptAt: time.Now(),
+		}
+	}
+	return s.webhookRepo.CreateDeliveries(deliveries)
+}
+
+// Run delivers due webhooks every interval until ctx is cancelled.
+func (s *WebhookService) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.deliverDue(ctx)
+		}
+	}
+}
+
+// deliverDue claims a batch of due deliveries and attempts them concurrently.
+// The lease outlasts the HTTP timeout so a claim cannot expire mid-attempt.
+func (s *WebhookService) deliverDue(ctx context.Context) {
+	now := time.Now()
+	lease := 2 * s.config.HTTPClient.Timeout
+	if lease <= 0 {
+		lease = time.Minute
+	}
+
+	deliveries, err := s.webhookRepo.ClaimDue(now, now.Add(lease), webhookBatchSize)
+	if err != nil {
+		s.logger.Error("failed to claim webhook deliveries", err)
+		return
+	}
+
+	// Attempts already started run to completion on shutdown rather than being cut off
+	ctx = context.WithoutCancel(ctx)
+
+	var wg sync.WaitGroup
+	for i := range deliveries {
+		wg.Add(1)
+		go func(delivery *models.WebhookDelivery) {
+			defer wg.Done()
+			s.attempt(ctx, delivery)
+		}(&deliveries[i])
+	}
+	wg.Wait()
+}
+
+// attempt sends one delivery and records the outcome, scheduling a retry or
+// giving up once MaxAttempts is reached.
+func (s *WebhookService) attempt(ctx context.Context, delivery *models.WebhookDelivery) {
+	now := time.Now()
+	delivery.Attempts++
+	delivery.LastAttemptAt = &now
+	delivery.ResponseStatus = 0
+	delivery.ResponseBody = ""
+	delivery.LastError = ""
+
+	status, body, err := s.send(ctx, delivery)
+	delivery.ResponseStatus = status
+	delivery.ResponseBody = body
+	switch {
+	case err == nil && status >= 200 && status < 300:
+		delivery.Status = models.WebhookDeliverySucceeded
+		delivery.DeliveredAt = &now
+	case delivery.Attempts >= s.config.MaxAttempts:
+		delivery.Status = models.WebhookDeliveryFailed
+	default:
+		delivery.NextAttemptAt = now.Add(backoffDelay(s.config.BackoffBase, s.config.BackoffMax, delivery.Attempts))
+	}
+	if err != nil {
+		delivery.LastError = err.Error()
+	} else if delivery.Status != models.WebhookDeliverySucceeded {
+		delivery.LastError = fmt.Sprintf("endpoint responded with status %d", status)
+	}
+
+	if err := s.webhookRepo.RecordAttempt(delivery); err != nil {
+		s.logger.Error("failed to record webhook delivery attempt", err)
+	}
+}
+
+// send POSTs the signed payload and returns the response status and the start of its body.
+func (s *WebhookService) send(ctx context.Context, delivery *models.WebhookDelivery) (int, string, error) {
+	endpoint, err := s.webhookRepo.FindEndpoint(delivery.EndpointID.String())
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to load endpoint: %w", err)
+	}
+	secret, err := s.encryptor.Decrypt(endpoint.Secret)
+	if err != nil {
+		return 0, "", fmt.Errorf("failed to decrypt endpoint secret: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.URL, strings.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(WebhookIDHeader, delivery.EventID.String())
+	req.Header.Set(WebhookEventHeader, delivery.EventType)
+	req.Header.Set(WebhookSignatureHeader, SignWebhook(secret, time.Now(), []byte(delivery.Payload)))
+
+	resp, err := s.config.HTTPClient.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, webhookResponseMaxSize))
+	// Kept in a text column, which takes neither NUL bytes nor the invalid UTF-8 the size limit may leave
+	text := strings.ToValidUTF8(strings.ReplaceAll(string(bytes.TrimSpace(body)), "\x00", ""), "")
+	return resp.StatusCode, text, nil
+}
+
+// SignWebhook returns the signature header value for a payload sent at t.
+func SignWebhook(secret []byte, t time.Time, payload []byte) string {
+	timestamp := strconv.FormatInt(t.Unix(), 10)
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(payload)
+	return "t=" + timestamp + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// WebhookUserData is the data of user events.
+func WebhookUserData(user *models.User) map[string]interface{} {
+	return map[string]interface{}{"user": webhookUser(user)}
+}
+
+func webhookUser(user *models.User) WebhookUser {
+	return WebhookUser{
+		ID:            user.ID.String(),
+		Email:         user.Email,
+		Name:          user.Name,
+		EmailVerified: user.EmailVerifiedAt != nil,
+		CreatedAt:     user.CreatedAt,
+	}
+}
+
+// validateWebhook checks an endpoint's URL and event filter.
+func (s *WebhookService) validateWebhook(reg WebhookRegistration) error {
+	u, err := url.Parse(reg.URL)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Hostname() == "" {
+		return apperrors.ErrInvalidRequest
+	}
+	if !s.config.AllowPrivateNetworks {
+		ctx, cancel := context.WithTimeout(context.Background(), webhookResolveTimeout)
+		defer cancel()
+		if err := checkWebhookHost(ctx, u.Hostname()); err != nil {
+			return apperrors.ErrWebhookDestination
+		}
+	}
+	if len(reg.Events) == 0 {
+		return apperrors.ErrUnknownEvent
+	}
+	for _, event := range reg.Events {
+		if event != WebhookEventAll && !slices.Contains(WebhookEvents, event) {
+			return apperrors.ErrUnknownEvent
+		}
+	}
+	return nil
+}
+
+// webhookNotFound maps a missing endpoint to ErrWebhookNotFound.
+func webhookNotFound(endpoint *models.WebhookEndpoint, err error) (*models.WebhookEndpoint, error) {
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, apperrors.ErrWebhookNotFound
+	}
+	return endpoint, err
+}
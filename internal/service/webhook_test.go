@@ -0,0 +1,84 @@
+package service
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+
+	apperrors "rest-api/internal/errors"
+	"rest-api/pkg/logger"
+)
+
+func TestIsPublicAddr(t *testing.T) {
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{"93.184.216.34", true},
+		{"2606:2800:220:1:248:1893:25c8:1946", true},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"0.0.0.0", false},
+		{"::", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"fd00::1", false},
+		{"169.254.169.254", false},
+		{"fe80::1", false},
+		{"100.64.0.1", false},
+		{"224.0.0.1", false},
+		{"255.255.255.255", false},
+		{"::ffff:127.0.0.1", false},
+		{"::ffff:10.0.0.1", false},
+	}
+	for _, tt := range tests {
+		if got := isPublicAddr(netip.MustParseAddr(tt.addr)); got != tt.want {
+			t.Errorf("isPublicAddr(%s) = %v, want %v", tt.addr, got, tt.want)
+		}
+	}
+}
+
+func TestWebhookHTTPClientRefusesPrivateAddresses(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	if _, err := NewWebhookHTTPClient(time.Second, false).Get(srv.URL); err == nil {
+		t.Error("connected to a loopback address")
+	}
+
+	resp, err := NewWebhookHTTPClient(time.Second, true).Get(srv.URL)
+	if err != nil {
+		t.Fatalf("private networks allowed: %v", err)
+	}
+	resp.Body.Close()
+}
+
+func TestValidateWebhookRejectsPrivateDestinations(t *testing.T) {
+	s := NewWebhookService(nil, nil, WebhookConfig{}, logger.NewLogger())
+	events := []string{WebhookEventAll}
+
+	for _, url := range []string{
+		"http://127.0.0.1/hook",
+		"http://[::1]:8080/hook",
+		"http://169.254.169.254/latest/meta-data",
+		"https://10.0.0.5/hook",
+		"http://localhost/hook",
+	} {
+		if err := s.validateWebhook(WebhookRegistration{URL: url, Events: events}); !errors.Is(err, apperrors.ErrWebhookDestination) {
+			t.Errorf("%s: got %v, want ErrWebhookDestination", url, err)
+		}
+	}
+
+	if err := s.validateWebhook(WebhookRegistration{URL: "https://93.184.216.34/hook", Events: events}); err != nil {
+		t.Errorf("public address: %v", err)
+	}
+
+	s = NewWebhookService(nil, nil, WebhookConfig{AllowPrivateNetworks: true}, logger.NewLogger())
+	if err := s.validateWebhook(WebhookRegistration{URL: "http://127.0.0.1/hook", Events: events}); err != nil {
+		t.Errorf("private networks allowed: %v", err)
+	}
+}
@@ -1,18 +1,33 @@
 package email
 
 import (
+	"bytes"
 	"fmt"
-	"net/smtp"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/textproto"
+	"time"
 )
 
+// Config configures how emails are sent.
 type Config struct {
-	Host     string
-	Port     int
-	Username string
-	Password string
-	From     string
+	From      string
+	Transport Transport
 }
 
+// Message is an email with a plain text part, an HTML part, or both. Messages
+// with both are sent as multipart/alternative so clients show the richest part
+// they support.
+type Message struct {
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// EmailService renders messages in MIME format and hands them to a Transport.
 type EmailService struct {
 	config Config
 }
@@ -23,17 +38,73 @@ func NewEmailService(config Config) *EmailService {
 	}
 }
 
-func (s *EmailService) SendEmail(to, subject, body string) error {
-	auth := smtp.PlainAuth("", s.config.Username, s.config.Password, s.config.Host)
+// Send delivers a message through the configured transport.
+func (s *EmailService) Send(message *Message) error {
+	msg, err := s.build(message)
+	if err != nil {
+		return err
+	}
+	return s.config.Transport.Send(s.config.From, message.To, msg)
+}
+
+// Close releases the transport's connections.
+func (s *EmailService) Close() error {
+	return s.config.Transport.Close()
+}
+
+// build renders the message in MIME format. Subjects are encoded so they may
+// contain any UTF-8 text, and parts are quoted-printable so long lines survive.
+func (s *EmailService) build(message *Message) ([]byte, error) {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "From: %s\r\n", s.config.From)
+	fmt.Fprintf(&buf, "To: %s\r\n", message.To)
+	fmt.Fprintf(&buf, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", message.Subject))
+	fmt.Fprintf(&buf, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	buf.WriteString("MIME-Version: 1.0\r\n")
 
-	msg := fmt.Sprintf("From: %s\r\n"+
-		"To: %s\r\n"+
-		"Subject: %s\r\n"+
-		"MIME-Version: 1.0\r\n"+
-		"Content-Type: text/html; charset=UTF-8\r\n"+
-		"\r\n"+
-		"%s\r\n", s.config.From, to, subject, body)
+	if message.Text == "" || message.HTML == "" {
+		contentType, body := "text/plain", message.Text
+		if message.HTML != "" {
+			contentType, body = "text/html", message.HTML
+		}
+		fmt.Fprintf(&buf, "Content-Type: %s; charset=UTF-8\r\n", contentType)
+		buf.WriteString("Content-Transfer-Encoding: quoted-printable\r\n\r\n")
+		if err := writeQuotedPrintable(&buf, body); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
 
-	addr := fmt.Sprintf("%s:%d", s.config.Host, s.config.Port)
-	return smtp.SendMail(addr, auth, s.config.From, []string{to}, []byte(msg))
+	mw := multipart.NewWriter(&buf)
+	fmt.Fprintf(&buf, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", mw.Boundary())
+
+	// Parts go from plainest to richest
+	for _, part := range []struct{ contentType, body string }{
+		{"text/plain", message.Text},
+		{"text/html", message.HTML},
+	} {
+		w, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType + "; charset=UTF-8"},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := writeQuotedPrintable(w, part.body); err != nil {
+			return nil, err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func writeQuotedPrintable(w io.Writer, body string) error {
+	qp := quotedprintable.NewWriter(w)
+	if _, err := qp.Write([]byte(body)); err != nil {
+		return err
+	}
+	return qp.Close()
 }
@@ -0,0 +1,257 @@
+package email
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// SMTP connection security modes.
+const (
+	// SMTPStartTLS upgrades plain connections with STARTTLS and refuses servers
+	// that don't offer it
+	SMTPStartTLS = "starttls"
+	// SMTPImplicitTLS speaks TLS from the start, usually on port 465
+	SMTPImplicitTLS = "tls"
+	// SMTPPlain never encrypts, for local mail catchers only
+	SMTPPlain = "none"
+)
+
+const (
+	defaultSMTPTimeout     = 30 * time.Second
+	defaultSMTPIdleTimeout = 30 * time.Second
+)
+
+type SMTPConfig struct {
+	Host string
+	Port int
+	// Username and Password authenticate with AUTH PLAIN. When Username is
+	// set, servers that don't offer AUTH are refused.
+	Username string
+	Password string
+	// TLS is SMTPStartTLS, SMTPImplicitTLS or SMTPPlain. It defaults to
+	// SMTPImplicitTLS on port 465 and SMTPStartTLS elsewhere.
+	TLS string
+	// Timeout bounds dialing, and separately a whole send on an open connection
+	Timeout time.Duration
+	// PoolSize is the number of idle connections kept open for reuse, and
+	// IdleTimeout how long they are kept
+	PoolSize    int
+	IdleTimeout time.Duration
+}
+
+// SMTPTransport sends messages to an SMTP server, reusing connections between
+// messages.
+type SMTPTransport struct {
+	config SMTPConfig
+	// rootCAs verifies the server certificate; nil means the system roots
+	rootCAs *x509.CertPool
+
+	mu     sync.Mutex
+	idle   []*smtpConn
+	closed bool
+}
+
+// smtpConn is an authenticated connection ready for the next message.
+type smtpConn struct {
+	conn     net.Conn
+	client   *smtp.Client
+	lastUsed time.Time
+}
+
+func NewSMTPTransport(config SMTPConfig) (*SMTPTransport, error) {
+	if config.TLS == "" {
+		config.TLS = SMTPStartTLS
+		if config.Port == 465 {
+			config.TLS = SMTPImplicitTLS
+		}
+	}
+	switch config.TLS {
+	case SMTPStartTLS, SMTPImplicitTLS, SMTPPlain:
+	default:
+		return nil, fmt.Errorf("unknown SMTP TLS mode %q", config.TLS)
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = defaultSMTPTimeout
+	}
+	if config.IdleTimeout <= 0 {
+		config.IdleTimeout = defaultSMTPIdleTimeout
+	}
+	return &SMTPTransport{config: config}, nil
+}
+
+func (t *SMTPTransport) Send(from, to string, msg []byte) error {
+	c, err := t.conn()
+	if err != nil {
+		return err
+	}
+	if err := c.conn.SetDeadline(time.Now().Add(t.config.Timeout)); err != nil {
+		c.close()
+		return err
+	}
+
+	if err := send(c.client, from, to, msg); err != nil {
+		// The server replied, so the connection is still usable once reset
+		var reply *textproto.Error
+		if errors.As(err, &reply) && c.client.Reset() == nil {
+			t.release(c)
+		} else {
+			c.close()
+		}
+		return err
+	}
+
+	t.release(c)
+	return nil
+}
+
+func send(client *smtp.Client, from, to string, msg []byte) error {
+	if err := client.Mail(from); err != nil {
+		return err
+	}
+	if err := client.Rcpt(to); err != nil {
+		return err
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	return w.Close()
+}
+
+// Close closes the idle connections and stops new ones from being pooled.
+func (t *SMTPTransport) Close() error {
+	t.mu.Lock()
+	idle := t.idle
+	t.idle = nil
+	t.closed = true
+	t.mu.Unlock()
+
+	for _, c := range idle {
+		c.quit()
+	}
+	return nil
+}
+
+// conn returns a pooled connection the server still answers on, or dials a
+// new one.
+func (t *SMTPTransport) conn() (*smtpConn, error) {
+	for {
+		t.mu.Lock()
+		if len(t.idle) == 0 {
+			t.mu.Unlock()
+			return t.dial()
+		}
+		c := t.idle[len(t.idle)-1]
+		t.idle = t.idle[:len(t.idle)-1]
+		t.mu.Unlock()
+
+		if time.Since(c.lastUsed) > t.config.IdleTimeout {
+			c.quit()
+			continue
+		}
+		// Servers drop idle connections on their own schedule
+		if err := c.conn.SetDeadline(time.Now().Add(t.config.Timeout)); err == nil && c.client.Noop() == nil {
+			return c, nil
+		}
+		c.close()
+	}
+}
+
+// release returns a connection to the pool, or quits it when the pool is full.
+func (t *SMTPTransport) release(c *smtpConn) {
+	c.lastUsed = time.Now()
+
+	t.mu.Lock()
+	if !t.closed && len(t.idle) < t.config.PoolSize {
+		t.idle = append(t.idle, c)
+		c = nil
+	}
+	t.mu.Unlock()
+
+	if c != nil {
+		c.quit()
+	}
+}
+
+// dial opens a connection and secures and authenticates it as configured.
+func (t *SMTPTransport) dial() (*smtpConn, error) {
+	addr := net.JoinHostPort(t.config.Host, strconv.Itoa(t.config.Port))
+	tlsConfig := &tls.Config{ServerName: t.config.Host, RootCAs: t.rootCAs}
+	dialer := &net.Dialer{Timeout: t.config.Timeout}
+
+	var conn net.Conn
+	var err error
+	if t.config.TLS == SMTPImplicitTLS {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := conn.SetDeadline(time.Now().Add(t.config.Timeout)); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	client, err := smtp.NewClient(conn, t.config.Host)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	c := &smtpConn{conn: conn, client: client}
+
+	if t.config.TLS == SMTPStartTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			c.close()
+			return nil, errors.New("smtp server does not support STARTTLS")
+		}
+		if err := client.StartTLS(tlsConfig); err != nil {
+			c.close()
+			return nil, err
+		}
+	}
+	// Never fall back to sending unauthenticated when credentials are configured
+	if t.config.Username != "" {
+		if ok, _ := client.Extension("AUTH"); !ok {
+			c.close()
+			return nil, errors.New("smtp server does not support AUTH")
+		}
+		if err := client.Auth(smtp.PlainAuth("", t.config.Username, t.config.Password, t.config.Host)); err != nil {
+			c.close()
+			return nil, err
+		}
+	}
+
+	return c, nil
+}
+
+// quit ends the session politely before closing the connection.
+func (c *smtpConn) quit() {
+	c.conn.SetDeadline(time.Now().Add(time.Second))
+	if c.client.Quit() != nil {
+		c.close()
+	}
+}
+
+func (c *smtpConn) close() {
+	c.client.Close()
+}
+
+// IsPermanent reports whether a send failed because the server rejected the
+// message outright (a 5xx reply), so retrying it cannot succeed.
+func IsPermanent(err error) bool {
+	var reply *textproto.Error
+	return errors.As(err, &reply) && reply.Code >= 500
+}
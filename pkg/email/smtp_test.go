@@ -0,0 +1,410 @@
+package email
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"net"
+	"net/http/httptest"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// received is a message accepted by fakeSMTPServer.
+type received struct {
+	from, to string
+	data     string
+	tls      bool
+	user     string
+}
+
+// fakeSMTPServer speaks just enough SMTP for SMTPTransport: EHLO, STARTTLS,
+// AUTH PLAIN, MAIL, RCPT, DATA, RSET, NOOP and QUIT. Recipients containing
+// "reject" are refused with a 550.
+type fakeSMTPServer struct {
+	fakeSMTPOptions
+	listener net.Listener
+	port     int
+	tls      *tls.Config
+	roots    *x509.CertPool
+
+	mu       sync.Mutex
+	conns    int
+	quits    int
+	messages []received
+	wg       sync.WaitGroup
+}
+
+type fakeSMTPOptions struct {
+	// implicitTLS speaks TLS from the start
+	implicitTLS bool
+	// startTLS offers STARTTLS on plain connections
+	startTLS bool
+	// noAuth leaves AUTH out of the extensions the server offers
+	noAuth bool
+	// dropAfterData closes the connection once a message has been accepted,
+	// as servers do with idle connections
+	dropAfterData bool
+}
+
+// newFakeSMTPServer listens on loopback with a certificate valid for 127.0.0.1.
+func newFakeSMTPServer(t *testing.T, options fakeSMTPOptions) *fakeSMTPServer {
+	t.Helper()
+
+	// Borrow httptest's certificate rather than generating one
+	https := httptest.NewTLSServer(nil)
+	https.Close()
+	s := &fakeSMTPServer{
+		fakeSMTPOptions: options,
+		tls:             &tls.Config{Certificates: https.TLS.Certificates},
+		roots:           x509.NewCertPool(),
+	}
+	s.roots.AddCert(https.Certificate())
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if options.implicitTLS {
+		listener = tls.NewListener(listener, s.tls)
+	}
+	s.listener = listener
+	s.port = listener.Addr().(*net.TCPAddr).Port
+
+	go s.serve()
+	t.Cleanup(func() {
+		listener.Close()
+		s.wg.Wait()
+	})
+	return s
+}
+
+// transport returns a transport for the server, trusting its certificate.
+func (s *fakeSMTPServer) transport(t *testing.T, config SMTPConfig) *SMTPTransport {
+	t.Helper()
+	config.Host = "127.0.0.1"
+	config.Port = s.port
+	transport, err := NewSMTPTransport(config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	transport.rootCAs = s.roots
+	t.Cleanup(func() { transport.Close() })
+	return transport
+}
+
+func (s *fakeSMTPServer) serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.conns++
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			defer conn.Close()
+			s.handle(conn)
+		}()
+	}
+}
+
+func (s *fakeSMTPServer) handle(conn net.Conn) {
+	conn.SetDeadline(time.Now().Add(10 * time.Second))
+	_, secure := conn.(*tls.Conn)
+	tp := textproto.NewConn(conn)
+	var msg received
+	var user string
+
+	tp.PrintfLine("220 fake ESMTP")
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+
+		switch strings.ToUpper(verb) {
+		case "EHLO":
+			extensions := []string{"fake"}
+			if s.startTLS && !secure {
+				extensions = append(extensions, "STARTTLS")
+			}
+			if !s.noAuth {
+				extensions = append(extensions, "AUTH PLAIN")
+			}
+			for i, extension := range extensions {
+				separator := "-"
+				if i == len(extensions)-1 {
+					separator = " "
+				}
+				tp.PrintfLine("250%s%s", separator, extension)
+			}
+		case "STARTTLS":
+			if !s.startTLS || secure {
+				tp.PrintfLine("502 not supported")
+				continue
+			}
+			tp.PrintfLine("220 ready")
+			conn = tls.Server(conn, s.tls)
+			tp = textproto.NewConn(conn)
+			secure = true
+		case "AUTH":
+			mechanism, initial, _ := strings.Cut(arg, " ")
+			creds, err := base64.StdEncoding.DecodeString(initial)
+			fields := strings.Split(string(creds), "\x00")
+			if mechanism != "PLAIN" || err != nil || len(fields) != 3 {
+				tp.PrintfLine("535 authentication failed")
+				continue
+			}
+			user = fields[1]
+			tp.PrintfLine("235 ok")
+		case "MAIL":
+			msg = received{from: strings.Trim(strings.TrimPrefix(arg, "FROM:"), "<>"), tls: secure, user: user}
+			tp.PrintfLine("250 ok")
+		case "RCPT":
+			msg.to = strings.Trim(strings.TrimPrefix(arg, "TO:"), "<>")
+			if strings.Contains(msg.to, "reject") {
+				tp.PrintfLine("550 no such user")
+				continue
+			}
+			tp.PrintfLine("250 ok")
+		case "DATA":
+			tp.PrintfLine("354 go ahead")
+			data, err := tp.ReadDotBytes()
+			if err != nil {
+				return
+			}
+			msg.data = string(data)
+			s.mu.Lock()
+			s.messages = append(s.messages, msg)
+			s.mu.Unlock()
+			tp.PrintfLine("250 queued")
+			if s.dropAfterData {
+				return
+			}
+		case "RSET", "NOOP":
+			tp.PrintfLine("250 ok")
+		case "QUIT":
+			s.mu.Lock()
+			s.quits++
+			s.mu.Unlock()
+			tp.PrintfLine("221 bye")
+			return
+		default:
+			tp.PrintfLine("500 unknown command")
+		}
+	}
+}
+
+func (s *fakeSMTPServer) stats() (conns, quits int, messages []received) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.conns, s.quits, append([]received(nil), s.messages...)
+}
+
+const testMessage = "Subject: hello\r\n\r\nhello\r\n"
+
+func TestSMTPTransportStartTLS(t *testing.T) {
+	server := newFakeSMTPServer(t, fakeSMTPOptions{startTLS: true})
+	transport := server.transport(t, SMTPConfig{Username: "mailer", Password: "secret", PoolSize: 1})
+
+	for i := 0; i < 2; i++ {
+		if err := transport.Send("noreply@example.com", "alice@example.com", []byte(testMessage)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	conns, _, messages := server.stats()
+	if conns != 1 {
+		t.Errorf("opened %d connections, want 1 reused", conns)
+	}
+	if len(messages) != 2 {
+		t.Fatalf("server received %d messages, want 2", len(messages))
+	}
+	for _, m := range messages {
+		if !m.tls || m.user != "mailer" || m.from != "noreply@example.com" || m.to != "alice@example.com" {
+			t.Errorf("unexpected delivery: %+v", m)
+		}
+		// The server sees lines ending in LF once the dot-encoding is undone
+		if want := strings.ReplaceAll(testMessage, "\r\n", "\n"); m.data != want {
+			t.Errorf("got message %q, want %q", m.data, want)
+		}
+	}
+}
+
+func TestSMTPTransportRequiresStartTLS(t *testing.T) {
+	server := newFakeSMTPServer(t, fakeSMTPOptions{})
+	transport := server.transport(t, SMTPConfig{Username: "mailer", Password: "secret"})
+
+	if err := transport.Send("noreply@example.com", "alice@example.com", []byte(testMessage)); err == nil {
+		t.Error("sent over a connection the server would not encrypt")
+	}
+	if _, _, messages := server.stats(); len(messages) != 0 {
+		t.Errorf("server received %d messages in plain text", len(messages))
+	}
+}
+
+func TestSMTPTransportRequiresAuth(t *testing.T) {
+	server := newFakeSMTPServer(t, fakeSMTPOptions{implicitTLS: true, noAuth: true})
+
+	// Without credentials there is nothing to authenticate
+	if err := server.transport(t, SMTPConfig{TLS: SMTPImplicitTLS}).Send("noreply@example.com", "alice@example.com", []byte(testMessage)); err != nil {
+		t.Fatal(err)
+	}
+
+	transport := server.transport(t, SMTPConfig{TLS: SMTPImplicitTLS, Username: "mailer", Password: "secret"})
+	if err := transport.Send("noreply@example.com", "alice@example.com", []byte(testMessage)); err == nil {
+		t.Error("sent unauthenticated although credentials are configured")
+	}
+	if _, _, messages := server.stats(); len(messages) != 1 {
+		t.Errorf("server received %d messages, want only the one sent without credentials", len(messages))
+	}
+}
+
+func TestSMTPTransportImplicitTLS(t *testing.T) {
+	server := newFakeSMTPServer(t, fakeSMTPOptions{implicitTLS: true})
+	transport := server.transport(t, SMTPConfig{TLS: SMTPImplicitTLS, Username: "mailer", Password: "secret"})
+
+	if err := transport.Send("noreply@example.com", "alice@example.com", []byte(testMessage)); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, messages := server.stats(); len(messages) != 1 || !messages[0].tls || messages[0].user != "mailer" {
+		t.Errorf("unexpected deliveries: %+v", messages)
+	}
+
+	// A STARTTLS client cannot talk to an implicit TLS port, and vice versa
+	starttls := server.transport(t, SMTPConfig{TLS: SMTPStartTLS, Timeout: time.Second})
+	if err := starttls.Send("noreply@example.com", "alice@example.com", []byte(testMessage)); err == nil {
+		t.Error("STARTTLS transport sent to an implicit TLS port")
+	}
+	plain := newFakeSMTPServer(t, fakeSMTPOptions{startTLS: true})
+	implicit := plain.transport(t, SMTPConfig{TLS: SMTPImplicitTLS, Timeout: time.Second})
+	if err := implicit.Send("noreply@example.com", "alice@example.com", []byte(testMessage)); err == nil {
+		t.Error("implicit TLS transport sent to a plain port")
+	}
+}
+
+func TestSMTPTransportRejectsUntrustedCertificates(t *testing.T) {
+	server := newFakeSMTPServer(t, fakeSMTPOptions{implicitTLS: true})
+	transport := server.transport(t, SMTPConfig{TLS: SMTPImplicitTLS})
+	transport.rootCAs = x509.NewCertPool()
+
+	err := transport.Send("noreply@example.com", "alice@example.com", []byte(testMessage))
+	var unknownAuthority x509.UnknownAuthorityError
+	if !errors.As(err, &unknownAuthority) {
+		t.Errorf("got %v, want an unknown authority error", err)
+	}
+}
+
+func TestSMTPTransportPool(t *testing.T) {
+	send := func(t *testing.T, transport *SMTPTransport, to string) error {
+		t.Helper()
+		return transport.Send("noreply@example.com", to, []byte(testMessage))
+	}
+
+	t.Run("no pool", func(t *testing.T) {
+		server := newFakeSMTPServer(t, fakeSMTPOptions{})
+		transport := server.transport(t, SMTPConfig{TLS: SMTPPlain})
+		for i := 0; i < 2; i++ {
+			if err := send(t, transport, "alice@example.com"); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if conns, quits, _ := server.stats(); conns != 2 || quits != 2 {
+			t.Errorf("got %d connections and %d quits, want a fresh connection per message", conns, quits)
+		}
+	})
+
+	t.Run("idle timeout", func(t *testing.T) {
+		server := newFakeSMTPServer(t, fakeSMTPOptions{})
+		transport := server.transport(t, SMTPConfig{TLS: SMTPPlain, PoolSize: 1, IdleTimeout: time.Millisecond})
+		if err := send(t, transport, "alice@example.com"); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(10 * time.Millisecond)
+		if err := send(t, transport, "alice@example.com"); err != nil {
+			t.Fatal(err)
+		}
+		if conns, quits, _ := server.stats(); conns != 2 || quits != 1 {
+			t.Errorf("got %d connections and %d quits, want the expired one replaced", conns, quits)
+		}
+	})
+
+	t.Run("dropped by server", func(t *testing.T) {
+		server := newFakeSMTPServer(t, fakeSMTPOptions{dropAfterData: true})
+		transport := server.transport(t, SMTPConfig{TLS: SMTPPlain, PoolSize: 1})
+		for i := 0; i < 2; i++ {
+			if err := send(t, transport, "alice@example.com"); err != nil {
+				t.Fatalf("message %d: %v", i, err)
+			}
+		}
+		if conns, _, messages := server.stats(); conns != 2 || len(messages) != 2 {
+			t.Errorf("got %d connections and %d messages, want a redial after the drop", conns, len(messages))
+		}
+	})
+
+	t.Run("rejected recipient", func(t *testing.T) {
+		server := newFakeSMTPServer(t, fakeSMTPOptions{})
+		transport := server.transport(t, SMTPConfig{TLS: SMTPPlain, PoolSize: 1})
+		if err := send(t, transport, "reject@example.com"); !IsPermanent(err) {
+			t.Errorf("got %v, want a permanent failure", err)
+		}
+		// The session is reset and reused for the next message
+		if err := send(t, transport, "alice@example.com"); err != nil {
+			t.Fatal(err)
+		}
+		if conns, _, messages := server.stats(); conns != 1 || len(messages) != 1 {
+			t.Errorf("got %d connections and %d messages, want one connection reused", conns, len(messages))
+		}
+	})
+
+	t.Run("close", func(t *testing.T) {
+		server := newFakeSMTPServer(t, fakeSMTPOptions{})
+		transport := server.transport(t, SMTPConfig{TLS: SMTPPlain, PoolSize: 2})
+		if err := send(t, transport, "alice@example.com"); err != nil {
+			t.Fatal(err)
+		}
+		transport.Close()
+		if err := send(t, transport, "alice@example.com"); err != nil {
+			t.Fatal(err)
+		}
+		// Both the pooled connection and the one opened after Close are quit
+		if conns, quits, _ := server.stats(); conns != 2 || quits != 2 {
+			t.Errorf("got %d connections and %d quits, want nothing pooled after Close", conns, quits)
+		}
+	})
+}
+
+func TestNewSMTPTransportTLSMode(t *testing.T) {
+	tests := []struct {
+		port int
+		tls  string
+		want string
+	}{
+		{587, "", SMTPStartTLS},
+		{25, "", SMTPStartTLS},
+		{465, "", SMTPImplicitTLS},
+		{465, SMTPPlain, SMTPPlain},
+	}
+	for _, tt := range tests {
+		transport, err := NewSMTPTransport(SMTPConfig{Host: "smtp.example.com", Port: tt.port, TLS: tt.tls})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if transport.config.TLS != tt.want {
+			t.Errorf("port %d, TLS %q: got %q, want %q", tt.port, tt.tls, transport.config.TLS, tt.want)
+		}
+	}
+
+	if _, err := NewSMTPTransport(SMTPConfig{Host: "smtp.example.com", Port: 25, TLS: "ssl"}); err == nil {
+		t.Error("accepted an unknown TLS mode")
+	}
+}
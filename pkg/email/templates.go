@@ -0,0 +1,294 @@
+package email
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	texttemplate "text/template"
+
+	"golang.org/x/text/language"
+)
+
+//go:embed templates
+var builtinTemplates embed.FS
+
+// TemplateConfig configures where email templates are loaded from.
+type TemplateConfig struct {
+	// Dirs are searched in order before the built-in templates, so a file
+	// placed in one of them replaces the built-in file with the same path
+	Dirs []string
+	// DefaultLocale is used when none of the recipient's languages is available
+	DefaultLocale string
+}
+
+// Templates renders transactional emails from html/template and text/template
+// files laid out as:
+//
+//	layout.html, layout.txt           shared layouts, executing the "content" template
+//	<locale>/layout.html, layout.txt  optional per-locale layouts
+//	<locale>/<name>.html, <name>.txt  one email, defining "content"; the text
+//	                                  file also defines "subject"
+//
+// Each file is looked up in the configured directories first and then among
+// the built-in templates, so overrides may replace single files or add locales.
+type Templates struct {
+	defaultLocale string
+	locales       []string
+	matcher       language.Matcher
+	sets          map[string]map[string]*templateSet
+}
+
+// templateSet is one email in one locale.
+type templateSet struct {
+	html *htmltemplate.Template
+	text *texttemplate.Template
+}
+
+// LoadTemplates parses every template up front, so mistakes in overrides are
+// reported at startup rather than when an email is sent.
+func LoadTemplates(config TemplateConfig) (*Templates, error) {
+	builtin, err := fs.Sub(builtinTemplates, "templates")
+	if err != nil {
+		return nil, err
+	}
+	sources := make([]fs.FS, 0, len(config.Dirs)+1)
+	for _, dir := range config.Dirs {
+		sources = append(sources, os.DirFS(dir))
+	}
+	sources = append(sources, builtin)
+	l := &templateLoader{sources: sources}
+
+	t := &Templates{
+		defaultLocale: config.DefaultLocale,
+		sets:          make(map[string]map[string]*templateSet),
+	}
+	if t.defaultLocale == "" {
+		t.defaultLocale = "en"
+	}
+
+	locales, err := l.locales()
+	if err != nil {
+		return nil, err
+	}
+	for _, locale := range locales {
+		names, err := l.names(locale)
+		if err != nil {
+			return nil, err
+		}
+		t.sets[locale] = make(map[string]*templateSet, len(names))
+		for _, name := range names {
+			set, err := l.parse(locale, name)
+			if err != nil {
+				return nil, err
+			}
+			t.sets[locale][name] = set
+		}
+	}
+	if _, ok := t.sets[t.defaultLocale]; !ok {
+		return nil, fmt.Errorf("email templates: no templates for default locale %q", t.defaultLocale)
+	}
+
+	// The default locale goes first so the matcher falls back to it
+	t.locales = append(t.locales, t.defaultLocale)
+	for _, locale := range locales {
+		if locale != t.defaultLocale {
+			t.locales = append(t.locales, locale)
+		}
+	}
+	tags := make([]language.Tag, len(t.locales))
+	for i, locale := range t.locales {
+		tags[i] = language.Make(locale)
+	}
+	t.matcher = language.NewMatcher(tags)
+
+	return t, nil
+}
+
+// Render renders the named email in the locale that best matches languages,
+// given in Accept-Language form (a single tag such as "de" also works). The
+// default locale is used when nothing matches or the matched locale lacks the
+// email.
+func (t *Templates) Render(name, languages string, data interface{}) (*Message, error) {
+	locale := t.defaultLocale
+	if prefs, _, err := language.ParseAcceptLanguage(languages); err == nil && len(prefs) > 0 {
+		if _, i, confidence := t.matcher.Match(prefs...); confidence != language.No {
+			locale = t.locales[i]
+		}
+	}
+
+	set, ok := t.sets[locale][name]
+	if !ok {
+		if set, ok = t.sets[t.defaultLocale][name]; !ok {
+			return nil, fmt.Errorf("email templates: unknown template %q", name)
+		}
+	}
+
+	var subject, text, html bytes.Buffer
+	if err := set.text.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return nil, err
+	}
+	if err := set.text.Execute(&text, data); err != nil {
+		return nil, err
+	}
+	if err := set.html.Execute(&html, data); err != nil {
+		return nil, err
+	}
+
+	return &Message{
+		Subject: strings.TrimSpace(subject.String()),
+		Text:    text.String(),
+		HTML:    html.String(),
+	}, nil
+}
+
+// templateLoader resolves template files across the override directories and
+// the built-in templates.
+type templateLoader struct {
+	sources []fs.FS
+}
+
+// read returns the first file found at name.
+func (l *templateLoader) read(name string) ([]byte, error) {
+	for _, source := range l.sources {
+		b, err := fs.ReadFile(source, name)
+		if err == nil {
+			return b, nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return nil, err
+		}
+	}
+	return nil, fmt.Errorf("email templates: %s: %w", name, fs.ErrNotExist)
+}
+
+// readFirst returns the first of names that exists in any source.
+func (l *templateLoader) readFirst(names ...string) ([]byte, string, error) {
+	for _, name := range names {
+		b, err := l.read(name)
+		if err == nil {
+			return b, name, nil
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			return nil, "", err
+		}
+	}
+	return nil, "", fmt.Errorf("email templates: %s: %w", names[len(names)-1], fs.ErrNotExist)
+}
+
+// locales returns the locale directories across all sources.
+func (l *templateLoader) locales() ([]string, error) {
+	seen := make(map[string]bool)
+	for _, source := range l.sources {
+		entries, err := fs.ReadDir(source, ".")
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return nil, err
+		}
+		for _, entry := range entries {
+			if !entry.IsDir() {
+				continue
+			}
+			if _, err := language.Parse(entry.Name()); err != nil {
+				return nil, fmt.Errorf("email templates: directory %q is not a locale", entry.Name())
+			}
+			seen[entry.Name()] = true
+		}
+	}
+	return sortedKeys(seen), nil
+}
+
+// names returns the emails available in a locale across all sources. Every
+// email needs both an .html and a .txt file, though they may come from
+// different sources.
+func (l *templateLoader) names(locale string) ([]string, error) {
+	files := make(map[string]map[string]bool)
+	for _, source := range l.sources {
+		entries, err := fs.ReadDir(source, locale)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return nil, err
+		}
+		for _, entry := range entries {
+			ext := path.Ext(entry.Name())
+			name := strings.TrimSuffix(entry.Name(), ext)
+			if entry.IsDir() || (ext != ".html" && ext != ".txt") || name == "layout" {
+				continue
+			}
+			if files[name] == nil {
+				files[name] = make(map[string]bool)
+			}
+			files[name][ext] = true
+		}
+	}
+
+	for name, exts := range files {
+		if !exts[".html"] || !exts[".txt"] {
+			return nil, fmt.Errorf("email templates: %s/%s needs both an .html and a .txt file", locale, name)
+		}
+	}
+	return sortedKeys(files), nil
+}
+
+// parse parses one email in a locale together with its layouts.
+func (l *templateLoader) parse(locale, name string) (*templateSet, error) {
+	funcs := map[string]interface{}{
+		"locale": func() string { return locale },
+	}
+
+	htmlLayout, htmlLayoutName, err := l.readFirst(locale+"/layout.html", "layout.html")
+	if err != nil {
+		return nil, err
+	}
+	htmlContent, err := l.read(locale + "/" + name + ".html")
+	if err != nil {
+		return nil, err
+	}
+	html, err := htmltemplate.New(htmlLayoutName).Funcs(funcs).Parse(string(htmlLayout))
+	if err == nil {
+		_, err = html.New(locale + "/" + name + ".html").Parse(string(htmlContent))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("email templates: %w", err)
+	}
+
+	textLayout, textLayoutName, err := l.readFirst(locale+"/layout.txt", "layout.txt")
+	if err != nil {
+		return nil, err
+	}
+	textContent, err := l.read(locale + "/" + name + ".txt")
+	if err != nil {
+		return nil, err
+	}
+	text, err := texttemplate.New(textLayoutName).Funcs(funcs).Parse(string(textLayout))
+	if err == nil {
+		_, err = text.New(locale + "/" + name + ".txt").Parse(string(textContent))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("email templates: %w", err)
+	}
+	if text.Lookup("subject") == nil {
+		return nil, fmt.Errorf("email templates: %s/%s.txt does not define a subject", locale, name)
+	}
+
+	return &templateSet{html: html, text: text}, nil
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,127 @@
+package email
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"rest-api/pkg/logger"
+
+	"go.uber.org/zap"
+)
+
+// Transport delivers rendered messages. Implementations must be safe for
+// concurrent use.
+type Transport interface {
+	// Send delivers msg, a complete MIME message, from one address to another.
+	Send(from, to string, msg []byte) error
+	// Close releases any connections the transport holds.
+	Close() error
+}
+
+// FileTransport writes every message to its own .eml file in a directory, for
+// local development. The files open in any mail client.
+type FileTransport struct {
+	dir string
+}
+
+func NewFileTransport(dir string) *FileTransport {
+	return &FileTransport{dir: dir}
+}
+
+// Send writes msg to <dir>/<time>-<random>.eml. The file is written under a
+// temporary name and renamed, so readers never see a partial message.
+func (t *FileTransport) Send(from, to string, msg []byte) error {
+	if err := os.MkdirAll(t.dir, 0o700); err != nil {
+		return err
+	}
+
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return err
+	}
+	name := fmt.Sprintf("%s-%s.eml", time.Now().UTC().Format("20060102T150405.000000000Z"), hex.EncodeToString(b))
+
+	// The envelope is not part of the message, so keep it in headers
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "X-Envelope-From: %s\r\nX-Envelope-To: %s\r\n", from, to)
+	buf.Write(msg)
+
+	tmp := filepath.Join(t.dir, "."+name+".tmp")
+	if err := os.WriteFile(tmp, buf.Bytes(), 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(t.dir, name))
+}
+
+func (t *FileTransport) Close() error {
+	return nil
+}
+
+// LogTransport writes messages to the structured log instead of sending them,
+// for development and tests. The log includes message bodies, and with them
+// any sign-in links, so it must not be used in production.
+type LogTransport struct {
+	logger *logger.Logger
+}
+
+func NewLogTransport(logger *logger.Logger) *LogTransport {
+	return &LogTransport{logger: logger}
+}
+
+func (t *LogTransport) Send(from, to string, msg []byte) error {
+	fields := []zap.Field{zap.String("from", from), zap.String("to", to)}
+	if parsed, err := mail.ReadMessage(bytes.NewReader(msg)); err == nil {
+		subject, err := new(mime.WordDecoder).DecodeHeader(parsed.Header.Get("Subject"))
+		if err != nil {
+			subject = parsed.Header.Get("Subject")
+		}
+		fields = append(fields, zap.String("subject", subject), zap.String("body", readableBody(parsed)))
+	} else {
+		fields = append(fields, zap.ByteString("message", msg))
+	}
+
+	t.logger.Info("email", fields...)
+	return nil
+}
+
+func (t *LogTransport) Close() error {
+	return nil
+}
+
+// readableBody returns the decoded plain text part of a message, or its only
+// part when it has no alternatives.
+func readableBody(msg *mail.Message) string {
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	if err == nil && strings.HasPrefix(mediaType, "multipart/") {
+		// Parts are decoded from quoted-printable by the reader
+		r := multipart.NewReader(msg.Body, params["boundary"])
+		for {
+			part, err := r.NextPart()
+			if err != nil {
+				return ""
+			}
+			if strings.HasPrefix(part.Header.Get("Content-Type"), "text/plain") {
+				b, _ := io.ReadAll(part)
+				return string(b)
+			}
+		}
+	}
+
+	var body io.Reader = msg.Body
+	if strings.EqualFold(msg.Header.Get("Content-Transfer-Encoding"), "quoted-printable") {
+		body = quotedprintable.NewReader(body)
+	}
+	b, _ := io.ReadAll(body)
+	return string(b)
+}
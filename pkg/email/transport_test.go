@@ -0,0 +1,97 @@
+package email
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"rest-api/pkg/logger"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestFileTransport(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "mail")
+	transport := NewFileTransport(dir)
+
+	for _, to := range []string{"alice@example.com", "bob@example.com"} {
+		if err := transport.Send("noreply@example.com", to, []byte(testMessage)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("got %d files, want one per message", len(entries))
+	}
+	for i, entry := range entries {
+		if !strings.HasSuffix(entry.Name(), ".eml") {
+			t.Errorf("unexpected file %s", entry.Name())
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode().Perm() != 0o600 {
+			t.Errorf("%s has mode %v, want 0600", entry.Name(), info.Mode().Perm())
+		}
+
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		to := []string{"alice@example.com", "bob@example.com"}[i]
+		want := "X-Envelope-From: noreply@example.com\r\nX-Envelope-To: " + to + "\r\n" + testMessage
+		if string(data) != want {
+			t.Errorf("got %q, want %q", data, want)
+		}
+	}
+}
+
+func TestLogTransport(t *testing.T) {
+	core, logs := observer.New(zap.InfoLevel)
+	transport := NewLogTransport(&logger.Logger{Logger: zap.New(core)})
+	s := NewEmailService(Config{From: "noreply@example.com", Transport: transport})
+
+	err := s.Send(&Message{
+		To:      "alice@example.com",
+		Subject: "Bienvenue à bord",
+		Text:    "Sign in at https://app.example.com/login?token=abc",
+		HTML:    "<p>Sign in <a href=\"https://app.example.com/login?token=abc\">here</a></p>",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := transport.Send("noreply@example.com", "bob@example.com", []byte("not a MIME message")); err != nil {
+		t.Fatal(err)
+	}
+
+	entries := logs.AllUntimed()
+	if len(entries) != 2 {
+		t.Fatalf("got %d log entries, want 2", len(entries))
+	}
+
+	fields := entries[0].ContextMap()
+	want := map[string]interface{}{
+		"from":    "noreply@example.com",
+		"to":      "alice@example.com",
+		"subject": "Bienvenue à bord",
+		"body":    "Sign in at https://app.example.com/login?token=abc",
+	}
+	for key, value := range want {
+		if fields[key] != value {
+			t.Errorf("%s: got %q, want %q", key, fields[key], value)
+		}
+	}
+
+	// Messages that cannot be parsed are logged as they are
+	if got := entries[1].ContextMap()["message"]; got != "not a MIME message" {
+		t.Errorf("unparsable message: got %q", got)
+	}
+}
@@ -0,0 +1,59 @@
+package encryption
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+)
+
+var ErrInvalidCiphertext = errors.New("invalid ciphertext")
+
+// Encryptor provides authenticated encryption (AES-256-GCM) for values stored at rest.
+type Encryptor struct {
+	aead cipher.AEAD
+}
+
+// NewEncryptor derives a 256-bit key from secret and returns an Encryptor using it.
+func NewEncryptor(secret string) *Encryptor {
+	key := sha256.Sum256([]byte(secret))
+
+	// Neither call can fail for a 32 byte key with the standard nonce size
+	block, _ := aes.NewCipher(key[:])
+	aead, _ := cipher.NewGCM(block)
+
+	return &Encryptor{aead: aead}
+}
+
+// Encrypt seals plaintext and returns it as a URL-safe base64 string with the nonce prepended.
+func (e *Encryptor) Encrypt(plaintext []byte) (string, error) {
+	nonce := make([]byte, e.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	sealed := e.aead.Seal(nonce, nonce, plaintext, nil)
+	return base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt opens a value produced by Encrypt.
+func (e *Encryptor) Decrypt(ciphertext string) ([]byte, error) {
+	data, err := base64.RawURLEncoding.DecodeString(ciphertext)
+	if err != nil {
+		return nil, ErrInvalidCiphertext
+	}
+
+	nonceSize := e.aead.NonceSize()
+	if len(data) < nonceSize {
+		return nil, ErrInvalidCiphertext
+	}
+
+	plaintext, err := e.aead.Open(nil, data[:nonceSize], data[nonceSize:], nil)
+	if err != nil {
+		return nil, ErrInvalidCiphertext
+	}
+
+	return plaintext, nil
+}
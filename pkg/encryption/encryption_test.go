@@ -0,0 +1,77 @@
+package encryption
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"testing"
+)
+
+func TestEncryptorRoundTrip(t *testing.T) {
+	e := NewEncryptor("0123456789abcdef0123456789abcdef")
+
+	for _, plaintext := range [][]byte{[]byte("JBSWY3DPEHPK3PXP"), {}, bytes.Repeat([]byte{0}, 1024)} {
+		sealed, err := e.Encrypt(plaintext)
+		if err != nil {
+			t.Fatal(err)
+		}
+		opened, err := e.Decrypt(sealed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(opened, plaintext) {
+			t.Errorf("got %q, want %q", opened, plaintext)
+		}
+	}
+
+	// A fresh nonce each time means equal plaintexts do not show
+	a, _ := e.Encrypt([]byte("secret"))
+	b, _ := e.Encrypt([]byte("secret"))
+	if a == b {
+		t.Error("encrypting twice gave the same ciphertext")
+	}
+}
+
+func TestEncryptorRejectsTampering(t *testing.T) {
+	e := NewEncryptor("0123456789abcdef0123456789abcdef")
+	sealed, err := e.Encrypt([]byte("JBSWY3DPEHPK3PXP"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := base64.RawURLEncoding.DecodeString(sealed)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i := range data {
+		tampered := bytes.Clone(data)
+		tampered[i] ^= 0x01
+		if _, err := e.Decrypt(base64.RawURLEncoding.EncodeToString(tampered)); !errors.Is(err, ErrInvalidCiphertext) {
+			t.Fatalf("flipped byte %d: got %v, want ErrInvalidCiphertext", i, err)
+		}
+	}
+
+	for name, ciphertext := range map[string]string{
+		"truncated":  base64.RawURLEncoding.EncodeToString(data[:len(data)-1]),
+		"nonce only": base64.RawURLEncoding.EncodeToString(data[:12]),
+		"short":      base64.RawURLEncoding.EncodeToString(data[:4]),
+		"empty":      "",
+		"not base64": "!!!",
+		"padded":     sealed + "==",
+		"extended":   base64.RawURLEncoding.EncodeToString(append(bytes.Clone(data), 0)),
+	} {
+		if _, err := e.Decrypt(ciphertext); !errors.Is(err, ErrInvalidCiphertext) {
+			t.Errorf("%s: got %v, want ErrInvalidCiphertext", name, err)
+		}
+	}
+}
+
+func TestEncryptorRejectsWrongKey(t *testing.T) {
+	sealed, err := NewEncryptor("0123456789abcdef0123456789abcdef").Encrypt([]byte("JBSWY3DPEHPK3PXP"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewEncryptor("0123456789abcdef0123456789abcdeF").Decrypt(sealed); !errors.Is(err, ErrInvalidCiphertext) {
+		t.Errorf("got %v, want ErrInvalidCiphertext", err)
+	}
+}
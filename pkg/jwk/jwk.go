@@ -0,0 +1,195 @@
+package jwk
+
+import (
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+)
+
+// Supported asymmetric signing algorithms (JWA names).
+const (
+	RS256 = "RS256"
+	ES256 = "ES256"
+	EdDSA = "EdDSA"
+)
+
+// minRSABits is the shortest RSA modulus accepted from a JWKS.
+const minRSABits = 2048
+
+var ErrUnsupportedAlgorithm = errors.New("unsupported signing algorithm")
+
+// Key is a public JSON Web Key (RFC 7517).
+type Key struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+
+	// RSA
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+
+	// EC and OKP
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// Set is a JSON Web Key Set as served from a jwks_uri.
+type Set struct {
+	Keys []Key `json:"keys"`
+}
+
+// GenerateKey creates a new private key for the given algorithm.
+func GenerateKey(alg string) (crypto.Signer, error) {
+	switch alg {
+	case RS256:
+		return rsa.GenerateKey(rand.Reader, 2048)
+	case ES256:
+		return ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case EdDSA:
+		_, key, err := ed25519.GenerateKey(rand.Reader)
+		return key, err
+	default:
+		return nil, ErrUnsupportedAlgorithm
+	}
+}
+
+// FromPublicKey converts a public key into its JWK representation.
+func FromPublicKey(kid, alg string, pub crypto.PublicKey) (Key, error) {
+	key := Key{Kid: kid, Alg: alg, Use: "sig"}
+
+	switch pub := pub.(type) {
+	case *rsa.PublicKey:
+		key.Kty = "RSA"
+		key.N = encode(pub.N.Bytes())
+		key.E = encode(big.NewInt(int64(pub.E)).Bytes())
+	case *ecdsa.PublicKey:
+		if pub.Curve != elliptic.P256() {
+			return Key{}, fmt.Errorf("unsupported curve %s", pub.Curve.Params().Name)
+		}
+		ecdhKey, err := pub.ECDH()
+		if err != nil {
+			return Key{}, err
+		}
+		// Uncompressed point encoding: 0x04 || X || Y
+		point := ecdhKey.Bytes()
+		size := (len(point) - 1) / 2
+		key.Kty = "EC"
+		key.Crv = "P-256"
+		key.X = encode(point[1 : 1+size])
+		key.Y = encode(point[1+size:])
+	case ed25519.PublicKey:
+		key.Kty = "OKP"
+		key.Crv = "Ed25519"
+		key.X = encode(pub)
+	default:
+		return Key{}, ErrUnsupportedAlgorithm
+	}
+
+	return key, nil
+}
+
+// Find returns the key with the given kid. An empty kid matches only when the
+// set holds a single key, as some providers omit kid in that case.
+func (s Set) Find(kid string) (Key, bool) {
+	if kid == "" && len(s.Keys) == 1 {
+		return s.Keys[0], true
+	}
+	for _, key := range s.Keys {
+		if key.Kid == kid {
+			return key, true
+		}
+	}
+	return Key{}, false
+}
+
+// PublicKey decodes the key material into an *rsa.PublicKey, *ecdsa.PublicKey
+// or ed25519.PublicKey.
+func (k Key) PublicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decode(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decode(k.E)
+		if err != nil {
+			return nil, err
+		}
+		modulus := new(big.Int).SetBytes(n)
+		if modulus.BitLen() < minRSABits {
+			return nil, errors.New("rsa key is too short")
+		}
+		// Public exponents are odd and greater than one
+		exponent := new(big.Int).SetBytes(e)
+		if !exponent.IsInt64() || exponent.Int64() > 1<<31-1 || exponent.Int64() < 3 || exponent.Bit(0) == 0 {
+			return nil, errors.New("invalid rsa exponent")
+		}
+		return &rsa.PublicKey{N: modulus, E: int(exponent.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		var ecdhCurve ecdh.Curve
+		switch k.Crv {
+		case "P-256":
+			curve, ecdhCurve = elliptic.P256(), ecdh.P256()
+		case "P-384":
+			curve, ecdhCurve = elliptic.P384(), ecdh.P384()
+		case "P-521":
+			curve, ecdhCurve = elliptic.P521(), ecdh.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %s", k.Crv)
+		}
+		x, err := decode(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decode(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		size := (curve.Params().BitSize + 7) / 8
+		if len(x) > size || len(y) > size {
+			return nil, errors.New("invalid ec point")
+		}
+		// crypto/ecdh rejects points that are not on the curve
+		point := make([]byte, 1+2*size)
+		point[0] = 4
+		copy(point[1+size-len(x):1+size], x)
+		copy(point[1+2*size-len(y):], y)
+		if _, err := ecdhCurve.NewPublicKey(point); err != nil {
+			return nil, errors.New("invalid ec point")
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %s", k.Crv)
+		}
+		x, err := decode(k.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid ed25519 key")
+		}
+		return ed25519.PublicKey(x), nil
+	default:
+		return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+	}
+}
+
+func decode(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(s)
+}
+
+func encode(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
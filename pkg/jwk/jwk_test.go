@@ -0,0 +1,148 @@
+package jwk
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestRoundTrip(t *testing.T) {
+	methods := map[string]jwt.SigningMethod{
+		RS256: jwt.SigningMethodRS256,
+		ES256: jwt.SigningMethodES256,
+		EdDSA: jwt.SigningMethodEdDSA,
+	}
+
+	for alg, method := range methods {
+		t.Run(alg, func(t *testing.T) {
+			signer, err := GenerateKey(alg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			key, err := FromPublicKey("kid-1", alg, signer.Public())
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			// Through JSON, as a verifier would receive it
+			data, err := json.Marshal(Set{Keys: []Key{key}})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var set Set
+			if err := json.Unmarshal(data, &set); err != nil {
+				t.Fatal(err)
+			}
+			published, found := set.Find("kid-1")
+			if !found || published.Alg != alg || published.Use != "sig" {
+				t.Fatalf("unexpected published key %+v", published)
+			}
+			pub, err := published.PublicKey()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			signed, err := jwt.NewWithClaims(method, jwt.MapClaims{"sub": "alice"}).SignedString(signer)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := jwt.Parse(signed, func(*jwt.Token) (interface{}, error) { return pub, nil }, jwt.WithValidMethods([]string{alg})); err != nil {
+				t.Errorf("token does not verify with the decoded key: %v", err)
+			}
+
+			// A different key of the same type must not verify it
+			other, err := GenerateKey(alg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := jwt.Parse(signed, func(*jwt.Token) (interface{}, error) { return other.Public(), nil }); err == nil {
+				t.Error("token verifies with another key")
+			}
+		})
+	}
+}
+
+func TestGenerateKeyRejectsUnknownAlgorithms(t *testing.T) {
+	for _, alg := range []string{"HS256", "none", ""} {
+		if _, err := GenerateKey(alg); !errors.Is(err, ErrUnsupportedAlgorithm) {
+			t.Errorf("%q: got %v, want ErrUnsupportedAlgorithm", alg, err)
+		}
+	}
+
+	p384, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := FromPublicKey("kid", "ES384", p384.Public()); err == nil {
+		t.Error("encoded a P-384 key")
+	}
+	if _, err := FromPublicKey("kid", "HS256", []byte("secret")); !errors.Is(err, ErrUnsupportedAlgorithm) {
+		t.Errorf("symmetric key: got %v, want ErrUnsupportedAlgorithm", err)
+	}
+}
+
+func TestPublicKeyRejectsMalformedKeys(t *testing.T) {
+	signer, err := GenerateKey(ES256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ec, err := FromPublicKey("kid", ES256, signer.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaSigner, err := GenerateKey(RS256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaKey, err := FromPublicKey("kid", RS256, rsaSigner.Public())
+	if err != nil {
+		t.Fatal(err)
+	}
+	offCurve := ec
+	offCurve.Y = ec.X
+
+	tests := []struct {
+		name string
+		key  Key
+	}{
+		{"point off the curve", offCurve},
+		{"oversized coordinate", Key{Kty: "EC", Crv: "P-256", X: encode(make([]byte, 33)), Y: ec.Y}},
+		{"unknown curve", Key{Kty: "EC", Crv: "secp256k1", X: ec.X, Y: ec.Y}},
+		{"bad base64", Key{Kty: "EC", Crv: "P-256", X: "!!!", Y: ec.Y}},
+		{"short ed25519 key", Key{Kty: "OKP", Crv: "Ed25519", X: encode(make([]byte, 31))}},
+		{"x25519 key", Key{Kty: "OKP", Crv: "X25519", X: encode(make([]byte, 32))}},
+		{"1024-bit rsa modulus", Key{Kty: "RSA", N: encode(append([]byte{0x80}, make([]byte, 127)...)), E: rsaKey.E}},
+		{"huge rsa exponent", Key{Kty: "RSA", N: rsaKey.N, E: encode([]byte{1, 0, 0, 0, 0, 0, 0, 0, 1})}},
+		{"rsa exponent one", Key{Kty: "RSA", N: rsaKey.N, E: encode([]byte{1})}},
+		{"even rsa exponent", Key{Kty: "RSA", N: rsaKey.N, E: encode([]byte{1, 0, 0})}},
+		{"symmetric key", Key{Kty: "oct"}},
+	}
+	for _, tt := range tests {
+		if _, err := tt.key.PublicKey(); err == nil {
+			t.Errorf("%s: decoded", tt.name)
+		}
+	}
+}
+
+func TestSetFind(t *testing.T) {
+	one := Set{Keys: []Key{{Kid: "a"}}}
+	if _, found := one.Find(""); !found {
+		t.Error("a single key without kid lookup not found")
+	}
+	if _, found := one.Find("b"); found {
+		t.Error("found an unknown kid")
+	}
+
+	two := Set{Keys: []Key{{Kid: "a"}, {Kid: "b"}}}
+	if key, found := two.Find("b"); !found || key.Kid != "b" {
+		t.Errorf("got %+v, want key b", key)
+	}
+	if _, found := two.Find(""); found {
+		t.Error("an empty kid matched one of several keys")
+	}
+}
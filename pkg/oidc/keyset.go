@@ -0,0 +1,58 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"rest-api/pkg/jwk"
+)
+
+// minRefreshInterval limits how often an unknown kid can trigger a JWKS fetch.
+const minRefreshInterval = time.Minute
+
+var ErrKeyNotFound = errors.New("signing key not found in provider jwks")
+
+// RemoteKeySet caches a provider's JWKS, refetching it when a token is signed
+// with a key it has not seen yet (the provider rotated its keys).
+type RemoteKeySet struct {
+	client *http.Client
+	url    string
+
+	mu        sync.Mutex
+	set       jwk.Set
+	fetchedAt time.Time
+}
+
+func NewRemoteKeySet(client *http.Client, url string) *RemoteKeySet {
+	return &RemoteKeySet{client: client, url: url}
+}
+
+// Key returns the public key for kid together with the key's declared algorithm.
+func (r *RemoteKeySet) Key(ctx context.Context, kid string) (crypto.PublicKey, string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key, found := r.set.Find(kid)
+	if !found && time.Since(r.fetchedAt) >= minRefreshInterval {
+		var set jwk.Set
+		if err := getJSON(ctx, r.client, r.url, "", &set); err != nil {
+			return nil, "", err
+		}
+		r.set = set
+		r.fetchedAt = time.Now()
+		key, found = r.set.Find(kid)
+	}
+	if !found {
+		return nil, "", ErrKeyNotFound
+	}
+
+	pub, err := key.PublicKey()
+	if err != nil {
+		return nil, "", err
+	}
+	return pub, key.Alg, nil
+}
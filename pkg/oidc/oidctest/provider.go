@@ -0,0 +1,200 @@
+// Package oidctest runs an in-process OpenID Connect provider for tests of
+// the relying party: discovery, JWKS, the token endpoint (authorization code
+// with PKCE) and userinfo.
+package oidctest
+
+import (
+	"crypto"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"time"
+
+	"rest-api/pkg/jwk"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ClientID is the client the provider issues ID tokens to by default.
+const ClientID = "test-client"
+
+type grant struct {
+	claims        jwt.MapClaims
+	codeChallenge string
+}
+
+// Provider is a mock OpenID Connect provider served over httptest. Its
+// issuer is the server URL.
+type Provider struct {
+	*httptest.Server
+	Issuer string
+
+	mu           sync.Mutex
+	signer       crypto.Signer
+	kid          string
+	keys         []jwk.Key
+	grants       map[string]grant
+	userInfo     map[string]interface{}
+	jwksRequests int
+	nextID       int
+}
+
+// NewProvider starts a provider with one RS256 signing key. Callers must Close it.
+func NewProvider() *Provider {
+	p := &Provider{grants: make(map[string]grant)}
+	p.RotateKey()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", p.discovery)
+	mux.HandleFunc("/jwks", p.jwks)
+	mux.HandleFunc("/token", p.token)
+	mux.HandleFunc("/userinfo", p.userinfo)
+	p.Server = httptest.NewServer(mux)
+	p.Issuer = p.Server.URL
+	return p
+}
+
+// RotateKey signs subsequent tokens with a new key, published in the JWKS
+// alongside the previous ones.
+func (p *Provider) RotateKey() {
+	signer, err := jwk.GenerateKey(jwk.RS256)
+	if err != nil {
+		panic(err)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.nextID++
+	kid := fmt.Sprintf("key-%d", p.nextID)
+	key, err := jwk.FromPublicKey(kid, jwk.RS256, signer.Public())
+	if err != nil {
+		panic(err)
+	}
+	p.signer, p.kid = signer, kid
+	p.keys = append(p.keys, key)
+}
+
+// JWKSRequests returns how many times the JWKS has been fetched.
+func (p *Provider) JWKSRequests() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.jwksRequests
+}
+
+// Claims returns valid ID token claims for subject, issued now to ClientID.
+func (p *Provider) Claims(subject, nonce string) jwt.MapClaims {
+	now := time.Now()
+	return jwt.MapClaims{
+		"iss":   p.Issuer,
+		"sub":   subject,
+		"aud":   ClientID,
+		"nonce": nonce,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	}
+}
+
+// Sign signs claims with the current key.
+func (p *Provider) Sign(claims jwt.MapClaims) string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = p.kid
+	signed, err := token.SignedString(p.signer)
+	if err != nil {
+		panic(err)
+	}
+	return signed
+}
+
+// Authorize records a completed authorization and returns its code. Redeeming
+// the code requires the verifier for codeChallenge (S256) and returns an ID
+// token with claims.
+func (p *Provider) Authorize(claims jwt.MapClaims, codeChallenge string) string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.nextID++
+	code := fmt.Sprintf("code-%d", p.nextID)
+	p.grants[code] = grant{claims: claims, codeChallenge: codeChallenge}
+	return code
+}
+
+// SetUserInfo sets the claims the userinfo endpoint returns.
+func (p *Provider) SetUserInfo(claims map[string]interface{}) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.userInfo = claims
+}
+
+func (p *Provider) discovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"issuer":                                p.Issuer,
+		"authorization_endpoint":                p.Issuer + "/authorize",
+		"token_endpoint":                        p.Issuer + "/token",
+		"userinfo_endpoint":                     p.Issuer + "/userinfo",
+		"jwks_uri":                              p.Issuer + "/jwks",
+		"id_token_signing_alg_values_supported": []string{jwk.RS256},
+		"code_challenge_methods_supported":      []string{"S256"},
+	})
+}
+
+func (p *Provider) jwks(w http.ResponseWriter, r *http.Request) {
+	p.mu.Lock()
+	p.jwksRequests++
+	set := jwk.Set{Keys: append([]jwk.Key(nil), p.keys...)}
+	p.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, set)
+}
+
+func (p *Provider) token(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost || r.ParseForm() != nil || r.PostForm.Get("grant_type") != "authorization_code" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+
+	p.mu.Lock()
+	code := r.PostForm.Get("code")
+	g, ok := p.grants[code]
+	delete(p.grants, code)
+	p.mu.Unlock()
+
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if !ok || base64.RawURLEncoding.EncodeToString(sum[:]) != g.codeChallenge {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token": "access-" + code,
+		"token_type":   "Bearer",
+		"expires_in":   3600,
+		"id_token":     p.Sign(g.claims),
+	})
+}
+
+func (p *Provider) userinfo(w http.ResponseWriter, r *http.Request) {
+	if !strings.HasPrefix(r.Header.Get("Authorization"), "Bearer access-") {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+
+	p.mu.Lock()
+	claims := p.userInfo
+	p.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, claims)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
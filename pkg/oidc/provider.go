@@ -0,0 +1,157 @@
+// Package oidc implements the relying party side of OpenID Connect: provider
+// discovery, remote JWKS caching and ID token verification.
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"golang.org/x/oauth2"
+)
+
+const maxResponseSize = 1 << 20
+
+var ErrIssuerMismatch = errors.New("discovery document issuer does not match")
+
+// Metadata is the subset of the provider discovery document (OpenID Connect
+// Discovery 1.0) used by the relying party.
+type Metadata struct {
+	Issuer                           string   `json:"issuer"`
+	AuthorizationEndpoint            string   `json:"authorization_endpoint"`
+	TokenEndpoint                    string   `json:"token_endpoint"`
+	UserinfoEndpoint                 string   `json:"userinfo_endpoint"`
+	JWKSURI                          string   `json:"jwks_uri"`
+	IDTokenSigningAlgValuesSupported []string `json:"id_token_signing_alg_values_supported"`
+	CodeChallengeMethodsSupported    []string `json:"code_challenge_methods_supported"`
+}
+
+// Provider is a discovered OpenID Connect provider.
+type Provider struct {
+	Metadata
+	client *http.Client
+	keys   *RemoteKeySet
+}
+
+// UserInfo is the standard claim set returned by the userinfo endpoint.
+type UserInfo struct {
+	Subject       string `json:"sub"`
+	Email         string `json:"email"`
+	EmailVerified Bool   `json:"email_verified"`
+	Name          string `json:"name"`
+}
+
+// Discover fetches issuer's discovery document. client may be nil to use
+// http.DefaultClient; tests point it at a local mock provider.
+func Discover(ctx context.Context, client *http.Client, issuer string) (*Provider, error) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	var metadata Metadata
+	wellKnown := strings.TrimSuffix(issuer, "/") + "/.well-known/openid-configuration"
+	if err := getJSON(ctx, client, wellKnown, "", &metadata); err != nil {
+		return nil, fmt.Errorf("oidc discovery: %w", err)
+	}
+
+	if metadata.Issuer != issuer {
+		return nil, fmt.Errorf("%w: expected %q, got %q", ErrIssuerMismatch, issuer, metadata.Issuer)
+	}
+	if metadata.AuthorizationEndpoint == "" || metadata.TokenEndpoint == "" || metadata.JWKSURI == "" {
+		return nil, errors.New("oidc discovery: document is missing required endpoints")
+	}
+
+	return &Provider{
+		Metadata: metadata,
+		client:   client,
+		keys:     NewRemoteKeySet(client, metadata.JWKSURI),
+	}, nil
+}
+
+// Endpoint returns the provider's OAuth 2.0 endpoints.
+func (p *Provider) Endpoint() oauth2.Endpoint {
+	return oauth2.Endpoint{
+		AuthURL:  p.AuthorizationEndpoint,
+		TokenURL: p.TokenEndpoint,
+	}
+}
+
+// Client returns the HTTP client used to reach the provider.
+func (p *Provider) Client() *http.Client {
+	return p.client
+}
+
+// Verifier returns an ID token verifier for tokens issued to clientID.
+func (p *Provider) Verifier(clientID string) *Verifier {
+	algs := p.IDTokenSigningAlgValuesSupported
+	if len(algs) == 0 {
+		algs = []string{"RS256"}
+	}
+	return &Verifier{
+		issuer:   p.Issuer,
+		clientID: clientID,
+		algs:     algs,
+		keys:     p.keys,
+	}
+}
+
+// UserInfo fetches the claims of the user the access token was issued to.
+func (p *Provider) UserInfo(ctx context.Context, accessToken string) (*UserInfo, error) {
+	if p.UserinfoEndpoint == "" {
+		return nil, errors.New("provider has no userinfo endpoint")
+	}
+
+	var info UserInfo
+	if err := getJSON(ctx, p.client, p.UserinfoEndpoint, accessToken, &info); err != nil {
+		return nil, fmt.Errorf("oidc userinfo: %w", err)
+	}
+	return &info, nil
+}
+
+// getJSON decodes the JSON body of a GET request, optionally authorized by a bearer token.
+func getJSON(ctx context.Context, client *http.Client, url, bearer string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Accept", "application/json")
+	if bearer != "" {
+		req.Header.Set("Authorization", "Bearer "+bearer)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseSize))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: unexpected status %s", url, resp.Status)
+	}
+
+	return json.Unmarshal(body, v)
+}
+
+// Bool accepts both JSON booleans and the "true"/"false" strings some
+// providers send for email_verified.
+type Bool bool
+
+func (b *Bool) UnmarshalJSON(data []byte) error {
+	switch string(data) {
+	case "true", `"true"`:
+		*b = true
+	case "false", `"false"`, "null":
+		*b = false
+	default:
+		return fmt.Errorf("invalid boolean %s", data)
+	}
+	return nil
+}
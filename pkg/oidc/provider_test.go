@@ -0,0 +1,101 @@
+package oidc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"rest-api/pkg/oidc/oidctest"
+)
+
+func TestDiscover(t *testing.T) {
+	op := oidctest.NewProvider()
+	defer op.Close()
+
+	p, err := Discover(context.Background(), op.Client(), op.Issuer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Issuer != op.Issuer || p.JWKSURI != op.Issuer+"/jwks" || p.Endpoint().TokenURL != op.Issuer+"/token" {
+		t.Errorf("unexpected metadata: %+v", p.Metadata)
+	}
+
+	// The document must name the issuer it was fetched for, exactly
+	if _, err := Discover(context.Background(), op.Client(), op.Issuer+"/"); !errors.Is(err, ErrIssuerMismatch) {
+		t.Errorf("issuer with trailing slash: got %v, want ErrIssuerMismatch", err)
+	}
+}
+
+func TestDiscoverRejectsBadDocuments(t *testing.T) {
+	var document map[string]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if document == nil {
+			http.NotFound(w, r)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(document)
+	}))
+	defer srv.Close()
+
+	if _, err := Discover(context.Background(), srv.Client(), srv.URL); err == nil {
+		t.Error("discovered a provider without a discovery document")
+	}
+
+	document = map[string]string{
+		"issuer":                 srv.URL,
+		"authorization_endpoint": srv.URL + "/authorize",
+		"token_endpoint":         srv.URL + "/token",
+	}
+	if _, err := Discover(context.Background(), srv.Client(), srv.URL); err == nil {
+		t.Error("discovered a provider without a jwks_uri")
+	}
+}
+
+func TestUserInfo(t *testing.T) {
+	op := oidctest.NewProvider()
+	defer op.Close()
+	op.SetUserInfo(map[string]interface{}{"sub": "alice", "email": "alice@example.com", "email_verified": "true"})
+
+	p, err := Discover(context.Background(), op.Client(), op.Issuer)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	info, err := p.UserInfo(context.Background(), "access-token")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Subject != "alice" || info.Email != "alice@example.com" || !info.EmailVerified {
+		t.Errorf("unexpected userinfo: %+v", info)
+	}
+
+	if _, err := p.UserInfo(context.Background(), "stolen"); err == nil {
+		t.Error("userinfo accepted an unknown access token")
+	}
+}
+
+func TestBoolUnmarshal(t *testing.T) {
+	tests := []struct {
+		json    string
+		want    bool
+		wantErr bool
+	}{
+		{`true`, true, false},
+		{`"true"`, true, false},
+		{`false`, false, false},
+		{`"false"`, false, false},
+		{`null`, false, false},
+		{`"yes"`, false, true},
+		{`1`, false, true},
+	}
+	for _, tt := range tests {
+		var b Bool
+		err := json.Unmarshal([]byte(tt.json), &b)
+		if (err != nil) != tt.wantErr || bool(b) != tt.want {
+			t.Errorf("%s: got %v, %v", tt.json, b, err)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package oidc
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// clockSkew is the leeway allowed when checking exp, iat and nbf.
+const clockSkew = time.Minute
+
+var (
+	ErrInvalidIDToken = errors.New("invalid id token")
+	ErrNonceMismatch  = errors.New("id token nonce does not match")
+)
+
+// IDToken holds the verified claims of an ID token.
+type IDToken struct {
+	Issuer        string
+	Subject       string
+	Audience      []string
+	Expiry        time.Time
+	Nonce         string
+	Email         string
+	EmailVerified bool
+	Name          string
+}
+
+type idTokenClaims struct {
+	Nonce           string `json:"nonce"`
+	Email           string `json:"email"`
+	EmailVerified   Bool   `json:"email_verified"`
+	Name            string `json:"name"`
+	AuthorizedParty string `json:"azp"`
+	jwt.RegisteredClaims
+}
+
+// Verifier checks ID tokens issued by one provider to one client.
+type Verifier struct {
+	issuer   string
+	clientID string
+	algs     []string
+	keys     *RemoteKeySet
+}
+
+// Verify checks the signature of rawIDToken against the provider JWKS and
+// validates its issuer, audience, expiry and nonce.
+func (v *Verifier) Verify(ctx context.Context, rawIDToken, nonce string) (*IDToken, error) {
+	claims := &idTokenClaims{}
+	_, err := jwt.ParseWithClaims(rawIDToken, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		key, alg, err := v.keys.Key(ctx, kid)
+		if err != nil {
+			return nil, err
+		}
+		if alg != "" && alg != token.Method.Alg() {
+			return nil, fmt.Errorf("key %q is for %s, token is signed with %s", kid, alg, token.Method.Alg())
+		}
+		return key, nil
+	},
+		jwt.WithValidMethods(v.algs),
+		jwt.WithIssuer(v.issuer),
+		jwt.WithAudience(v.clientID),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(clockSkew),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidIDToken, err)
+	}
+
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("%w: missing subject", ErrInvalidIDToken)
+	}
+
+	// With several audiences the token must name us as the authorized party
+	if (len(claims.Audience) > 1 || claims.AuthorizedParty != "") && claims.AuthorizedParty != v.clientID {
+		return nil, fmt.Errorf("%w: authorized party mismatch", ErrInvalidIDToken)
+	}
+
+	if nonce == "" || subtle.ConstantTimeCompare([]byte(claims.Nonce), []byte(nonce)) != 1 {
+		return nil, ErrNonceMismatch
+	}
+
+	return &IDToken{
+		Issuer:        claims.Issuer,
+		Subject:       claims.Subject,
+		Audience:      claims.Audience,
+		Expiry:        claims.ExpiresAt.Time,
+		Nonce:         claims.Nonce,
+		Email:         claims.Email,
+		EmailVerified: bool(claims.EmailVerified),
+		Name:          claims.Name,
+	}, nil
+}
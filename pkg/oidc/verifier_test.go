@@ -0,0 +1,175 @@
+package oidc
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"rest-api/pkg/jwk"
+	"rest-api/pkg/oidc/oidctest"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const testNonce = "n-0S6_WzA2Mj"
+
+func newTestVerifier(t *testing.T) (*oidctest.Provider, *Provider, *Verifier) {
+	t.Helper()
+	op := oidctest.NewProvider()
+	t.Cleanup(op.Close)
+
+	p, err := Discover(context.Background(), op.Client(), op.Issuer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return op, p, p.Verifier(oidctest.ClientID)
+}
+
+func TestVerify(t *testing.T) {
+	op, _, v := newTestVerifier(t)
+
+	claims := op.Claims("alice", testNonce)
+	claims["email"] = "alice@example.com"
+	claims["email_verified"] = true
+	token, err := v.Verify(context.Background(), op.Sign(claims), testNonce)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token.Subject != "alice" || token.Issuer != op.Issuer || token.Email != "alice@example.com" || !token.EmailVerified {
+		t.Errorf("unexpected token: %+v", token)
+	}
+}
+
+func TestVerifyRejectsInvalidClaims(t *testing.T) {
+	op, _, v := newTestVerifier(t)
+	now := time.Now()
+
+	tests := []struct {
+		name   string
+		modify func(jwt.MapClaims)
+		want   error
+	}{
+		{"other issuer", func(c jwt.MapClaims) { c["iss"] = "https://attacker.example.com" }, ErrInvalidIDToken},
+		{"no issuer", func(c jwt.MapClaims) { delete(c, "iss") }, ErrInvalidIDToken},
+		{"other audience", func(c jwt.MapClaims) { c["aud"] = "other-client" }, ErrInvalidIDToken},
+		{"several audiences without azp", func(c jwt.MapClaims) { c["aud"] = []string{oidctest.ClientID, "other-client"} }, ErrInvalidIDToken},
+		{"other authorized party", func(c jwt.MapClaims) { c["azp"] = "other-client" }, ErrInvalidIDToken},
+		{"expired", func(c jwt.MapClaims) { c["exp"] = now.Add(-2 * clockSkew).Unix() }, ErrInvalidIDToken},
+		{"no expiry", func(c jwt.MapClaims) { delete(c, "exp") }, ErrInvalidIDToken},
+		{"issued in the future", func(c jwt.MapClaims) { c["iat"] = now.Add(2 * clockSkew).Unix() }, ErrInvalidIDToken},
+		{"no subject", func(c jwt.MapClaims) { delete(c, "sub") }, ErrInvalidIDToken},
+		{"other nonce", func(c jwt.MapClaims) { c["nonce"] = "replayed" }, ErrNonceMismatch},
+		{"no nonce", func(c jwt.MapClaims) { delete(c, "nonce") }, ErrNonceMismatch},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := op.Claims("alice", testNonce)
+			tt.modify(claims)
+			if _, err := v.Verify(context.Background(), op.Sign(claims), testNonce); !errors.Is(err, tt.want) {
+				t.Errorf("got %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	// An empty expected nonce never matches, even a token without one
+	claims := op.Claims("alice", "")
+	if _, err := v.Verify(context.Background(), op.Sign(claims), ""); !errors.Is(err, ErrNonceMismatch) {
+		t.Errorf("empty nonce: got %v, want ErrNonceMismatch", err)
+	}
+}
+
+func TestVerifyAllowsClockSkew(t *testing.T) {
+	op, _, v := newTestVerifier(t)
+
+	claims := op.Claims("alice", testNonce)
+	claims["exp"] = time.Now().Add(-clockSkew / 2).Unix()
+	if _, err := v.Verify(context.Background(), op.Sign(claims), testNonce); err != nil {
+		t.Errorf("expired within the allowed skew: %v", err)
+	}
+
+	claims = op.Claims("alice", testNonce)
+	claims["aud"] = []string{oidctest.ClientID, "other-client"}
+	claims["azp"] = oidctest.ClientID
+	if _, err := v.Verify(context.Background(), op.Sign(claims), testNonce); err != nil {
+		t.Errorf("several audiences with us as authorized party: %v", err)
+	}
+}
+
+func TestVerifyRejectsUnknownKeys(t *testing.T) {
+	op, _, v := newTestVerifier(t)
+	claims := op.Claims("alice", testNonce)
+
+	signer, err := jwk.GenerateKey(jwk.RS256)
+	if err != nil {
+		t.Fatal(err)
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = "unknown"
+	forged, err := token.SignedString(signer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := v.Verify(context.Background(), forged, testNonce); !errors.Is(err, ErrInvalidIDToken) {
+		t.Errorf("unknown kid: got %v, want ErrInvalidIDToken", err)
+	}
+
+	// Reusing a published kid does not help if the signature is not the provider's
+	token.Header["kid"] = "key-1"
+	forged, err = token.SignedString(signer)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := v.Verify(context.Background(), forged, testNonce); !errors.Is(err, ErrInvalidIDToken) {
+		t.Errorf("forged signature: got %v, want ErrInvalidIDToken", err)
+	}
+
+	// Neither do algorithms the provider does not sign with
+	unsigned, err := jwt.NewWithClaims(jwt.SigningMethodNone, claims).SignedString(jwt.UnsafeAllowNoneSignatureType)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := v.Verify(context.Background(), unsigned, testNonce); !errors.Is(err, ErrInvalidIDToken) {
+		t.Errorf("alg none: got %v, want ErrInvalidIDToken", err)
+	}
+	hmac, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := v.Verify(context.Background(), hmac, testNonce); !errors.Is(err, ErrInvalidIDToken) {
+		t.Errorf("HS256: got %v, want ErrInvalidIDToken", err)
+	}
+}
+
+func TestVerifyRefetchesRotatedKeys(t *testing.T) {
+	op, p, v := newTestVerifier(t)
+
+	if _, err := v.Verify(context.Background(), op.Sign(op.Claims("alice", testNonce)), testNonce); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := v.Verify(context.Background(), op.Sign(op.Claims("alice", testNonce)), testNonce); err != nil {
+		t.Fatal(err)
+	}
+	if n := op.JWKSRequests(); n != 1 {
+		t.Fatalf("fetched the jwks %d times, want once", n)
+	}
+
+	// A new kid right after a fetch is not refetched, so unknown kids cannot
+	// be used to make us hammer the provider
+	op.RotateKey()
+	rotated := op.Sign(op.Claims("alice", testNonce))
+	if _, err := v.Verify(context.Background(), rotated, testNonce); !errors.Is(err, ErrInvalidIDToken) {
+		t.Errorf("got %v, want ErrInvalidIDToken within the refresh interval", err)
+	}
+	if n := op.JWKSRequests(); n != 1 {
+		t.Errorf("fetched the jwks %d times within the refresh interval, want once", n)
+	}
+
+	p.keys.fetchedAt = time.Now().Add(-minRefreshInterval)
+	if _, err := v.Verify(context.Background(), rotated, testNonce); err != nil {
+		t.Errorf("rotated key: %v", err)
+	}
+	if n := op.JWKSRequests(); n != 2 {
+		t.Errorf("fetched the jwks %d times, want twice", n)
+	}
+}
@@ -0,0 +1,83 @@
+// Package password hashes and verifies user passwords. Hashes are stored as
+// self-describing strings (PHC format for Argon2id, modular crypt format for
+// bcrypt) so the algorithm and parameters can change without a migration.
+package password
+
+import (
+	"errors"
+	"strings"
+)
+
+var (
+	ErrInvalidHash      = errors.New("invalid password hash")
+	ErrUnknownAlgorithm = errors.New("unknown password hash algorithm")
+)
+
+// Hasher implements a single password hashing algorithm.
+type Hasher interface {
+	// ID is the algorithm identifier found in hashes this hasher produces.
+	ID() string
+	Hash(password string) (string, error)
+	// Verify reports whether password matches encoded.
+	Verify(password, encoded string) (bool, error)
+	// NeedsRehash reports whether encoded uses weaker parameters than the hasher's current ones.
+	NeedsRehash(encoded string) bool
+}
+
+// Manager hashes new passwords with a preferred hasher and verifies hashes
+// produced by any registered hasher.
+type Manager struct {
+	preferred Hasher
+	hashers   map[string]Hasher
+}
+
+// NewManager returns a Manager that hashes with preferred and also accepts
+// hashes produced by legacy.
+func NewManager(preferred Hasher, legacy ...Hasher) *Manager {
+	m := &Manager{
+		preferred: preferred,
+		hashers:   map[string]Hasher{preferred.ID(): preferred},
+	}
+	for _, h := range legacy {
+		m.hashers[h.ID()] = h
+	}
+	return m
+}
+
+// Hash hashes password with the preferred hasher.
+func (m *Manager) Hash(password string) (string, error) {
+	return m.preferred.Hash(password)
+}
+
+// Verify checks password against encoded. rehash is true when the password
+// matched but encoded should be replaced by a fresh Hash of the password.
+func (m *Manager) Verify(password, encoded string) (ok, rehash bool, err error) {
+	id := algorithm(encoded)
+	h, found := m.hashers[id]
+	if !found {
+		if id == "" {
+			return false, false, ErrInvalidHash
+		}
+		return false, false, ErrUnknownAlgorithm
+	}
+
+	ok, err = h.Verify(password, encoded)
+	if err != nil || !ok {
+		return false, false, err
+	}
+
+	return true, h != m.preferred || h.NeedsRehash(encoded), nil
+}
+
+// algorithm returns the identifier of the algorithm that produced encoded.
+func algorithm(encoded string) string {
+	parts := strings.SplitN(encoded, "$", 3)
+	if len(parts) < 3 || parts[0] != "" {
+		return ""
+	}
+	switch parts[1] {
+	case "2a", "2b", "2y":
+		return bcryptID
+	}
+	return parts[1]
+}
@@ -0,0 +1,137 @@
+package password
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// testParams are cheap enough for tests while still passing decodeArgon2id's checks.
+var testParams = Argon2Params{Memory: 64, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32}
+
+func newTestManager() *Manager {
+	return NewManager(NewArgon2id(testParams), NewBcrypt(bcrypt.MinCost))
+}
+
+func TestManagerRoundTrip(t *testing.T) {
+	m := newTestManager()
+
+	encoded, err := m.Hash("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(encoded, "$argon2id$v=19$m=64,t=1,p=1$") {
+		t.Fatalf("unexpected encoding: %s", encoded)
+	}
+
+	ok, rehash, err := m.Verify("correct horse", encoded)
+	if err != nil || !ok || rehash {
+		t.Errorf("correct password: got ok=%v rehash=%v err=%v, want ok without rehash", ok, rehash, err)
+	}
+
+	ok, _, err = m.Verify("wrong horse", encoded)
+	if err != nil || ok {
+		t.Errorf("wrong password: got ok=%v err=%v, want a mismatch without error", ok, err)
+	}
+}
+
+func TestManagerRehashesWeakerArgon2id(t *testing.T) {
+	weak, err := NewArgon2id(testParams).Hash("correct horse")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stronger := testParams
+	stronger.Iterations = 2
+	m := NewManager(NewArgon2id(stronger))
+
+	ok, rehash, err := m.Verify("correct horse", weak)
+	if err != nil || !ok || !rehash {
+		t.Errorf("got ok=%v rehash=%v err=%v, want ok with rehash", ok, rehash, err)
+	}
+}
+
+func TestManagerRehashesLegacyBcrypt(t *testing.T) {
+	m := newTestManager()
+
+	for _, prefix := range []string{"$2a$", "$2b$", "$2y$"} {
+		legacy, err := bcrypt.GenerateFromPassword([]byte("correct horse"), bcrypt.MinCost)
+		if err != nil {
+			t.Fatal(err)
+		}
+		encoded := prefix + string(legacy[4:])
+
+		ok, rehash, err := m.Verify("correct horse", encoded)
+		if err != nil || !ok || !rehash {
+			t.Errorf("%s: got ok=%v rehash=%v err=%v, want ok with rehash", prefix, ok, rehash, err)
+		}
+
+		ok, rehash, err = m.Verify("wrong horse", encoded)
+		if err != nil || ok || rehash {
+			t.Errorf("%s wrong password: got ok=%v rehash=%v err=%v, want a mismatch", prefix, ok, rehash, err)
+		}
+	}
+}
+
+func TestManagerRejectsUnknownHashes(t *testing.T) {
+	m := newTestManager()
+
+	if _, _, err := m.Verify("x", "plaintext"); !errors.Is(err, ErrInvalidHash) {
+		t.Errorf("unprefixed hash: got %v, want ErrInvalidHash", err)
+	}
+	if _, _, err := m.Verify("x", "$scrypt$ln=15,r=8,p=1$c2FsdA$aGFzaA"); !errors.Is(err, ErrUnknownAlgorithm) {
+		t.Errorf("scrypt hash: got %v, want ErrUnknownAlgorithm", err)
+	}
+}
+
+func TestArgon2idRejectsMalformedHashes(t *testing.T) {
+	const (
+		salt = "c29tZXNhbHRzb21lc2FsdA"                      // 16 bytes
+		key  = "a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2V5a2U" // 32 bytes
+	)
+	hash := func(version, params, salt, key string) string {
+		return "$argon2id$" + version + "$" + params + "$" + salt + "$" + key
+	}
+
+	tests := []struct {
+		name    string
+		encoded string
+	}{
+		{"too few fields", "$argon2id$v=19$m=64,t=1,p=1$" + salt},
+		{"wrong algorithm", "$argon2i$v=19$m=64,t=1,p=1$" + salt + "$" + key},
+		{"wrong version", hash("v=16", "m=64,t=1,p=1", salt, key)},
+		{"garbled parameters", hash("v=19", "m=x,t=1,p=1", salt, key)},
+		{"zero iterations", hash("v=19", "m=64,t=0,p=1", salt, key)},
+		{"too many iterations", hash("v=19", "m=64,t=1000,p=1", salt, key)},
+		{"zero parallelism", hash("v=19", "m=64,t=1,p=0", salt, key)},
+		{"memory below 8 per lane", hash("v=19", "m=31,t=1,p=4", salt, key)},
+		{"too much memory", hash("v=19", "m=4294967295,t=1,p=1", salt, key)},
+		{"memory overflows", hash("v=19", "m=99999999999,t=1,p=1", salt, key)},
+		{"salt not base64", hash("v=19", "m=64,t=1,p=1", "!!!", key)},
+		{"salt too short", hash("v=19", "m=64,t=1,p=1", "c2FsdA", key)},
+		{"salt too long", hash("v=19", "m=64,t=1,p=1", strings.Repeat("c2FsdHNhbHRz", 10), key)},
+		{"key not base64", hash("v=19", "m=64,t=1,p=1", salt, "!!!")},
+		{"empty key", hash("v=19", "m=64,t=1,p=1", salt, "")},
+		{"key too short", hash("v=19", "m=64,t=1,p=1", salt, "a2V5")},
+		{"key too long", hash("v=19", "m=64,t=1,p=1", salt, strings.Repeat("a2V5a2V5", 20))},
+	}
+
+	a := NewArgon2id(testParams)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if ok, err := a.Verify("x", tt.encoded); ok || !errors.Is(err, ErrInvalidHash) {
+				t.Errorf("got ok=%v err=%v, want ErrInvalidHash", ok, err)
+			}
+			if !a.NeedsRehash(tt.encoded) {
+				t.Error("malformed hash does not need a rehash")
+			}
+		})
+	}
+
+	// The same fields within bounds decode fine
+	if _, err := a.Verify("x", hash("v=19", "m=64,t=1,p=1", salt, key)); err != nil {
+		t.Errorf("well-formed hash: %v", err)
+	}
+}
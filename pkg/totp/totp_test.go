@@ -0,0 +1,128 @@
+package totp
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the ASCII key "12345678901234567890" used by the RFC test vectors.
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestHOTPVectors(t *testing.T) {
+	// RFC 4226 Appendix D
+	want := []string{"755224", "287082", "359152", "969429", "338314", "254676", "287922", "162583", "399871", "520489"}
+	for counter, code := range want {
+		if got := generate([]byte("12345678901234567890"), int64(counter)); got != code {
+			t.Errorf("counter %d: got %s, want %s", counter, got, code)
+		}
+	}
+}
+
+func TestTOTPVectors(t *testing.T) {
+	// RFC 6238 Appendix B (SHA1), truncated to our 6 digits
+	tests := []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+	for _, tt := range tests {
+		got, err := Code(rfcSecret, time.Unix(tt.unix, 0))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("t=%d: got %s, want %s", tt.unix, got, tt.want)
+		}
+	}
+}
+
+func TestValidateSkew(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	code := func(offset time.Duration) string {
+		c, err := Code(rfcSecret, now.Add(offset))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return c
+	}
+
+	tests := []struct {
+		name   string
+		offset time.Duration
+		skew   int
+		ok     bool
+	}{
+		{"current step", 0, 0, true},
+		{"previous step without skew", -Period * time.Second, 0, false},
+		{"previous step", -Period * time.Second, 1, true},
+		{"next step", Period * time.Second, 1, true},
+		{"two steps back", -2 * Period * time.Second, 1, false},
+		{"two steps ahead", 2 * Period * time.Second, 1, false},
+	}
+	for _, tt := range tests {
+		step, ok := Validate(rfcSecret, code(tt.offset), now, tt.skew)
+		if ok != tt.ok {
+			t.Errorf("%s: got %v, want %v", tt.name, ok, tt.ok)
+		}
+		if ok && step != Step(now.Add(tt.offset)) {
+			t.Errorf("%s: matched step %d, want %d", tt.name, step, Step(now.Add(tt.offset)))
+		}
+	}
+}
+
+func TestValidateRejectsMalformedInput(t *testing.T) {
+	now := time.Unix(1234567890, 0)
+	valid, err := Code(rfcSecret, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, code := range []string{"", "00592", "0059240", "89005924", " 05924", "abcdef"} {
+		if _, ok := Validate(rfcSecret, code, now, 1); ok {
+			t.Errorf("code %q accepted", code)
+		}
+	}
+	for _, secret := range []string{"", "not base32!", "1"} {
+		if _, ok := Validate(secret, valid, now, 1); ok {
+			t.Errorf("secret %q accepted", secret)
+		}
+		if _, err := Code(secret, now); !errors.Is(err, ErrInvalidSecret) {
+			t.Errorf("secret %q: got %v, want ErrInvalidSecret", secret, err)
+		}
+	}
+
+	// Secrets are often typed in lowercase groups of four
+	spaced := strings.ToLower("GEZD GNBV GY3T QOJQ GEZD GNBV GY3T QOJQ")
+	if _, ok := Validate(spaced, valid, now, 0); !ok {
+		t.Error("spaced lowercase secret rejected")
+	}
+}
+
+func TestGenerateSecret(t *testing.T) {
+	secret, err := GenerateSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, err := decodeSecret(secret)
+	if err != nil || len(key) != secretSize {
+		t.Fatalf("got %d byte key, err %v; want %d bytes", len(key), err, secretSize)
+	}
+
+	u, err := url.Parse(URL("Example Co", "alice@example.com", secret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := u.Query()
+	if u.Scheme != "otpauth" || u.Host != "totp" || query.Get("secret") != secret || query.Get("issuer") != "Example Co" || query.Get("digits") != "6" {
+		t.Errorf("unexpected provisioning URI %s", u)
+	}
+}
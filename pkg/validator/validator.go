@@ -1,8 +1,10 @@
 package validator
 
 import (
+	"encoding/json"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/go-playground/validator/v10"
 )
@@ -11,6 +13,10 @@ type RegisterRequest struct {
 	Email    string `json:"email" binding:"required,email"`
 	Password string `json:"password" binding:"required,min=8"`
 	Name     string `json:"name" binding:"required"`
+	// InvitationToken joins the inviting organization; required with REGISTRATION_MODE=invite_only
+	InvitationToken string `json:"invitation_token"`
+	// Locale is the language emails are sent in; the request's Accept-Language is used when omitted
+	Locale string `json:"locale" binding:"omitempty,bcp47_language_tag" example:"de"`
 }
 
 type LoginRequest struct {
@@ -31,6 +37,185 @@ type MagicLinkRequest struct {
 	Email string `json:"email" binding:"required,email" example:"user@example.com"`
 }
 
+type MFACodeRequest struct {
+	Code string `json:"code" binding:"required" example:"123456"`
+}
+
+type MFALoginRequest struct {
+	MFAToken string `json:"mfa_token" binding:"required" example:"mfa-challenge-token"`
+	Code     string `json:"code" binding:"required" example:"123456"`
+}
+
+type PasskeyRegisterRequest struct {
+	CeremonyID string          `json:"ceremony_id" binding:"required"`
+	Name       string          `json:"name" example:"MacBook Touch ID"`
+	Credential json.RawMessage `json:"credential" binding:"required" swaggertype:"object"`
+}
+
+type PasskeyLoginRequest struct {
+	CeremonyID string          `json:"ceremony_id" binding:"required"`
+	Credential json.RawMessage `json:"credential" binding:"required" swaggertype:"object"`
+}
+
+type RenamePasskeyRequest struct {
+	Name string `json:"name" binding:"required,max=100" example:"YubiKey"`
+}
+
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required" example:"refresh-token-123"`
+}
+
+type VerifyEmailRequest struct {
+	Token string `json:"token" binding:"required" example:"verification-token-123"`
+}
+
+type ResendVerificationRequest struct {
+	Email string `json:"email" binding:"required,email" example:"user@example.com"`
+}
+
+type UnlockAccountRequest struct {
+	Token string `json:"token" binding:"required" example:"unlock-token-123"`
+}
+
+// OAuthAuthorizeRequest carries the parameters of an OAuth 2.0 authorization
+// request, as query parameters or in the consent decision body.
+type OAuthAuthorizeRequest struct {
+	ClientID            string `form:"client_id" json:"client_id"`
+	RedirectURI         string `form:"redirect_uri" json:"redirect_uri"`
+	ResponseType        string `form:"response_type" json:"response_type" example:"code"`
+	Scope               string `form:"scope" json:"scope" example:"openid email profile"`
+	State               string `form:"state" json:"state"`
+	Nonce               string `form:"nonce" json:"nonce"`
+	CodeChallenge       string `form:"code_challenge" json:"code_challenge"`
+	CodeChallengeMethod string `form:"code_challenge_method" json:"code_challenge_method" example:"S256"`
+}
+
+type OAuthConsentRequest struct {
+	OAuthAuthorizeRequest
+	Approve bool `json:"approve"`
+}
+
+type OAuthTokenRequest struct {
+	GrantType    string `form:"grant_type" binding:"required" example:"authorization_code"`
+	Code         string `form:"code"`
+	RedirectURI  string `form:"redirect_uri"`
+	CodeVerifier string `form:"code_verifier"`
+	RefreshToken string `form:"refresh_token"`
+	Scope        string `form:"scope"`
+	ClientID     string `form:"client_id"`
+	ClientSecret string `form:"client_secret"`
+}
+
+type RegisterOAuthClientRequest struct {
+	Name         string   `json:"name" binding:"required,max=100" example:"My App"`
+	RedirectURIs []string `json:"redirect_uris" example:"https://app.example.com/callback"`
+	GrantTypes   []string `json:"grant_types" binding:"required,min=1" example:"authorization_code,refresh_token"`
+	Scopes       []string `json:"scopes" example:"openid,email,profile,offline_access"`
+	Public       bool     `json:"public"`
+	FirstParty   bool     `json:"first_party"`
+}
+
+type CreatePersonalAccessTokenRequest struct {
+	Name   string   `json:"name" binding:"required,max=100" example:"CI deploy"`
+	Scopes []string `json:"scopes" binding:"required,min=1" example:"profile:read,sessions:read"`
+	// ExpiresInDays of 0 creates a token that never expires, if the server allows it
+	ExpiresInDays int `json:"expires_in_days" binding:"min=0" example:"90"`
+}
+
+type CreateRoleRequest struct {
+	Name        string   `json:"name" binding:"required" example:"support"`
+	Description string   `json:"description" binding:"max=200" example:"Helps users with their accounts"`
+	Permissions []string `json:"permissions" binding:"required,min=1" example:"users:read,users:write"`
+}
+
+type AssignRoleRequest struct {
+	RoleID string `json:"role_id" binding:"required,uuid" example:"123e4567-e89b-12d3-a456-426614174000"`
+}
+
+type CreateOrganizationRequest struct {
+	Name string `json:"name" binding:"required,max=100" example:"Acme Inc"`
+	// Slug defaults to one derived from the name
+	Slug string `json:"slug" binding:"max=50" example:"acme"`
+}
+
+type SwitchOrganizationRequest struct {
+	OrganizationID string `json:"organization_id" binding:"required,uuid" example:"123e4567-e89b-12d3-a456-426614174000"`
+}
+
+type CreateInvitationRequest struct {
+	Email string `json:"email" binding:"required,email" example:"user@example.com"`
+	Role  string `json:"role" binding:"required,oneof=admin member" example:"member"`
+}
+
+type AcceptInvitationRequest struct {
+	Token string `json:"token" binding:"required" example:"invitation-token-123"`
+}
+
+type ListUsersRequest struct {
+	Search   string `form:"q" example:"john"`
+	Status   string `form:"status" binding:"omitempty,oneof=active disabled locked" example:"active"`
+	Verified *bool  `form:"verified" example:"true"`
+	Page     int    `form:"page" binding:"omitempty,min=1" example:"1"`
+	PerPage  int    `form:"per_page" binding:"omitempty,min=1,max=100" example:"20"`
+}
+
+type ImpersonateUserRequest struct {
+	// Reason is kept in the audit log
+	Reason string `json:"reason" binding:"required,max=500" example:"Reproducing ticket #1234"`
+}
+
+type ListActivityRequest struct {
+	Page    int `form:"page" binding:"omitempty,min=1" example:"1"`
+	PerPage int `form:"per_page" binding:"omitempty,min=1,max=200" example:"50"`
+}
+
+type ListAuditEventsRequest struct {
+	// Types may be repeated to match any of several event types
+	Types    []string   `form:"type" example:"login"`
+	UserID   string     `form:"user_id" binding:"omitempty,uuid" example:"123e4567-e89b-12d3-a456-426614174000"`
+	ActorID  string     `form:"actor_id" binding:"omitempty,uuid" example:"123e4567-e89b-12d3-a456-426614174000"`
+	TargetID string     `form:"target_id" binding:"omitempty,uuid" example:"123e4567-e89b-12d3-a456-426614174000"`
+	Outcome  string     `form:"outcome" binding:"omitempty,oneof=success failure" example:"failure"`
+	From     *time.Time `form:"from" time_format:"2006-01-02T15:04:05Z07:00" example:"2024-01-01T00:00:00Z"`
+	To       *time.Time `form:"to" time_format:"2006-01-02T15:04:05Z07:00" example:"2024-02-01T00:00:00Z"`
+	Page     int        `form:"page" binding:"omitempty,min=1" example:"1"`
+	PerPage  int        `form:"per_page" binding:"omitempty,min=1,max=200" example:"50"`
+}
+
+type CreateWebhookRequest struct {
+	URL string `json:"url" binding:"required,url,max=2048" example:"https://billing.example.com/webhooks/auth"`
+	// Secret is generated when omitted
+	Secret      string   `json:"secret" binding:"omitempty,min=16,max=256" example:"whsec_0123456789abcdef"`
+	Events      []string `json:"events" binding:"required,min=1" example:"user.registered,user.deleted"`
+	Description string   `json:"description" binding:"max=500" example:"Billing system"`
+}
+
+type UpdateWebhookRequest struct {
+	URL string `json:"url" binding:"required,url,max=2048" example:"https://billing.example.com/webhooks/auth"`
+	// Secret replaces the signing secret when given
+	Secret      string   `json:"secret" binding:"omitempty,min=16,max=256" example:"whsec_0123456789abcdef"`
+	Events      []string `json:"events" binding:"required,min=1" example:"user.registered,user.deleted"`
+	Description string   `json:"description" binding:"max=500" example:"Billing system"`
+	Active      *bool    `json:"active" binding:"required" example:"true"`
+}
+
+type ListWebhookDeliveriesRequest struct {
+	Status  string `form:"status" binding:"omitempty,oneof=pending succeeded failed" example:"failed"`
+	Page    int    `form:"page" binding:"omitempty,min=1" example:"1"`
+	PerPage int    `form:"per_page" binding:"omitempty,min=1,max=100" example:"20"`
+}
+
+type UpdateLocaleRequest struct {
+	// Locale is a BCP 47 language tag; empty follows the request's Accept-Language
+	Locale string `json:"locale" binding:"omitempty,bcp47_language_tag" example:"de"`
+}
+
+type ListEmailMessagesRequest struct {
+	Status  string `form:"status" binding:"omitempty,oneof=pending sent dead" example:"dead"`
+	Page    int    `form:"page" binding:"omitempty,min=1" example:"1"`
+	PerPage int    `form:"per_page" binding:"omitempty,min=1,max=100" example:"20"`
+}
+
 type Validator struct {
 	validate *validator.Validate
 }